@@ -0,0 +1,127 @@
+// Package cmd provides shared Azure connection flags and client construction.
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/haepapa/getblobz/internal/azure"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// flagBinding maps a command-line flag to its configuration key.
+type flagBinding struct {
+	flag string
+	key  string
+}
+
+// azureFlagBindings lists the Azure connection flags registered by addAzureFlags.
+var azureFlagBindings = []flagBinding{
+	{"connection-string", "azure.connection_string"},
+	{"account-name", "azure.account_name"},
+	{"account-key", "azure.account_key"},
+	{"use-managed-identity", "azure.use_managed_identity"},
+	{"tenant-id", "azure.tenant_id"},
+	{"client-id", "azure.client_id"},
+	{"client-secret", "azure.client_secret"},
+	{"use-azure-cli", "azure.use_azure_cli"},
+	{"anonymous", "azure.anonymous"},
+	{"proxy-url", "azure.proxy_url"},
+	{"blob-endpoint", "azure.blob_endpoint"},
+	{"endpoint-suffix", "azure.endpoint_suffix"},
+}
+
+// addAzureFlags registers the Azure authentication and connection flags on cmd.
+func addAzureFlags(cmd *cobra.Command) {
+	cmd.Flags().String("connection-string", "", "Azure Storage connection string")
+	cmd.Flags().String("account-name", "", "Storage account name")
+	cmd.Flags().String("account-key", "", "Storage account key")
+	cmd.Flags().Bool("use-managed-identity", false, "use Azure Managed Identity")
+	cmd.Flags().String("tenant-id", "", "Azure AD tenant ID")
+	cmd.Flags().String("client-id", "", "Azure AD client ID")
+	cmd.Flags().String("client-secret", "", "Azure AD client secret")
+	cmd.Flags().Bool("use-azure-cli", false, "use Azure CLI credentials")
+	cmd.Flags().Bool("anonymous", false, "access a public container without credentials")
+	cmd.Flags().String("proxy-url", "", "HTTP proxy for Azure traffic (defaults to HTTPS_PROXY)")
+	cmd.Flags().String("blob-endpoint", "", "custom blob service URL (overrides endpoint suffix)")
+	cmd.Flags().String("endpoint-suffix", "core.windows.net", "storage endpoint suffix (e.g., core.usgovcloudapi.net)")
+}
+
+// bindFlags binds the given flags of cmd to their configuration keys.
+// Viper keeps a single flag per key, so commands sharing flags bind them
+// when they run rather than at init time.
+func bindFlags(cmd *cobra.Command, bindings []flagBinding) error {
+	for _, b := range bindings {
+		if err := viper.BindPFlag(b.key, cmd.Flags().Lookup(b.flag)); err != nil {
+			return fmt.Errorf("failed to bind %s: %w", b.flag, err)
+		}
+	}
+	return nil
+}
+
+// newAzureClient creates an Azure client from the loaded configuration.
+func newAzureClient() (*azure.Client, error) {
+	azClient, err := azure.CreateClient(&cfg.Azure)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Azure client: %w", err)
+	}
+	client := azure.NewClient(azClient)
+	client.SetMaxConcurrentRequests(cfg.Performance.MaxConcurrentRequests)
+	return client, nil
+}
+
+// containerCompletionTimeout bounds the account listing done while the shell
+// waits for completions.
+const containerCompletionTimeout = 10 * time.Second
+
+// registerContainerCompletion completes the --container flag of cmd with the
+// containers in the configured storage account.
+func registerContainerCompletion(cmd *cobra.Command) {
+	if err := cmd.RegisterFlagCompletionFunc("container", completeContainers); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to register container completion: %v\n", err)
+	}
+}
+
+// completeContainers lists the containers whose names start with toComplete,
+// using the Azure flags already given on the command line and the loaded
+// configuration. Nothing is offered if the account cannot be listed.
+func completeContainers(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if err := bindFlags(cmd, azureFlagBindings); err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	if err := unmarshalConfig(); err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	if err := cfg.ValidateAzure(); err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	client, err := newAzureClient()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(ctx, containerCompletionTimeout)
+	defer cancel()
+
+	containers, err := client.ListContainers(ctx)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var names []string
+	for _, c := range containers {
+		if strings.HasPrefix(c.Name, toComplete) {
+			names = append(names, c.Name)
+		}
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
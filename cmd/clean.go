@@ -0,0 +1,103 @@
+// Package cmd provides the clean command for removing orphaned local files.
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/haepapa/getblobz/internal/storage"
+	"github.com/haepapa/getblobz/internal/sync"
+	"github.com/spf13/cobra"
+)
+
+// cleanCmd represents the clean command.
+var cleanCmd = &cobra.Command{
+	Use:   "clean",
+	Short: "Remove local files that are not tracked in the state database",
+	Long: `Clean walks the output path and finds files that no blob in the state
+database points to, such as leftover .tmp files from interrupted downloads,
+manually copied files, or files from a since-narrowed prefix.
+
+By default the files are only listed. Use --force to delete them. The state
+database and its lock file are never removed, even if they live under the
+output path. Clean refuses to run while a sync holds the state database, since
+its partial .tmp files would be removed.
+
+Examples:
+  # List orphaned files
+  getblobz clean --output-path ./downloads
+
+  # Delete them
+  getblobz clean --output-path ./downloads --force`,
+	RunE: runClean,
+}
+
+func init() {
+	rootCmd.AddCommand(cleanCmd)
+
+	cleanCmd.Flags().String("output-path", "./data", "local destination path to clean")
+	cleanCmd.Flags().String("state-db", "./.sync-state.db", "path to state database")
+	cleanCmd.Flags().Bool("force", false, "delete orphaned files instead of listing them")
+}
+
+func runClean(cmd *cobra.Command, args []string) error {
+	outputPath, _ := cmd.Flags().GetString("output-path")
+	dbPath, _ := cmd.Flags().GetString("state-db")
+	force, _ := cmd.Flags().GetBool("force")
+
+	lock, err := storage.AcquireLock(dbPath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = lock.Release() }()
+
+	db, err := storage.OpenWithBusyTimeout(dbPath, cfg.State.BusyTimeout())
+	if err != nil {
+		return fmt.Errorf("failed to open state database: %w", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	tracked, err := db.GetTrackedLocalPaths()
+	if err != nil {
+		return fmt.Errorf("failed to get tracked files: %w", err)
+	}
+
+	orphans, err := sync.FindOrphans(outputPath, tracked, sync.StateFiles(dbPath))
+	if err != nil {
+		return fmt.Errorf("failed to scan output path: %w", err)
+	}
+
+	var totalBytes int64
+	var failed int
+	for _, orphan := range orphans {
+		kind := "untracked"
+		if orphan.Temp {
+			kind = "stale tmp"
+		}
+
+		if !force {
+			fmt.Printf("  • %s (%s, %d bytes)\n", orphan.Path, kind, orphan.Size)
+			totalBytes += orphan.Size
+			continue
+		}
+
+		if err := os.Remove(orphan.Path); err != nil {
+			fmt.Printf("  • %s: failed to delete: %v\n", orphan.Path, err)
+			failed++
+			continue
+		}
+		fmt.Printf("  • %s (%s) deleted\n", orphan.Path, kind)
+		totalBytes += orphan.Size
+	}
+
+	if !force {
+		fmt.Printf("\nFound %d orphaned files (%d bytes). Run with --force to delete them.\n", len(orphans), totalBytes)
+		return nil
+	}
+
+	fmt.Printf("\nDeleted %d orphaned files (%d bytes).\n", len(orphans)-failed, totalBytes)
+	if failed > 0 {
+		return fmt.Errorf("%d files could not be deleted", failed)
+	}
+	return nil
+}
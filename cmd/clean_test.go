@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/haepapa/getblobz/internal/config"
+	"github.com/haepapa/getblobz/internal/storage"
+)
+
+func TestRunClean_LockedAndLockFileProtected(t *testing.T) {
+	out := t.TempDir()
+	dbPath := filepath.Join(out, "state.db")
+
+	db, err := storage.Open(dbPath)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	_ = db.Close()
+
+	prevCfg := cfg
+	cfg = config.Default()
+	t.Cleanup(func() {
+		cfg = prevCfg
+		_ = cleanCmd.Flags().Set("output-path", "./data")
+		_ = cleanCmd.Flags().Set("state-db", "./.sync-state.db")
+		_ = cleanCmd.Flags().Set("force", "false")
+	})
+	for name, value := range map[string]string{"output-path": out, "state-db": dbPath, "force": "true"} {
+		if err := cleanCmd.Flags().Set(name, value); err != nil {
+			t.Fatalf("failed to set --%s: %v", name, err)
+		}
+	}
+
+	lock, err := storage.AcquireLock(dbPath)
+	if err != nil {
+		t.Fatalf("failed to acquire lock: %v", err)
+	}
+	if err := runClean(cleanCmd, nil); !errors.Is(err, storage.ErrLocked) {
+		t.Errorf("clean while locked: err = %v, want ErrLocked", err)
+	}
+	if err := lock.Release(); err != nil {
+		t.Fatalf("failed to release lock: %v", err)
+	}
+
+	if err := runClean(cleanCmd, nil); err != nil {
+		t.Fatalf("clean failed: %v", err)
+	}
+	if _, err := os.Stat(storage.LockPath(dbPath)); err != nil {
+		t.Errorf("expected the lock file to survive clean: %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+// Package cmd provides the completion command for generating shell completion scripts.
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// completionCmd represents the completion command.
+var completionCmd = &cobra.Command{
+	Use:   "completion [bash|zsh|fish|powershell]",
+	Short: "Generate a shell completion script",
+	Long: `Completion writes a script to standard output that enables completion of
+getblobz commands and flags in your shell. The --container flag completes
+the names of the containers in the configured storage account.
+
+Bash (requires the bash-completion package):
+  # Current session
+  source <(getblobz completion bash)
+
+  # Every session, on Linux
+  getblobz completion bash > /etc/bash_completion.d/getblobz
+
+  # Every session, on macOS with Homebrew
+  getblobz completion bash > $(brew --prefix)/etc/bash_completion.d/getblobz
+
+Zsh:
+  # Enable completion once, if it is not already
+  echo "autoload -U compinit; compinit" >> ~/.zshrc
+
+  # Every session
+  getblobz completion zsh > "${fpath[1]}/_getblobz"
+
+Fish:
+  # Current session
+  getblobz completion fish | source
+
+  # Every session
+  getblobz completion fish > ~/.config/fish/completions/getblobz.fish
+
+PowerShell:
+  # Current session
+  getblobz completion powershell | Out-String | Invoke-Expression
+
+  # Every session: add the line above to your PowerShell profile`,
+	DisableFlagsInUseLine: true,
+	ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
+	Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	RunE:                  runCompletion,
+}
+
+func init() {
+	rootCmd.AddCommand(completionCmd)
+}
+
+func runCompletion(cmd *cobra.Command, args []string) error {
+	var err error
+	switch args[0] {
+	case "bash":
+		err = rootCmd.GenBashCompletionV2(os.Stdout, true)
+	case "zsh":
+		err = rootCmd.GenZshCompletion(os.Stdout)
+	case "fish":
+		err = rootCmd.GenFishCompletion(os.Stdout, true)
+	case "powershell":
+		err = rootCmd.GenPowerShellCompletionWithDesc(os.Stdout)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to generate %s completion: %w", args[0], err)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+// Package cmd provides the config command for checking and showing the effective configuration.
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/haepapa/getblobz/internal/config"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// configCmd represents the config command.
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Check or show the effective configuration",
+	Long: `Config works with the effective configuration: the defaults, overridden by
+the config file, then environment variables, then flags. Neither
+subcommand changes anything.`,
+}
+
+// configValidateCmd represents the config validate command.
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check that the effective configuration is valid",
+	Long: `Validate loads the effective configuration and runs the same checks as
+sync, reporting the problem found if it is invalid. It does not contact Azure;
+use doctor to also check credentials and connectivity.
+
+Examples:
+  # Check the discovered config file
+  getblobz config validate
+
+  # Check a specific file
+  getblobz config validate --config ./getblobz.yaml`,
+	RunE: runConfigValidate,
+	// The problem is already reported, so usage would only add noise.
+	SilenceUsage: true,
+}
+
+// configShowCmd represents the config show command.
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the effective configuration with secrets redacted",
+	Long: `Show prints the effective configuration as YAML, after precedence between
+defaults, the config file, environment variables and flags is applied.
+Account keys, client secrets, connection strings, SAS tokens and webhook
+URLs are masked.
+
+Examples:
+  # Show the settings a sync would use
+  getblobz config show
+
+  # Show them for a specific file and container
+  getblobz config show --config ./getblobz.yaml --container mycontainer`,
+	RunE: runConfigShow,
+}
+
+// configFlagBindings lists the config flags that share configuration keys with sync.
+var configFlagBindings = []flagBinding{
+	{"container", "sync.container"},
+	{"output-path", "sync.output_path"},
+	{"prefix", "sync.prefix"},
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configValidateCmd, configShowCmd)
+
+	for _, cmd := range []*cobra.Command{configValidateCmd, configShowCmd} {
+		cmd.Flags().String("container", "", "Azure container name")
+		cmd.Flags().String("output-path", "./data", "local destination path")
+		cmd.Flags().String("prefix", "", "only sync blobs with this prefix")
+		addAzureFlags(cmd)
+	}
+}
+
+// loadEffectiveConfig binds the flags of cmd and decodes the merged settings.
+func loadEffectiveConfig(cmd *cobra.Command) error {
+	if err := bindFlags(cmd, append(azureFlagBindings, configFlagBindings...)); err != nil {
+		return err
+	}
+	if err := unmarshalConfig(); err != nil {
+		return fmt.Errorf("failed to parse configuration: %w", err)
+	}
+	return nil
+}
+
+func runConfigValidate(cmd *cobra.Command, args []string) error {
+	if err := loadEffectiveConfig(cmd); err != nil {
+		return err
+	}
+	return validateConfig(os.Stdout, cfg, viper.ConfigFileUsed())
+}
+
+func runConfigShow(cmd *cobra.Command, args []string) error {
+	if err := loadEffectiveConfig(cmd); err != nil {
+		return err
+	}
+	return showConfig(os.Stdout, cfg)
+}
+
+// validateConfig validates c, loaded from file, and reports the outcome to w.
+func validateConfig(w io.Writer, c *config.Config, file string) error {
+	source := "defaults, environment and flags only"
+	if file != "" {
+		source = file
+	}
+	fmt.Fprintf(w, "Config source: %s\n", source)
+
+	if err := c.Validate(); err != nil {
+		fmt.Fprintf(w, "[FAIL] Configuration is invalid: %v\n", err)
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	fmt.Fprintln(w, "[PASS] Configuration is valid")
+	return nil
+}
+
+// showConfig writes c to w as YAML with its secrets redacted.
+func showConfig(w io.Writer, c *config.Config) error {
+	redacted := c.Redact()
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(redacted.Settings()); err != nil {
+		return fmt.Errorf("failed to write configuration: %w", err)
+	}
+	return enc.Close()
+}
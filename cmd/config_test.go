@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/haepapa/getblobz/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+func TestValidateConfig(t *testing.T) {
+	valid := config.Default()
+	valid.Sync.Container = "backups"
+	valid.Azure.ConnectionString = "DefaultEndpointsProtocol=https;AccountName=acct;AccountKey=a2V5;EndpointSuffix=core.windows.net"
+
+	invalid := config.Default()
+	*invalid = *valid
+	invalid.Sync.Workers = 0
+
+	tests := []struct {
+		name    string
+		cfg     *config.Config
+		wantErr bool
+		wantOut string
+	}{
+		{"valid", valid, false, "[PASS] Configuration is valid"},
+		{"invalid", invalid, true, "[FAIL] Configuration is invalid: workers must be between 1 and 100"},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		err := validateConfig(&out, tt.cfg, "getblobz.yaml")
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+		if !strings.Contains(out.String(), "Config source: getblobz.yaml") {
+			t.Errorf("%s: output does not name the config file:\n%s", tt.name, out.String())
+		}
+		if !strings.Contains(out.String(), tt.wantOut) {
+			t.Errorf("%s: output = %q, want it to contain %q", tt.name, out.String(), tt.wantOut)
+		}
+	}
+}
+
+func TestShowConfig_RedactsSecrets(t *testing.T) {
+	c := config.Default()
+	c.Sync.Container = "backups"
+	c.Azure.AccountName = "acct"
+	c.Azure.AccountKey = "c2VjcmV0LWtleQ=="
+	c.Azure.ClientSecret = "client-secret-value"
+	c.Notifications.WebhookURL = "https://hooks.example.com/services/T000/B000/token-value"
+
+	var out bytes.Buffer
+	if err := showConfig(&out, c); err != nil {
+		t.Fatalf("showConfig failed: %v", err)
+	}
+
+	for _, secret := range []string{c.Azure.AccountKey, c.Azure.ClientSecret, "token-value"} {
+		if strings.Contains(out.String(), secret) {
+			t.Errorf("output contains secret %q:\n%s", secret, out.String())
+		}
+	}
+
+	var settings struct {
+		Azure struct {
+			AccountName string `yaml:"account_name"`
+			AccountKey  string `yaml:"account_key"`
+		} `yaml:"azure"`
+		Sync struct {
+			Container string `yaml:"container"`
+			Workers   int    `yaml:"workers"`
+		} `yaml:"sync"`
+		Hooks struct {
+			Timeout string `yaml:"timeout"`
+		} `yaml:"hooks"`
+	}
+	if err := yaml.Unmarshal(out.Bytes(), &settings); err != nil {
+		t.Fatalf("output is not YAML: %v", err)
+	}
+	if settings.Azure.AccountName != "acct" || settings.Azure.AccountKey != "***" {
+		t.Errorf("azure = %+v, want account acct with a masked key", settings.Azure)
+	}
+	if settings.Sync.Container != "backups" || settings.Sync.Workers != c.Sync.Workers {
+		t.Errorf("sync = %+v, want container backups and %d workers", settings.Sync, c.Sync.Workers)
+	}
+	if settings.Hooks.Timeout != "5m0s" {
+		t.Errorf("hooks timeout = %q, want 5m0s", settings.Hooks.Timeout)
+	}
+}
@@ -0,0 +1,71 @@
+// Package cmd provides the containers command for listing storage containers.
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// containersCmd represents the containers command.
+var containersCmd = &cobra.Command{
+	Use:   "containers",
+	Short: "List containers in the storage account",
+	Long: `Containers lists the containers available in an Azure Storage account.
+
+It uses the same authentication settings as sync, which makes it useful for
+confirming the exact container name before running a sync.
+
+Examples:
+  # List containers using a connection string
+  getblobz containers --connection-string "..."
+
+  # List containers using Azure CLI credentials
+  getblobz containers --account-name myaccount --use-azure-cli`,
+	RunE: runContainers,
+}
+
+func init() {
+	rootCmd.AddCommand(containersCmd)
+
+	addAzureFlags(containersCmd)
+}
+
+func runContainers(cmd *cobra.Command, args []string) error {
+	if err := bindFlags(cmd, azureFlagBindings); err != nil {
+		return err
+	}
+
+	if err := unmarshalConfig(); err != nil {
+		return fmt.Errorf("failed to parse configuration: %w", err)
+	}
+
+	if err := cfg.ValidateAzure(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	client, err := newAzureClient()
+	if err != nil {
+		return err
+	}
+
+	containers, err := client.ListContainers(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	if len(containers) == 0 {
+		fmt.Println("No containers found")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tLAST MODIFIED")
+	for _, c := range containers {
+		fmt.Fprintf(w, "%s\t%s\n", c.Name, c.LastModified.Format("2006-01-02 15:04:05"))
+	}
+
+	return w.Flush()
+}
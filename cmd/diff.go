@@ -0,0 +1,119 @@
+// Package cmd provides the diff command for comparing a container with its local copy.
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/haepapa/getblobz/internal/storage"
+	"github.com/haepapa/getblobz/internal/sync"
+	"github.com/spf13/cobra"
+)
+
+// diffCmd represents the diff command.
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Show how a container differs from its local copy",
+	Long: `Diff lists the blobs in a container and compares them with the state
+database and the local files it records. It reports blobs that exist only
+remotely or whose local copy is missing (a sync would download them), synced
+blobs that were deleted remotely (mirroring would delete them), and synced
+blobs whose ETag or size has changed.
+
+Diff is read-only: nothing is downloaded, deleted or recorded.
+
+Examples:
+  # Show what the next sync would change
+  getblobz diff --container mycontainer --connection-string "..."
+
+  # JSON output for tooling
+  getblobz diff --container mycontainer --connection-string "..." --json`,
+	RunE: runDiff,
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+
+	diffCmd.Flags().String("container", "", "Azure container name (required)")
+	diffCmd.Flags().String("prefix", "", "only compare blobs with this prefix")
+	addAzureFlags(diffCmd)
+	registerContainerCompletion(diffCmd)
+	diffCmd.Flags().String("state-db", "./.sync-state.db", "path to state database")
+	diffCmd.Flags().Bool("json", false, "write the differences as JSON")
+
+	if err := diffCmd.MarkFlagRequired("container"); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to mark required flag: %v\n", err)
+	}
+}
+
+func runDiff(cmd *cobra.Command, args []string) error {
+	dbPath, _ := cmd.Flags().GetString("state-db")
+	asJSON, _ := cmd.Flags().GetBool("json")
+
+	if err := bindFlags(cmd, append(azureFlagBindings, listFlagBindings...)); err != nil {
+		return err
+	}
+
+	if err := unmarshalConfig(); err != nil {
+		return fmt.Errorf("failed to parse configuration: %w", err)
+	}
+
+	if err := cfg.ValidateAzure(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	client, err := newAzureClient()
+	if err != nil {
+		return err
+	}
+
+	db, err := storage.OpenReadOnly(dbPath, cfg.State.BusyTimeout())
+	if err != nil {
+		return fmt.Errorf("failed to open state database: %w", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	diff, err := sync.ComputeDiff(cmd.Context(), client, db.WithContainer(cfg.Sync.Container),
+		cfg.Sync.Container, cfg.Sync.Prefix, listPageSize)
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(diff)
+	}
+
+	printDiff(diff)
+	return nil
+}
+
+// printDiff writes the human-readable list of differences.
+func printDiff(diff *sync.Diff) {
+	if len(diff.RemoteOnly) > 0 {
+		fmt.Printf("Remote only (%d):\n", len(diff.RemoteOnly))
+		for _, e := range diff.RemoteOnly {
+			fmt.Printf("  + %s (%d bytes, %s)\n", e.Blob, e.RemoteSize, e.Reason)
+		}
+		fmt.Println()
+	}
+	if len(diff.Changed) > 0 {
+		fmt.Printf("Changed (%d):\n", len(diff.Changed))
+		for _, e := range diff.Changed {
+			fmt.Printf("  ~ %s (%d bytes remote, %d bytes local, %s)\n", e.Blob, e.RemoteSize, e.LocalSize, e.Reason)
+		}
+		fmt.Println()
+	}
+	if len(diff.LocalOnly) > 0 {
+		fmt.Printf("Local only (%d):\n", len(diff.LocalOnly))
+		for _, e := range diff.LocalOnly {
+			fmt.Printf("  - %s (%s, %s)\n", e.Blob, e.LocalPath, e.Reason)
+		}
+		fmt.Println()
+	}
+
+	fmt.Printf("%d remote only, %d changed, %d local only\n",
+		len(diff.RemoteOnly), len(diff.Changed), len(diff.LocalOnly))
+}
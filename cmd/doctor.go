@@ -0,0 +1,185 @@
+// Package cmd provides the doctor command for diagnosing configuration and connectivity.
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/haepapa/getblobz/internal/azure"
+	"github.com/haepapa/getblobz/internal/sync"
+	"github.com/spf13/cobra"
+)
+
+// doctorCmd represents the doctor command.
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Diagnose configuration, credentials, and connectivity",
+	Long: `Doctor runs a series of checks that a sync depends on and reports each one
+as passed or failed:
+  - Configuration is valid
+  - The Azure client can be created from the credentials
+  - The container exists and blobs can be listed
+  - The output path is writable
+  - Free disk space is below the stop threshold
+
+It exits with an error if any critical check fails. Run it first when a sync
+fails with authentication or connectivity errors.
+
+Examples:
+  # Check a connection string setup
+  getblobz doctor --container mycontainer --connection-string "..."
+
+  # Check the settings in a config file
+  getblobz doctor --config ./getblobz.yaml`,
+	RunE: runDoctor,
+	// Failed checks are already reported, so usage would only add noise.
+	SilenceUsage: true,
+}
+
+// doctorFlagBindings lists the doctor flags that share configuration keys with sync.
+var doctorFlagBindings = []flagBinding{
+	{"container", "sync.container"},
+	{"output-path", "sync.output_path"},
+	{"prefix", "sync.prefix"},
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+
+	doctorCmd.Flags().String("container", "", "Azure container name")
+	doctorCmd.Flags().String("output-path", "./data", "local destination path")
+	doctorCmd.Flags().String("prefix", "", "blob prefix to check read access for")
+	addAzureFlags(doctorCmd)
+	registerContainerCompletion(doctorCmd)
+}
+
+// doctorReport prints check results and counts critical failures.
+type doctorReport struct {
+	failures int
+}
+
+// pass reports a passed check.
+func (r *doctorReport) pass(check string) {
+	fmt.Printf("  [PASS] %s\n", check)
+}
+
+// warn reports a non-critical problem.
+func (r *doctorReport) warn(check string, detail string) {
+	fmt.Printf("  [WARN] %s: %s\n", check, detail)
+}
+
+// fail reports a failed critical check.
+func (r *doctorReport) fail(check string, err error) {
+	r.failures++
+	fmt.Printf("  [FAIL] %s: %v\n", check, err)
+}
+
+// skip reports a check that could not run because an earlier check failed.
+func (r *doctorReport) skip(check string) {
+	fmt.Printf("  [SKIP] %s\n", check)
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	if err := bindFlags(cmd, append(azureFlagBindings, doctorFlagBindings...)); err != nil {
+		return err
+	}
+
+	report := &doctorReport{}
+	fmt.Println("Running checks:")
+
+	if err := unmarshalConfig(); err != nil {
+		report.fail("Configuration", err)
+	} else if err := cfg.Validate(); err != nil {
+		report.fail("Configuration", err)
+	} else {
+		report.pass("Configuration")
+	}
+
+	client, err := newAzureClient()
+	if err != nil {
+		report.fail("Azure client", err)
+	} else {
+		report.pass("Azure client")
+	}
+
+	switch {
+	case client == nil || cfg.Sync.Container == "":
+		report.skip("Container exists")
+		report.skip("Container readable")
+	default:
+		doctorCheckContainer(cmd, report, client)
+	}
+
+	outputOK := doctorCheckOutputPath(report)
+
+	if outputOK {
+		doctorCheckDiskSpace(report)
+	} else {
+		report.skip("Disk space")
+	}
+
+	fmt.Println()
+	if report.failures > 0 {
+		return fmt.Errorf("%d critical checks failed", report.failures)
+	}
+
+	fmt.Println("All critical checks passed.")
+	return nil
+}
+
+// doctorCheckContainer checks that the container exists and that blobs can be listed.
+func doctorCheckContainer(cmd *cobra.Command, report *doctorReport, client *azure.Client) {
+	exists, err := client.ContainerExists(cmd.Context(), cfg.Sync.Container)
+	if err != nil {
+		report.fail("Container exists", err)
+		report.skip("Container readable")
+		return
+	}
+	if !exists {
+		report.fail("Container exists", fmt.Errorf("container '%s' not found", cfg.Sync.Container))
+		report.skip("Container readable")
+		return
+	}
+	report.pass("Container exists")
+
+	if _, _, err := client.ListBlobs(cmd.Context(), cfg.Sync.Container, cfg.Sync.Prefix, nil, 1); err != nil {
+		report.fail("Container readable", err)
+		return
+	}
+	report.pass("Container readable")
+}
+
+// doctorCheckOutputPath checks that a file can be created in the output path.
+func doctorCheckOutputPath(report *doctorReport) bool {
+	if err := os.MkdirAll(cfg.Sync.OutputPath, 0755); err != nil {
+		report.fail("Output path writable", err)
+		return false
+	}
+
+	file, err := os.CreateTemp(cfg.Sync.OutputPath, ".getblobz-doctor-*")
+	if err != nil {
+		report.fail("Output path writable", err)
+		return false
+	}
+	_ = file.Close()
+	_ = os.Remove(file.Name())
+
+	report.pass("Output path writable")
+	return true
+}
+
+// doctorCheckDiskSpace checks filesystem usage against the disk thresholds.
+func doctorCheckDiskSpace(report *doctorReport) {
+	usage, err := sync.DiskUsagePercent(filepath.Clean(cfg.Sync.OutputPath))
+	switch {
+	case err != nil:
+		report.fail("Disk space", err)
+	case usage >= cfg.Sync.DiskStopPercent:
+		report.fail("Disk space", fmt.Errorf("usage %d%% >= stop threshold %d%%", usage, cfg.Sync.DiskStopPercent))
+	case usage >= cfg.Sync.DiskWarnPercent:
+		report.warn("Disk space", fmt.Sprintf("usage %d%% >= warn threshold %d%%", usage, cfg.Sync.DiskWarnPercent))
+	default:
+		report.pass("Disk space")
+	}
+}
@@ -0,0 +1,113 @@
+// Package cmd provides the du command for reporting local space usage.
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/haepapa/getblobz/internal/organizer"
+	"github.com/spf13/cobra"
+)
+
+// duCmd represents the du command.
+var duCmd = &cobra.Command{
+	Use:   "du",
+	Short: "Show local space usage by folder, partition or extension",
+	Long: `Du walks the output path and reports how much space its files use,
+grouped by top-level folder, by the partition folder the configured folder
+organization strategy placed them in, or by file extension, followed by a
+total. It only reads the local filesystem.
+
+Examples:
+  # Space used by each top-level folder
+  getblobz du --output-path ./downloads
+
+  # The ten largest date or hash partitions
+  getblobz du --config config.yaml --by partition --top 10
+
+  # Space by file extension, as JSON
+  getblobz du --by extension --json`,
+	RunE: runDu,
+}
+
+// duOutput is the JSON document written by du --json.
+type duOutput struct {
+	Path   string            `json:"path"`
+	By     string            `json:"by"`
+	Groups []organizer.Usage `json:"groups"`
+	Files  int64             `json:"files"`
+	Bytes  int64             `json:"bytes"`
+}
+
+func init() {
+	rootCmd.AddCommand(duCmd)
+
+	duCmd.Flags().String("output-path", "./data", "local destination path to measure")
+	duCmd.Flags().String("by", "folder", "group files by folder, partition or extension")
+	duCmd.Flags().Int("top", 0, "only show the largest N groups (0 = all)")
+	duCmd.Flags().Bool("json", false, "write the usage as JSON")
+}
+
+func runDu(cmd *cobra.Command, args []string) error {
+	by, _ := cmd.Flags().GetString("by")
+	top, _ := cmd.Flags().GetInt("top")
+	asJSON, _ := cmd.Flags().GetBool("json")
+
+	if top < 0 {
+		return fmt.Errorf("--top must not be negative")
+	}
+
+	if err := bindFlags(cmd, []flagBinding{{"output-path", "sync.output_path"}}); err != nil {
+		return err
+	}
+	if err := unmarshalConfig(); err != nil {
+		return fmt.Errorf("failed to parse configuration: %w", err)
+	}
+	outputPath := cfg.Sync.OutputPath
+
+	var key func(string) string
+	switch by {
+	case "folder":
+		key = organizer.TopFolder
+	case "partition":
+		key = organizer.New(&cfg.Sync.FolderOrganization, outputPath).Partition
+	case "extension":
+		key = organizer.FileExtension
+	default:
+		return fmt.Errorf("--by must be folder, partition or extension")
+	}
+
+	usage, err := organizer.DiskUsage(outputPath, key)
+	if err != nil {
+		return fmt.Errorf("failed to scan output path: %w", err)
+	}
+
+	out := duOutput{Path: outputPath, By: by, Groups: usage}
+	for _, u := range usage {
+		out.Files += u.Files
+		out.Bytes += u.Bytes
+	}
+	if top > 0 && len(out.Groups) > top {
+		out.Groups = out.Groups[:top]
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(out)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "BYTES\tFILES\t")
+	for _, u := range out.Groups {
+		fmt.Fprintf(w, "%d\t%d\t  %s\n", u.Bytes, u.Files, u.Key)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Printf("\nTotal: %d files, %d bytes in %d %ss\n", out.Files, out.Bytes, len(usage), by)
+	return nil
+}
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/haepapa/getblobz/internal/storage"
+)
+
+func seedExportDB(t *testing.T) *storage.DB {
+	t.Helper()
+
+	db, err := storage.Open(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	md5 := "rL0Y20zC+Fzt72VPzMSk2A=="
+	synced := time.Date(2024, 3, 4, 5, 6, 7, 0, time.UTC)
+	blobs := []*storage.BlobState{
+		{
+			BlobName:     "data/a.csv",
+			BlobPath:     "data/a.csv",
+			LocalPath:    "/out/data/a.csv",
+			SizeBytes:    42,
+			ContentMD5:   &md5,
+			ContentType:  "text/csv",
+			LastModified: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+			FirstSeenAt:  synced,
+			LastSyncedAt: &synced,
+			Status:       storage.BlobStatusDownloaded,
+		},
+		{
+			BlobName:     "data/b, \"quoted\".csv",
+			BlobPath:     "data/b, \"quoted\".csv",
+			LocalPath:    "/out/data/b.csv",
+			SizeBytes:    7,
+			LastModified: time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC),
+			FirstSeenAt:  synced,
+			Status:       storage.BlobStatusFailed,
+		},
+	}
+	scoped := db.WithContainer("backups")
+	for _, blob := range blobs {
+		if err := scoped.UpsertBlobState(blob); err != nil {
+			t.Fatalf("failed to seed %s: %v", blob.BlobName, err)
+		}
+	}
+	return db
+}
+
+func TestWriteExport_CSV(t *testing.T) {
+	db := seedExportDB(t)
+
+	var buf bytes.Buffer
+	count, err := writeExport(&buf, db, "csv", "")
+	if err != nil {
+		t.Fatalf("writeExport() error = %v", err)
+	}
+	if count != 2 {
+		t.Errorf("count = %d, want 2", count)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to parse CSV: %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("records = %d, want a header and 2 rows", len(records))
+	}
+	if !reflect.DeepEqual(records[0], exportColumns) {
+		t.Errorf("header = %v, want %v", records[0], exportColumns)
+	}
+	want := []string{
+		"backups", "data/a.csv", "/out/data/a.csv", "42", "downloaded",
+		"rL0Y20zC+Fzt72VPzMSk2A==", "text/csv", "2024-01-02T03:04:05Z", "2024-03-04T05:06:07Z",
+	}
+	if !reflect.DeepEqual(records[1], want) {
+		t.Errorf("row = %v, want %v", records[1], want)
+	}
+	if records[2][1] != "data/b, \"quoted\".csv" || records[2][8] != "" {
+		t.Errorf("row = %v, want the quoted name and no last-synced time", records[2])
+	}
+}
+
+func TestWriteExport_JSONByStatus(t *testing.T) {
+	db := seedExportDB(t)
+
+	var buf bytes.Buffer
+	count, err := writeExport(&buf, db, "json", storage.BlobStatusFailed)
+	if err != nil {
+		t.Fatalf("writeExport() error = %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("count = %d, want 1", count)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("lines = %d, want 1: %q", len(lines), buf.String())
+	}
+	var entry exportEntry
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatalf("failed to parse %q: %v", lines[0], err)
+	}
+	if entry.BlobName != "data/b, \"quoted\".csv" || entry.Status != storage.BlobStatusFailed || entry.Container != "backups" {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+	if entry.LastSyncedAt != nil {
+		t.Errorf("LastSyncedAt = %v, want nil", entry.LastSyncedAt)
+	}
+}
@@ -18,35 +18,23 @@ var initCmd = &cobra.Command{
 The generated file can be customised and used with the --config flag
 or placed in one of the auto-discovery locations.
 
+An existing file is never replaced unless --force is given. With --minimal
+only the required settings are written, without the commented examples.
+
 Examples:
   # Generate config in current directory
   getblobz init
 
   # Generate config at specific path
-  getblobz init --config /path/to/config.yaml`,
-	RunE: runInit,
-}
+  getblobz init --config /path/to/config.yaml
 
-func init() {
-	rootCmd.AddCommand(initCmd)
+  # Replace an existing config with a minimal one
+  getblobz init --force --minimal`,
+	RunE: runInit,
 }
 
-func runInit(cmd *cobra.Command, args []string) error {
-	configPath := cfgFile
-	if configPath == "" {
-		configPath = "./getblobz.yaml"
-	}
-
-	if _, err := os.Stat(configPath); err == nil {
-		return fmt.Errorf("config file already exists at %s", configPath)
-	}
-
-	dir := filepath.Dir(configPath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("failed to create directory: %w", err)
-	}
-
-	template := `# getblobz configuration file
+// configTemplate is the fully commented configuration written by init.
+const configTemplate = `# getblobz configuration file
 # Documentation: https://github.com/haepapa/getblobz
 
 azure:
@@ -71,32 +59,87 @@ azure:
   # account_name: "mystorageaccount"
   # use_azure_cli: true
 
+  # Option 6: Anonymous access to a public container
+  # account_name: "mystorageaccount"
+  # anonymous: true
+
+  # Endpoint suffix for sovereign clouds (account name auth only)
+  # endpoint_suffix: "core.windows.net"  # e.g. core.usgovcloudapi.net, core.chinacloudapi.cn
+
+  # Custom blob endpoint URL for Private Link or Azurite (account name or anonymous auth only)
+  # blob_endpoint: "https://mystorageaccount.privatelink.blob.core.windows.net/"
+
+  # HTTP proxy for Azure traffic (defaults to HTTPS_PROXY/NO_PROXY env vars)
+  # proxy_url: "http://proxy.example.com:8080"
+
+  # Azure SDK retry policy (0 = SDK default)
+  retry_policy:
+    max_retries: 0            # Retries per request (-1 disables retries)
+    try_timeout: "0s"         # Timeout for a single attempt
+    retry_delay: "0s"         # Initial backoff delay (grows exponentially)
+    max_retry_delay: "0s"     # Maximum backoff delay
+
 sync:
-  container: "mycontainer"
+  container: "mycontainer"    # Or list several under containers: [{name, prefix, output_subpath}]
   output_path: "./downloads"
   prefix: ""                  # Optional: filter blobs by prefix
+  include: []                 # Optional: glob patterns to sync, e.g. ["*.parquet", "data/**/*.csv"]
+  exclude: []                 # Optional: glob patterns to skip, e.g. ["_temp/"]; wins over include
+  modified_after: ""          # Optional: only blobs modified since, e.g. "7d" or "2024-01-01T00:00:00Z"
+  modified_before: ""         # Optional: only blobs modified before, same formats
   workers: 10                 # Concurrent download workers
   batch_size: 5000            # Blobs per listing batch
-  skip_existing: true         # Skip already downloaded files
+  overwrite_policy: "skip"    # Re-download existing files: skip (if unchanged), newer, always or never
+  download_order: "listed"    # listed, smallest_first, largest_first or random
+  adopt: false                # Record matching untracked local files as downloaded
+  dedupe: false               # Hardlink blobs with the same MD5 as a downloaded one instead of downloading them
   verify_checksums: true      # Verify MD5 after download
+  preserve_timestamps: true   # Set file mtimes to the blob's last-modified time
+  temp_dir: ""                # Partial download directory (empty = next to each file)
+  file_mode: "0644"           # Permissions of downloaded files (octal)
+  dir_mode: "0755"            # Permissions of created directories (octal)
+  blob_timeout: "10m"         # Retry a download that receives no data for this long (0 = off)
+  unsafe_names: "reject"      # Blobs named like "../x" or "/x": reject or rewrite
+  decompress_on_download: false  # Decompress gzip/deflate Content-Encoding blobs
+  # version: "2024-01-02T03:04:05Z"  # Download blobs as of this version ID or time (needs blob versioning)
+  mirror: false               # Delete local files whose blobs were removed remotely
+  mirror_dry_run: false       # Log what mirror would delete without deleting
+  dry_run: false              # Only report what would be downloaded; write nothing
+  manifest_path: ""           # Write an NDJSON manifest of each run's downloads here
+  manifest_all: false         # List every tracked file in the manifest, not just the run's
+  rehydrate: false            # Rehydrate archive-tier blobs for download on a later run
   
   # Folder organization settings for managing large file collections
   folder_organization:
     enabled: false            # Enable automatic folder organization
     max_files_per_folder: 10000  # Maximum files per folder (100-100000)
-    strategy: "sequential"    # Organization strategy: sequential, partition_key, or date
+    strategy: "sequential"    # Organization strategy: sequential, partition_key, date, extension, or template
     partition_depth: 2        # Hash partition depth for partition_key strategy (1-4)
+    use_download_date: false  # Date strategy: use download date instead of blob last-modified
+    folder_template: ""       # Template strategy layout, e.g. "{year}/{ext}/{hash2}"
+                              # Tokens: {year} {month} {day} {ext} {hash2} {hash4} {prefix1}
 
 watch:
   enabled: false              # Continuous monitoring mode
   interval: "5m"              # Check interval (e.g., "5m", "1h")
+  # cron: "0 2 * * *"         # Cron schedule instead of interval (remove interval)
+  metrics_addr: ""            # Serve Prometheus metrics here (e.g., ":9090")
+  health_addr: ""             # Serve /healthz and /readyz here (e.g., ":8080")
 
 logging:
   level: "info"               # debug, info, warn, error
   format: "text"              # text, json
+  color: "auto"               # Coloured levels: auto (terminal, unless NO_COLOR), always, never
+  file: ""                    # Also write logs to this file, rotated by size
+  stdout: true                # Write logs to stdout (false requires file)
+  max_size_mb: 100            # Rotate the log file at this size
+  max_backups: 5              # Rotated log files to keep (0 = all)
+  max_age_days: 30            # Days to keep rotated log files (0 = no limit)
+  audit_file: ""              # Append a JSON line per downloaded or deleted file here
 
 state:
   database: "./.sync-state.db"  # SQLite state database path
+  busy_timeout_ms: 5000       # Wait this long for another process's lock before failing
 
 performance:
   max_memory_mb: 0            # 0 = auto-detect
@@ -105,10 +148,59 @@ performance:
   throttle_threshold: 0.8     # System load threshold for throttling
   bandwidth_limit: ""         # e.g., "50M" for 50 MB/s
   disk_buffer_mb: 32          # Disk write buffer size
+  metrics_interval: "10s"     # How often to record performance metrics (0 = disabled)
+  chunk_threshold_mb: 256     # Use parallel chunked downloads above this size (0 = disabled)
+  chunk_size_mb: 8            # Size of each ranged read in a chunked download
+  chunk_concurrency: 4        # Concurrent ranged reads per chunked download
+  max_concurrent_requests: 0  # Cap on Azure requests in flight at once (0 = unlimited)
+  adaptive_workers: false     # Scale active workers (up to sync.workers) with measured throughput
+
+retry:
+  max_attempts: 3             # Attempts per blob, including the first (1 = no retries)
+  base_delay: "1s"            # Backoff before the first retry; doubles each retry
+  max_delay: "30s"            # Upper bound on the backoff between retries
+
+notifications:
+  webhook_url: ""             # POST a JSON run summary here when a sync finishes
+  webhook_on: "always"        # always, or failure (run failed or blobs failed)
+
+hooks:
+  post_download: ""           # Run per downloaded file, e.g. "process.sh {path} {blob}"
+  post_run: ""                # Run after each completed run; tokens {run_id} {status} {downloaded} {failed}
+  timeout: "5m"               # Kill a hook that runs longer than this
+  fail_on_error: false        # Mark the file or run failed when its hook fails
 `
 
-	if err := os.WriteFile(configPath, []byte(template), 0644); err != nil {
-		return fmt.Errorf("failed to write config file: %w", err)
+// minimalConfigTemplate is the configuration written by init --minimal.
+const minimalConfigTemplate = `# getblobz configuration file
+# Run "getblobz init" without --minimal for every available setting.
+
+azure:
+  connection_string: "DefaultEndpointsProtocol=https;AccountName=...;AccountKey=...;EndpointSuffix=core.windows.net"
+
+sync:
+  container: "mycontainer"
+  output_path: "./downloads"
+`
+
+func init() {
+	rootCmd.AddCommand(initCmd)
+
+	initCmd.Flags().Bool("force", false, "overwrite an existing config file")
+	initCmd.Flags().Bool("minimal", false, "write only the required settings, without the commented examples")
+}
+
+func runInit(cmd *cobra.Command, args []string) error {
+	force, _ := cmd.Flags().GetBool("force")
+	minimal, _ := cmd.Flags().GetBool("minimal")
+
+	configPath := cfgFile
+	if configPath == "" {
+		configPath = "./getblobz.yaml"
+	}
+
+	if err := writeConfigTemplate(configPath, force, minimal); err != nil {
+		return err
 	}
 
 	fmt.Printf("Configuration file created at: %s\n", configPath)
@@ -117,3 +209,25 @@ performance:
 
 	return nil
 }
+
+// writeConfigTemplate writes the configuration template, or the minimal one,
+// to configPath. An existing file is only replaced if force is set.
+func writeConfigTemplate(configPath string, force, minimal bool) error {
+	if _, err := os.Stat(configPath); err == nil && !force {
+		return fmt.Errorf("config file already exists at %s (use --force to overwrite it)", configPath)
+	}
+
+	dir := filepath.Dir(configPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	template := configTemplate
+	if minimal {
+		template = minimalConfigTemplate
+	}
+	if err := os.WriteFile(configPath, []byte(template), 0644); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestWriteConfigTemplate_RefusesToOverwrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "getblobz.yaml")
+	if err := os.WriteFile(path, []byte("existing: true\n"), 0644); err != nil {
+		t.Fatalf("failed to write existing config: %v", err)
+	}
+
+	err := writeConfigTemplate(path, false, false)
+	if err == nil || !strings.Contains(err.Error(), "--force") {
+		t.Errorf("error = %v, want refusal mentioning --force", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read config: %v", err)
+	}
+	if string(data) != "existing: true\n" {
+		t.Errorf("existing config was changed to:\n%s", data)
+	}
+}
+
+func TestWriteConfigTemplate_Force(t *testing.T) {
+	tests := []struct {
+		name    string
+		minimal bool
+		want    string
+	}{
+		{"full", false, configTemplate},
+		{"minimal", true, minimalConfigTemplate},
+	}
+
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "nested", "getblobz.yaml")
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("existing: true\n"), 0644); err != nil {
+			t.Fatalf("failed to write existing config: %v", err)
+		}
+
+		if err := writeConfigTemplate(path, true, tt.minimal); err != nil {
+			t.Fatalf("%s: writeConfigTemplate failed: %v", tt.name, err)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("%s: failed to read config: %v", tt.name, err)
+		}
+		if string(data) != tt.want {
+			t.Errorf("%s: config was not replaced by the template", tt.name)
+		}
+
+		var settings map[string]interface{}
+		if err := yaml.Unmarshal(data, &settings); err != nil {
+			t.Errorf("%s: template is not valid YAML: %v", tt.name, err)
+		}
+		if _, ok := settings["sync"]; !ok {
+			t.Errorf("%s: template has no sync section", tt.name)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+// Package cmd provides the list command for browsing blobs without downloading.
+package cmd
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/haepapa/getblobz/internal/azure"
+	"github.com/spf13/cobra"
+)
+
+// listPageSize is the number of blobs requested per listing page.
+const listPageSize = 5000
+
+// listCmd represents the list command.
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List blobs in a container without downloading",
+	Long: `List shows the blobs in a container, optionally under a prefix, with their
+size and last-modified time, followed by a total count and size. It is
+read-only and does not use the state database, which makes it useful for
+sizing a sync before running it.
+
+Examples:
+  # List blobs under a prefix
+  getblobz list --container mycontainer --connection-string "..." --prefix "logs/2024/"
+
+  # Detailed view including ETag, access tier, and MD5
+  getblobz list --container mycontainer --connection-string "..." --long
+
+  # JSON output for scripting
+  getblobz list --container mycontainer --connection-string "..." --json
+
+  # Include every version and snapshot of each blob
+  getblobz list --container mycontainer --connection-string "..." --include-versions --include-snapshots`,
+	RunE: runList,
+}
+
+// listEntry is the JSON representation of a listed blob.
+type listEntry struct {
+	Name         string    `json:"name"`
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"last_modified"`
+	ETag         string    `json:"etag,omitempty"`
+	AccessTier   string    `json:"access_tier,omitempty"`
+	ContentMD5   string    `json:"content_md5,omitempty"`
+	// Version and snapshot fields are set only when they were requested.
+	VersionID        string `json:"version_id,omitempty"`
+	IsCurrentVersion bool   `json:"is_current_version,omitempty"`
+	Snapshot         string `json:"snapshot,omitempty"`
+}
+
+// listOutput is the JSON document written by list --json.
+type listOutput struct {
+	Container  string      `json:"container"`
+	Prefix     string      `json:"prefix"`
+	Count      int         `json:"count"`
+	TotalBytes int64       `json:"total_bytes"`
+	Blobs      []listEntry `json:"blobs"`
+}
+
+// listFlagBindings lists the list flags that share configuration keys with sync.
+var listFlagBindings = []flagBinding{
+	{"container", "sync.container"},
+	{"prefix", "sync.prefix"},
+}
+
+func init() {
+	rootCmd.AddCommand(listCmd)
+
+	listCmd.Flags().String("container", "", "Azure container name (required)")
+	listCmd.Flags().String("prefix", "", "only list blobs with this prefix")
+	addAzureFlags(listCmd)
+	registerContainerCompletion(listCmd)
+	listCmd.Flags().Bool("long", false, "show ETag, access tier, and MD5 for each blob")
+	listCmd.Flags().Bool("json", false, "write the listing as JSON")
+	listCmd.Flags().Bool("include-versions", false, "list every version of each blob (needs blob versioning)")
+	listCmd.Flags().Bool("include-snapshots", false, "list blob snapshots")
+
+	if err := listCmd.MarkFlagRequired("container"); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to mark required flag: %v\n", err)
+	}
+}
+
+func runList(cmd *cobra.Command, args []string) error {
+	long, _ := cmd.Flags().GetBool("long")
+	asJSON, _ := cmd.Flags().GetBool("json")
+	var opts azure.ListOptions
+	opts.Versions, _ = cmd.Flags().GetBool("include-versions")
+	opts.Snapshots, _ = cmd.Flags().GetBool("include-snapshots")
+
+	if err := bindFlags(cmd, append(azureFlagBindings, listFlagBindings...)); err != nil {
+		return err
+	}
+
+	if err := unmarshalConfig(); err != nil {
+		return fmt.Errorf("failed to parse configuration: %w", err)
+	}
+
+	if err := cfg.ValidateAzure(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	client, err := newAzureClient()
+	if err != nil {
+		return err
+	}
+
+	var blobs []*azure.BlobInfo
+	var marker *string
+	for {
+		page, next, err := client.ListBlobsWithOptions(cmd.Context(), cfg.Sync.Container, cfg.Sync.Prefix, marker, listPageSize, opts)
+		if err != nil {
+			return err
+		}
+		blobs = append(blobs, page...)
+
+		if next == nil {
+			break
+		}
+		marker = next
+	}
+
+	var totalBytes int64
+	for _, blob := range blobs {
+		totalBytes += blob.Size
+	}
+
+	if asJSON {
+		out := listOutput{
+			Container:  cfg.Sync.Container,
+			Prefix:     cfg.Sync.Prefix,
+			Count:      len(blobs),
+			TotalBytes: totalBytes,
+			Blobs:      make([]listEntry, 0, len(blobs)),
+		}
+		for _, blob := range blobs {
+			entry := listEntry{Name: blob.Name, Size: blob.Size, LastModified: blob.LastModified}
+			if long {
+				entry.ETag = blob.ETag
+				entry.AccessTier = blob.AccessTier
+				if len(blob.ContentMD5) > 0 {
+					entry.ContentMD5 = base64.StdEncoding.EncodeToString(blob.ContentMD5)
+				}
+			}
+			if opts.Versions {
+				entry.VersionID = blob.VersionID
+				entry.IsCurrentVersion = blob.IsCurrentVersion
+			}
+			if opts.Snapshots {
+				entry.Snapshot = blob.Snapshot
+			}
+			out.Blobs = append(out.Blobs, entry)
+		}
+
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(out)
+	}
+
+	header := "NAME\tSIZE\tLAST MODIFIED"
+	if long {
+		header += "\tETAG\tTIER\tMD5"
+	}
+	if opts.Versions {
+		header += "\tVERSION"
+	}
+	if opts.Snapshots {
+		header += "\tSNAPSHOT"
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, header)
+	for _, blob := range blobs {
+		fmt.Fprintf(w, "%s\t%d\t%s", blob.Name, blob.Size, blob.LastModified.Format(time.RFC3339))
+		if long {
+			md5Str := ""
+			if len(blob.ContentMD5) > 0 {
+				md5Str = base64.StdEncoding.EncodeToString(blob.ContentMD5)
+			}
+			fmt.Fprintf(w, "\t%s\t%s\t%s", blob.ETag, blob.AccessTier, md5Str)
+		}
+		if opts.Versions {
+			version := blob.VersionID
+			if blob.IsCurrentVersion {
+				version += " (current)"
+			}
+			fmt.Fprintf(w, "\t%s", version)
+		}
+		if opts.Snapshots {
+			fmt.Fprintf(w, "\t%s", blob.Snapshot)
+		}
+		fmt.Fprintln(w)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Printf("\n%d blobs, %d bytes\n", len(blobs), totalBytes)
+	return nil
+}
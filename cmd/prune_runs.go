@@ -0,0 +1,82 @@
+// Package cmd provides the prune-runs command for trimming sync run history.
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/haepapa/getblobz/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+// pruneRunsCmd represents the prune-runs command.
+var pruneRunsCmd = &cobra.Command{
+	Use:   "prune-runs",
+	Short: "Delete old sync run history from the state database",
+	Long: `Prune-runs deletes old sync runs together with their performance metrics
+and error log entries, which otherwise grow without bound in watch mode.
+A run is kept if it is younger than --keep-days or among the newest
+--keep-last runs of its container. Running runs and the latest run of each
+container are always kept, and the state of synced blobs is never touched.
+
+Examples:
+  # Keep the last 30 days of history
+  getblobz prune-runs --keep-days 30
+
+  # Keep the 100 most recent runs per container, then reclaim the space
+  getblobz prune-runs --keep-last 100 --vacuum`,
+	RunE: runPruneRuns,
+}
+
+func init() {
+	rootCmd.AddCommand(pruneRunsCmd)
+
+	pruneRunsCmd.Flags().String("state-db", "./.sync-state.db", "path to state database")
+	pruneRunsCmd.Flags().Int("keep-days", 0, "keep runs started within this many days")
+	pruneRunsCmd.Flags().Int("keep-last", 0, "keep this many of the most recent runs per container")
+	pruneRunsCmd.Flags().Bool("vacuum", false, "compact the database file afterwards")
+}
+
+func runPruneRuns(cmd *cobra.Command, args []string) error {
+	dbPath, _ := cmd.Flags().GetString("state-db")
+	keepDays, _ := cmd.Flags().GetInt("keep-days")
+	keepLast, _ := cmd.Flags().GetInt("keep-last")
+	vacuum, _ := cmd.Flags().GetBool("vacuum")
+
+	if keepDays < 0 || keepLast < 0 {
+		return fmt.Errorf("--keep-days and --keep-last must not be negative")
+	}
+	if keepDays == 0 && keepLast == 0 {
+		return fmt.Errorf("at least one of --keep-days or --keep-last is required")
+	}
+
+	// Pruning while a sync runs would race with the run's own records.
+	lock, err := storage.AcquireLock(dbPath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = lock.Release() }()
+
+	db, err := storage.OpenWithBusyTimeout(dbPath, cfg.State.BusyTimeout())
+	if err != nil {
+		return fmt.Errorf("failed to open state database: %w", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	var keepSince time.Time
+	if keepDays > 0 {
+		keepSince = time.Now().AddDate(0, 0, -keepDays)
+	}
+
+	result, err := db.PruneSyncRuns(keepSince, keepLast)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Removed %d sync runs, %d performance metrics and %d error log entries\n",
+		result.Runs, result.Metrics, result.Errors)
+
+	if vacuum {
+		return vacuumDB(db, dbPath)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+// Package cmd provides the push command for uploading local files.
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/haepapa/getblobz/internal/storage"
+	"github.com/haepapa/getblobz/internal/sync"
+	"github.com/spf13/cobra"
+)
+
+// pushCmd represents the push command.
+var pushCmd = &cobra.Command{
+	Use:   "push",
+	Short: "Upload a local directory to Azure Storage",
+	Long: `Push uploads files from a local directory to an Azure Blob Storage container.
+
+Each file is uploaded as a block blob named after its path relative to the
+source directory, under the optional prefix. Files whose MD5 already matches
+the remote blob are skipped, as are files that sync downloaded and that have
+not changed since. Upload results are recorded in the state database.
+
+Examples:
+  # Push the default output directory back to its container
+  getblobz push --container mycontainer --connection-string "..."
+
+  # Push a directory under a prefix
+  getblobz push --container mycontainer --connection-string "..." --source-path ./reports --prefix "reports/"`,
+	RunE: runPush,
+}
+
+// pushFlagBindings lists the push flags that share configuration keys with sync.
+var pushFlagBindings = []flagBinding{
+	{"container", "sync.container"},
+	{"source-path", "sync.output_path"},
+	{"prefix", "sync.prefix"},
+	{"workers", "sync.workers"},
+	{"force", "sync.force_resync"},
+	{"state-db", "state.database"},
+}
+
+func init() {
+	rootCmd.AddCommand(pushCmd)
+
+	pushCmd.Flags().String("container", "", "Azure container name (required)")
+	pushCmd.Flags().String("source-path", "./data", "local directory to upload")
+	addAzureFlags(pushCmd)
+	registerContainerCompletion(pushCmd)
+	pushCmd.Flags().String("prefix", "", "blob name prefix for uploaded files")
+	pushCmd.Flags().Int("workers", 10, "number of concurrent upload workers")
+	pushCmd.Flags().Bool("force", false, "upload all files even if unchanged")
+	pushCmd.Flags().String("state-db", "./.sync-state.db", "path to state database")
+
+	if err := pushCmd.MarkFlagRequired("container"); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to mark required flag: %v\n", err)
+	}
+}
+
+func runPush(cmd *cobra.Command, args []string) error {
+	if err := bindFlags(cmd, append(azureFlagBindings, pushFlagBindings...)); err != nil {
+		return err
+	}
+
+	if err := unmarshalConfig(); err != nil {
+		return fmt.Errorf("failed to parse configuration: %w", err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	log, err := newLogger()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = log.Close() }()
+
+	db, err := storage.OpenWithBusyTimeout(cfg.State.Database, cfg.State.BusyTimeout())
+	if err != nil {
+		return fmt.Errorf("failed to open state database: %w", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	client, err := newAzureClient()
+	if err != nil {
+		return err
+	}
+
+	pusher := sync.NewPusher(cfg, client, db, log)
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigChan)
+
+	go func() {
+		if _, ok := <-sigChan; ok {
+			log.Info("Received interrupt signal, stopping...")
+			pusher.Stop()
+		}
+	}()
+
+	return pusher.Start()
+}
@@ -0,0 +1,118 @@
+// Package cmd provides the retry command for re-downloading failed blobs.
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/haepapa/getblobz/internal/storage"
+	"github.com/haepapa/getblobz/internal/sync"
+	"github.com/spf13/cobra"
+)
+
+// retryCmd represents the retry command.
+var retryCmd = &cobra.Command{
+	Use:   "retry",
+	Short: "Re-download blobs that failed in earlier syncs",
+	Long: `Retry resets failed blobs in the state database to pending and runs the
+download phase only. The container is not listed again, which makes this much
+cheaper than a full --force-resync after transient failures.
+
+Examples:
+  # Retry all failed blobs
+  getblobz retry --container mycontainer --connection-string "..."
+
+  # Retry only blobs that failed with network errors
+  getblobz retry --container mycontainer --connection-string "..." --error-type network`,
+	RunE: runRetry,
+}
+
+// retryFlagBindings lists the retry flags that share configuration keys with sync.
+var retryFlagBindings = []flagBinding{
+	{"container", "sync.container"},
+	{"workers", "sync.workers"},
+	{"state-db", "state.database"},
+}
+
+// retryErrorTypes are the error categories accepted by --error-type.
+var retryErrorTypes = map[string]bool{
+	storage.ErrorTypeNetwork:   true,
+	storage.ErrorTypeChecksum:  true,
+	storage.ErrorTypeDisk:      true,
+	storage.ErrorTypeAuth:      true,
+	storage.ErrorTypeNotFound:  true,
+	storage.ErrorTypeThrottled: true,
+	storage.ErrorTypeUnknown:   true,
+}
+
+func init() {
+	rootCmd.AddCommand(retryCmd)
+
+	retryCmd.Flags().String("container", "", "Azure container name (required)")
+	addAzureFlags(retryCmd)
+	registerContainerCompletion(retryCmd)
+	retryCmd.Flags().Int("workers", 10, "number of concurrent download workers")
+	retryCmd.Flags().String("state-db", "./.sync-state.db", "path to state database")
+	retryCmd.Flags().String("error-type", "", "only retry blobs whose last error was of this type (network, checksum, disk, auth, not_found, throttled, unknown)")
+
+	if err := retryCmd.MarkFlagRequired("container"); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to mark required flag: %v\n", err)
+	}
+}
+
+func runRetry(cmd *cobra.Command, args []string) error {
+	errorType, _ := cmd.Flags().GetString("error-type")
+	if errorType != "" && !retryErrorTypes[errorType] {
+		return fmt.Errorf("invalid error type %q: must be network, checksum, disk, auth, not_found, throttled, or unknown", errorType)
+	}
+
+	if err := bindFlags(cmd, append(azureFlagBindings, retryFlagBindings...)); err != nil {
+		return err
+	}
+
+	if err := unmarshalConfig(); err != nil {
+		return fmt.Errorf("failed to parse configuration: %w", err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	log, err := newLogger()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = log.Close() }()
+
+	lock, err := storage.AcquireLock(cfg.State.Database)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = lock.Release() }()
+
+	db, err := storage.OpenWithBusyTimeout(cfg.State.Database, cfg.State.BusyTimeout())
+	if err != nil {
+		return fmt.Errorf("failed to open state database: %w", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	client, err := newAzureClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signalContext(log)
+	defer stop()
+
+	auditLog, err := openAuditLog()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = auditLog.Close() }()
+
+	syncer := sync.New(cfg, client, db, log)
+	syncer.SetContext(ctx)
+	syncer.SetAuditLog(auditLog)
+
+	return syncer.Retry(errorType)
+}
@@ -2,10 +2,15 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
+	"github.com/haepapa/getblobz/internal/audit"
 	"github.com/haepapa/getblobz/internal/config"
+	"github.com/haepapa/getblobz/pkg/logger"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -53,6 +58,7 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is ./getblobz.yaml or ~/.config/getblobz/config.yaml)")
 	rootCmd.PersistentFlags().String("log-level", "info", "log level (debug, info, warn, error)")
 	rootCmd.PersistentFlags().String("log-format", "text", "log format (text, json)")
+	rootCmd.PersistentFlags().String("log-file", "", "also write logs to this file, rotated by size")
 
 	if err := viper.BindPFlag("logging.level", rootCmd.PersistentFlags().Lookup("log-level")); err != nil {
 		fmt.Fprintf(os.Stderr, "failed to bind log-level flag: %v\n", err)
@@ -60,6 +66,9 @@ func init() {
 	if err := viper.BindPFlag("logging.format", rootCmd.PersistentFlags().Lookup("log-format")); err != nil {
 		fmt.Fprintf(os.Stderr, "failed to bind log-format flag: %v\n", err)
 	}
+	if err := viper.BindPFlag("logging.file", rootCmd.PersistentFlags().Lookup("log-file")); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to bind log-file flag: %v\n", err)
+	}
 }
 
 // initConfig reads in config file and ENV variables if set.
@@ -84,8 +93,65 @@ func initConfig() {
 	viper.AutomaticEnv()
 
 	if err := viper.ReadInConfig(); err == nil {
-		if err := viper.Unmarshal(cfg); err != nil {
+		if err := unmarshalConfig(); err != nil {
 			fmt.Fprintf(os.Stderr, "Error parsing config: %v\n", err)
 		}
 	}
 }
+
+// unmarshalConfig decodes the merged viper settings into cfg and expands
+// environment variable references in its string values.
+func unmarshalConfig() error {
+	if err := viper.Unmarshal(cfg); err != nil {
+		return err
+	}
+	return cfg.ExpandEnv()
+}
+
+// newLogger creates a logger from the logging configuration.
+func newLogger() (*logger.Logger, error) {
+	log, err := logger.New(logger.Config{
+		Level:      cfg.Logging.Level,
+		Format:     cfg.Logging.Format,
+		Color:      cfg.Logging.Color,
+		File:       cfg.Logging.File,
+		FileOnly:   !cfg.Logging.Stdout,
+		MaxSizeMB:  cfg.Logging.MaxSizeMB,
+		MaxBackups: cfg.Logging.MaxBackups,
+		MaxAgeDays: cfg.Logging.MaxAgeDays,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create logger: %w", err)
+	}
+	return log, nil
+}
+
+// openAuditLog opens the configured audit log, or returns nil if none is set.
+func openAuditLog() (*audit.Log, error) {
+	if cfg.Logging.AuditFile == "" {
+		return nil, nil
+	}
+	return audit.Open(cfg.Logging.AuditFile)
+}
+
+// signalContext returns a context that is cancelled when the process
+// receives an interrupt or SIGTERM. Calling stop releases the signal handler.
+func signalContext(log *logger.Logger) (ctx context.Context, stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case <-sigChan:
+			log.Info("Received interrupt signal, stopping...")
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(sigChan)
+		cancel()
+	}
+}
@@ -2,11 +2,12 @@
 package cmd
 
 import (
-	"database/sql"
+	"encoding/json"
 	"fmt"
+	"os"
 	"time"
 
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/haepapa/getblobz/internal/storage"
 	"github.com/spf13/cobra"
 )
 
@@ -26,113 +27,279 @@ Examples:
   getblobz status
 
   # Show status for specific database
-  getblobz status --state-db /path/to/.sync-state.db`,
+  getblobz status --state-db /path/to/.sync-state.db
+
+  # Machine-readable output for health checks
+  getblobz status --json`,
 	RunE: runStatus,
 }
 
+// statusReport is the data shown by the status command.
+type statusReport struct {
+	Containers     []statusContainer   `json:"containers"`
+	SyncRuns       statusRuns          `json:"sync_runs"`
+	LastRun        *statusLastRun      `json:"last_run,omitempty"`
+	Blobs          statusBlobs         `json:"blobs"`
+	Performance    *statusPerformance  `json:"performance,omitempty"`
+	RecentFailures []statusFailureInfo `json:"recent_failures"`
+}
+
+// statusContainer holds the state of one synced container.
+type statusContainer struct {
+	Name      string      `json:"name"`
+	LastCheck *time.Time  `json:"last_check,omitempty"`
+	Blobs     statusBlobs `json:"blobs"`
+}
+
+// statusRuns holds sync run counts by status. It mirrors storage.RunCounts.
+type statusRuns struct {
+	Total     int64 `json:"total"`
+	Running   int64 `json:"running"`
+	Completed int64 `json:"completed"`
+	Failed    int64 `json:"failed"`
+}
+
+// statusLastRun describes the most recently completed sync run and the
+// average throughput it achieved.
+type statusLastRun struct {
+	ID              int64     `json:"id"`
+	Container       string    `json:"container"`
+	CompletedAt     time.Time `json:"completed_at"`
+	DurationSeconds float64   `json:"duration_seconds"`
+	DownloadedFiles int64     `json:"downloaded_files"`
+	TotalBytes      int64     `json:"total_bytes"`
+	AvgMbps         float64   `json:"avg_mbps"`
+	AvgFilesPerSec  float64   `json:"avg_files_per_sec"`
+}
+
+// statusBlobs holds blob counts by status.
+type statusBlobs struct {
+	Total      int64 `json:"total"`
+	Downloaded int64 `json:"downloaded"`
+	Pending    int64 `json:"pending"`
+	Failed     int64 `json:"failed"`
+	Skipped    int64 `json:"skipped"`
+	Archived   int64 `json:"archived"`
+	// DownloadedBytes is the total size of the downloaded blobs.
+	DownloadedBytes int64 `json:"downloaded_bytes"`
+}
+
+// statusPerformance summarizes the performance metrics of the most recent
+// run that recorded any, along with the latest throttling state. It mirrors
+// storage.PerformanceSummary.
+type statusPerformance struct {
+	SyncRunID          int64     `json:"sync_run_id"`
+	Samples            int64     `json:"samples"`
+	PeakFilesPerSec    float64   `json:"peak_files_per_sec"`
+	AvgFilesPerSec     float64   `json:"avg_files_per_sec"`
+	PeakMbps           float64   `json:"peak_mbps"`
+	AvgMbps            float64   `json:"avg_mbps"`
+	PeakMemoryMB       int64     `json:"peak_memory_mb"`
+	ThrottledDuringRun bool      `json:"throttled_during_run"`
+	Throttled          bool      `json:"throttled"`
+	AsOf               time.Time `json:"as_of"`
+}
+
+// statusFailureInfo describes a recently failed blob.
+type statusFailureInfo struct {
+	BlobName     string     `json:"blob_name"`
+	ErrorMessage string     `json:"error_message"`
+	LastSyncedAt *time.Time `json:"last_synced_at"`
+}
+
 func init() {
 	rootCmd.AddCommand(statusCmd)
 
 	statusCmd.Flags().String("state-db", "./.sync-state.db", "path to state database")
+	statusCmd.Flags().Bool("json", false, "write status as JSON")
 }
 
 func runStatus(cmd *cobra.Command, args []string) error {
 	dbPath, _ := cmd.Flags().GetString("state-db")
+	asJSON, _ := cmd.Flags().GetBool("json")
 
-	sqlDB, err := sql.Open("sqlite3", dbPath)
+	db, err := storage.OpenReadOnly(dbPath, cfg.State.BusyTimeout())
 	if err != nil {
 		return fmt.Errorf("failed to open state database: %w", err)
 	}
-	defer func() { _ = sqlDB.Close() }()
-
-	var totalRuns, runningRuns, completedRuns, failedRuns int
-	err = sqlDB.QueryRow(`
-		SELECT 
-			COUNT(*) as total,
-			SUM(CASE WHEN status = 'running' THEN 1 ELSE 0 END) as running,
-			SUM(CASE WHEN status = 'completed' THEN 1 ELSE 0 END) as completed,
-			SUM(CASE WHEN status = 'failed' THEN 1 ELSE 0 END) as failed
-		FROM sync_runs
-	`).Scan(&totalRuns, &runningRuns, &completedRuns, &failedRuns)
-	if err != nil && err != sql.ErrNoRows {
-		return fmt.Errorf("failed to query sync runs: %w", err)
-	}
-
-	var totalBlobs, downloadedBlobs, pendingBlobs, failedBlobs, skippedBlobs int64
-	err = sqlDB.QueryRow(`
-		SELECT 
-			COUNT(*) as total,
-			SUM(CASE WHEN status = 'downloaded' THEN 1 ELSE 0 END) as downloaded,
-			SUM(CASE WHEN status = 'pending' THEN 1 ELSE 0 END) as pending,
-			SUM(CASE WHEN status = 'failed' THEN 1 ELSE 0 END) as failed,
-			SUM(CASE WHEN status = 'skipped' THEN 1 ELSE 0 END) as skipped
-		FROM blob_state
-	`).Scan(&totalBlobs, &downloadedBlobs, &pendingBlobs, &failedBlobs, &skippedBlobs)
-	if err != nil && err != sql.ErrNoRows {
-		return fmt.Errorf("failed to query blob state: %w", err)
-	}
-
-	var lastCheckTime *time.Time
-	var containerName string
-	err = sqlDB.QueryRow(`
-		SELECT container_name, last_check_time FROM sync_checkpoint WHERE id = 1
-	`).Scan(&containerName, &lastCheckTime)
-	if err != nil && err != sql.ErrNoRows {
-		return fmt.Errorf("failed to query checkpoint: %w", err)
+	defer func() { _ = db.Close() }()
+
+	report, err := loadStatus(db)
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+
+	printStatus(report)
+	return nil
+}
+
+// loadStatus reads the status report from the state database.
+func loadStatus(db *storage.DB) (*statusReport, error) {
+	report := &statusReport{Containers: []statusContainer{}, RecentFailures: []statusFailureInfo{}}
+
+	stats, err := db.GetAggregateStats()
+	if err != nil {
+		return nil, err
+	}
+	report.SyncRuns = statusRuns(stats.Runs)
+	report.Blobs = newStatusBlobs(stats)
+
+	names, err := db.ContainerNames()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		stats, err := db.WithContainer(name).GetAggregateStats()
+		if err != nil {
+			return nil, err
+		}
+		container := statusContainer{Name: name, Blobs: newStatusBlobs(stats)}
+		if cp := stats.LatestCheckpoint; cp != nil && !cp.LastCheckTime.IsZero() {
+			container.LastCheck = &cp.LastCheckTime
+		}
+		report.Containers = append(report.Containers, container)
+	}
+
+	run, err := db.GetLatestSyncRun(storage.SyncStatusCompleted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query last sync run: %w", err)
+	}
+	if run != nil && run.CompletedAt != nil {
+		report.LastRun = &statusLastRun{
+			ID:              run.ID,
+			Container:       run.ContainerName,
+			CompletedAt:     *run.CompletedAt,
+			DurationSeconds: run.CompletedAt.Sub(run.StartedAt).Seconds(),
+			DownloadedFiles: run.DownloadedFiles,
+			TotalBytes:      run.TotalBytes,
+		}
+		if run.AvgMbps != nil {
+			report.LastRun.AvgMbps = *run.AvgMbps
+		}
+		if run.AvgFilesPerSec != nil {
+			report.LastRun.AvgFilesPerSec = *run.AvgFilesPerSec
+		}
+	}
+
+	perf, err := db.GetLatestPerformanceSummary()
+	if err != nil {
+		return nil, err
+	}
+	if perf != nil {
+		p := statusPerformance(*perf)
+		report.Performance = &p
+	}
+
+	if report.Blobs.Failed > 0 {
+		failures, err := db.GetRecentFailures(5)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query recent failures: %w", err)
+		}
+		for _, blob := range failures {
+			failure := statusFailureInfo{BlobName: blob.BlobName, LastSyncedAt: blob.LastSyncedAt}
+			if blob.ErrorMessage != nil {
+				failure.ErrorMessage = *blob.ErrorMessage
+			}
+			report.RecentFailures = append(report.RecentFailures, failure)
+		}
+	}
+
+	return report, nil
+}
+
+// newStatusBlobs reports the blob counts and bytes downloaded of stats.
+func newStatusBlobs(stats *storage.AggregateStats) statusBlobs {
+	return statusBlobs{
+		Total:           stats.Blobs.Total,
+		Downloaded:      stats.Blobs.Downloaded,
+		Pending:         stats.Blobs.Pending,
+		Failed:          stats.Blobs.Failed,
+		Skipped:         stats.Blobs.Skipped,
+		Archived:        stats.Blobs.Archived,
+		DownloadedBytes: stats.DownloadedBytes,
 	}
+}
 
+// printStatus writes the human-readable status report.
+func printStatus(report *statusReport) {
 	fmt.Println("╔═══════════════════════════════════════════════════════════╗")
 	fmt.Println("║           getblobz - Sync Status                         ║")
 	fmt.Println("╚═══════════════════════════════════════════════════════════╝")
 	fmt.Println()
 
-	if containerName != "" {
-		fmt.Printf("Container:     %s\n", containerName)
-		if lastCheckTime != nil {
-			fmt.Printf("Last Check:    %s\n", lastCheckTime.Format("2006-01-02 15:04:05"))
+	for _, c := range report.Containers {
+		name := c.Name
+		if name == "" {
+			name = "(unknown)"
+		}
+		fmt.Printf("Container:     %s\n", name)
+		if c.LastCheck != nil {
+			fmt.Printf("Last Check:    %s\n", c.LastCheck.Format("2006-01-02 15:04:05"))
+		}
+		if len(report.Containers) > 1 {
+			fmt.Printf("Blobs:         %d total, %d downloaded, %d pending, %d failed\n",
+				c.Blobs.Total, c.Blobs.Downloaded, c.Blobs.Pending, c.Blobs.Failed)
 		}
 		fmt.Println()
 	}
 
 	fmt.Println("Sync Runs:")
-	fmt.Printf("  Total:       %d\n", totalRuns)
-	fmt.Printf("  Running:     %d\n", runningRuns)
-	fmt.Printf("  Completed:   %d\n", completedRuns)
-	fmt.Printf("  Failed:      %d\n", failedRuns)
+	fmt.Printf("  Total:       %d\n", report.SyncRuns.Total)
+	fmt.Printf("  Running:     %d\n", report.SyncRuns.Running)
+	fmt.Printf("  Completed:   %d\n", report.SyncRuns.Completed)
+	fmt.Printf("  Failed:      %d\n", report.SyncRuns.Failed)
 	fmt.Println()
 
+	if run := report.LastRun; run != nil {
+		fmt.Printf("Last Run (run %d, completed %s):\n", run.ID, run.CompletedAt.Format("2006-01-02 15:04:05"))
+		fmt.Printf("  Duration:    %s\n", (time.Duration(run.DurationSeconds * float64(time.Second))).Round(time.Second))
+		fmt.Printf("  Downloaded:  %d files, %d bytes\n", run.DownloadedFiles, run.TotalBytes)
+		fmt.Printf("  Throughput:  %.1f Mbps, %.1f files/sec\n", run.AvgMbps, run.AvgFilesPerSec)
+		fmt.Println()
+	}
+
+	if perf := report.Performance; perf != nil {
+		fmt.Printf("Performance (run %d, %d samples):\n", perf.SyncRunID, perf.Samples)
+		fmt.Printf("  Files/sec:   %.1f peak, %.1f avg\n", perf.PeakFilesPerSec, perf.AvgFilesPerSec)
+		fmt.Printf("  Mbps:        %.1f peak, %.1f avg\n", perf.PeakMbps, perf.AvgMbps)
+		fmt.Printf("  Peak Memory: %d MB\n", perf.PeakMemoryMB)
+		fmt.Printf("  Throttled:   %s during run, %s now (as of %s)\n",
+			yesNo(perf.ThrottledDuringRun), yesNo(perf.Throttled), perf.AsOf.Format("2006-01-02 15:04:05"))
+		fmt.Println()
+	}
+
 	fmt.Println("Blobs:")
-	fmt.Printf("  Total:       %d\n", totalBlobs)
-	fmt.Printf("  Downloaded:  %d\n", downloadedBlobs)
-	fmt.Printf("  Pending:     %d\n", pendingBlobs)
-	fmt.Printf("  Failed:      %d\n", failedBlobs)
-	fmt.Printf("  Skipped:     %d\n", skippedBlobs)
+	fmt.Printf("  Total:       %d\n", report.Blobs.Total)
+	fmt.Printf("  Downloaded:  %d\n", report.Blobs.Downloaded)
+	fmt.Printf("  Pending:     %d\n", report.Blobs.Pending)
+	fmt.Printf("  Failed:      %d\n", report.Blobs.Failed)
+	fmt.Printf("  Skipped:     %d\n", report.Blobs.Skipped)
+	fmt.Printf("  Archived:    %d\n", report.Blobs.Archived)
+	fmt.Printf("  Bytes:       %d downloaded\n", report.Blobs.DownloadedBytes)
 	fmt.Println()
 
-	if failedBlobs > 0 {
+	if len(report.RecentFailures) > 0 {
 		fmt.Println("Recent Failures:")
-		rows, err := sqlDB.Query(`
-			SELECT blob_name, error_message, last_synced_at
-			FROM blob_state 
-			WHERE status = 'failed'
-			ORDER BY last_synced_at DESC
-			LIMIT 5
-		`)
-		if err == nil {
-			defer func() { _ = rows.Close() }()
-			for rows.Next() {
-				var blobName, errorMsg string
-				var lastSynced *time.Time
-				if err := rows.Scan(&blobName, &errorMsg, &lastSynced); err == nil {
-					timeStr := "never"
-					if lastSynced != nil {
-						timeStr = lastSynced.Format("2006-01-02 15:04:05")
-					}
-					fmt.Printf("  • %s\n    Error: %s\n    Time: %s\n", blobName, errorMsg, timeStr)
-				}
+		for _, failure := range report.RecentFailures {
+			timeStr := "never"
+			if failure.LastSyncedAt != nil {
+				timeStr = failure.LastSyncedAt.Format("2006-01-02 15:04:05")
 			}
+			fmt.Printf("  • %s\n    Error: %s\n    Time: %s\n", failure.BlobName, failure.ErrorMessage, timeStr)
 		}
 	}
+}
 
-	return nil
+// yesNo formats a boolean for the human-readable report.
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
 }
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/haepapa/getblobz/internal/config"
+	"github.com/haepapa/getblobz/internal/storage"
+)
+
+func TestRunStatus_MissingDatabase(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "typo.db")
+
+	prevCfg := cfg
+	cfg = config.Default()
+	t.Cleanup(func() {
+		cfg = prevCfg
+		_ = statusCmd.Flags().Set("state-db", "./.sync-state.db")
+	})
+	if err := statusCmd.Flags().Set("state-db", dbPath); err != nil {
+		t.Fatalf("failed to set --state-db: %v", err)
+	}
+
+	if err := runStatus(statusCmd, nil); !errors.Is(err, storage.ErrDatabaseNotFound) {
+		t.Errorf("status of a missing database: err = %v, want ErrDatabaseNotFound", err)
+	}
+	if _, err := os.Stat(dbPath); !os.IsNotExist(err) {
+		t.Errorf("expected status not to create a database, stat err = %v", err)
+	}
+}
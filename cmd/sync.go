@@ -2,16 +2,17 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
-	"os/signal"
-	"syscall"
 	"time"
 
-	"github.com/haepapa/getblobz/internal/azure"
+	"github.com/haepapa/getblobz/internal/audit"
+	"github.com/haepapa/getblobz/internal/sdnotify"
 	"github.com/haepapa/getblobz/internal/storage"
 	"github.com/haepapa/getblobz/internal/sync"
-	"github.com/haepapa/getblobz/pkg/logger"
+	"github.com/haepapa/getblobz/internal/telemetry"
+	"github.com/robfig/cron/v3"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -33,67 +34,73 @@ Examples:
   getblobz sync --container mycontainer --connection-string "..." --watch
 
   # Sync with prefix filter
-  getblobz sync --container mycontainer --connection-string "..." --prefix "data/2024/"`,
+  getblobz sync --container mycontainer --connection-string "..." --prefix "data/2024/"
+
+  # Sync only parquet files, skipping temporary folders
+  getblobz sync --container mycontainer --connection-string "..." --include "*.parquet" --exclude "_temp/"
+
+  # Sync only blobs modified in the last week
+  getblobz sync --container mycontainer --connection-string "..." --modified-after 7d
+
+  # Preview what a sync would download without writing anything
+  getblobz sync --container mycontainer --connection-string "..." --prefix "data/2024/" --dry-run`,
 	RunE: runSync,
 }
 
 func init() {
 	rootCmd.AddCommand(syncCmd)
 
-	syncCmd.Flags().String("container", "", "Azure container name (required)")
+	syncCmd.Flags().String("container", "", "Azure container name (required unless sync.containers is configured)")
 	syncCmd.Flags().String("output-path", "./data", "local destination path")
-	syncCmd.Flags().String("connection-string", "", "Azure Storage connection string")
-	syncCmd.Flags().String("account-name", "", "Storage account name")
-	syncCmd.Flags().String("account-key", "", "Storage account key")
-	syncCmd.Flags().Bool("use-managed-identity", false, "use Azure Managed Identity")
-	syncCmd.Flags().String("tenant-id", "", "Azure AD tenant ID")
-	syncCmd.Flags().String("client-id", "", "Azure AD client ID")
-	syncCmd.Flags().String("client-secret", "", "Azure AD client secret")
-	syncCmd.Flags().Bool("use-azure-cli", false, "use Azure CLI credentials")
+	addAzureFlags(syncCmd)
+	registerContainerCompletion(syncCmd)
 	syncCmd.Flags().String("prefix", "", "only sync blobs with this prefix")
+	syncCmd.Flags().StringArray("include", nil, "only sync blobs matching this glob pattern (repeatable)")
+	syncCmd.Flags().StringArray("exclude", nil, "skip blobs matching this glob pattern (repeatable)")
+	syncCmd.Flags().String("modified-after", "", "only sync blobs modified at or after this time (RFC3339, date, or age like 7d)")
+	syncCmd.Flags().String("modified-before", "", "only sync blobs modified before this time (RFC3339, date, or age like 1d)")
 	syncCmd.Flags().Int("workers", 10, "number of concurrent download workers")
 	syncCmd.Flags().Int("batch-size", 5000, "number of blobs to list per batch")
 	syncCmd.Flags().Bool("watch", false, "continuously watch for new files")
 	syncCmd.Flags().Duration("watch-interval", 5*time.Minute, "interval between checks in watch mode")
+	syncCmd.Flags().String("watch-cron", "", "cron expression scheduling runs in watch mode instead of an interval (e.g. \"0 2 * * *\")")
+	syncCmd.Flags().String("metrics-addr", "", "serve Prometheus metrics on this address (e.g. :9090)")
+	syncCmd.Flags().String("health-addr", "", "serve /healthz and /readyz on this address (e.g. :8080)")
 	syncCmd.Flags().String("state-db", "./.sync-state.db", "path to state database")
 	syncCmd.Flags().Bool("force-resync", false, "ignore state and re-download all files")
+	syncCmd.Flags().String("manifest-path", "", "write an NDJSON manifest of downloaded files here when a run completes")
+	syncCmd.Flags().Bool("manifest-all", false, "list every tracked file in the manifest, not just this run's downloads")
+	syncCmd.Flags().Bool("dry-run", false, "list what would be downloaded without writing files or state")
+	syncCmd.Flags().Bool("resume", false, "continue the most recent interrupted run instead of starting fresh")
 	syncCmd.Flags().Bool("skip-existing", true, "skip files that already exist locally")
+	syncCmd.Flags().String("overwrite-policy", "", "when to re-download files that exist locally (skip, newer, always, never)")
+	syncCmd.Flags().String("download-order", "listed", "order to download pending blobs in (listed, smallest_first, largest_first, random)")
+	syncCmd.Flags().Bool("adopt", false, "record matching files already in the output path as downloaded instead of downloading them")
+	syncCmd.Flags().Bool("dedupe", false, "hardlink blobs identical to an already downloaded blob instead of downloading them")
 	syncCmd.Flags().Bool("verify-checksums", true, "verify MD5 checksums after download")
+	syncCmd.Flags().String("unsafe-names", "reject", "handling of blob names that escape the output path (reject, rewrite)")
+	syncCmd.Flags().String("temp-dir", "", "directory for partial downloads (default: next to each file)")
+	syncCmd.Flags().Bool("preserve-timestamps", true, "set downloaded file mtimes to the blob's last-modified time")
+	syncCmd.Flags().String("file-mode", "0644", "octal permissions for downloaded files")
+	syncCmd.Flags().String("dir-mode", "0755", "octal permissions for created directories")
+	syncCmd.Flags().Duration("blob-timeout", 10*time.Minute, "retry a download that receives no data for this long (0 = no timeout)")
+	syncCmd.Flags().Bool("decompress", false, "decompress gzip/deflate encoded blobs on download")
+	syncCmd.Flags().String("blob-version", "", "download each blob as of this version ID or RFC 3339 time (needs blob versioning)")
+	syncCmd.Flags().Bool("delete", false, "delete local files whose blobs were removed from the container")
+	syncCmd.Flags().Bool("delete-dry-run", false, "log local files that --delete would remove without deleting them")
+	syncCmd.Flags().Bool("rehydrate", false, "rehydrate archive-tier blobs so a later run can download them")
 	syncCmd.Flags().Int("disk-warn-percent", 80, "filesystem usage percent to warn at (1-99)")
 	syncCmd.Flags().Int("disk-stop-percent", 90, "filesystem usage percent to stop at (1-99)")
+	syncCmd.Flags().String("bandwidth-limit", "", "aggregate download bandwidth limit (e.g., 10M, 500K)")
+	syncCmd.Flags().Int("max-concurrent-requests", 0, "maximum Azure requests in flight at once (0 = unlimited)")
+	syncCmd.Flags().Bool("adaptive-workers", false, "scale active workers up to --workers based on measured throughput")
 	syncCmd.Flags().Bool("organize-folders", false, "enable folder organization")
 	syncCmd.Flags().Int("max-files-per-folder", 10000, "maximum files per folder")
-	syncCmd.Flags().String("folder-strategy", "sequential", "folder organization strategy (sequential, partition_key, date)")
+	syncCmd.Flags().String("folder-strategy", "sequential", "folder organization strategy (sequential, partition_key, date, extension, template)")
 	syncCmd.Flags().Int("partition-depth", 2, "partition depth for partition_key strategy")
+	syncCmd.Flags().String("folder-template", "", "folder layout for the template strategy (e.g., \"{year}/{ext}/{hash2}\")")
+	syncCmd.Flags().Bool("use-download-date", false, "date strategy: use the download date instead of blob last-modified")
 
-	if err := syncCmd.MarkFlagRequired("container"); err != nil {
-		fmt.Fprintf(os.Stderr, "failed to mark required flag: %v\n", err)
-	}
-
-	if err := viper.BindPFlag("azure.connection_string", syncCmd.Flags().Lookup("connection-string")); err != nil {
-		fmt.Fprintf(os.Stderr, "failed to bind connection-string: %v\n", err)
-	}
-	if err := viper.BindPFlag("azure.account_name", syncCmd.Flags().Lookup("account-name")); err != nil {
-		fmt.Fprintf(os.Stderr, "failed to bind account-name: %v\n", err)
-	}
-	if err := viper.BindPFlag("azure.account_key", syncCmd.Flags().Lookup("account-key")); err != nil {
-		fmt.Fprintf(os.Stderr, "failed to bind account-key: %v\n", err)
-	}
-	if err := viper.BindPFlag("azure.use_managed_identity", syncCmd.Flags().Lookup("use-managed-identity")); err != nil {
-		fmt.Fprintf(os.Stderr, "failed to bind use-managed-identity: %v\n", err)
-	}
-	if err := viper.BindPFlag("azure.tenant_id", syncCmd.Flags().Lookup("tenant-id")); err != nil {
-		fmt.Fprintf(os.Stderr, "failed to bind tenant-id: %v\n", err)
-	}
-	if err := viper.BindPFlag("azure.client_id", syncCmd.Flags().Lookup("client-id")); err != nil {
-		fmt.Fprintf(os.Stderr, "failed to bind client-id: %v\n", err)
-	}
-	if err := viper.BindPFlag("azure.client_secret", syncCmd.Flags().Lookup("client-secret")); err != nil {
-		fmt.Fprintf(os.Stderr, "failed to bind client-secret: %v\n", err)
-	}
-	if err := viper.BindPFlag("azure.use_azure_cli", syncCmd.Flags().Lookup("use-azure-cli")); err != nil {
-		fmt.Fprintf(os.Stderr, "failed to bind use-azure-cli: %v\n", err)
-	}
 	if err := viper.BindPFlag("sync.container", syncCmd.Flags().Lookup("container")); err != nil {
 		fmt.Fprintf(os.Stderr, "failed to bind container: %v\n", err)
 	}
@@ -103,6 +110,18 @@ func init() {
 	if err := viper.BindPFlag("sync.prefix", syncCmd.Flags().Lookup("prefix")); err != nil {
 		fmt.Fprintf(os.Stderr, "failed to bind prefix: %v\n", err)
 	}
+	if err := viper.BindPFlag("sync.include", syncCmd.Flags().Lookup("include")); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to bind include: %v\n", err)
+	}
+	if err := viper.BindPFlag("sync.exclude", syncCmd.Flags().Lookup("exclude")); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to bind exclude: %v\n", err)
+	}
+	if err := viper.BindPFlag("sync.modified_after", syncCmd.Flags().Lookup("modified-after")); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to bind modified-after: %v\n", err)
+	}
+	if err := viper.BindPFlag("sync.modified_before", syncCmd.Flags().Lookup("modified-before")); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to bind modified-before: %v\n", err)
+	}
 	if err := viper.BindPFlag("sync.workers", syncCmd.Flags().Lookup("workers")); err != nil {
 		fmt.Fprintf(os.Stderr, "failed to bind workers: %v\n", err)
 	}
@@ -112,18 +131,84 @@ func init() {
 	if err := viper.BindPFlag("sync.skip_existing", syncCmd.Flags().Lookup("skip-existing")); err != nil {
 		fmt.Fprintf(os.Stderr, "failed to bind skip-existing: %v\n", err)
 	}
+	if err := syncCmd.Flags().MarkDeprecated("skip-existing", "use --overwrite-policy instead"); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to deprecate skip-existing: %v\n", err)
+	}
+	if err := viper.BindPFlag("sync.overwrite_policy", syncCmd.Flags().Lookup("overwrite-policy")); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to bind overwrite-policy: %v\n", err)
+	}
+	if err := viper.BindPFlag("sync.download_order", syncCmd.Flags().Lookup("download-order")); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to bind download-order: %v\n", err)
+	}
+	if err := viper.BindPFlag("sync.adopt", syncCmd.Flags().Lookup("adopt")); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to bind adopt: %v\n", err)
+	}
+	if err := viper.BindPFlag("sync.dedupe", syncCmd.Flags().Lookup("dedupe")); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to bind dedupe: %v\n", err)
+	}
 	if err := viper.BindPFlag("sync.verify_checksums", syncCmd.Flags().Lookup("verify-checksums")); err != nil {
 		fmt.Fprintf(os.Stderr, "failed to bind verify-checksums: %v\n", err)
 	}
 	if err := viper.BindPFlag("sync.force_resync", syncCmd.Flags().Lookup("force-resync")); err != nil {
 		fmt.Fprintf(os.Stderr, "failed to bind force-resync: %v\n", err)
 	}
+	if err := viper.BindPFlag("sync.manifest_path", syncCmd.Flags().Lookup("manifest-path")); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to bind manifest-path: %v\n", err)
+	}
+	if err := viper.BindPFlag("sync.manifest_all", syncCmd.Flags().Lookup("manifest-all")); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to bind manifest-all: %v\n", err)
+	}
+	if err := viper.BindPFlag("sync.dry_run", syncCmd.Flags().Lookup("dry-run")); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to bind dry-run: %v\n", err)
+	}
+	if err := viper.BindPFlag("sync.unsafe_names", syncCmd.Flags().Lookup("unsafe-names")); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to bind unsafe-names: %v\n", err)
+	}
+	if err := viper.BindPFlag("sync.temp_dir", syncCmd.Flags().Lookup("temp-dir")); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to bind temp-dir: %v\n", err)
+	}
+	if err := viper.BindPFlag("sync.preserve_timestamps", syncCmd.Flags().Lookup("preserve-timestamps")); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to bind preserve-timestamps: %v\n", err)
+	}
+	if err := viper.BindPFlag("sync.file_mode", syncCmd.Flags().Lookup("file-mode")); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to bind file-mode: %v\n", err)
+	}
+	if err := viper.BindPFlag("sync.dir_mode", syncCmd.Flags().Lookup("dir-mode")); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to bind dir-mode: %v\n", err)
+	}
+	if err := viper.BindPFlag("sync.blob_timeout", syncCmd.Flags().Lookup("blob-timeout")); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to bind blob-timeout: %v\n", err)
+	}
+	if err := viper.BindPFlag("sync.decompress_on_download", syncCmd.Flags().Lookup("decompress")); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to bind decompress: %v\n", err)
+	}
+	if err := viper.BindPFlag("sync.version", syncCmd.Flags().Lookup("blob-version")); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to bind blob-version: %v\n", err)
+	}
+	if err := viper.BindPFlag("sync.mirror", syncCmd.Flags().Lookup("delete")); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to bind delete: %v\n", err)
+	}
+	if err := viper.BindPFlag("sync.mirror_dry_run", syncCmd.Flags().Lookup("delete-dry-run")); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to bind delete-dry-run: %v\n", err)
+	}
+	if err := viper.BindPFlag("sync.rehydrate", syncCmd.Flags().Lookup("rehydrate")); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to bind rehydrate: %v\n", err)
+	}
 	if err := viper.BindPFlag("sync.disk_warn_percent", syncCmd.Flags().Lookup("disk-warn-percent")); err != nil {
 		fmt.Fprintf(os.Stderr, "failed to bind disk-warn-percent: %v\n", err)
 	}
 	if err := viper.BindPFlag("sync.disk_stop_percent", syncCmd.Flags().Lookup("disk-stop-percent")); err != nil {
 		fmt.Fprintf(os.Stderr, "failed to bind disk-stop-percent: %v\n", err)
 	}
+	if err := viper.BindPFlag("performance.bandwidth_limit", syncCmd.Flags().Lookup("bandwidth-limit")); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to bind bandwidth-limit: %v\n", err)
+	}
+	if err := viper.BindPFlag("performance.max_concurrent_requests", syncCmd.Flags().Lookup("max-concurrent-requests")); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to bind max-concurrent-requests: %v\n", err)
+	}
+	if err := viper.BindPFlag("performance.adaptive_workers", syncCmd.Flags().Lookup("adaptive-workers")); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to bind adaptive-workers: %v\n", err)
+	}
 	if err := viper.BindPFlag("sync.folder_organization.enabled", syncCmd.Flags().Lookup("organize-folders")); err != nil {
 		fmt.Fprintf(os.Stderr, "failed to bind organize-folders: %v\n", err)
 	}
@@ -136,74 +221,240 @@ func init() {
 	if err := viper.BindPFlag("sync.folder_organization.partition_depth", syncCmd.Flags().Lookup("partition-depth")); err != nil {
 		fmt.Fprintf(os.Stderr, "failed to bind partition-depth: %v\n", err)
 	}
+	if err := viper.BindPFlag("sync.folder_organization.folder_template", syncCmd.Flags().Lookup("folder-template")); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to bind folder-template: %v\n", err)
+	}
+	if err := viper.BindPFlag("sync.folder_organization.use_download_date", syncCmd.Flags().Lookup("use-download-date")); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to bind use-download-date: %v\n", err)
+	}
 	if err := viper.BindPFlag("watch.enabled", syncCmd.Flags().Lookup("watch")); err != nil {
 		fmt.Fprintf(os.Stderr, "failed to bind watch: %v\n", err)
 	}
 	if err := viper.BindPFlag("watch.interval", syncCmd.Flags().Lookup("watch-interval")); err != nil {
 		fmt.Fprintf(os.Stderr, "failed to bind watch-interval: %v\n", err)
 	}
+	if err := viper.BindPFlag("watch.cron", syncCmd.Flags().Lookup("watch-cron")); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to bind watch-cron: %v\n", err)
+	}
+	if err := viper.BindPFlag("watch.metrics_addr", syncCmd.Flags().Lookup("metrics-addr")); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to bind metrics-addr: %v\n", err)
+	}
+	if err := viper.BindPFlag("watch.health_addr", syncCmd.Flags().Lookup("health-addr")); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to bind health-addr: %v\n", err)
+	}
 	if err := viper.BindPFlag("state.database", syncCmd.Flags().Lookup("state-db")); err != nil {
 		fmt.Fprintf(os.Stderr, "failed to bind state-db: %v\n", err)
 	}
 }
 
+// readyStaleIntervals is the number of watch intervals without a successful
+// run after which /readyz reports the process as not ready.
+const readyStaleIntervals = 3
+
 func runSync(cmd *cobra.Command, args []string) error {
-	if err := viper.Unmarshal(cfg); err != nil {
+	if err := bindFlags(cmd, azureFlagBindings); err != nil {
+		return err
+	}
+
+	if err := unmarshalConfig(); err != nil {
 		return fmt.Errorf("failed to parse configuration: %w", err)
 	}
 
 	if err := cfg.Validate(); err != nil {
 		return fmt.Errorf("invalid configuration: %w", err)
 	}
+	// The interval always has a default, so only one that was set
+	// explicitly conflicts with a cron schedule.
+	if cfg.Watch.Cron != "" && viper.IsSet("watch.interval") {
+		return fmt.Errorf("invalid configuration: watch.cron and watch.interval are mutually exclusive")
+	}
+	// Validate has already checked the expression.
+	schedule, _ := cfg.Watch.Schedule()
 
-	log, err := logger.New(logger.Config{
-		Level:  cfg.Logging.Level,
-		Format: cfg.Logging.Format,
-	})
+	log, err := newLogger()
 	if err != nil {
-		return fmt.Errorf("failed to create logger: %w", err)
+		return err
 	}
 	defer func() { _ = log.Close() }()
+	log.Debugw("Loaded configuration", "file", viper.ConfigFileUsed(), "azure", cfg.Azure.Redact())
+
+	// A dry run writes nothing, so it can run alongside a real sync.
+	if !cfg.Sync.DryRun {
+		lock, err := storage.AcquireLock(cfg.State.Database)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = lock.Release() }()
+	}
 
-	db, err := storage.Open(cfg.State.Database)
+	db, err := storage.OpenWithBusyTimeout(cfg.State.Database, cfg.State.BusyTimeout())
 	if err != nil {
 		return fmt.Errorf("failed to open state database: %w", err)
 	}
 	defer func() { _ = db.Close() }()
 
-	azClient, err := azure.CreateClient(&cfg.Azure)
+	client, err := newAzureClient()
 	if err != nil {
-		return fmt.Errorf("failed to create Azure client: %w", err)
+		return err
+	}
+
+	var metrics *telemetry.Metrics
+	if cfg.Watch.MetricsAddr != "" {
+		metrics = telemetry.New()
+		srv, err := telemetry.Serve(cfg.Watch.MetricsAddr, metrics)
+		if err != nil {
+			return fmt.Errorf("failed to start metrics server: %w", err)
+		}
+		defer func() { _ = srv.Shutdown() }()
+		log.Infow("Serving metrics", "addr", srv.Addr())
+	}
+
+	var health *telemetry.Health
+	if cfg.Watch.HealthAddr != "" {
+		health = telemetry.NewHealth(watchPeriod(cfg.Watch.Interval, schedule, time.Now()) * readyStaleIntervals)
+		srv, err := telemetry.ServeHealth(cfg.Watch.HealthAddr, health)
+		if err != nil {
+			return fmt.Errorf("failed to start health server: %w", err)
+		}
+		defer func() { _ = srv.Shutdown() }()
+		log.Infow("Serving health checks", "addr", srv.Addr())
+	}
+
+	// A signal cancels ctx, which interrupts the run in progress and ends
+	// the watch loop; each run derives its own context from it.
+	ctx, stop := signalContext(log)
+	defer stop()
+
+	// Containers are synced one after another, each with its own state.
+	containerCfgs := cfg.ForEachContainer()
+	// The progress display shares the terminal with text logs; it would only
+	// corrupt JSON logs or redirected output.
+	showProgress := cfg.Logging.Format != "json" && isTerminal(os.Stdout)
+
+	// A dry run writes nothing, including audit entries.
+	var auditLog *audit.Log
+	if !cfg.Sync.DryRun {
+		if auditLog, err = openAuditLog(); err != nil {
+			return err
+		}
+		defer func() { _ = auditLog.Close() }()
 	}
 
-	client := azure.NewClient(azClient)
+	var syncers []*sync.Syncer
+	for _, containerCfg := range containerCfgs {
+		syncer := sync.New(containerCfg, client, db, log)
+		syncer.SetContext(ctx)
+		syncer.SetMetrics(metrics)
+		syncer.SetAuditLog(auditLog)
+		if showProgress {
+			syncer.SetProgressOutput(os.Stdout)
+		}
+		syncers = append(syncers, syncer)
+	}
 
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	if cfg.Watch.Enabled {
+		defer func() {
+			if err := sdnotify.Notify(sdnotify.Stopping); err != nil {
+				log.Warnw("Failed to notify systemd", "error", err)
+			}
+		}()
 
-	syncer := sync.New(cfg, client, db, log)
+		stopWatchdog := make(chan struct{})
+		defer close(stopWatchdog)
+		go sdnotify.RunWatchdog(stopWatchdog, func(err error) {
+			log.Warnw("Failed to notify systemd watchdog", "error", err)
+		})
+	}
 
-	go func() {
-		<-sigChan
-		log.Info("Received interrupt signal, stopping...")
-		syncer.Stop()
-	}()
+	resume, _ := cmd.Flags().GetBool("resume")
+	// ready is set once systemd has been told the watcher is up.
+	ready := false
 
 	for {
-		if err := syncer.Start(); err != nil {
-			log.Errorw("Sync failed", "error", err)
-			if !cfg.Watch.Enabled {
-				return err
+		var errs []error
+		for i, syncer := range syncers {
+			// A signal between containers leaves nothing to resume.
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			run := syncer.Start
+			if resume {
+				run = syncer.Resume
+			}
+
+			if err := run(); err != nil {
+				if errors.Is(err, sync.ErrInterrupted) {
+					log.Info("Sync interrupted; run again with --resume to continue")
+					return nil
+				}
+				// A signal during setup stops the sync before a run is recorded.
+				if ctx.Err() != nil {
+					return nil
+				}
+				container := containerCfgs[i].Sync.Container
+				log.Errorw("Sync failed", "container", container, "error", err)
+				if len(syncers) > 1 {
+					err = fmt.Errorf("container %s: %w", container, err)
+				}
+				errs = append(errs, err)
+			}
+		}
+		resume = false
+
+		if len(errs) > 0 {
+			health.RunFailed(errors.Join(errs...))
+		} else {
+			health.RunSucceeded()
+			if cfg.Watch.Enabled && !ready {
+				if err := sdnotify.Notify(sdnotify.Ready); err != nil {
+					log.Warnw("Failed to notify systemd", "error", err)
+				}
+				ready = true
 			}
 		}
 
-		if !cfg.Watch.Enabled {
+		// A dry run is a one-off preview, even with watch mode configured.
+		if !cfg.Watch.Enabled || cfg.Sync.DryRun {
+			if len(errs) > 0 {
+				return errors.Join(errs...)
+			}
 			break
 		}
 
-		log.Infow("Watch mode: sleeping", "interval", cfg.Watch.Interval)
-		time.Sleep(cfg.Watch.Interval)
+		wait := cfg.Watch.Interval
+		if schedule != nil {
+			next := schedule.Next(time.Now())
+			wait = time.Until(next)
+			log.Infow("Watch mode: waiting for next scheduled run", "next_run", next.Format(time.RFC3339))
+		} else {
+			log.Infow("Watch mode: sleeping", "interval", wait)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
 	}
 
 	return nil
 }
+
+// watchPeriod returns the typical time between watch runs: the gap between
+// the next two scheduled runs after now, or interval without a schedule.
+func watchPeriod(interval time.Duration, schedule cron.Schedule, now time.Time) time.Duration {
+	if schedule == nil {
+		return interval
+	}
+	next := schedule.Next(now)
+	return schedule.Next(next).Sub(next)
+}
+
+// isTerminal reports whether f is an interactive terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
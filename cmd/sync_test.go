@@ -0,0 +1,101 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// watchSubprocessEnv marks the test binary re-executed to run the sync
+// command in its own process.
+const watchSubprocessEnv = "GETBLOBZ_TEST_WATCH_ARGS"
+
+func TestMain(m *testing.M) {
+	if args := os.Getenv(watchSubprocessEnv); args != "" {
+		rootCmd.SetArgs(strings.Split(args, "\n"))
+		Execute()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func TestRunSync_WatchExitsOnSignalWhileSleeping(t *testing.T) {
+	// Every container lookup fails fast, so each watch run ends at once
+	// and the process spends its time sleeping between runs.
+	storageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("x-ms-error-code", "ContainerNotFound")
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer storageServer.Close()
+
+	dir := t.TempDir()
+	connectionString := "DefaultEndpointsProtocol=http;AccountName=devstoreaccount1;" +
+		"AccountKey=Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw==;" +
+		"BlobEndpoint=" + storageServer.URL + "/devstoreaccount1"
+	args := []string{
+		"sync",
+		"--container", "missing",
+		"--connection-string", connectionString,
+		"--output-path", filepath.Join(dir, "out"),
+		"--state-db", filepath.Join(dir, "state.db"),
+		"--watch",
+		"--watch-interval", "1h",
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "HOME="+dir, watchSubprocessEnv+"="+strings.Join(args, "\n"))
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("failed to pipe stdout: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("failed to start sync: %v", err)
+	}
+	defer func() { _ = cmd.Process.Kill() }()
+
+	sleeping := make(chan struct{})
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			if strings.Contains(scanner.Text(), "Watch mode: sleeping") {
+				close(sleeping)
+				break
+			}
+		}
+		// Keep draining so the process never blocks writing logs.
+		for scanner.Scan() {
+		}
+	}()
+
+	select {
+	case <-sleeping:
+	case <-time.After(30 * time.Second):
+		t.Fatal("sync never reached the watch sleep")
+	}
+
+	if err := cmd.Process.Signal(syscall.SIGTERM); err != nil {
+		t.Fatalf("failed to send SIGTERM: %v", err)
+	}
+
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	select {
+	case err := <-exited:
+		if err != nil {
+			t.Errorf("sync exited with error: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("sync did not exit after SIGTERM")
+	}
+}
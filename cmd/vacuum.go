@@ -0,0 +1,67 @@
+// Package cmd provides the vacuum command for compacting the state database.
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/haepapa/getblobz/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+// vacuumCmd represents the vacuum command.
+var vacuumCmd = &cobra.Command{
+	Use:   "vacuum",
+	Short: "Compact the state database and flush its write-ahead log",
+	Long: `Vacuum rebuilds the state database to return the space left by deleted
+rows, for example after prune-runs or a forced resync, and folds the
+write-ahead log back into the database file. The size before and after is
+reported. It refuses to run while a sync is using the database.
+
+Examples:
+  getblobz vacuum --state-db /var/lib/getblobz/.sync-state.db`,
+	RunE: runVacuum,
+}
+
+func init() {
+	rootCmd.AddCommand(vacuumCmd)
+
+	vacuumCmd.Flags().String("state-db", "./.sync-state.db", "path to state database")
+}
+
+func runVacuum(cmd *cobra.Command, args []string) error {
+	dbPath, _ := cmd.Flags().GetString("state-db")
+
+	lock, err := storage.AcquireLock(dbPath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = lock.Release() }()
+
+	db, err := storage.OpenWithBusyTimeout(dbPath, cfg.State.BusyTimeout())
+	if err != nil {
+		return fmt.Errorf("failed to open state database: %w", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	return vacuumDB(db, dbPath)
+}
+
+// vacuumDB compacts db, stored at dbPath, and reports the size reclaimed.
+// The caller must hold the database lock.
+func vacuumDB(db *storage.DB, dbPath string) error {
+	before, err := storage.FileSize(dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to get database size: %w", err)
+	}
+
+	if err := db.Vacuum(); err != nil {
+		return err
+	}
+
+	after, err := storage.FileSize(dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to get database size: %w", err)
+	}
+	fmt.Printf("Database compacted: %d bytes -> %d bytes (%d reclaimed)\n", before, after, before-after)
+	return nil
+}
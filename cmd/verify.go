@@ -0,0 +1,93 @@
+// Package cmd provides the verify command for checking local files against state.
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/haepapa/getblobz/internal/storage"
+	"github.com/haepapa/getblobz/internal/sync"
+	"github.com/spf13/cobra"
+)
+
+// verifyCmd represents the verify command.
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check downloaded files against the state database",
+	Long: `Verify checks every downloaded blob recorded in the state database and
+confirms its local file still exists and has the recorded size. With
+--checksums it also recomputes each file's MD5 and compares it to the
+recorded Content-MD5.
+
+Missing and mismatched files are reported. With --repair they are marked
+pending so that the next sync downloads them again.
+
+Examples:
+  # Check that all downloaded files exist with the right size
+  getblobz verify
+
+  # Also compare checksums and queue broken files for re-download
+  getblobz verify --checksums --repair`,
+	RunE: runVerify,
+}
+
+func init() {
+	rootCmd.AddCommand(verifyCmd)
+
+	verifyCmd.Flags().String("state-db", "./.sync-state.db", "path to state database")
+	verifyCmd.Flags().Bool("checksums", false, "recompute and compare MD5 checksums")
+	verifyCmd.Flags().Bool("repair", false, "mark missing or mismatched files pending for the next sync")
+}
+
+func runVerify(cmd *cobra.Command, args []string) error {
+	dbPath, _ := cmd.Flags().GetString("state-db")
+	checksums, _ := cmd.Flags().GetBool("checksums")
+	repair, _ := cmd.Flags().GetBool("repair")
+
+	// Only --repair writes to the database.
+	open := storage.OpenReadOnly
+	if repair {
+		open = storage.OpenExisting
+	}
+	db, err := open(dbPath, cfg.State.BusyTimeout())
+	if err != nil {
+		return fmt.Errorf("failed to open state database: %w", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	blobs, err := db.GetBlobStatesByStatus(storage.BlobStatusDownloaded)
+	if err != nil {
+		return fmt.Errorf("failed to get downloaded blobs: %w", err)
+	}
+
+	var problems int
+	for _, blob := range blobs {
+		problem, err := sync.VerifyLocalFile(blob, checksums)
+		if err != nil {
+			return fmt.Errorf("failed to verify %s: %w", blob.BlobName, err)
+		}
+		if problem == "" {
+			continue
+		}
+
+		problems++
+		fmt.Printf("  • %s: %s\n    Path: %s\n", blob.BlobName, problem, blob.LocalPath)
+
+		if repair {
+			blob.Status = storage.BlobStatusPending
+			if err := db.UpsertBlobState(blob); err != nil {
+				return fmt.Errorf("failed to mark %s pending: %w", blob.BlobName, err)
+			}
+		}
+	}
+
+	fmt.Printf("\nVerified %d files: %d ok, %d with problems\n", len(blobs), len(blobs)-problems, problems)
+
+	if problems == 0 {
+		return nil
+	}
+	if repair {
+		fmt.Println("Marked files with problems as pending; run sync to repair them.")
+		return nil
+	}
+	return fmt.Errorf("%d files failed verification", problems)
+}
@@ -0,0 +1,91 @@
+// Package audit provides an append-only record of the files a sync writes
+// and deletes, kept apart from the operational logs.
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Actions recorded in the audit log.
+const (
+	// ActionDownloaded records a blob written to its local path.
+	ActionDownloaded = "downloaded"
+	// ActionDeleted records a local file removed because its blob was deleted.
+	ActionDeleted = "deleted"
+)
+
+// Entry is one line of the audit log.
+type Entry struct {
+	Time       time.Time `json:"time"`
+	Action     string    `json:"action"`
+	RunID      int64     `json:"run_id,omitempty"`
+	Container  string    `json:"container"`
+	Blob       string    `json:"blob"`
+	LocalPath  string    `json:"local_path"`
+	Size       int64     `json:"size"`
+	ContentMD5 string    `json:"content_md5,omitempty"`
+	ETag       string    `json:"etag,omitempty"`
+}
+
+// Log appends entries to an audit file as newline-delimited JSON. It is
+// safe for concurrent use, and a nil *Log records nothing.
+type Log struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// Open opens the audit log at path for appending, creating it and its
+// directory if needed. Existing entries are never truncated.
+func Open(path string) (*Log, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	return &Log{file: file}, nil
+}
+
+// Record appends entry, setting its time to now if it is zero. Each entry
+// is a single write, so entries from concurrent workers never interleave.
+func (l *Log) Record(entry Entry) error {
+	if l == nil {
+		return nil
+	}
+	if entry.Time.IsZero() {
+		entry.Time = time.Now().UTC()
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.file.Write(line); err != nil {
+		return fmt.Errorf("failed to write audit entry: %w", err)
+	}
+	return nil
+}
+
+// Close syncs and closes the audit log.
+func (l *Log) Close() error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.file.Sync(); err != nil {
+		_ = l.file.Close()
+		return fmt.Errorf("failed to sync audit log: %w", err)
+	}
+	return l.file.Close()
+}
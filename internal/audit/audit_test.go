@@ -0,0 +1,72 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func readEntries(t *testing.T, path string) []Entry {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open audit log: %v", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("failed to parse audit line %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("failed to read audit log: %v", err)
+	}
+	return entries
+}
+
+func TestLog_AppendsAcrossReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "getblobz.jsonl")
+
+	for i, blob := range []string{"a.txt", "b.txt"} {
+		l, err := Open(path)
+		if err != nil {
+			t.Fatalf("open %d: %v", i, err)
+		}
+		if err := l.Record(Entry{Action: ActionDownloaded, Blob: blob, Size: 3}); err != nil {
+			t.Fatalf("record %s: %v", blob, err)
+		}
+		if err := l.Close(); err != nil {
+			t.Fatalf("close %d: %v", i, err)
+		}
+	}
+
+	entries := readEntries(t, path)
+	if len(entries) != 2 {
+		t.Fatalf("entries = %d, want 2", len(entries))
+	}
+	for i, want := range []string{"a.txt", "b.txt"} {
+		if entries[i].Blob != want || entries[i].Action != ActionDownloaded {
+			t.Errorf("entry %d = %+v, want a download of %s", i, entries[i], want)
+		}
+		if entries[i].Time.IsZero() {
+			t.Errorf("entry %d has no time", i)
+		}
+	}
+}
+
+func TestLog_NilRecordsNothing(t *testing.T) {
+	var l *Log
+	if err := l.Record(Entry{Action: ActionDeleted, Blob: "a.txt"}); err != nil {
+		t.Errorf("Record() on nil log error = %v", err)
+	}
+	if err := l.Close(); err != nil {
+		t.Errorf("Close() on nil log error = %v", err)
+	}
+}
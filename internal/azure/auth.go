@@ -3,7 +3,13 @@ package azure
 
 import (
 	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
 	"github.com/haepapa/getblobz/internal/config"
@@ -11,10 +17,15 @@ import (
 
 // CreateClient creates an Azure Blob Storage client based on the provided configuration.
 // It supports multiple authentication methods: connection string, account key,
-// managed identity, service principal, and Azure CLI credentials.
+// managed identity, service principal, Azure CLI credentials, and anonymous
+// access to public containers.
 func CreateClient(cfg *config.AzureConfig) (*azblob.Client, error) {
 	if cfg.ConnectionString != "" {
-		return createClientFromConnectionString(cfg.ConnectionString)
+		return createClientFromConnectionString(cfg)
+	}
+
+	if cfg.Anonymous {
+		return createAnonymousClient(cfg)
 	}
 
 	if cfg.AccountName != "" {
@@ -25,24 +36,55 @@ func CreateClient(cfg *config.AzureConfig) (*azblob.Client, error) {
 }
 
 // createClientFromConnectionString creates a client using a connection string.
-func createClientFromConnectionString(connectionString string) (*azblob.Client, error) {
-	client, err := azblob.NewClientFromConnectionString(connectionString, nil)
+func createClientFromConnectionString(cfg *config.AzureConfig) (*azblob.Client, error) {
+	clientOpts, err := clientOptions(cfg, "")
+	if err != nil {
+		return nil, err
+	}
+	client, err := azblob.NewClientFromConnectionString(cfg.ConnectionString, clientOpts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create client from connection string: %w", err)
 	}
 	return client, nil
 }
 
+// createAnonymousClient creates a client that sends no credentials, for
+// containers with public read access.
+func createAnonymousClient(cfg *config.AzureConfig) (*azblob.Client, error) {
+	if cfg.AccountName == "" && cfg.BlobEndpoint == "" {
+		return nil, fmt.Errorf("anonymous access requires an account name or blob endpoint")
+	}
+
+	serviceURL := serviceURL(cfg)
+	clientOpts, err := clientOptions(cfg, serviceURL)
+	if err != nil {
+		return nil, err
+	}
+	client, err := azblob.NewClientWithNoCredential(serviceURL, clientOpts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create anonymous client: %w", err)
+	}
+	return client, nil
+}
+
 // createClientFromAccountName creates a client using account name with various auth methods.
 func createClientFromAccountName(cfg *config.AzureConfig) (*azblob.Client, error) {
-	serviceURL := fmt.Sprintf("https://%s.blob.core.windows.net/", cfg.AccountName)
+	serviceURL := serviceURL(cfg)
+	clientOpts, err := clientOptions(cfg, serviceURL)
+	if err != nil {
+		return nil, err
+	}
+	credOpts := azcore.ClientOptions{
+		Cloud:     cloudConfiguration(endpointSuffix(cfg)),
+		Transport: clientOpts.Transport,
+	}
 
 	if cfg.AccountKey != "" {
 		cred, err := azblob.NewSharedKeyCredential(cfg.AccountName, cfg.AccountKey)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create shared key credential: %w", err)
 		}
-		client, err := azblob.NewClientWithSharedKeyCredential(serviceURL, cred, nil)
+		client, err := azblob.NewClientWithSharedKeyCredential(serviceURL, cred, clientOpts)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create client with shared key: %w", err)
 		}
@@ -50,11 +92,13 @@ func createClientFromAccountName(cfg *config.AzureConfig) (*azblob.Client, error
 	}
 
 	if cfg.UseManagedIdentity {
-		cred, err := azidentity.NewManagedIdentityCredential(nil)
+		cred, err := azidentity.NewManagedIdentityCredential(&azidentity.ManagedIdentityCredentialOptions{
+			ClientOptions: credOpts,
+		})
 		if err != nil {
 			return nil, fmt.Errorf("failed to create managed identity credential: %w", err)
 		}
-		client, err := azblob.NewClient(serviceURL, cred, nil)
+		client, err := azblob.NewClient(serviceURL, cred, clientOpts)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create client with managed identity: %w", err)
 		}
@@ -66,12 +110,12 @@ func createClientFromAccountName(cfg *config.AzureConfig) (*azblob.Client, error
 			cfg.TenantID,
 			cfg.ClientID,
 			cfg.ClientSecret,
-			nil,
+			&azidentity.ClientSecretCredentialOptions{ClientOptions: credOpts},
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create service principal credential: %w", err)
 		}
-		client, err := azblob.NewClient(serviceURL, cred, nil)
+		client, err := azblob.NewClient(serviceURL, cred, clientOpts)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create client with service principal: %w", err)
 		}
@@ -83,7 +127,7 @@ func createClientFromAccountName(cfg *config.AzureConfig) (*azblob.Client, error
 		if err != nil {
 			return nil, fmt.Errorf("failed to create Azure CLI credential: %w", err)
 		}
-		client, err := azblob.NewClient(serviceURL, cred, nil)
+		client, err := azblob.NewClient(serviceURL, cred, clientOpts)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create client with Azure CLI: %w", err)
 		}
@@ -92,3 +136,78 @@ func createClientFromAccountName(cfg *config.AzureConfig) (*azblob.Client, error
 
 	return nil, fmt.Errorf("no valid authentication method found for account name")
 }
+
+// serviceURL returns the blob service URL for the account. An explicit
+// BlobEndpoint (Private Link, Azurite, etc.) is used verbatim; otherwise the
+// URL is derived from the account name and endpoint suffix.
+func serviceURL(cfg *config.AzureConfig) string {
+	if cfg.BlobEndpoint != "" {
+		return cfg.BlobEndpoint
+	}
+	return fmt.Sprintf("https://%s.blob.%s/", cfg.AccountName, endpointSuffix(cfg))
+}
+
+// clientOptions builds the blob client options shared by every authentication method.
+// Credentials are allowed over plain HTTP only for explicit http:// endpoints
+// such as a local Azurite emulator.
+func clientOptions(cfg *config.AzureConfig, serviceURL string) (*azblob.ClientOptions, error) {
+	transport, err := newTransport(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &azblob.ClientOptions{
+		ClientOptions: azcore.ClientOptions{
+			Retry: policy.RetryOptions{
+				MaxRetries:    cfg.RetryPolicy.MaxRetries,
+				TryTimeout:    cfg.RetryPolicy.TryTimeout,
+				RetryDelay:    cfg.RetryPolicy.RetryDelay,
+				MaxRetryDelay: cfg.RetryPolicy.MaxRetryDelay,
+			},
+			Transport:                       transport,
+			InsecureAllowCredentialWithHTTP: strings.HasPrefix(strings.ToLower(serviceURL), "http://"),
+		},
+	}, nil
+}
+
+// newTransport builds the HTTP client used for Azure requests. An explicit
+// ProxyURL takes precedence; otherwise HTTPS_PROXY/HTTP_PROXY/NO_PROXY apply.
+func newTransport(cfg *config.AzureConfig) (*http.Client, error) {
+	proxy := http.ProxyFromEnvironment
+	if cfg.ProxyURL != "" {
+		proxyURL, err := url.Parse(cfg.ProxyURL)
+		if err != nil || proxyURL.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %q", cfg.ProxyURL)
+		}
+		proxy = http.ProxyURL(proxyURL)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxy
+
+	return &http.Client{Transport: transport}, nil
+}
+
+// endpointSuffix returns the configured storage endpoint suffix, falling back
+// to the Azure public cloud suffix when unset.
+func endpointSuffix(cfg *config.AzureConfig) string {
+	suffix := strings.Trim(strings.TrimSpace(cfg.EndpointSuffix), ".")
+	if suffix == "" {
+		return "core.windows.net"
+	}
+	return suffix
+}
+
+// cloudConfiguration maps a storage endpoint suffix to the matching Azure cloud
+// so that credentials request tokens from the correct authority.
+// Unknown suffixes (e.g. Azure Stack) fall back to the public cloud.
+func cloudConfiguration(suffix string) cloud.Configuration {
+	switch strings.ToLower(suffix) {
+	case "core.usgovcloudapi.net":
+		return cloud.AzureGovernment
+	case "core.chinacloudapi.cn":
+		return cloud.AzureChina
+	default:
+		return cloud.AzurePublic
+	}
+}
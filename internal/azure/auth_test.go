@@ -0,0 +1,91 @@
+package azure
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/haepapa/getblobz/internal/config"
+)
+
+func TestCreateClient_UsesProxy(t *testing.T) {
+	var proxied atomic.Int32
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Host == "storage.invalid" {
+			proxied.Add(1)
+		}
+		w.Header().Set("x-ms-error-code", "ContainerNotFound")
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer proxy.Close()
+
+	sdkClient, err := CreateClient(&config.AzureConfig{
+		AccountName:  "devstoreaccount1",
+		AccountKey:   "Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw==",
+		BlobEndpoint: "http://storage.invalid/devstoreaccount1",
+		ProxyURL:     proxy.URL,
+		RetryPolicy:  config.RetryPolicyConfig{MaxRetries: -1},
+	})
+	if err != nil {
+		t.Fatalf("CreateClient error: %v", err)
+	}
+
+	exists, err := NewClient(sdkClient).ContainerExists(context.Background(), "mycontainer")
+	if err != nil {
+		t.Fatalf("ContainerExists error: %v", err)
+	}
+	if exists {
+		t.Error("expected container to not exist")
+	}
+	if proxied.Load() == 0 {
+		t.Error("expected request to be routed through the proxy")
+	}
+}
+
+func TestCreateClient_Anonymous(t *testing.T) {
+	var requests, authorized atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.Header.Get("Authorization") != "" {
+			authorized.Add(1)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	sdkClient, err := CreateClient(&config.AzureConfig{
+		Anonymous:    true,
+		BlobEndpoint: server.URL + "/publicaccount",
+		RetryPolicy:  config.RetryPolicyConfig{MaxRetries: -1},
+	})
+	if err != nil {
+		t.Fatalf("CreateClient error: %v", err)
+	}
+
+	exists, err := NewClient(sdkClient).ContainerExists(context.Background(), "open-data")
+	if err != nil {
+		t.Fatalf("ContainerExists error: %v", err)
+	}
+	if !exists {
+		t.Error("expected container to exist")
+	}
+	if requests.Load() == 0 || authorized.Load() != 0 {
+		t.Errorf("got %d requests, %d with credentials; want requests without credentials", requests.Load(), authorized.Load())
+	}
+
+	if _, err := CreateClient(&config.AzureConfig{Anonymous: true}); err == nil {
+		t.Error("expected error for anonymous access without an account name or endpoint")
+	}
+}
+
+func TestCreateClient_InvalidProxy(t *testing.T) {
+	_, err := CreateClient(&config.AzureConfig{
+		ConnectionString: "DefaultEndpointsProtocol=https;AccountName=a;AccountKey=a2V5;EndpointSuffix=core.windows.net",
+		ProxyURL:         "://bad",
+	})
+	if err == nil {
+		t.Error("expected error for invalid proxy URL")
+	}
+}
@@ -2,18 +2,34 @@
 package azure
 
 import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
 	"context"
+	"crypto/md5"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blockblob"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
 )
 
 // Client wraps the Azure Blob Storage client with application-specific operations.
 type Client struct {
 	client *azblob.Client
+	// requests holds one slot per in-flight request when the number of
+	// concurrent requests is limited; nil means unlimited.
+	requests chan struct{}
 }
 
 // NewClient creates a new Azure client wrapper.
@@ -21,30 +37,158 @@ func NewClient(client *azblob.Client) *Client {
 	return &Client{client: client}
 }
 
+// SetMaxConcurrentRequests limits the number of requests the client has in
+// flight at once, across all callers sharing it (0 = unlimited). A download
+// holds its slot until the body has been read. It must be called before the
+// client is used.
+func (c *Client) SetMaxConcurrentRequests(n int) {
+	if n <= 0 {
+		c.requests = nil
+		return
+	}
+	c.requests = make(chan struct{}, n)
+}
+
+// acquire waits for a request slot, returning early if ctx is cancelled.
+// Every successful acquire must be paired with a release.
+func (c *Client) acquire(ctx context.Context) error {
+	if c.requests == nil {
+		return nil
+	}
+	select {
+	case c.requests <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// release frees a slot taken by acquire.
+func (c *Client) release() {
+	if c.requests != nil {
+		<-c.requests
+	}
+}
+
 // BlobInfo contains metadata about a blob.
 type BlobInfo struct {
 	Name         string
 	Path         string
 	Size         int64
 	ETag         string
-	LastModified string
+	LastModified time.Time
 	ContentMD5   []byte
+	Metadata     map[string]string
+	// Tags are the blob's index tags, set only when the listing asked for
+	// them with ListOptions.Tags.
+	Tags map[string]string
+	// AccessTier is the blob's access tier (Hot, Cool, Cold, Archive).
+	AccessTier string
+	// ArchiveStatus is set while an archived blob is being rehydrated.
+	ArchiveStatus string
+	// ContentType is the blob's Content-Type (empty if not set).
+	ContentType string
+	// VersionID identifies this version of the blob when versioning is
+	// enabled on the account.
+	VersionID string
+	// IsCurrentVersion is set on the current version when versions are listed.
+	IsCurrentVersion bool
+	// Snapshot is the snapshot timestamp when this entry is a snapshot.
+	Snapshot string
+}
+
+// ListOptions selects the extra entries a listing includes.
+type ListOptions struct {
+	// Versions lists every version of each blob, not only the current one.
+	Versions bool
+	// Snapshots lists the snapshots of each blob.
+	Snapshots bool
+	// Tags lists each blob's index tags. Reading tags needs a permission of
+	// its own (Tags on a SAS), so it is only requested when asked for.
+	Tags bool
+}
+
+// NormalizeETag strips the double quotes that surround an ETag on some
+// service responses, so the same ETag always compares equal.
+func NormalizeETag(etag string) string {
+	if len(etag) >= 2 && etag[0] == '"' && etag[len(etag)-1] == '"' {
+		return etag[1 : len(etag)-1]
+	}
+	return etag
+}
+
+// AccessTierArchive is the access tier of blobs that must be rehydrated before download.
+const AccessTierArchive = string(blob.AccessTierArchive)
+
+// ContainerInfo contains metadata about a container.
+type ContainerInfo struct {
+	Name         string
+	LastModified time.Time
+}
+
+// ListContainers lists all containers in the storage account.
+func (c *Client) ListContainers(ctx context.Context) ([]*ContainerInfo, error) {
+	pager := c.client.NewListContainersPager(nil)
+
+	var containers []*ContainerInfo
+	for pager.More() {
+		if err := c.acquire(ctx); err != nil {
+			return nil, fmt.Errorf("failed to list containers: %w", err)
+		}
+		page, err := pager.NextPage(ctx)
+		c.release()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list containers: %w", err)
+		}
+
+		for _, item := range page.ContainerItems {
+			if item.Name == nil {
+				continue
+			}
+
+			info := &ContainerInfo{Name: *item.Name}
+			if item.Properties != nil && item.Properties.LastModified != nil {
+				info.LastModified = *item.Properties.LastModified
+			}
+			containers = append(containers, info)
+		}
+	}
+
+	return containers, nil
+}
+
+// ListBlobs lists one page of blobs in a container with the given prefix,
+// starting at marker (nil for the first page). It returns the continuation
+// token for the next page, or nil when the listing is complete.
+func (c *Client) ListBlobs(ctx context.Context, containerName, prefix string, marker *string, maxResults int32) ([]*BlobInfo, *string, error) {
+	return c.ListBlobsWithOptions(ctx, containerName, prefix, marker, maxResults, ListOptions{})
 }
 
-// ListBlobs lists all blobs in a container with the given prefix.
-// It handles pagination automatically using continuation tokens.
-func (c *Client) ListBlobs(ctx context.Context, containerName, prefix string, maxResults int32) ([]*BlobInfo, *string, error) {
+// ListBlobsWithOptions is ListBlobs, also listing the versions, snapshots or
+// tags that opts selects. The entries of one blob are listed together, so they
+// may be split across pages.
+func (c *Client) ListBlobsWithOptions(ctx context.Context, containerName, prefix string, marker *string, maxResults int32, opts ListOptions) ([]*BlobInfo, *string, error) {
 	pager := c.client.NewListBlobsFlatPager(containerName, &azblob.ListBlobsFlatOptions{
 		Prefix:     &prefix,
+		Marker:     marker,
 		MaxResults: &maxResults,
-		Include:    container.ListBlobsInclude{Metadata: true},
+		Include: container.ListBlobsInclude{
+			Metadata:  true,
+			Tags:      opts.Tags,
+			Versions:  opts.Versions,
+			Snapshots: opts.Snapshots,
+		},
 	})
 
 	var blobs []*BlobInfo
 	var continuationToken *string
 
 	if pager.More() {
+		if err := c.acquire(ctx); err != nil {
+			return nil, nil, fmt.Errorf("failed to list blobs: %w", err)
+		}
 		page, err := pager.NextPage(ctx)
+		c.release()
 		if err != nil {
 			return nil, nil, fmt.Errorf("failed to list blobs: %w", err)
 		}
@@ -54,35 +198,124 @@ func (c *Client) ListBlobs(ctx context.Context, containerName, prefix string, ma
 				continue
 			}
 
-			blobInfo := &BlobInfo{
-				Name: *item.Name,
-				Path: *item.Name,
+			blobs = append(blobs, newBlobInfo(item))
+		}
+
+		if page.NextMarker != nil && *page.NextMarker != "" {
+			continuationToken = page.NextMarker
+		}
+	}
+
+	return blobs, continuationToken, nil
+}
+
+// ListBlobsHierarchy lists the blobs and virtual directories directly under prefix,
+// using delimiter to group deeper paths into prefixes. Prefixes are returned
+// separately so callers can recurse into selected directories only.
+func (c *Client) ListBlobsHierarchy(ctx context.Context, containerName, prefix, delimiter string) ([]string, []*BlobInfo, error) {
+	containerClient := c.client.ServiceClient().NewContainerClient(containerName)
+	pager := containerClient.NewListBlobsHierarchyPager(delimiter, &container.ListBlobsHierarchyOptions{
+		Prefix:  &prefix,
+		Include: container.ListBlobsInclude{Metadata: true},
+	})
+
+	var prefixes []string
+	var blobs []*BlobInfo
+
+	for pager.More() {
+		if err := c.acquire(ctx); err != nil {
+			return nil, nil, fmt.Errorf("failed to list blob hierarchy: %w", err)
+		}
+		page, err := pager.NextPage(ctx)
+		c.release()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list blob hierarchy: %w", err)
+		}
+
+		for _, p := range page.Segment.BlobPrefixes {
+			if p.Name != nil {
+				prefixes = append(prefixes, *p.Name)
 			}
+		}
 
-			if item.Properties != nil {
-				if item.Properties.ContentLength != nil {
-					blobInfo.Size = *item.Properties.ContentLength
-				}
-				if item.Properties.ETag != nil {
-					blobInfo.ETag = string(*item.Properties.ETag)
-				}
-				if item.Properties.LastModified != nil {
-					blobInfo.LastModified = item.Properties.LastModified.Format("2006-01-02T15:04:05Z")
-				}
-				if item.Properties.ContentMD5 != nil {
-					blobInfo.ContentMD5 = item.Properties.ContentMD5
-				}
+		for _, item := range page.Segment.BlobItems {
+			if item.Name == nil {
+				continue
 			}
+			blobs = append(blobs, newBlobInfo(item))
+		}
+	}
+
+	return prefixes, blobs, nil
+}
+
+// newBlobInfo converts a listing item into a BlobInfo.
+func newBlobInfo(item *container.BlobItem) *BlobInfo {
+	blobInfo := &BlobInfo{
+		Name: *item.Name,
+		Path: *item.Name,
+	}
 
-			blobs = append(blobs, blobInfo)
+	if item.Properties != nil {
+		if item.Properties.ContentLength != nil {
+			blobInfo.Size = *item.Properties.ContentLength
+		}
+		if item.Properties.ETag != nil {
+			blobInfo.ETag = NormalizeETag(string(*item.Properties.ETag))
+		}
+		if item.Properties.LastModified != nil {
+			blobInfo.LastModified = item.Properties.LastModified.UTC()
+		}
+		if item.Properties.ContentMD5 != nil {
+			blobInfo.ContentMD5 = item.Properties.ContentMD5
 		}
+		if item.Properties.ContentType != nil {
+			blobInfo.ContentType = *item.Properties.ContentType
+		}
+		if item.Properties.AccessTier != nil {
+			blobInfo.AccessTier = string(*item.Properties.AccessTier)
+		}
+		if item.Properties.ArchiveStatus != nil {
+			blobInfo.ArchiveStatus = string(*item.Properties.ArchiveStatus)
+		}
+	}
 
-		if page.NextMarker != nil && *page.NextMarker != "" {
-			continuationToken = page.NextMarker
+	blobInfo.Metadata = derefMap(item.Metadata)
+
+	if item.VersionID != nil {
+		blobInfo.VersionID = *item.VersionID
+	}
+	if item.IsCurrentVersion != nil {
+		blobInfo.IsCurrentVersion = *item.IsCurrentVersion
+	}
+	if item.Snapshot != nil {
+		blobInfo.Snapshot = *item.Snapshot
+	}
+
+	if item.BlobTags != nil && len(item.BlobTags.BlobTagSet) > 0 {
+		blobInfo.Tags = make(map[string]string, len(item.BlobTags.BlobTagSet))
+		for _, tag := range item.BlobTags.BlobTagSet {
+			if tag.Key != nil && tag.Value != nil {
+				blobInfo.Tags[*tag.Key] = *tag.Value
+			}
 		}
 	}
 
-	return blobs, continuationToken, nil
+	return blobInfo
+}
+
+// derefMap converts an SDK metadata map of string pointers into plain strings.
+func derefMap(m map[string]*string) map[string]string {
+	if len(m) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		if v != nil {
+			out[k] = *v
+		}
+	}
+	return out
 }
 
 // DownloadBlob downloads a blob to the provided writer.
@@ -90,16 +323,254 @@ func (c *Client) ListBlobs(ctx context.Context, containerName, prefix string, ma
 func (c *Client) DownloadBlob(ctx context.Context, containerName, blobName string, writer io.Writer) error {
 	blobClient := c.client.ServiceClient().NewContainerClient(containerName).NewBlobClient(blobName)
 
+	if err := c.acquire(ctx); err != nil {
+		return fmt.Errorf("failed to download blob: %w", err)
+	}
+	defer c.release()
+
+	resp, err := blobClient.DownloadStream(ctx, &blob.DownloadStreamOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to download blob: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if _, err := copyContext(ctx, writer, resp.Body); err != nil {
+		return fmt.Errorf("failed to copy blob data: %w", err)
+	}
+
+	return nil
+}
+
+// DownloadBlobRange downloads a blob starting at offset to the provided writer.
+// It is used to resume partially downloaded blobs without re-fetching data
+// that is already on disk. A non-empty etag makes the request conditional,
+// so bytes of a since-overwritten blob are never appended to a partial file;
+// IsConditionNotMetError reports that failure.
+func (c *Client) DownloadBlobRange(ctx context.Context, containerName, blobName string, offset int64, etag string, writer io.Writer) error {
+	blobClient := c.client.ServiceClient().NewContainerClient(containerName).NewBlobClient(blobName)
+
+	opts := &blob.DownloadStreamOptions{
+		Range:            blob.HTTPRange{Offset: offset},
+		AccessConditions: ifMatch(etag),
+	}
+
+	if err := c.acquire(ctx); err != nil {
+		return fmt.Errorf("failed to download blob range: %w", err)
+	}
+	defer c.release()
+
+	resp, err := blobClient.DownloadStream(ctx, opts)
+	if err != nil {
+		return fmt.Errorf("failed to download blob range: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if _, err := copyContext(ctx, writer, resp.Body); err != nil {
+		return fmt.Errorf("failed to copy blob data: %w", err)
+	}
+
+	return nil
+}
+
+// ifMatch returns access conditions that fail a request with 412 unless the
+// blob still has the given ETag, or nil when etag is empty.
+func ifMatch(etag string) *blob.AccessConditions {
+	if etag == "" {
+		return nil
+	}
+	match := azcore.ETag(`"` + NormalizeETag(etag) + `"`)
+	return &blob.AccessConditions{
+		ModifiedAccessConditions: &blob.ModifiedAccessConditions{IfMatch: &match},
+	}
+}
+
+// DownloadBlobVersion downloads the given version of a blob to the provided
+// writer.
+func (c *Client) DownloadBlobVersion(ctx context.Context, containerName, blobName, versionID string, writer io.Writer) error {
+	blobClient, err := c.client.ServiceClient().NewContainerClient(containerName).NewBlobClient(blobName).WithVersionID(versionID)
+	if err != nil {
+		return fmt.Errorf("invalid blob version %q: %w", versionID, err)
+	}
+
+	if err := c.acquire(ctx); err != nil {
+		return fmt.Errorf("failed to download blob version: %w", err)
+	}
+	defer c.release()
+
+	resp, err := blobClient.DownloadStream(ctx, &blob.DownloadStreamOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to download blob version: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if _, err := copyContext(ctx, writer, resp.Body); err != nil {
+		return fmt.Errorf("failed to copy blob data: %w", err)
+	}
+
+	return nil
+}
+
+// DownloadBlobDecompressed downloads a blob and transparently decompresses
+// it when its Content-Encoding is gzip or deflate. If raw is non-nil it
+// receives the bytes as stored in Azure, so callers can still verify the
+// blob's Content-MD5.
+func (c *Client) DownloadBlobDecompressed(ctx context.Context, containerName, blobName string, writer, raw io.Writer) error {
+	blobClient := c.client.ServiceClient().NewContainerClient(containerName).NewBlobClient(blobName)
+
+	if err := c.acquire(ctx); err != nil {
+		return fmt.Errorf("failed to download blob: %w", err)
+	}
+	defer c.release()
+
 	resp, err := blobClient.DownloadStream(ctx, &blob.DownloadStreamOptions{})
 	if err != nil {
 		return fmt.Errorf("failed to download blob: %w", err)
 	}
 	defer func() { _ = resp.Body.Close() }()
 
-	if _, err := io.Copy(writer, resp.Body); err != nil {
+	var body io.Reader = resp.Body
+	if raw != nil {
+		body = io.TeeReader(body, raw)
+	}
+
+	encoding := ""
+	if resp.ContentEncoding != nil {
+		encoding = strings.ToLower(strings.TrimSpace(*resp.ContentEncoding))
+	}
+
+	decoded, err := decodeContent(encoding, body)
+	if err != nil {
+		return fmt.Errorf("failed to decode %s content: %w", encoding, err)
+	}
+	defer func() { _ = decoded.Close() }()
+
+	if _, err := copyContext(ctx, writer, decoded); err != nil {
 		return fmt.Errorf("failed to copy blob data: %w", err)
 	}
 
+	// Drain any trailing bytes so raw sees the complete stored content.
+	if raw != nil {
+		if _, err := copyContext(ctx, io.Discard, body); err != nil {
+			return fmt.Errorf("failed to read blob data: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// contextReader fails reads once its context is cancelled.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+// copyContext copies src to dst like io.Copy, but checks ctx between reads
+// so that a cancelled download stops promptly instead of draining the stream.
+func copyContext(ctx context.Context, dst io.Writer, src io.Reader) (int64, error) {
+	return io.Copy(dst, &contextReader{ctx: ctx, r: src})
+}
+
+// decodeContent wraps body in a decompressor matching the content encoding.
+// Deflate accepts both zlib-wrapped (as HTTP specifies) and raw streams.
+func decodeContent(encoding string, body io.Reader) (io.ReadCloser, error) {
+	switch encoding {
+	case "gzip", "x-gzip":
+		return gzip.NewReader(body)
+	case "deflate":
+		br := bufio.NewReader(body)
+		header, err := br.Peek(2)
+		if err == nil && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+			return zlib.NewReader(br)
+		}
+		return flate.NewReader(br), nil
+	default:
+		return io.NopCloser(body), nil
+	}
+}
+
+// DownloadBlobChunked downloads a blob of the given size into file using
+// concurrent ranged reads. Each chunk is written at its own offset, so the
+// file does not need to be written sequentially. If etag is non-empty, every
+// chunk is requested only if the blob still has that ETag, so the file never
+// mixes bytes from two versions of the blob.
+func (c *Client) DownloadBlobChunked(ctx context.Context, containerName, blobName, etag string, file io.WriterAt, size, chunkSize int64, concurrency int) error {
+	if chunkSize <= 0 {
+		return fmt.Errorf("chunk size must be positive")
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	blobClient := c.client.ServiceClient().NewContainerClient(containerName).NewBlobClient(blobName)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	offsets := make(chan int64)
+	errCh := make(chan error, concurrency)
+	var wg sync.WaitGroup
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for offset := range offsets {
+				count := min(chunkSize, size-offset)
+				if err := c.downloadChunk(ctx, blobClient, etag, file, offset, count); err != nil {
+					errCh <- err
+					cancel()
+					return
+				}
+			}
+		}()
+	}
+
+feed:
+	for offset := int64(0); offset < size; offset += chunkSize {
+		select {
+		case offsets <- offset:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(offsets)
+	wg.Wait()
+	close(errCh)
+
+	if err := <-errCh; err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// downloadChunk downloads count bytes starting at offset and writes them to
+// the same offset in file. Each chunk is a request of its own.
+func (c *Client) downloadChunk(ctx context.Context, blobClient *blob.Client, etag string, file io.WriterAt, offset, count int64) error {
+	if err := c.acquire(ctx); err != nil {
+		return fmt.Errorf("failed to download chunk at offset %d: %w", offset, err)
+	}
+	defer c.release()
+
+	resp, err := blobClient.DownloadStream(ctx, &blob.DownloadStreamOptions{
+		Range:            blob.HTTPRange{Offset: offset, Count: count},
+		AccessConditions: ifMatch(etag),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to download chunk at offset %d: %w", offset, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if _, err := copyContext(ctx, io.NewOffsetWriter(file, offset), resp.Body); err != nil {
+		return fmt.Errorf("failed to write chunk at offset %d: %w", offset, err)
+	}
+
 	return nil
 }
 
@@ -107,7 +578,11 @@ func (c *Client) DownloadBlob(ctx context.Context, containerName, blobName strin
 func (c *Client) GetBlobProperties(ctx context.Context, containerName, blobName string) (*BlobInfo, error) {
 	blobClient := c.client.ServiceClient().NewContainerClient(containerName).NewBlobClient(blobName)
 
+	if err := c.acquire(ctx); err != nil {
+		return nil, fmt.Errorf("failed to get blob properties: %w", err)
+	}
 	props, err := blobClient.GetProperties(ctx, nil)
+	c.release()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get blob properties: %w", err)
 	}
@@ -121,24 +596,103 @@ func (c *Client) GetBlobProperties(ctx context.Context, containerName, blobName
 		info.Size = *props.ContentLength
 	}
 	if props.ETag != nil {
-		info.ETag = string(*props.ETag)
+		info.ETag = NormalizeETag(string(*props.ETag))
 	}
 	if props.LastModified != nil {
-		info.LastModified = props.LastModified.Format("2006-01-02T15:04:05Z")
+		info.LastModified = props.LastModified.UTC()
 	}
 	if props.ContentMD5 != nil {
 		info.ContentMD5 = props.ContentMD5
 	}
+	if props.ContentType != nil {
+		info.ContentType = *props.ContentType
+	}
+	if props.AccessTier != nil {
+		info.AccessTier = *props.AccessTier
+	}
+	if props.ArchiveStatus != nil {
+		info.ArchiveStatus = *props.ArchiveStatus
+	}
+	info.Metadata = derefMap(props.Metadata)
 
 	return info, nil
 }
 
+// RehydrateBlob moves an archived blob to targetTier so it can be downloaded.
+// Rehydration is asynchronous and can take several hours to complete.
+func (c *Client) RehydrateBlob(ctx context.Context, containerName, blobName, targetTier string) error {
+	blobClient := c.client.ServiceClient().NewContainerClient(containerName).NewBlobClient(blobName)
+
+	if err := c.acquire(ctx); err != nil {
+		return fmt.Errorf("failed to set blob tier: %w", err)
+	}
+	defer c.release()
+
+	if _, err := blobClient.SetTier(ctx, blob.AccessTier(targetTier), nil); err != nil {
+		return fmt.Errorf("failed to set blob tier: %w", err)
+	}
+
+	return nil
+}
+
+// UploadBlob uploads the contents of reader as a block blob.
+// contentMD5 is stored on the blob so later runs can detect unchanged
+// content. When it is nil and reader is seekable, the MD5 is computed first.
+func (c *Client) UploadBlob(ctx context.Context, containerName, blobName string, reader io.Reader, size int64, contentMD5 []byte) error {
+	blobClient := c.client.ServiceClient().NewContainerClient(containerName).NewBlockBlobClient(blobName)
+
+	opts := &blockblob.UploadStreamOptions{
+		BlockSize: uploadBlockSize(size),
+	}
+
+	if contentMD5 != nil {
+		opts.HTTPHeaders = &blob.HTTPHeaders{BlobContentMD5: contentMD5}
+	} else if seeker, ok := reader.(io.ReadSeeker); ok {
+		hasher := md5.New()
+		if _, err := io.Copy(hasher, seeker); err != nil {
+			return fmt.Errorf("failed to hash upload content: %w", err)
+		}
+		if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("failed to rewind upload content: %w", err)
+		}
+		opts.HTTPHeaders = &blob.HTTPHeaders{BlobContentMD5: hasher.Sum(nil)}
+	}
+
+	if err := c.acquire(ctx); err != nil {
+		return fmt.Errorf("failed to upload blob: %w", err)
+	}
+	defer c.release()
+
+	if _, err := blobClient.UploadStream(ctx, reader, opts); err != nil {
+		return fmt.Errorf("failed to upload blob: %w", err)
+	}
+
+	return nil
+}
+
+// uploadBlockSize picks a block size large enough to stay under the
+// 50,000-block limit of a block blob.
+func uploadBlockSize(size int64) int64 {
+	const minBlockSize = 4 * 1024 * 1024
+	const maxBlocks = 50000
+
+	blockSize := int64(minBlockSize)
+	for size/blockSize >= maxBlocks {
+		blockSize *= 2
+	}
+	return blockSize
+}
+
 // ContainerExists checks if a container exists.
 func (c *Client) ContainerExists(ctx context.Context, containerName string) (bool, error) {
 	containerClient := c.client.ServiceClient().NewContainerClient(containerName)
+	if err := c.acquire(ctx); err != nil {
+		return false, fmt.Errorf("failed to check container: %w", err)
+	}
 	_, err := containerClient.GetProperties(ctx, nil)
+	c.release()
 	if err != nil {
-		if isNotFoundError(err) {
+		if IsNotFoundError(err) {
 			return false, nil
 		}
 		return false, fmt.Errorf("failed to check container: %w", err)
@@ -146,11 +700,61 @@ func (c *Client) ContainerExists(ctx context.Context, containerName string) (boo
 	return true, nil
 }
 
-// isNotFoundError checks if an error is a "not found" error.
-func isNotFoundError(err error) bool {
+// IsNotFoundError checks if an error is a "not found" error.
+// It recognises missing containers and blobs returned by any client method.
+func IsNotFoundError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if bloberror.HasCode(err, bloberror.ContainerNotFound, bloberror.BlobNotFound, bloberror.ResourceNotFound) {
+		return true
+	}
+
+	// HEAD requests carry no error body, so fall back to the status code.
+	var respErr *azcore.ResponseError
+	if errors.As(err, &respErr) {
+		return respErr.StatusCode == http.StatusNotFound
+	}
+
+	return false
+}
+
+// IsConditionNotMetError checks if a conditional request failed because the
+// blob no longer matches the given ETag.
+func IsConditionNotMetError(err error) bool {
 	if err == nil {
 		return false
 	}
-	// Check for Azure SDK not found errors
+
+	if bloberror.HasCode(err, bloberror.ConditionNotMet) {
+		return true
+	}
+
+	var respErr *azcore.ResponseError
+	if errors.As(err, &respErr) {
+		return respErr.StatusCode == http.StatusPreconditionFailed
+	}
+
 	return false
 }
+
+// IsAuthError checks if an error was caused by rejected credentials or
+// insufficient permissions on the storage account.
+func IsAuthError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	return bloberror.HasCode(err,
+		bloberror.AuthenticationFailed,
+		bloberror.AuthorizationFailure,
+		bloberror.AuthorizationPermissionMismatch,
+		bloberror.AuthorizationProtocolMismatch,
+		bloberror.AuthorizationResourceTypeMismatch,
+		bloberror.AuthorizationServiceMismatch,
+		bloberror.AuthorizationSourceIPMismatch,
+		bloberror.InsufficientAccountPermissions,
+		bloberror.NoAuthenticationInformation,
+	)
+}
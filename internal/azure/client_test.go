@@ -0,0 +1,449 @@
+package azure
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+)
+
+// newFakeClient returns a Client pointed at a test server driven by handler.
+func newFakeClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	sdkClient, err := azblob.NewClientWithNoCredential(server.URL+"/devstoreaccount1", &azblob.ClientOptions{
+		ClientOptions: azcore.ClientOptions{
+			Retry: policy.RetryOptions{MaxRetries: -1},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	return NewClient(sdkClient)
+}
+
+func TestIsNotFoundError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"plain error", errors.New("boom"), false},
+		{"container not found", &azcore.ResponseError{StatusCode: http.StatusNotFound, ErrorCode: "ContainerNotFound"}, true},
+		{"blob not found", &azcore.ResponseError{StatusCode: http.StatusNotFound, ErrorCode: "BlobNotFound"}, true},
+		{"bare 404", &azcore.ResponseError{StatusCode: http.StatusNotFound}, true},
+		{"wrapped 404", fmt.Errorf("wrapped: %w", &azcore.ResponseError{StatusCode: http.StatusNotFound}), true},
+		{"forbidden", &azcore.ResponseError{StatusCode: http.StatusForbidden, ErrorCode: "AuthorizationFailure"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsNotFoundError(tt.err); got != tt.want {
+				t.Errorf("IsNotFoundError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContainerExists_NotFound(t *testing.T) {
+	c := newFakeClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("x-ms-error-code", "ContainerNotFound")
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	exists, err := c.ContainerExists(context.Background(), "missing")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if exists {
+		t.Error("expected container to not exist")
+	}
+}
+
+func TestContainerExists_OtherError(t *testing.T) {
+	c := newFakeClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("x-ms-error-code", "AuthorizationFailure")
+		w.WriteHeader(http.StatusForbidden)
+	})
+
+	if _, err := c.ContainerExists(context.Background(), "forbidden"); err == nil {
+		t.Error("expected an error for a forbidden response")
+	}
+}
+
+func TestDownloadBlobChunked(t *testing.T) {
+	content := []byte(strings.Repeat("0123456789abcdef", 1000))
+
+	c := newFakeClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var start, end int64
+		if _, err := fmt.Sscanf(r.Header.Get("x-ms-range"), "bytes=%d-%d", &start, &end); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Length", strconv.FormatInt(end-start+1, 10))
+		w.WriteHeader(http.StatusPartialContent)
+		_, _ = w.Write(content[start : end+1])
+	})
+
+	file, err := os.Create(filepath.Join(t.TempDir(), "blob.tmp"))
+	if err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	err = c.DownloadBlobChunked(context.Background(), "container", "blob", "", file, int64(len(content)), 1000, 4)
+	if err != nil {
+		t.Fatalf("DownloadBlobChunked error: %v", err)
+	}
+
+	got, err := os.ReadFile(file.Name())
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Errorf("downloaded content mismatch: got %d bytes, want %d", len(got), len(content))
+	}
+}
+
+func TestDownloadBlobChunked_IfMatch(t *testing.T) {
+	content := []byte(strings.Repeat("0123456789abcdef", 1000))
+
+	// The blob is overwritten once the first chunk has been served.
+	var served atomic.Int32
+	c := newFakeClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-Match") != `"0x1"` || served.Add(1) > 1 {
+			w.Header().Set("x-ms-error-code", "ConditionNotMet")
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		var start, end int64
+		if _, err := fmt.Sscanf(r.Header.Get("x-ms-range"), "bytes=%d-%d", &start, &end); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Length", strconv.FormatInt(end-start+1, 10))
+		w.WriteHeader(http.StatusPartialContent)
+		_, _ = w.Write(content[start : end+1])
+	})
+
+	file, err := os.Create(filepath.Join(t.TempDir(), "blob.tmp"))
+	if err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	err = c.DownloadBlobChunked(context.Background(), "container", "blob", "0x1", file, int64(len(content)), 1000, 1)
+	if !IsConditionNotMetError(err) {
+		t.Errorf("DownloadBlobChunked across an overwrite: err = %v, want a condition-not-met error", err)
+	}
+}
+
+func TestDownloadBlobRange_IfMatch(t *testing.T) {
+	c := newFakeClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-Match") != `"0x1"` {
+			w.Header().Set("x-ms-error-code", "ConditionNotMet")
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		w.Header().Set("Content-Length", "4")
+		w.WriteHeader(http.StatusPartialContent)
+		_, _ = w.Write([]byte("tail"))
+	})
+
+	var buf bytes.Buffer
+	if err := c.DownloadBlobRange(context.Background(), "container", "blob", 4, "0x1", &buf); err != nil {
+		t.Fatalf("DownloadBlobRange with the current ETag: %v", err)
+	}
+	if buf.String() != "tail" {
+		t.Errorf("downloaded %q, want %q", buf.String(), "tail")
+	}
+
+	err := c.DownloadBlobRange(context.Background(), "container", "blob", 4, `"0x2"`, &buf)
+	if !IsConditionNotMetError(err) {
+		t.Errorf("DownloadBlobRange with a stale ETag: err = %v, want a condition-not-met error", err)
+	}
+}
+
+func TestDownloadBlobVersion(t *testing.T) {
+	const versionID = "2024-01-02T03:04:05.1234567Z"
+
+	c := newFakeClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("versionid"); got != versionID {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Length", "3")
+		_, _ = w.Write([]byte("old"))
+	})
+
+	var buf bytes.Buffer
+	if err := c.DownloadBlobVersion(context.Background(), "container", "blob", versionID, &buf); err != nil {
+		t.Fatalf("DownloadBlobVersion error: %v", err)
+	}
+	if buf.String() != "old" {
+		t.Errorf("downloaded %q, want %q", buf.String(), "old")
+	}
+}
+
+func TestMaxConcurrentRequests(t *testing.T) {
+	const limit = 3
+	content := []byte(strings.Repeat("x", 4000))
+
+	var inFlight, peak atomic.Int32
+	c := newFakeClient(t, func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+
+		if r.Method == http.MethodHead {
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		var start, end int64
+		if _, err := fmt.Sscanf(r.Header.Get("x-ms-range"), "bytes=%d-%d", &start, &end); err != nil {
+			start, end = 0, int64(len(content)-1)
+		}
+		w.Header().Set("Content-Length", strconv.FormatInt(end-start+1, 10))
+		w.WriteHeader(http.StatusPartialContent)
+		_, _ = w.Write(content[start : end+1])
+	})
+	c.SetMaxConcurrentRequests(limit)
+
+	dir := t.TempDir()
+	ctx := context.Background()
+	errCh := make(chan error, 30)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(3)
+		go func(i int) {
+			defer wg.Done()
+			file, err := os.Create(filepath.Join(dir, fmt.Sprintf("chunked-%d", i)))
+			if err != nil {
+				errCh <- err
+				return
+			}
+			defer func() { _ = file.Close() }()
+			errCh <- c.DownloadBlobChunked(ctx, "container", "blob", "", file, int64(len(content)), 500, 8)
+		}(i)
+		go func() {
+			defer wg.Done()
+			errCh <- c.DownloadBlob(ctx, "container", "blob", io.Discard)
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := c.GetBlobProperties(ctx, "container", "blob")
+			errCh <- err
+		}()
+	}
+	wg.Wait()
+	close(errCh)
+
+	for err := range errCh {
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+	}
+	if got := peak.Load(); got > limit {
+		t.Errorf("peak concurrent requests = %d, want at most %d", got, limit)
+	}
+}
+
+func TestMaxConcurrentRequests_Cancelled(t *testing.T) {
+	c := newFakeClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	c.SetMaxConcurrentRequests(1)
+
+	// Hold the only slot so the next request has to wait for it.
+	if err := c.acquire(context.Background()); err != nil {
+		t.Fatalf("acquire failed: %v", err)
+	}
+	defer c.release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := c.GetBlobProperties(ctx, "container", "blob"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded while waiting for a slot, got %v", err)
+	}
+}
+
+func TestNormalizeETag(t *testing.T) {
+	tests := []struct {
+		etag string
+		want string
+	}{
+		{`"0x8DC1234567890AB"`, "0x8DC1234567890AB"},
+		{"0x8DC1234567890AB", "0x8DC1234567890AB"},
+		{`"`, `"`},
+		{"", ""},
+		{`"0x8DC"extra`, `"0x8DC"extra`},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeETag(tt.etag); got != tt.want {
+			t.Errorf("NormalizeETag(%q) = %q, want %q", tt.etag, got, tt.want)
+		}
+	}
+}
+
+func TestNewBlobInfo_MetadataAndTags(t *testing.T) {
+	name := "data/file.csv"
+	dataset := "sales"
+	key, value := "dataset", "sales"
+
+	info := newBlobInfo(&container.BlobItem{
+		Name:     &name,
+		Metadata: map[string]*string{"dataset": &dataset},
+		BlobTags: &container.BlobTags{
+			BlobTagSet: []*container.BlobTag{{Key: &key, Value: &value}},
+		},
+	})
+
+	if info.Metadata["dataset"] != "sales" {
+		t.Errorf("expected metadata dataset=sales, got %v", info.Metadata)
+	}
+	if info.Tags["dataset"] != "sales" {
+		t.Errorf("expected tag dataset=sales, got %v", info.Tags)
+	}
+}
+
+func TestListBlobsWithOptions_TagsOptIn(t *testing.T) {
+	var include string
+	c := newFakeClient(t, func(w http.ResponseWriter, r *http.Request) {
+		include = r.URL.Query().Get("include")
+		w.Header().Set("Content-Type", "application/xml")
+		_, _ = w.Write([]byte(`<?xml version="1.0" encoding="utf-8"?><EnumerationResults><Blobs></Blobs><NextMarker /></EnumerationResults>`))
+	})
+
+	tests := []struct {
+		name     string
+		opts     ListOptions
+		wantTags bool
+	}{
+		{"default listing", ListOptions{}, false},
+		{"tags requested", ListOptions{Tags: true}, true},
+	}
+	for _, tt := range tests {
+		if _, _, err := c.ListBlobsWithOptions(context.Background(), "container", "", nil, 10, tt.opts); err != nil {
+			t.Fatalf("%s: ListBlobsWithOptions error: %v", tt.name, err)
+		}
+		if got := strings.Contains(include, "tags"); got != tt.wantTags {
+			t.Errorf("%s: include = %q, want tags requested = %v", tt.name, include, tt.wantTags)
+		}
+	}
+}
+
+func TestNewBlobInfo_ContentType(t *testing.T) {
+	name := "data/file.csv"
+	contentType := "text/csv"
+
+	info := newBlobInfo(&container.BlobItem{
+		Name:       &name,
+		Properties: &container.BlobProperties{ContentType: &contentType},
+	})
+
+	if info.ContentType != "text/csv" {
+		t.Errorf("ContentType = %q, want %q", info.ContentType, "text/csv")
+	}
+}
+
+func TestDecodeContent(t *testing.T) {
+	want := "hello compressed world"
+
+	var gz, zl, fl bytes.Buffer
+	gw := gzip.NewWriter(&gz)
+	_, _ = gw.Write([]byte(want))
+	_ = gw.Close()
+	zw := zlib.NewWriter(&zl)
+	_, _ = zw.Write([]byte(want))
+	_ = zw.Close()
+	fw, _ := flate.NewWriter(&fl, flate.DefaultCompression)
+	_, _ = fw.Write([]byte(want))
+	_ = fw.Close()
+
+	tests := []struct {
+		encoding string
+		body     []byte
+	}{
+		{"gzip", gz.Bytes()},
+		{"deflate", zl.Bytes()},
+		{"deflate", fl.Bytes()},
+		{"", []byte(want)},
+	}
+
+	for _, tt := range tests {
+		r, err := decodeContent(tt.encoding, bytes.NewReader(tt.body))
+		if err != nil {
+			t.Fatalf("decodeContent(%q) error: %v", tt.encoding, err)
+		}
+		got, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("read %q error: %v", tt.encoding, err)
+		}
+		if string(got) != want {
+			t.Errorf("decodeContent(%q) = %q, want %q", tt.encoding, got, want)
+		}
+	}
+}
+
+// slowReader returns one byte per read after a short delay, forever.
+type slowReader struct{}
+
+func (slowReader) Read(p []byte) (int, error) {
+	time.Sleep(5 * time.Millisecond)
+	p[0] = 'x'
+	return 1, nil
+}
+
+func TestCopyContext_StopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := copyContext(ctx, io.Discard, slowReader{})
+		done <- err
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("copy did not stop after cancellation")
+	}
+}
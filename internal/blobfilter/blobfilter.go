@@ -0,0 +1,124 @@
+// Package blobfilter selects blobs by name using include and exclude glob
+// patterns such as "*.parquet", "logs/**/2024-*.json" or "_temp/".
+//
+// Patterns use path.Match syntax for each "/"-separated segment, and a "**"
+// segment matches any number of segments, including none. A pattern without
+// a "/" matches the blob's base name at any depth, and a pattern ending in
+// "/" matches everything beneath a directory of that name at any depth.
+// Other patterns, and any pattern starting with "/", are matched against the
+// full blob name.
+package blobfilter
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Filter decides which blobs are included in a sync.
+type Filter struct {
+	include [][]string
+	exclude [][]string
+}
+
+// New compiles include and exclude patterns into a Filter.
+func New(include, exclude []string) (*Filter, error) {
+	f := &Filter{}
+	for _, p := range include {
+		segments, err := compile(p)
+		if err != nil {
+			return nil, err
+		}
+		f.include = append(f.include, segments)
+	}
+	for _, p := range exclude {
+		segments, err := compile(p)
+		if err != nil {
+			return nil, err
+		}
+		f.exclude = append(f.exclude, segments)
+	}
+	return f, nil
+}
+
+// Validate checks that every pattern is well formed.
+func Validate(patterns []string) error {
+	for _, p := range patterns {
+		if _, err := compile(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Match reports whether the blob name passes the filter: it matches no
+// exclude pattern and, when include patterns are set, at least one of them.
+// Excludes take precedence over includes. A nil Filter matches everything.
+func (f *Filter) Match(name string) bool {
+	if f == nil {
+		return true
+	}
+	segments := strings.Split(name, "/")
+	for _, p := range f.exclude {
+		if matchSegments(p, segments) {
+			return false
+		}
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, p := range f.include {
+		if matchSegments(p, segments) {
+			return true
+		}
+	}
+	return false
+}
+
+// compile normalises a pattern into segments and checks its syntax.
+func compile(pattern string) ([]string, error) {
+	anchored := strings.HasPrefix(pattern, "/")
+	p := strings.TrimPrefix(pattern, "/")
+	if p == "" || p == "/" {
+		return nil, fmt.Errorf("empty filter pattern %q", pattern)
+	}
+
+	if !anchored && !strings.Contains(strings.TrimSuffix(p, "/"), "/") {
+		p = "**/" + p
+	}
+	if strings.HasSuffix(p, "/") {
+		p += "**"
+	}
+
+	segments := strings.Split(p, "/")
+	for _, segment := range segments {
+		if _, err := path.Match(segment, ""); err != nil {
+			return nil, fmt.Errorf("invalid filter pattern %q: %w", pattern, err)
+		}
+	}
+	return segments, nil
+}
+
+// matchSegments matches name segments against pattern segments.
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			rest := pattern[1:]
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(rest, name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
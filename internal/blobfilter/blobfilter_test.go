@@ -0,0 +1,50 @@
+package blobfilter
+
+import "testing"
+
+func TestFilter_Match(t *testing.T) {
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		blob    string
+		want    bool
+	}{
+		{"no patterns", nil, nil, "any/blob.txt", true},
+		{"base name include", []string{"*.parquet"}, nil, "data/2024/part-0.parquet", true},
+		{"base name include miss", []string{"*.parquet"}, nil, "data/2024/part-0.csv", false},
+		{"anchored include", []string{"data/*.csv"}, nil, "data/a.csv", true},
+		{"anchored include is not recursive", []string{"data/*.csv"}, nil, "data/2024/a.csv", false},
+		{"recursive include", []string{"data/**/*.csv"}, nil, "data/2024/01/a.csv", true},
+		{"recursive include matches zero segments", []string{"data/**/*.csv"}, nil, "data/a.csv", true},
+		{"leading recursive segment", []string{"**/logs/*.json"}, nil, "app/eu/logs/x.json", true},
+		{"rooted base name", []string{"/*.txt"}, nil, "nested/a.txt", false},
+		{"directory exclude", nil, []string{"_temp/"}, "jobs/_temp/part.bin", false},
+		{"directory exclude at root", nil, []string{"_temp/"}, "_temp/part.bin", false},
+		{"directory exclude leaves siblings", nil, []string{"_temp/"}, "jobs/final/part.bin", true},
+		{"exclude beats include", []string{"*.parquet"}, []string{"_temp/"}, "jobs/_temp/a.parquet", false},
+		{"exclude beats recursive include", []string{"data/**"}, []string{"**/*.tmp"}, "data/x/y.tmp", false},
+		{"include with exclude miss", []string{"*.parquet"}, []string{"*.tmp"}, "data/a.parquet", true},
+	}
+
+	for _, tt := range tests {
+		f, err := New(tt.include, tt.exclude)
+		if err != nil {
+			t.Fatalf("%s: New failed: %v", tt.name, err)
+		}
+		if got := f.Match(tt.blob); got != tt.want {
+			t.Errorf("%s: Match(%q) = %v, want %v", tt.name, tt.blob, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Validate([]string{"*.csv", "data/**", "_temp/"}); err != nil {
+		t.Errorf("expected valid patterns, got %v", err)
+	}
+	for _, bad := range []string{"[a-", "", "/"} {
+		if err := Validate([]string{bad}); err == nil {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+	}
+}
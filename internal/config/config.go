@@ -4,19 +4,32 @@ package config
 
 import (
 	"fmt"
+	"math"
+	"net"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/haepapa/getblobz/internal/blobfilter"
+	"github.com/haepapa/getblobz/internal/hook"
+	"github.com/haepapa/getblobz/internal/pathtemplate"
+	"github.com/robfig/cron/v3"
 )
 
 // Config represents the complete application configuration.
 type Config struct {
-	Azure       AzureConfig       `mapstructure:"azure"`
-	Sync        SyncConfig        `mapstructure:"sync"`
-	Watch       WatchConfig       `mapstructure:"watch"`
-	Logging     LoggingConfig     `mapstructure:"logging"`
-	State       StateConfig       `mapstructure:"state"`
-	Performance PerformanceConfig `mapstructure:"performance"`
+	Azure         AzureConfig         `mapstructure:"azure"`
+	Sync          SyncConfig          `mapstructure:"sync"`
+	Watch         WatchConfig         `mapstructure:"watch"`
+	Logging       LoggingConfig       `mapstructure:"logging"`
+	State         StateConfig         `mapstructure:"state"`
+	Performance   PerformanceConfig   `mapstructure:"performance"`
+	Retry         RetryConfig         `mapstructure:"retry"`
+	Notifications NotificationsConfig `mapstructure:"notifications"`
+	Hooks         HooksConfig         `mapstructure:"hooks"`
 }
 
 // AzureConfig contains Azure Storage authentication and connection settings.
@@ -37,6 +50,31 @@ type AzureConfig struct {
 	ClientSecret string `mapstructure:"client_secret"`
 	// UseAzureCLI enables Azure CLI credential authentication.
 	UseAzureCLI bool `mapstructure:"use_azure_cli"`
+	// Anonymous accesses public containers without credentials, using the
+	// account name or BlobEndpoint.
+	Anonymous bool `mapstructure:"anonymous"`
+	// EndpointSuffix is the storage endpoint suffix for the target cloud
+	// (e.g., core.windows.net, core.usgovcloudapi.net, core.chinacloudapi.cn).
+	EndpointSuffix string `mapstructure:"endpoint_suffix"`
+	// BlobEndpoint overrides the derived blob service URL (e.g., Private Link or Azurite).
+	BlobEndpoint string `mapstructure:"blob_endpoint"`
+	// ProxyURL routes Azure traffic through an HTTP proxy (defaults to HTTPS_PROXY/NO_PROXY).
+	ProxyURL string `mapstructure:"proxy_url"`
+	// RetryPolicy tunes the Azure SDK retry behaviour.
+	RetryPolicy RetryPolicyConfig `mapstructure:"retry_policy"`
+}
+
+// RetryPolicyConfig contains Azure SDK retry settings.
+// Zero values fall back to the SDK defaults.
+type RetryPolicyConfig struct {
+	// MaxRetries is the maximum number of retries per request (-1 disables retries).
+	MaxRetries int32 `mapstructure:"max_retries"`
+	// TryTimeout is the maximum time allowed for a single attempt.
+	TryTimeout time.Duration `mapstructure:"try_timeout"`
+	// RetryDelay is the initial delay before retrying; it grows exponentially.
+	RetryDelay time.Duration `mapstructure:"retry_delay"`
+	// MaxRetryDelay caps the delay between retries.
+	MaxRetryDelay time.Duration `mapstructure:"max_retry_delay"`
 }
 
 // SyncConfig contains synchronisation operation settings.
@@ -47,22 +85,116 @@ type SyncConfig struct {
 	OutputPath string `mapstructure:"output_path"`
 	// Prefix filters blobs to only those starting with this prefix.
 	Prefix string `mapstructure:"prefix"`
+	// Include limits the sync to blobs matching at least one of these glob patterns (empty = all).
+	Include []string `mapstructure:"include"`
+	// Exclude skips blobs matching any of these glob patterns; it takes precedence over Include.
+	Exclude []string `mapstructure:"exclude"`
+	// ModifiedAfter skips blobs last modified before this time (RFC3339 or relative, e.g. "7d").
+	ModifiedAfter string `mapstructure:"modified_after"`
+	// ModifiedBefore skips blobs last modified at or after this time (same formats as ModifiedAfter).
+	ModifiedBefore string `mapstructure:"modified_before"`
 	// Workers specifies the number of concurrent download workers.
 	Workers int `mapstructure:"workers"`
 	// BatchSize is the number of blobs to list per API call.
 	BatchSize int `mapstructure:"batch_size"`
 	// SkipExisting skips downloading files that already exist locally.
+	//
+	// Deprecated: use OverwritePolicy. SkipExisting is only consulted when
+	// OverwritePolicy is empty, as "skip" when true and "always" when false.
 	SkipExisting bool `mapstructure:"skip_existing"`
+	// OverwritePolicy decides when a blob whose file already exists locally
+	// is downloaded again: "skip" skips synced blobs that have not changed,
+	// "newer" overwrites only files older than the blob, "always" downloads
+	// every blob and "never" leaves existing files alone (empty = derived
+	// from SkipExisting).
+	OverwritePolicy string `mapstructure:"overwrite_policy"`
+	// DownloadOrder is the order pending blobs are downloaded in: "listed"
+	// (as discovered), "smallest_first", "largest_first" or "random".
+	DownloadOrder string `mapstructure:"download_order"`
+	// Adopt records untracked local files that already match a blob's size,
+	// and MD5 when checksums are verified, as downloaded instead of
+	// downloading them again.
+	Adopt bool `mapstructure:"adopt"`
+	// Dedupe hardlinks a blob to the local file of an already downloaded blob
+	// with the same Content-MD5 instead of downloading it again.
+	Dedupe bool `mapstructure:"dedupe"`
 	// VerifyChecksums enables MD5 checksum verification after download.
 	VerifyChecksums bool `mapstructure:"verify_checksums"`
+	// UnsafeNames controls blobs whose names would escape the output directory:
+	// "reject" marks them failed, "rewrite" drops the leading ".." and root components
+	// and marks failed any blob whose local path another blob listed earlier took.
+	UnsafeNames string `mapstructure:"unsafe_names"`
+	// TempDir is where partial downloads are written (empty = next to the destination file).
+	TempDir string `mapstructure:"temp_dir"`
+	// PreserveTimestamps sets each downloaded file's modification time to the blob's last-modified time.
+	PreserveTimestamps bool `mapstructure:"preserve_timestamps"`
+	// FileMode is the octal permission mode of downloaded files (e.g., "0600").
+	FileMode string `mapstructure:"file_mode"`
+	// DirMode is the octal permission mode of created directories (e.g., "0700").
+	DirMode string `mapstructure:"dir_mode"`
+	// FilePerm is FileMode parsed, set by Validate.
+	FilePerm os.FileMode `mapstructure:"-"`
+	// DirPerm is DirMode parsed, set by Validate.
+	DirPerm os.FileMode `mapstructure:"-"`
+	// BlobTimeout abandons a download attempt once no data has arrived for
+	// this long, so a stalled transfer is retried (0 = no timeout).
+	BlobTimeout time.Duration `mapstructure:"blob_timeout"`
+	// DecompressOnDownload decompresses blobs stored with gzip or deflate Content-Encoding.
+	DecompressOnDownload bool `mapstructure:"decompress_on_download"`
+	// Version pins the sync to a point in time: each blob is downloaded as
+	// its newest version with an ID (a UTC timestamp) not after this version
+	// ID or RFC 3339 time, and blobs without one are left out. Requires blob
+	// versioning on the account (empty = current versions).
+	Version string `mapstructure:"version"`
+	// VersionTime is Version parsed, set by Validate.
+	VersionTime time.Time `mapstructure:"-"`
 	// ForceResync forces re-download of all files ignoring state.
 	ForceResync bool `mapstructure:"force_resync"`
+	// Mirror removes local files whose blobs no longer exist in the container.
+	Mirror bool `mapstructure:"mirror"`
+	// MirrorDryRun logs the local files mirroring would remove without deleting them.
+	MirrorDryRun bool `mapstructure:"mirror_dry_run"`
+	// ManifestPath is where a newline-delimited JSON manifest of downloaded
+	// files is written when a run completes (empty = disabled).
+	ManifestPath string `mapstructure:"manifest_path"`
+	// ManifestAll lists every tracked file in the manifest, not just the
+	// files downloaded by the run.
+	ManifestAll bool `mapstructure:"manifest_all"`
+	// DryRun runs discovery and reports what would be downloaded without
+	// writing any files or state.
+	DryRun bool `mapstructure:"dry_run"`
+	// Rehydrate triggers rehydration of archive-tier blobs found during discovery.
+	Rehydrate bool `mapstructure:"rehydrate"`
 	// DiskWarnPercent is the filesystem usage percent at which a warning is logged.
 	DiskWarnPercent int `mapstructure:"disk_warn_percent"`
 	// DiskStopPercent is the filesystem usage percent at which downloads stop.
 	DiskStopPercent int `mapstructure:"disk_stop_percent"`
 	// FolderOrganization contains settings for organizing files into folders.
 	FolderOrganization FolderOrganizationConfig `mapstructure:"folder_organization"`
+	// Containers lists several containers to sync in turn, as an alternative to Container.
+	Containers []ContainerConfig `mapstructure:"containers"`
+}
+
+// Overwrite returns the effective overwrite policy, falling back to the
+// deprecated SkipExisting when OverwritePolicy is not set.
+func (s SyncConfig) Overwrite() string {
+	if s.OverwritePolicy != "" {
+		return s.OverwritePolicy
+	}
+	if s.SkipExisting {
+		return "skip"
+	}
+	return "always"
+}
+
+// ContainerConfig describes one container in a multi-container sync.
+type ContainerConfig struct {
+	// Name is the Azure Blob Storage container name.
+	Name string `mapstructure:"name"`
+	// Prefix filters this container's blobs to those starting with it.
+	Prefix string `mapstructure:"prefix"`
+	// OutputSubpath is the directory under the output path for this container (default: the container name).
+	OutputSubpath string `mapstructure:"output_subpath"`
 }
 
 // FolderOrganizationConfig contains settings for organizing downloaded files into folders.
@@ -71,10 +203,14 @@ type FolderOrganizationConfig struct {
 	Enabled bool `mapstructure:"enabled"`
 	// MaxFilesPerFolder is the maximum number of files per folder.
 	MaxFilesPerFolder int `mapstructure:"max_files_per_folder"`
-	// Strategy determines the folder organization strategy (partition_key, date, sequential).
+	// Strategy determines the folder organization strategy (partition_key, date, sequential, extension, template).
 	Strategy string `mapstructure:"strategy"`
 	// PartitionDepth is the depth of partition key hashing (for partition_key strategy).
 	PartitionDepth int `mapstructure:"partition_depth"`
+	// UseDownloadDate makes the date strategy use the download date instead of the blob's last-modified date.
+	UseDownloadDate bool `mapstructure:"use_download_date"`
+	// FolderTemplate is the folder layout for the template strategy, e.g. "{year}/{ext}/{hash2}".
+	FolderTemplate string `mapstructure:"folder_template"`
 }
 
 // WatchConfig contains continuous sync monitoring settings.
@@ -83,6 +219,24 @@ type WatchConfig struct {
 	Enabled bool `mapstructure:"enabled"`
 	// Interval is the duration between sync runs in watch mode.
 	Interval time.Duration `mapstructure:"interval"`
+	// Cron is a standard five-field cron expression, such as "0 2 * * *",
+	// that schedules runs in watch mode instead of Interval (empty = use
+	// Interval).
+	Cron string `mapstructure:"cron"`
+	// MetricsAddr is the address to serve Prometheus metrics on, such as
+	// ":9090" (empty = disabled).
+	MetricsAddr string `mapstructure:"metrics_addr"`
+	// HealthAddr is the address to serve /healthz and /readyz on, such as
+	// ":8080" (empty = disabled).
+	HealthAddr string `mapstructure:"health_addr"`
+}
+
+// Schedule returns the parsed Cron expression, or nil if Cron is empty.
+func (w WatchConfig) Schedule() (cron.Schedule, error) {
+	if w.Cron == "" {
+		return nil, nil
+	}
+	return cron.ParseStandard(w.Cron)
 }
 
 // LoggingConfig contains logging configuration.
@@ -91,12 +245,36 @@ type LoggingConfig struct {
 	Level string `mapstructure:"level"`
 	// Format specifies the log output format (text, json).
 	Format string `mapstructure:"format"`
+	// Color controls coloured log levels in text output on stdout: "auto"
+	// colours them on a terminal unless NO_COLOR is set, "always" or "never".
+	Color string `mapstructure:"color"`
+	// File is a path logs are also written to, rotated by size (empty = disabled).
+	File string `mapstructure:"file"`
+	// Stdout writes logs to standard output; it can only be turned off when File is set.
+	Stdout bool `mapstructure:"stdout"`
+	// MaxSizeMB is the size in megabytes at which File is rotated.
+	MaxSizeMB int `mapstructure:"max_size_mb"`
+	// MaxBackups is the number of rotated log files to keep (0 = all).
+	MaxBackups int `mapstructure:"max_backups"`
+	// MaxAgeDays is the number of days to keep rotated log files (0 = no limit).
+	MaxAgeDays int `mapstructure:"max_age_days"`
+	// AuditFile is a path that a JSON line is appended to for every file
+	// downloaded or deleted by mirroring (empty = disabled).
+	AuditFile string `mapstructure:"audit_file"`
 }
 
 // StateConfig contains state database configuration.
 type StateConfig struct {
 	// Database is the path to the SQLite state database file.
 	Database string `mapstructure:"database"`
+	// BusyTimeoutMS is how long to wait for a lock held by another process
+	// using the database before failing, in milliseconds.
+	BusyTimeoutMS int `mapstructure:"busy_timeout_ms"`
+}
+
+// BusyTimeout returns BusyTimeoutMS as a duration.
+func (s StateConfig) BusyTimeout() time.Duration {
+	return time.Duration(s.BusyTimeoutMS) * time.Millisecond
 }
 
 // PerformanceConfig contains performance tuning and resource limit settings.
@@ -111,21 +289,82 @@ type PerformanceConfig struct {
 	ThrottleThreshold float64 `mapstructure:"throttle_threshold"`
 	// BandwidthLimit limits network bandwidth (e.g., "10M", "100K").
 	BandwidthLimit string `mapstructure:"bandwidth_limit"`
+	// BandwidthBytes is BandwidthLimit in bytes per second, set by Validate (0 = unlimited).
+	BandwidthBytes int64 `mapstructure:"-"`
 	// DiskBufferMB is the disk write buffer size in megabytes.
 	DiskBufferMB int `mapstructure:"disk_buffer_mb"`
+	// MetricsInterval is how often performance metrics are recorded during a sync (0 = disabled).
+	MetricsInterval time.Duration `mapstructure:"metrics_interval"`
+	// ChunkThresholdMB is the blob size above which parallel chunked downloads are used (0 = disabled).
+	ChunkThresholdMB int `mapstructure:"chunk_threshold_mb"`
+	// ChunkSizeMB is the size of each ranged read in a chunked download.
+	ChunkSizeMB int `mapstructure:"chunk_size_mb"`
+	// ChunkConcurrency is the number of concurrent ranged reads per chunked download.
+	ChunkConcurrency int `mapstructure:"chunk_concurrency"`
+	// MaxConcurrentRequests caps the Azure requests in flight at once across
+	// all workers and chunked downloads (0 = unlimited).
+	MaxConcurrentRequests int `mapstructure:"max_concurrent_requests"`
+	// AdaptiveWorkers scales the active workers between 1 and Sync.Workers
+	// based on measured throughput and throttling, sampled every MetricsInterval.
+	AdaptiveWorkers bool `mapstructure:"adaptive_workers"`
+}
+
+// RetryConfig controls how failed transfers are retried.
+type RetryConfig struct {
+	// MaxAttempts is the total number of attempts per blob, including the first (1 = no retries).
+	MaxAttempts int `mapstructure:"max_attempts"`
+	// BaseDelay is the backoff before the first retry; it doubles on each subsequent retry.
+	BaseDelay time.Duration `mapstructure:"base_delay"`
+	// MaxDelay caps the backoff between retries.
+	MaxDelay time.Duration `mapstructure:"max_delay"`
+}
+
+// NotificationsConfig controls notifications sent when a sync run finishes.
+type NotificationsConfig struct {
+	// WebhookURL receives a JSON summary of each finished run (empty = disabled).
+	WebhookURL string `mapstructure:"webhook_url"`
+	// WebhookOn selects the runs reported: "always", or "failure" for runs that
+	// failed or had blobs fail.
+	WebhookOn string `mapstructure:"webhook_on"`
+}
+
+// HooksConfig contains commands run as files are downloaded and runs finish.
+type HooksConfig struct {
+	// PostDownload is run for each downloaded file; {path}, {blob} and
+	// {container} are substituted (empty = disabled).
+	PostDownload string `mapstructure:"post_download"`
+	// PostRun is run after each completed run; {run_id}, {status},
+	// {container}, {downloaded} and {failed} are substituted (empty = disabled).
+	PostRun string `mapstructure:"post_run"`
+	// Timeout bounds each hook command.
+	Timeout time.Duration `mapstructure:"timeout"`
+	// FailOnError marks the file, or the run, as failed when its hook fails.
+	// By default hook failures are only logged.
+	FailOnError bool `mapstructure:"fail_on_error"`
 }
 
 // Default returns a Config with sensible default values.
 func Default() *Config {
 	return &Config{
+		Azure: AzureConfig{
+			EndpointSuffix: "core.windows.net",
+		},
 		Sync: SyncConfig{
-			OutputPath:      "./data",
-			Workers:         10,
-			BatchSize:       5000,
-			SkipExisting:    true,
-			VerifyChecksums: true,
-			DiskWarnPercent: 80,
-			DiskStopPercent: 90,
+			OutputPath:         "./data",
+			Workers:            10,
+			BatchSize:          5000,
+			SkipExisting:       true,
+			DownloadOrder:      "listed",
+			VerifyChecksums:    true,
+			PreserveTimestamps: true,
+			BlobTimeout:        10 * time.Minute,
+			FileMode:           "0644",
+			DirMode:            "0755",
+			FilePerm:           0644,
+			DirPerm:            0755,
+			UnsafeNames:        "reject",
+			DiskWarnPercent:    80,
+			DiskStopPercent:    90,
 			FolderOrganization: FolderOrganizationConfig{
 				Enabled:           false,
 				MaxFilesPerFolder: 10000,
@@ -138,11 +377,17 @@ func Default() *Config {
 			Interval: 5 * time.Minute,
 		},
 		Logging: LoggingConfig{
-			Level:  "info",
-			Format: "text",
+			Level:      "info",
+			Format:     "text",
+			Color:      "auto",
+			Stdout:     true,
+			MaxSizeMB:  100,
+			MaxBackups: 5,
+			MaxAgeDays: 30,
 		},
 		State: StateConfig{
-			Database: "./.sync-state.db",
+			Database:      "./.sync-state.db",
+			BusyTimeoutMS: 5000,
 		},
 		Performance: PerformanceConfig{
 			MaxMemoryMB:       0,
@@ -150,29 +395,36 @@ func Default() *Config {
 			AutoThrottle:      false,
 			ThrottleThreshold: 0.8,
 			DiskBufferMB:      32,
+			MetricsInterval:   10 * time.Second,
+			ChunkThresholdMB:  256,
+			ChunkSizeMB:       8,
+			ChunkConcurrency:  4,
+		},
+		Retry: RetryConfig{
+			MaxAttempts: 3,
+			BaseDelay:   1 * time.Second,
+			MaxDelay:    30 * time.Second,
+		},
+		Notifications: NotificationsConfig{
+			WebhookOn: "always",
+		},
+		Hooks: HooksConfig{
+			Timeout: 5 * time.Minute,
 		},
 	}
 }
 
 // Validate checks if the configuration is valid and returns an error if not.
 func (c *Config) Validate() error {
-	if c.Sync.Container == "" {
+	if c.Sync.Container == "" && len(c.Sync.Containers) == 0 {
 		return fmt.Errorf("container name is required")
 	}
-
-	if c.Azure.ConnectionString == "" && c.Azure.AccountName == "" {
-		return fmt.Errorf("either connection string or account name must be provided")
+	if err := c.validateContainers(); err != nil {
+		return err
 	}
 
-	if c.Azure.AccountName != "" && c.Azure.ConnectionString == "" {
-		hasAuth := c.Azure.AccountKey != "" ||
-			c.Azure.UseManagedIdentity ||
-			(c.Azure.TenantID != "" && c.Azure.ClientID != "" && c.Azure.ClientSecret != "") ||
-			c.Azure.UseAzureCLI
-
-		if !hasAuth {
-			return fmt.Errorf("authentication method required when using account name")
-		}
+	if err := c.ValidateAzure(); err != nil {
+		return err
 	}
 
 	if c.Sync.Workers < 1 || c.Sync.Workers > 100 {
@@ -183,6 +435,42 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("batch size must be between 1 and 10000")
 	}
 
+	if err := blobfilter.Validate(c.Sync.Include); err != nil {
+		return fmt.Errorf("invalid include pattern: %w", err)
+	}
+	if err := blobfilter.Validate(c.Sync.Exclude); err != nil {
+		return fmt.Errorf("invalid exclude pattern: %w", err)
+	}
+
+	now := time.Now()
+	after, err := ParseTimeBound(c.Sync.ModifiedAfter, now)
+	if err != nil {
+		return fmt.Errorf("invalid modified-after: %w", err)
+	}
+	before, err := ParseTimeBound(c.Sync.ModifiedBefore, now)
+	if err != nil {
+		return fmt.Errorf("invalid modified-before: %w", err)
+	}
+	if !after.IsZero() && !before.IsZero() && !after.Before(before) {
+		return fmt.Errorf("modified-after must be earlier than modified-before")
+	}
+
+	switch c.Sync.OverwritePolicy {
+	case "", "skip", "newer", "always", "never":
+	default:
+		return fmt.Errorf("overwrite policy must be skip, newer, always or never")
+	}
+
+	switch c.Sync.DownloadOrder {
+	case "", "listed", "smallest_first", "largest_first", "random":
+	default:
+		return fmt.Errorf("download order must be listed, smallest_first, largest_first or random")
+	}
+
+	if c.Sync.UnsafeNames != "reject" && c.Sync.UnsafeNames != "rewrite" {
+		return fmt.Errorf("unsafe names must be reject or rewrite")
+	}
+
 	if c.Sync.DiskWarnPercent < 1 || c.Sync.DiskWarnPercent > 99 {
 		return fmt.Errorf("disk warn percent must be between 1 and 99")
 	}
@@ -201,6 +489,123 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("throttle threshold must be between 0.1 and 1.0")
 	}
 
+	if c.Sync.FilePerm, err = ParseFileMode(c.Sync.FileMode); err != nil {
+		return fmt.Errorf("invalid file mode: %w", err)
+	}
+	if c.Sync.DirPerm, err = ParseFileMode(c.Sync.DirMode); err != nil {
+		return fmt.Errorf("invalid directory mode: %w", err)
+	}
+
+	if c.Sync.Version != "" {
+		if c.Sync.VersionTime, err = time.Parse(time.RFC3339Nano, c.Sync.Version); err != nil {
+			return fmt.Errorf("version must be a version ID or RFC 3339 time: %w", err)
+		}
+		if c.Sync.DecompressOnDownload {
+			return fmt.Errorf("decompressing on download is not supported with a pinned version")
+		}
+	}
+
+	if c.Sync.BlobTimeout < 0 {
+		return fmt.Errorf("blob timeout must not be negative")
+	}
+
+	switch c.Logging.Color {
+	case "auto", "always", "never":
+	default:
+		return fmt.Errorf("log color must be auto, always or never")
+	}
+	if !c.Logging.Stdout && c.Logging.File == "" {
+		return fmt.Errorf("logging to stdout can only be disabled when a log file is set")
+	}
+	if c.Logging.MaxSizeMB < 1 {
+		return fmt.Errorf("log max size must be at least 1 MB")
+	}
+	if c.Logging.MaxBackups < 0 || c.Logging.MaxAgeDays < 0 {
+		return fmt.Errorf("log max backups and max age must not be negative")
+	}
+
+	if c.State.BusyTimeoutMS < 0 {
+		return fmt.Errorf("state busy timeout must not be negative")
+	}
+
+	if c.Performance.MetricsInterval < 0 || (c.Performance.MetricsInterval > 0 && c.Performance.MetricsInterval < time.Second) {
+		return fmt.Errorf("metrics interval must be 0 (disabled) or at least 1s")
+	}
+
+	bandwidth, err := ParseBandwidth(c.Performance.BandwidthLimit)
+	if err != nil {
+		return fmt.Errorf("invalid bandwidth limit: %w", err)
+	}
+	c.Performance.BandwidthBytes = bandwidth
+
+	if c.Performance.ChunkThresholdMB < 0 {
+		return fmt.Errorf("chunk threshold must not be negative")
+	}
+	if c.Performance.ChunkThresholdMB > 0 {
+		if c.Performance.ChunkSizeMB < 1 {
+			return fmt.Errorf("chunk size must be at least 1 MB")
+		}
+		if c.Performance.ChunkConcurrency < 1 || c.Performance.ChunkConcurrency > 64 {
+			return fmt.Errorf("chunk concurrency must be between 1 and 64")
+		}
+	}
+
+	if c.Performance.MaxConcurrentRequests < 0 {
+		return fmt.Errorf("max concurrent requests must not be negative")
+	}
+	if c.Performance.AdaptiveWorkers && c.Performance.MetricsInterval == 0 {
+		return fmt.Errorf("adaptive workers require a metrics interval")
+	}
+
+	if c.Retry.MaxAttempts < 1 || c.Retry.MaxAttempts > 20 {
+		return fmt.Errorf("retry max attempts must be between 1 and 20")
+	}
+	if c.Retry.BaseDelay < 0 {
+		return fmt.Errorf("retry base delay must not be negative")
+	}
+	if c.Retry.MaxDelay < c.Retry.BaseDelay {
+		return fmt.Errorf("retry max delay must be at least the base delay")
+	}
+
+	if _, err := c.Watch.Schedule(); err != nil {
+		return fmt.Errorf("invalid watch cron expression: %w", err)
+	}
+
+	if c.Watch.MetricsAddr != "" {
+		if _, _, err := net.SplitHostPort(c.Watch.MetricsAddr); err != nil {
+			return fmt.Errorf("invalid metrics address: %w", err)
+		}
+	}
+	if c.Watch.HealthAddr != "" {
+		if _, _, err := net.SplitHostPort(c.Watch.HealthAddr); err != nil {
+			return fmt.Errorf("invalid health address: %w", err)
+		}
+	}
+
+	if c.Notifications.WebhookURL != "" {
+		u, err := url.Parse(c.Notifications.WebhookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("webhook URL must be an http or https URL")
+		}
+	}
+	if c.Notifications.WebhookOn != "always" && c.Notifications.WebhookOn != "failure" {
+		return fmt.Errorf("webhook_on must be always or failure")
+	}
+
+	if c.Hooks.PostDownload != "" {
+		if _, err := hook.Parse(c.Hooks.PostDownload, hook.PostDownloadTokens); err != nil {
+			return fmt.Errorf("invalid post_download hook: %w", err)
+		}
+	}
+	if c.Hooks.PostRun != "" {
+		if _, err := hook.Parse(c.Hooks.PostRun, hook.PostRunTokens); err != nil {
+			return fmt.Errorf("invalid post_run hook: %w", err)
+		}
+	}
+	if (c.Hooks.PostDownload != "" || c.Hooks.PostRun != "") && c.Hooks.Timeout <= 0 {
+		return fmt.Errorf("hook timeout must be positive")
+	}
+
 	if c.Sync.FolderOrganization.Enabled {
 		if c.Sync.FolderOrganization.MaxFilesPerFolder < 100 || c.Sync.FolderOrganization.MaxFilesPerFolder > 100000 {
 			return fmt.Errorf("max files per folder must be between 100 and 100000")
@@ -210,9 +615,17 @@ func (c *Config) Validate() error {
 			"sequential":    true,
 			"partition_key": true,
 			"date":          true,
+			"extension":     true,
+			"template":      true,
 		}
 		if !validStrategies[c.Sync.FolderOrganization.Strategy] {
-			return fmt.Errorf("invalid folder organization strategy: must be sequential, partition_key, or date")
+			return fmt.Errorf("invalid folder organization strategy: must be sequential, partition_key, date, extension, or template")
+		}
+
+		if c.Sync.FolderOrganization.Strategy == "template" {
+			if err := pathtemplate.Validate(c.Sync.FolderOrganization.FolderTemplate); err != nil {
+				return fmt.Errorf("invalid folder template: %w", err)
+			}
 		}
 
 		if c.Sync.FolderOrganization.PartitionDepth < 1 || c.Sync.FolderOrganization.PartitionDepth > 4 {
@@ -223,6 +636,186 @@ func (c *Config) Validate() error {
 	return nil
 }
 
+// validateContainers checks the multi-container list.
+func (c *Config) validateContainers() error {
+	if len(c.Sync.Containers) == 0 {
+		return nil
+	}
+	if c.Sync.Container != "" {
+		return fmt.Errorf("set either container or containers, not both")
+	}
+
+	names := make(map[string]bool)
+	subpaths := make(map[string]bool)
+	for _, cc := range c.Sync.Containers {
+		if cc.Name == "" {
+			return fmt.Errorf("every entry in containers needs a name")
+		}
+		if names[cc.Name] {
+			return fmt.Errorf("container %q is listed more than once", cc.Name)
+		}
+		names[cc.Name] = true
+
+		subpath := cc.outputSubpath()
+		if !filepath.IsLocal(subpath) {
+			return fmt.Errorf("output subpath %q for container %q must be a relative path inside the output path", subpath, cc.Name)
+		}
+		if subpaths[filepath.Clean(subpath)] {
+			return fmt.Errorf("output subpath %q is used by more than one container", subpath)
+		}
+		subpaths[filepath.Clean(subpath)] = true
+	}
+	return nil
+}
+
+// outputSubpath returns the directory for the container under the output path.
+func (cc ContainerConfig) outputSubpath() string {
+	if cc.OutputSubpath != "" {
+		return cc.OutputSubpath
+	}
+	return cc.Name
+}
+
+// ForEachContainer returns one configuration per container to sync. With a
+// single container this is the configuration itself; with sync.containers
+// each copy has its container, prefix, and output path filled in, and any
+// manifest path gains the container name so that manifests do not collide.
+func (c *Config) ForEachContainer() []*Config {
+	if len(c.Sync.Containers) == 0 {
+		return []*Config{c}
+	}
+
+	configs := make([]*Config, 0, len(c.Sync.Containers))
+	for _, cc := range c.Sync.Containers {
+		copied := *c
+		copied.Sync.Container = cc.Name
+		copied.Sync.Prefix = cc.Prefix
+		copied.Sync.OutputPath = filepath.Join(c.Sync.OutputPath, cc.outputSubpath())
+		copied.Sync.Containers = nil
+		if c.Sync.ManifestPath != "" {
+			ext := filepath.Ext(c.Sync.ManifestPath)
+			copied.Sync.ManifestPath = strings.TrimSuffix(c.Sync.ManifestPath, ext) + "." + cc.Name + ext
+		}
+		configs = append(configs, &copied)
+	}
+	return configs
+}
+
+// ValidateAzure checks the Azure connection settings only.
+// It is used by commands that talk to Azure without running a sync.
+func (c *Config) ValidateAzure() error {
+	// Anonymous access needs only a URL, which a blob endpoint gives as well.
+	anonymousEndpoint := c.Azure.Anonymous && c.Azure.BlobEndpoint != ""
+	if c.Azure.ConnectionString == "" && c.Azure.AccountName == "" && !anonymousEndpoint {
+		return fmt.Errorf("either connection string or account name must be provided")
+	}
+
+	if c.Azure.AccountName != "" && c.Azure.ConnectionString == "" {
+		hasAuth := c.Azure.AccountKey != "" ||
+			c.Azure.UseManagedIdentity ||
+			(c.Azure.TenantID != "" && c.Azure.ClientID != "" && c.Azure.ClientSecret != "") ||
+			c.Azure.UseAzureCLI ||
+			c.Azure.Anonymous
+
+		if !hasAuth {
+			return fmt.Errorf("authentication method required when using account name")
+		}
+	}
+
+	if c.Azure.RetryPolicy.MaxRetries < -1 {
+		return fmt.Errorf("retry policy max retries must be -1 or greater")
+	}
+	if c.Azure.RetryPolicy.TryTimeout < 0 || c.Azure.RetryPolicy.RetryDelay < 0 || c.Azure.RetryPolicy.MaxRetryDelay < 0 {
+		return fmt.Errorf("retry policy durations must not be negative")
+	}
+
+	return nil
+}
+
+// ParseFileMode parses an octal permission mode such as "0644" or "755".
+func ParseFileMode(mode string) (os.FileMode, error) {
+	bits, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not an octal mode", mode)
+	}
+	if bits > 0777 {
+		return 0, fmt.Errorf("%q has bits outside the permission bits 0777", mode)
+	}
+	return os.FileMode(bits), nil
+}
+
+// ParseBandwidth converts a bandwidth limit such as "10M" or "100K" into bytes
+// per second. Suffixes K, M, and G are binary multiples and may be followed by
+// "B"; a bare number is bytes per second. An empty string means unlimited (0).
+func ParseBandwidth(limit string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(limit))
+	if s == "" {
+		return 0, nil
+	}
+
+	s = strings.TrimSuffix(s, "B")
+	multiplier := int64(1)
+	if n := len(s); n > 0 {
+		switch s[n-1] {
+		case 'K':
+			multiplier = 1024
+		case 'M':
+			multiplier = 1024 * 1024
+		case 'G':
+			multiplier = 1024 * 1024 * 1024
+		}
+		if multiplier > 1 {
+			s = s[:n-1]
+		}
+	}
+
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil || value <= 0 || math.IsInf(value, 0) || math.IsNaN(value) {
+		return 0, fmt.Errorf("%q must be a positive number with an optional K, M, or G suffix", limit)
+	}
+
+	return int64(value * float64(multiplier)), nil
+}
+
+// ParseTimeBound converts a time filter into an absolute time. It accepts an
+// RFC3339 timestamp, a date such as "2024-01-31" (midnight UTC), or an age
+// relative to now such as "90m", "12h", "7d" or "2w". An empty string means
+// no bound (the zero time).
+func ParseTimeBound(value string, now time.Time) (time.Time, error) {
+	v := strings.TrimSpace(value)
+	if v == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("2006-01-02", v); err == nil {
+		return t, nil
+	}
+
+	unit := time.Duration(0)
+	switch {
+	case strings.HasSuffix(v, "d"):
+		unit = 24 * time.Hour
+	case strings.HasSuffix(v, "w"):
+		unit = 7 * 24 * time.Hour
+	}
+	if unit > 0 {
+		n, err := strconv.Atoi(v[:len(v)-1])
+		if err != nil || n < 0 {
+			return time.Time{}, fmt.Errorf("%q must be an RFC3339 time or an age such as 12h or 7d", value)
+		}
+		return now.Add(-time.Duration(n) * unit), nil
+	}
+
+	age, err := time.ParseDuration(v)
+	if err != nil || age < 0 {
+		return time.Time{}, fmt.Errorf("%q must be an RFC3339 time or an age such as 12h or 7d", value)
+	}
+	return now.Add(-age), nil
+}
+
 // GetConfigPath returns the configuration file path based on priority:
 // 1. Explicit path if provided
 // 2. Current directory (./getblobz.yaml or ./getblobz.yml)
@@ -0,0 +1,136 @@
+// Package config provides environment variable expansion for configuration values.
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// ExpandEnv replaces environment variable references in every string value
+// of the configuration. See ExpandEnvString for the supported syntax.
+func (c *Config) ExpandEnv() error {
+	return expandValue(reflect.ValueOf(c).Elem(), "", os.LookupEnv)
+}
+
+// expandValue walks v and expands strings in place, naming failures by
+// their mapstructure key path.
+func expandValue(v reflect.Value, key string, lookup func(string) (string, bool)) error {
+	switch v.Kind() {
+	case reflect.String:
+		expanded, err := ExpandEnvString(v.String(), lookup)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		v.SetString(expanded)
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name := field.Tag.Get("mapstructure")
+			if name == "" {
+				name = strings.ToLower(field.Name)
+			}
+			if key != "" {
+				name = key + "." + name
+			}
+			if err := expandValue(v.Field(i), name, lookup); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			if err := expandValue(v.Index(i), fmt.Sprintf("%s[%d]", key, i), lookup); err != nil {
+				return err
+			}
+		}
+	case reflect.Pointer:
+		if !v.IsNil() {
+			return expandValue(v.Elem(), key, lookup)
+		}
+	}
+	return nil
+}
+
+// ExpandEnvString expands $VAR, ${VAR} and ${VAR:-default} references in s
+// using lookup. A reference to an unset variable is an error unless it has
+// a default, which is also used when the variable is set but empty. "$$"
+// produces a literal "$", as does a "$" not followed by a variable name.
+func ExpandEnvString(s string, lookup func(string) (string, bool)) (string, error) {
+	if !strings.Contains(s, "$") {
+		return s, nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+
+		switch next := s[i+1]; {
+		case next == '$':
+			b.WriteByte('$')
+			i++
+		case next == '{':
+			end := strings.IndexByte(s[i+2:], '}')
+			if end < 0 {
+				return "", fmt.Errorf("unterminated variable reference in %q", s)
+			}
+			ref := s[i+2 : i+2+end]
+			name, def, hasDefault := strings.Cut(ref, ":-")
+			if !isEnvName(name) {
+				return "", fmt.Errorf("invalid variable reference ${%s}", ref)
+			}
+			value, ok := lookup(name)
+			switch {
+			case hasDefault && value == "":
+				value = def
+			case !ok:
+				return "", fmt.Errorf("environment variable %s is not set", name)
+			}
+			b.WriteString(value)
+			i += end + 2
+		case isEnvNameStart(next):
+			end := i + 2
+			for end < len(s) && isEnvNameChar(s[end]) {
+				end++
+			}
+			name := s[i+1 : end]
+			value, ok := lookup(name)
+			if !ok {
+				return "", fmt.Errorf("environment variable %s is not set", name)
+			}
+			b.WriteString(value)
+			i = end - 1
+		default:
+			b.WriteByte('$')
+		}
+	}
+
+	return b.String(), nil
+}
+
+func isEnvName(name string) bool {
+	if name == "" || !isEnvNameStart(name[0]) {
+		return false
+	}
+	for i := 1; i < len(name); i++ {
+		if !isEnvNameChar(name[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isEnvNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isEnvNameChar(c byte) bool {
+	return isEnvNameStart(c) || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,54 @@
+// Package config provides masking of credentials in configuration values.
+package config
+
+import (
+	"net/url"
+)
+
+// redacted replaces secret values in configuration that is logged or shown.
+const redacted = "***"
+
+// Redact returns a copy of the Azure settings that is safe to log, with the
+// account key, client secret and connection string masked, along with any
+// SAS token or password in the endpoint and proxy URLs. Empty values are
+// left empty so it remains clear which settings are in use.
+func (a AzureConfig) Redact() AzureConfig {
+	a.ConnectionString = redactSecret(a.ConnectionString)
+	a.AccountKey = redactSecret(a.AccountKey)
+	a.ClientSecret = redactSecret(a.ClientSecret)
+	a.BlobEndpoint = redactURL(a.BlobEndpoint)
+	a.ProxyURL = redactURL(a.ProxyURL)
+	return a
+}
+
+// Redact returns a copy of the configuration that is safe to show, with the
+// Azure credentials masked as AzureConfig.Redact does and the notification
+// webhook URL, which usually embeds a token, masked entirely.
+func (c Config) Redact() Config {
+	c.Azure = c.Azure.Redact()
+	c.Notifications.WebhookURL = redactSecret(c.Notifications.WebhookURL)
+	return c
+}
+
+func redactSecret(value string) string {
+	if value == "" {
+		return ""
+	}
+	return redacted
+}
+
+// redactURL masks the SAS query of a URL, and its password the way
+// url.URL.Redacted does, or the whole value if it cannot be parsed.
+func redactURL(value string) string {
+	if value == "" {
+		return ""
+	}
+	u, err := url.Parse(value)
+	if err != nil {
+		return redacted
+	}
+	if u.RawQuery != "" && u.Query().Has("sig") {
+		u.RawQuery = redacted
+	}
+	return u.Redacted()
+}
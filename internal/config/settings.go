@@ -0,0 +1,51 @@
+// Package config provides the configuration as settings keyed like the config file.
+package config
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// durationType is the type of time.Duration settings, shown as strings.
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Settings returns the configuration as nested maps keyed by the same names
+// as the config file, such as settings["sync"]["output_path"]. Durations are
+// formatted as strings like "5m0s", and fields derived by Validate are left
+// out.
+func (c *Config) Settings() map[string]interface{} {
+	return settingsOf(reflect.ValueOf(*c))
+}
+
+// settingsOf maps the fields of struct v by their mapstructure names.
+func settingsOf(v reflect.Value) map[string]interface{} {
+	settings := make(map[string]interface{})
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+		if name == "" || name == "-" || !field.IsExported() {
+			continue
+		}
+		settings[name] = settingValue(v.Field(i))
+	}
+	return settings
+}
+
+// settingValue converts one configuration value for Settings.
+func settingValue(v reflect.Value) interface{} {
+	switch {
+	case v.Type() == durationType:
+		return time.Duration(v.Int()).String()
+	case v.Kind() == reflect.Struct:
+		return settingsOf(v)
+	case v.Kind() == reflect.Slice:
+		items := make([]interface{}, v.Len())
+		for i := range items {
+			items[i] = settingValue(v.Index(i))
+		}
+		return items
+	}
+	return v.Interface()
+}
@@ -0,0 +1,152 @@
+// Package hook runs user-configured commands when files land or runs finish.
+//
+// A hook is a command template such as `process.sh --input {path}`. It is
+// split into arguments like a shell would split it, honouring single and
+// double quotes, but it is not run through a shell: substituted values such
+// as blob names are always passed as literal argument text.
+package hook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// PostDownloadTokens are the tokens available to post-download hooks.
+var PostDownloadTokens = []string{"path", "blob", "container"}
+
+// PostRunTokens are the tokens available to post-run hooks.
+var PostRunTokens = []string{"run_id", "status", "container", "downloaded", "failed"}
+
+// Command is a parsed hook command template.
+type Command struct {
+	args []string
+}
+
+// Parse splits template into arguments and checks that it only uses the
+// given tokens.
+func Parse(template string, tokens []string) (*Command, error) {
+	args, err := split(template)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("hook command is empty")
+	}
+
+	for _, arg := range args {
+		rest := arg
+		for {
+			open := strings.IndexByte(rest, '{')
+			if open < 0 {
+				break
+			}
+			end := strings.IndexByte(rest[open:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated token in hook argument %q", arg)
+			}
+			name := rest[open+1 : open+end]
+			if !contains(tokens, name) {
+				return nil, fmt.Errorf("unknown token {%s} in hook command; available: {%s}", name, strings.Join(tokens, "} {"))
+			}
+			rest = rest[open+end+1:]
+		}
+	}
+
+	return &Command{args: args}, nil
+}
+
+// Result holds the output of a hook run.
+type Result struct {
+	Stdout string
+	Stderr string
+}
+
+// Run substitutes vars into the command and runs it, killing it if it takes
+// longer than timeout or ctx is cancelled. The variables are also passed in
+// the environment as GETBLOBZ_<NAME>.
+func (c *Command) Run(ctx context.Context, timeout time.Duration, vars map[string]string) (Result, error) {
+	pairs := make([]string, 0, len(vars)*2)
+	env := make([]string, 0, len(vars))
+	for name, value := range vars {
+		pairs = append(pairs, "{"+name+"}", value)
+		env = append(env, "GETBLOBZ_"+strings.ToUpper(name)+"="+value)
+	}
+	replacer := strings.NewReplacer(pairs...)
+
+	args := make([]string, len(c.args))
+	for i, arg := range c.args {
+		args[i] = replacer.Replace(arg)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Env = append(cmd.Environ(), env...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	result := Result{Stdout: strings.TrimSpace(stdout.String()), Stderr: strings.TrimSpace(stderr.String())}
+	if ctx.Err() == context.DeadlineExceeded {
+		return result, fmt.Errorf("hook timed out after %s", timeout)
+	}
+	if err != nil {
+		return result, fmt.Errorf("hook %s failed: %w", args[0], err)
+	}
+	return result, nil
+}
+
+// split breaks s into arguments at unquoted whitespace. Quotes group text
+// into one argument and are removed; there are no escape sequences.
+func split(s string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inArg := false
+	var quote rune
+
+	for _, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote in hook command", quote)
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
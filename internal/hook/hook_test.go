@@ -0,0 +1,70 @@
+package hook
+
+import (
+	"context"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		template string
+		want     []string
+		wantErr  bool
+	}{
+		{"process.sh {path}", []string{"process.sh", "{path}"}, false},
+		{`notify --blob "{blob}" --note 'two words'`, []string{"notify", "--blob", "{blob}", "--note", "two words"}, false},
+		{"  spaced\targs  ", []string{"spaced", "args"}, false},
+		{`run --empty ""`, []string{"run", "--empty", ""}, false},
+		{"", nil, true},
+		{"process.sh {size}", nil, true},
+		{"process.sh {path", nil, true},
+		{`process.sh "unterminated`, nil, true},
+	}
+
+	for _, tt := range tests {
+		cmd, err := Parse(tt.template, PostDownloadTokens)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Parse(%q) error = %v, wantErr %v", tt.template, err, tt.wantErr)
+			continue
+		}
+		if err == nil && !reflect.DeepEqual(cmd.args, tt.want) {
+			t.Errorf("Parse(%q) = %q, want %q", tt.template, cmd.args, tt.want)
+		}
+	}
+}
+
+func TestCommand_Run(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+
+	cmd, err := Parse(`sh -c 'echo "$1 $GETBLOBZ_BLOB"; echo oops >&2' hook {path}`, PostDownloadTokens)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	// Substituted values are passed literally, never interpreted by a shell.
+	vars := map[string]string{"path": "/data/a b;rm -rf x", "blob": "a b", "container": "c"}
+	result, err := cmd.Run(context.Background(), 5*time.Second, vars)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if result.Stdout != "/data/a b;rm -rf x a b" {
+		t.Errorf("stdout = %q", result.Stdout)
+	}
+	if result.Stderr != "oops" {
+		t.Errorf("stderr = %q", result.Stderr)
+	}
+
+	slow, err := Parse("sleep 5", PostRunTokens)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if _, err := slow.Run(context.Background(), 50*time.Millisecond, nil); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("expected a timeout error, got %v", err)
+	}
+}
@@ -0,0 +1,112 @@
+// Package notify sends sync run summaries to external services.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// webhookTimeout bounds each delivery attempt.
+const webhookTimeout = 10 * time.Second
+
+// webhookAttempts is the number of delivery attempts, including the first.
+const webhookAttempts = 3
+
+// RunSummary is the JSON body posted when a sync run finishes.
+type RunSummary struct {
+	RunID           int64       `json:"run_id"`
+	Container       string      `json:"container"`
+	Status          string      `json:"status"`
+	StartedAt       time.Time   `json:"started_at"`
+	CompletedAt     time.Time   `json:"completed_at"`
+	DurationSeconds float64     `json:"duration_seconds"`
+	TotalFiles      int64       `json:"total_files"`
+	DownloadedFiles int64       `json:"downloaded_files"`
+	FailedFiles     int64       `json:"failed_files"`
+	TotalBytes      int64       `json:"total_bytes"`
+	Error           string      `json:"error,omitempty"`
+	TopErrors       []BlobError `json:"top_errors,omitempty"`
+}
+
+// BlobError describes the last error for a blob that failed during a run.
+type BlobError struct {
+	Blob    string `json:"blob"`
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// Webhook posts run summaries to a URL.
+type Webhook struct {
+	url    string
+	client *http.Client
+	// retryDelay is the wait before the second attempt; it doubles after that.
+	retryDelay time.Duration
+}
+
+// NewWebhook creates a Webhook that posts to url.
+func NewWebhook(url string) *Webhook {
+	return &Webhook{
+		url:        url,
+		client:     &http.Client{Timeout: webhookTimeout},
+		retryDelay: 2 * time.Second,
+	}
+}
+
+// Send posts summary to the webhook. Network errors, 429 and 5xx responses
+// are retried; other responses fail immediately.
+func (w *Webhook) Send(ctx context.Context, summary *RunSummary) error {
+	body, err := json.Marshal(summary)
+	if err != nil {
+		return fmt.Errorf("failed to encode run summary: %w", err)
+	}
+
+	delay := w.retryDelay
+	var lastErr error
+	for attempt := 0; attempt < webhookAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			delay *= 2
+		}
+
+		retry, err := w.post(ctx, body)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if !retry {
+			break
+		}
+	}
+
+	return fmt.Errorf("failed to send webhook: %w", lastErr)
+}
+
+// post makes a single delivery attempt and reports whether a failure is
+// worth retrying.
+func (w *Webhook) post(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	_ = resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("webhook returned %s", resp.Status)
+}
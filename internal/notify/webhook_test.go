@@ -0,0 +1,73 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWebhook_Send(t *testing.T) {
+	var calls int
+	var got RunSummary
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		// The first delivery fails and must be retried.
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type = %q, want application/json", ct)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("failed to decode payload: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	webhook := NewWebhook(server.URL)
+	webhook.retryDelay = time.Millisecond
+
+	summary := &RunSummary{
+		RunID:       7,
+		Container:   "logs",
+		Status:      "completed",
+		FailedFiles: 1,
+		TopErrors:   []BlobError{{Blob: "a.txt", Type: "network", Message: "connection reset"}},
+	}
+	if err := webhook.Send(context.Background(), summary); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	if calls != 2 {
+		t.Errorf("webhook called %d times, want 2", calls)
+	}
+	if got.RunID != 7 || got.Container != "logs" || got.FailedFiles != 1 {
+		t.Errorf("unexpected payload: %+v", got)
+	}
+	if len(got.TopErrors) != 1 || got.TopErrors[0].Blob != "a.txt" {
+		t.Errorf("unexpected top errors: %+v", got.TopErrors)
+	}
+}
+
+func TestWebhook_Send_ClientErrorNotRetried(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	webhook := NewWebhook(server.URL)
+	webhook.retryDelay = time.Millisecond
+
+	if err := webhook.Send(context.Background(), &RunSummary{}); err == nil {
+		t.Fatal("expected an error for a 400 response")
+	}
+	if calls != 1 {
+		t.Errorf("webhook called %d times, want 1", calls)
+	}
+}
@@ -6,11 +6,14 @@ import (
 	"encoding/hex"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/haepapa/getblobz/internal/config"
+	"github.com/haepapa/getblobz/internal/pathtemplate"
 )
 
 // Organizer manages folder organization for downloaded files.
@@ -21,6 +24,8 @@ type Organizer struct {
 	folderCounts  map[string]int
 	currentFolder string
 	folderIndex   int
+	// now returns the current time; replaced in tests.
+	now func() time.Time
 }
 
 // New creates a new Organizer instance.
@@ -30,14 +35,17 @@ func New(cfg *config.FolderOrganizationConfig, basePath string) *Organizer {
 		basePath:     basePath,
 		folderCounts: make(map[string]int),
 		folderIndex:  0,
+		now:          time.Now,
 	}
 }
 
 // GetTargetPath returns the appropriate folder path for a file based on the organization strategy.
 // This method is thread-safe and ensures files are distributed according to the configured strategy.
-func (o *Organizer) GetTargetPath(blobName string, blobPath string) string {
+// lastModified is the blob's last-modified time, used by the date and template strategies.
+func (o *Organizer) GetTargetPath(blobName string, blobPath string, lastModified time.Time) string {
+	relPath := LocalRelPath(blobPath)
 	if !o.cfg.Enabled {
-		return filepath.Join(o.basePath, blobPath)
+		return filepath.Join(o.basePath, relPath)
 	}
 
 	o.mu.Lock()
@@ -49,19 +57,53 @@ func (o *Organizer) GetTargetPath(blobName string, blobPath string) string {
 	case "partition_key":
 		folder = o.getPartitionKeyFolder(blobName)
 	case "date":
-		folder = o.getDateFolder()
+		folder = o.getDateFolder(lastModified)
+	case "extension":
+		folder = pathtemplate.Extension(blobPath)
+	case "template":
+		folder = o.getTemplateFolder(blobName, blobPath, lastModified)
 	case "sequential":
 		folder = o.getSequentialFolder()
 	default:
 		folder = o.getSequentialFolder()
 	}
 
-	targetPath := filepath.Join(o.basePath, folder, blobPath)
+	targetPath := filepath.Join(o.basePath, folder, relPath)
 	o.trackFile(folder)
 
 	return targetPath
 }
 
+// LocalRelPath converts a blob path into a relative local path using the
+// platform separator. Backslashes are treated as separators, and leading
+// "..", "." and root components are dropped so the result always stays
+// inside the directory it is joined to.
+func LocalRelPath(blobPath string) string {
+	cleaned := path.Clean("/" + strings.ReplaceAll(blobPath, "\\", "/"))
+	return filepath.FromSlash(strings.TrimPrefix(cleaned, "/"))
+}
+
+// EscapesRoot reports whether a blob path would resolve outside the
+// directory it is joined to if used verbatim: absolute paths, Windows drive
+// or UNC paths, and paths whose ".." segments climb above the root.
+func EscapesRoot(blobPath string) bool {
+	p := strings.ReplaceAll(blobPath, "\\", "/")
+	if strings.HasPrefix(p, "/") || (len(p) >= 2 && p[1] == ':') {
+		return true
+	}
+	cleaned := path.Clean(p)
+	return cleaned == ".." || strings.HasPrefix(cleaned, "../")
+}
+
+// Within reports whether target is dir or a path inside it.
+func Within(dir, target string) bool {
+	rel, err := filepath.Rel(dir, target)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
 // getPartitionKeyFolder generates a folder path based on hash partitioning of the blob name.
 // This distributes files evenly across folders using hash-based partitioning,
 // which is optimal for analytics workloads like Apache Spark.
@@ -84,17 +126,37 @@ func (o *Organizer) getPartitionKeyFolder(blobName string) string {
 	return filepath.Join(parts...)
 }
 
-// getDateFolder generates a folder path based on the current date.
+// getDateFolder generates a folder path based on the blob's last-modified date,
+// or the download date when UseDownloadDate is set or lastModified is unknown.
 // Format: YYYY/MM/DD for hierarchical date-based organization.
-func (o *Organizer) getDateFolder() string {
-	now := time.Now()
+func (o *Organizer) getDateFolder(lastModified time.Time) string {
+	date := lastModified.UTC()
+	if o.cfg.UseDownloadDate || lastModified.IsZero() {
+		date = o.now()
+	}
+
 	return filepath.Join(
-		fmt.Sprintf("%04d", now.Year()),
-		fmt.Sprintf("%02d", now.Month()),
-		fmt.Sprintf("%02d", now.Day()),
+		fmt.Sprintf("%04d", date.Year()),
+		fmt.Sprintf("%02d", date.Month()),
+		fmt.Sprintf("%02d", date.Day()),
 	)
 }
 
+// getTemplateFolder generates a folder path by rendering the configured folder template.
+// The template is validated with the configuration, so a render error places the
+// file directly under the base path.
+func (o *Organizer) getTemplateFolder(blobName, blobPath string, lastModified time.Time) string {
+	folder, err := pathtemplate.Render(o.cfg.FolderTemplate, pathtemplate.Blob{
+		Name:         blobName,
+		Path:         blobPath,
+		LastModified: lastModified,
+	})
+	if err != nil {
+		return ""
+	}
+	return folder
+}
+
 // getSequentialFolder generates a sequential folder path (folder_0000, folder_0001, etc.).
 // When the current folder reaches the max file limit, it automatically creates the next folder.
 func (o *Organizer) getSequentialFolder() string {
@@ -124,7 +186,7 @@ func (o *Organizer) LoadState() error {
 	switch o.cfg.Strategy {
 	case "sequential":
 		return o.loadSequentialState()
-	case "partition_key", "date":
+	case "partition_key", "date", "extension", "template":
 		return o.loadPartitionedState()
 	}
 
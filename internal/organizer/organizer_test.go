@@ -3,7 +3,9 @@ package organizer
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/haepapa/getblobz/internal/config"
 )
@@ -14,7 +16,7 @@ func TestOrganizer_Disabled(t *testing.T) {
 	}
 
 	org := New(cfg, "/data")
-	path := org.GetTargetPath("blob1.txt", "files/blob1.txt")
+	path := org.GetTargetPath("blob1.txt", "files/blob1.txt", time.Time{})
 
 	expected := filepath.Join("/data", "files/blob1.txt")
 	if path != expected {
@@ -22,6 +24,55 @@ func TestOrganizer_Disabled(t *testing.T) {
 	}
 }
 
+func TestOrganizer_Disabled_CleansPaths(t *testing.T) {
+	base := t.TempDir()
+	tests := []struct {
+		name     string
+		basePath string
+		blobPath string
+		want     string
+	}{
+		{"trailing slash on base", base + "/", "files/blob1.txt", filepath.Join(base, "files", "blob1.txt")},
+		{"windows separators", base, `files\2024\blob1.txt`, filepath.Join(base, "files", "2024", "blob1.txt")},
+		{"doubled slashes", base, "files//blob1.txt", filepath.Join(base, "files", "blob1.txt")},
+		{"parent traversal", base, "../../etc/x", filepath.Join(base, "etc", "x")},
+		{"windows traversal", base, `..\..\etc\x`, filepath.Join(base, "etc", "x")},
+		{"absolute name", base, "/etc/x", filepath.Join(base, "etc", "x")},
+		{"inner traversal", base, "files/../../../x", filepath.Join(base, "x")},
+	}
+
+	for _, tt := range tests {
+		org := New(&config.FolderOrganizationConfig{Enabled: false}, tt.basePath)
+		got := org.GetTargetPath(tt.blobPath, tt.blobPath, time.Time{})
+		if got != tt.want {
+			t.Errorf("%s: GetTargetPath(%q) = %s, want %s", tt.name, tt.blobPath, got, tt.want)
+		}
+		if rel, err := filepath.Rel(base, got); err != nil || strings.HasPrefix(rel, "..") {
+			t.Errorf("%s: %s escapes the output directory", tt.name, got)
+		}
+	}
+}
+
+func TestEscapesRoot(t *testing.T) {
+	tests := map[string]bool{
+		"files/blob1.txt":    false,
+		"files/../blob1.txt": false,
+		"..data/blob1.txt":   false,
+		"../../etc/passwd":   true,
+		`..\etc\passwd`:      true,
+		"files/../../x":      true,
+		"/absolute/path":     true,
+		`C:\Windows\x`:       true,
+		`\\server\share\x`:   true,
+	}
+
+	for blobPath, want := range tests {
+		if got := EscapesRoot(blobPath); got != want {
+			t.Errorf("EscapesRoot(%q) = %v, want %v", blobPath, got, want)
+		}
+	}
+}
+
 func TestOrganizer_Sequential(t *testing.T) {
 	cfg := &config.FolderOrganizationConfig{
 		Enabled:           true,
@@ -33,7 +84,7 @@ func TestOrganizer_Sequential(t *testing.T) {
 
 	paths := []string{}
 	for i := 0; i < 10; i++ {
-		path := org.GetTargetPath("blob.txt", "file.txt")
+		path := org.GetTargetPath("blob.txt", "file.txt", time.Time{})
 		paths = append(paths, path)
 	}
 
@@ -59,14 +110,14 @@ func TestOrganizer_PartitionKey(t *testing.T) {
 
 	org := New(cfg, "/data")
 
-	path1 := org.GetTargetPath("blob1.txt", "file.txt")
-	path2 := org.GetTargetPath("blob1.txt", "file.txt")
+	path1 := org.GetTargetPath("blob1.txt", "file.txt", time.Time{})
+	path2 := org.GetTargetPath("blob1.txt", "file.txt", time.Time{})
 
 	if path1 != path2 {
 		t.Errorf("Same blob name should produce same path")
 	}
 
-	path3 := org.GetTargetPath("blob2.txt", "file.txt")
+	path3 := org.GetTargetPath("blob2.txt", "file.txt", time.Time{})
 	if path1 == path3 {
 		t.Logf("Different blob names might produce different paths (not guaranteed)")
 	}
@@ -79,13 +130,94 @@ func TestOrganizer_DateStrategy(t *testing.T) {
 	}
 
 	org := New(cfg, "/data")
-	path := org.GetTargetPath("blob.txt", "file.txt")
+	path := org.GetTargetPath("blob.txt", "file.txt", time.Time{})
 
 	if !contains(path, "/data/") {
 		t.Errorf("Path should contain base path")
 	}
 }
 
+func TestOrganizer_DateStrategy_LastModified(t *testing.T) {
+	cfg := &config.FolderOrganizationConfig{
+		Enabled:  true,
+		Strategy: "date",
+	}
+
+	org := New(cfg, "/data")
+	org.now = func() time.Time { return time.Date(2024, 6, 30, 12, 0, 0, 0, time.UTC) }
+
+	lastModified := time.Date(2023, 2, 5, 23, 30, 0, 0, time.UTC)
+	path := org.GetTargetPath("blob.txt", "file.txt", lastModified)
+
+	expected := filepath.Join("/data", "2023", "02", "05", "file.txt")
+	if path != expected {
+		t.Errorf("Expected %s, got %s", expected, path)
+	}
+}
+
+func TestOrganizer_DateStrategy_DownloadDate(t *testing.T) {
+	cfg := &config.FolderOrganizationConfig{
+		Enabled:         true,
+		Strategy:        "date",
+		UseDownloadDate: true,
+	}
+
+	org := New(cfg, "/data")
+	org.now = func() time.Time { return time.Date(2024, 6, 30, 12, 0, 0, 0, time.UTC) }
+
+	lastModified := time.Date(2023, 2, 5, 23, 30, 0, 0, time.UTC)
+	path := org.GetTargetPath("blob.txt", "file.txt", lastModified)
+
+	expected := filepath.Join("/data", "2024", "06", "30", "file.txt")
+	if path != expected {
+		t.Errorf("Expected %s, got %s", expected, path)
+	}
+}
+
+func TestOrganizer_ExtensionStrategy(t *testing.T) {
+	cfg := &config.FolderOrganizationConfig{
+		Enabled:  true,
+		Strategy: "extension",
+	}
+
+	org := New(cfg, "/data")
+
+	tests := []struct {
+		blobPath string
+		expected string
+	}{
+		{"logs/app.json", filepath.Join("/data", "json", "logs/app.json")},
+		{"tables/part-0.PARQUET", filepath.Join("/data", "parquet", "tables/part-0.PARQUET")},
+		{"exports/report.Csv", filepath.Join("/data", "csv", "exports/report.Csv")},
+		{"bin/README", filepath.Join("/data", "noext", "bin/README")},
+	}
+
+	for _, tt := range tests {
+		path := org.GetTargetPath(tt.blobPath, tt.blobPath, time.Time{})
+		if path != tt.expected {
+			t.Errorf("GetTargetPath(%q) = %s, want %s", tt.blobPath, path, tt.expected)
+		}
+	}
+}
+
+func TestOrganizer_TemplateStrategy(t *testing.T) {
+	cfg := &config.FolderOrganizationConfig{
+		Enabled:        true,
+		Strategy:       "template",
+		FolderTemplate: "{prefix1}/{year}/{ext}",
+	}
+
+	org := New(cfg, "/data")
+
+	lastModified := time.Date(2023, 2, 5, 23, 30, 0, 0, time.UTC)
+	path := org.GetTargetPath("sensors/reading.json", "sensors/reading.json", lastModified)
+
+	expected := filepath.Join("/data", "sensors", "2023", "json", "sensors/reading.json")
+	if path != expected {
+		t.Errorf("Expected %s, got %s", expected, path)
+	}
+}
+
 func TestOrganizer_LoadState(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -130,7 +262,7 @@ func TestOrganizer_GetStats(t *testing.T) {
 	org := New(cfg, "/data")
 
 	for i := 0; i < 7; i++ {
-		org.GetTargetPath("blob.txt", "file.txt")
+		org.GetTargetPath("blob.txt", "file.txt", time.Time{})
 	}
 
 	stats := org.GetStats()
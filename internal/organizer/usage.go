@@ -0,0 +1,120 @@
+// Package organizer provides disk usage reporting for organized output folders.
+package organizer
+
+import (
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/haepapa/getblobz/internal/pathtemplate"
+)
+
+// RootFolder is the folder reported for files directly under the base path.
+const RootFolder = "."
+
+// Usage is the space used by the files in one group.
+type Usage struct {
+	Key   string `json:"key"`
+	Files int64  `json:"files"`
+	Bytes int64  `json:"bytes"`
+}
+
+// FolderDepth returns the number of directory levels the organization
+// strategy adds below the base path, or 1 if organization is disabled.
+func (o *Organizer) FolderDepth() int {
+	if !o.cfg.Enabled {
+		return 1
+	}
+	switch o.cfg.Strategy {
+	case "partition_key":
+		if o.cfg.PartitionDepth > 0 {
+			return o.cfg.PartitionDepth
+		}
+	case "date":
+		return 3
+	case "template":
+		return strings.Count(strings.Trim(o.cfg.FolderTemplate, "/"), "/") + 1
+	}
+	return 1
+}
+
+// Partition returns the organizer folder that the file at relPath, relative
+// to the base path, was placed in: the first FolderDepth directories of its
+// path, or RootFolder for a file directly under the base path.
+func (o *Organizer) Partition(relPath string) string {
+	return leadingDirs(relPath, o.FolderDepth())
+}
+
+// TopFolder returns the top-level folder of the file at relPath, relative to
+// the base path, or RootFolder for a file directly under the base path.
+func TopFolder(relPath string) string {
+	return leadingDirs(relPath, 1)
+}
+
+// FileExtension returns the lower-cased extension of the file at relPath, as
+// used by the extension strategy.
+func FileExtension(relPath string) string {
+	return pathtemplate.Extension(filepath.ToSlash(relPath))
+}
+
+// leadingDirs returns up to depth leading directories of relPath.
+func leadingDirs(relPath string, depth int) string {
+	dirs := strings.Split(filepath.ToSlash(filepath.Dir(relPath)), "/")
+	if len(dirs) == 1 && dirs[0] == "." {
+		return RootFolder
+	}
+	if len(dirs) > depth {
+		dirs = dirs[:depth]
+	}
+	return strings.Join(dirs, "/")
+}
+
+// DiskUsage walks basePath and totals the regular files under it, grouped by
+// key, which is given each file's path relative to basePath. Groups are
+// returned largest first.
+func DiskUsage(basePath string, key func(relPath string) string) ([]Usage, error) {
+	byKey := make(map[string]*Usage)
+	err := filepath.WalkDir(basePath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(basePath, path)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		k := key(rel)
+		u, ok := byKey[k]
+		if !ok {
+			u = &Usage{Key: k}
+			byKey[k] = u
+		}
+		u.Files++
+		u.Bytes += info.Size()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	usage := make([]Usage, 0, len(byKey))
+	for _, u := range byKey {
+		usage = append(usage, *u)
+	}
+	sort.Slice(usage, func(i, j int) bool {
+		if usage[i].Bytes != usage[j].Bytes {
+			return usage[i].Bytes > usage[j].Bytes
+		}
+		return usage[i].Key < usage[j].Key
+	})
+	return usage, nil
+}
@@ -0,0 +1,95 @@
+package organizer
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/haepapa/getblobz/internal/config"
+)
+
+func TestDiskUsage(t *testing.T) {
+	base := t.TempDir()
+	org := New(&config.FolderOrganizationConfig{Enabled: true, Strategy: "date"}, base)
+
+	jan := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	feb := time.Date(2024, 2, 3, 4, 5, 6, 0, time.UTC)
+	files := []struct {
+		blob     string
+		modified time.Time
+		size     int
+	}{
+		{"logs/a.log", jan, 10},
+		{"logs/b.LOG", jan, 20},
+		{"data/c.csv", feb, 40},
+		{"README", feb, 5},
+	}
+	for _, f := range files {
+		path := org.GetTargetPath(f.blob, f.blob, f.modified)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(strings.Repeat("x", f.size)), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(base, "top.txt"), []byte("12345678"), 0644); err != nil {
+		t.Fatalf("failed to write top.txt: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		key  func(string) string
+		want []Usage
+	}{
+		{"folder", TopFolder, []Usage{
+			{Key: "2024", Files: 4, Bytes: 75},
+			{Key: RootFolder, Files: 1, Bytes: 8},
+		}},
+		{"partition", org.Partition, []Usage{
+			{Key: "2024/02/03", Files: 2, Bytes: 45},
+			{Key: "2024/01/02", Files: 2, Bytes: 30},
+			{Key: RootFolder, Files: 1, Bytes: 8},
+		}},
+		{"extension", FileExtension, []Usage{
+			{Key: "csv", Files: 1, Bytes: 40},
+			{Key: "log", Files: 2, Bytes: 30},
+			{Key: "txt", Files: 1, Bytes: 8},
+			{Key: "noext", Files: 1, Bytes: 5},
+		}},
+	}
+
+	for _, tt := range tests {
+		got, err := DiskUsage(base, tt.key)
+		if err != nil {
+			t.Fatalf("%s: DiskUsage failed: %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: usage = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestOrganizer_FolderDepth(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.FolderOrganizationConfig
+		want int
+	}{
+		{"disabled", config.FolderOrganizationConfig{Strategy: "date"}, 1},
+		{"sequential", config.FolderOrganizationConfig{Enabled: true, Strategy: "sequential"}, 1},
+		{"partition key", config.FolderOrganizationConfig{Enabled: true, Strategy: "partition_key", PartitionDepth: 2}, 2},
+		{"date", config.FolderOrganizationConfig{Enabled: true, Strategy: "date"}, 3},
+		{"template", config.FolderOrganizationConfig{Enabled: true, Strategy: "template", FolderTemplate: "{year}/{ext}/{hash2}"}, 3},
+	}
+
+	for _, tt := range tests {
+		cfg := tt.cfg
+		if got := New(&cfg, "/data").FolderDepth(); got != tt.want {
+			t.Errorf("%s: FolderDepth() = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
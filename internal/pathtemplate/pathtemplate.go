@@ -0,0 +1,121 @@
+// Package pathtemplate renders folder layout templates such as
+// "{year}/{ext}/{hash2}" for the template folder organization strategy.
+package pathtemplate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// NoExtension is the folder name used for files without a file extension.
+const NoExtension = "noext"
+
+// NoPrefix is the value of {prefix1} for blobs at the container root.
+const NoPrefix = "root"
+
+// Blob holds the blob attributes available to a template.
+type Blob struct {
+	// Name is the full blob name.
+	Name string
+	// Path is the blob path used to build the local file path.
+	Path string
+	// LastModified is the blob's last-modified time.
+	LastModified time.Time
+}
+
+// tokens maps each supported token to the function that renders it.
+var tokens = map[string]func(b Blob) string{
+	"year":    func(b Blob) string { return fmt.Sprintf("%04d", b.LastModified.UTC().Year()) },
+	"month":   func(b Blob) string { return fmt.Sprintf("%02d", b.LastModified.UTC().Month()) },
+	"day":     func(b Blob) string { return fmt.Sprintf("%02d", b.LastModified.UTC().Day()) },
+	"ext":     func(b Blob) string { return Extension(b.Path) },
+	"hash2":   func(b Blob) string { return nameHash(b.Name)[:2] },
+	"hash4":   func(b Blob) string { return nameHash(b.Name)[:4] },
+	"prefix1": func(b Blob) string { return firstSegment(b.Name) },
+}
+
+// Render expands the tokens in tmpl for blob and returns the resulting
+// relative folder path using the OS path separator.
+func Render(tmpl string, blob Blob) (string, error) {
+	var out strings.Builder
+
+	rest := tmpl
+	for rest != "" {
+		open := strings.IndexAny(rest, "{}")
+		if open < 0 {
+			out.WriteString(rest)
+			break
+		}
+		if rest[open] == '}' {
+			return "", fmt.Errorf("unexpected '}' in template %q", tmpl)
+		}
+
+		out.WriteString(rest[:open])
+		rest = rest[open+1:]
+
+		end := strings.IndexAny(rest, "{}")
+		if end < 0 || rest[end] != '}' {
+			return "", fmt.Errorf("unclosed '{' in template %q", tmpl)
+		}
+
+		name := rest[:end]
+		render, ok := tokens[name]
+		if !ok {
+			return "", fmt.Errorf("unknown token {%s} in template %q", name, tmpl)
+		}
+		out.WriteString(render(blob))
+		rest = rest[end+1:]
+	}
+
+	folder := filepath.FromSlash(path.Clean(out.String()))
+	if folder == "." || !filepath.IsLocal(folder) {
+		return "", fmt.Errorf("template %q must render to a relative folder path", tmpl)
+	}
+
+	return folder, nil
+}
+
+// Validate checks tmpl by rendering it against a sample blob.
+func Validate(tmpl string) error {
+	if strings.TrimSpace(tmpl) == "" {
+		return fmt.Errorf("template must not be empty")
+	}
+
+	_, err := Render(tmpl, Blob{
+		Name:         "sample/dir/file.txt",
+		Path:         "sample/dir/file.txt",
+		LastModified: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC),
+	})
+	return err
+}
+
+// Extension returns the lower-cased file extension of blobPath without the
+// leading dot, or NoExtension when it has none.
+func Extension(blobPath string) string {
+	ext := strings.ToLower(strings.TrimPrefix(path.Ext(blobPath), "."))
+	if ext == "" {
+		return NoExtension
+	}
+	return ext
+}
+
+// nameHash returns the hex SHA-256 digest of a blob name.
+func nameHash(name string) string {
+	hash := sha256.Sum256([]byte(name))
+	return hex.EncodeToString(hash[:])
+}
+
+// firstSegment returns the first path segment of a blob name, or NoPrefix
+// when the blob is at the container root.
+func firstSegment(name string) string {
+	segment, _, found := strings.Cut(strings.TrimPrefix(name, "/"), "/")
+	if !found || segment == "" {
+		return NoPrefix
+	}
+	return segment
+}
@@ -0,0 +1,88 @@
+package pathtemplate
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRender(t *testing.T) {
+	blob := Blob{
+		Name:         "sensors/2023/reading.JSON",
+		Path:         "sensors/2023/reading.JSON",
+		LastModified: time.Date(2023, 2, 5, 23, 30, 0, 0, time.UTC),
+	}
+
+	tests := []struct {
+		tmpl string
+		want string
+	}{
+		{"{year}/{month}/{day}", filepath.Join("2023", "02", "05")},
+		{"{ext}", "json"},
+		{"{prefix1}/{year}", filepath.Join("sensors", "2023")},
+		{"archive/{year}-{month}", filepath.Join("archive", "2023-02")},
+	}
+
+	for _, tt := range tests {
+		got, err := Render(tt.tmpl, blob)
+		if err != nil {
+			t.Errorf("Render(%q) unexpected error: %v", tt.tmpl, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Render(%q) = %s, want %s", tt.tmpl, got, tt.want)
+		}
+	}
+}
+
+func TestRender_Hash(t *testing.T) {
+	blob := Blob{Name: "file.txt", Path: "file.txt"}
+
+	hash2, err := Render("{hash2}", blob)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	hash4, err := Render("{hash4}", blob)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	if len(hash2) != 2 || len(hash4) != 4 || hash4[:2] != hash2 {
+		t.Errorf("unexpected hashes: hash2=%s hash4=%s", hash2, hash4)
+	}
+}
+
+func TestRender_NoExtensionOrPrefix(t *testing.T) {
+	got, err := Render("{prefix1}/{ext}", Blob{Name: "README", Path: "README"})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	want := filepath.Join(NoPrefix, NoExtension)
+	if got != want {
+		t.Errorf("Render = %s, want %s", got, want)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		tmpl    string
+		wantErr bool
+	}{
+		{"{year}/{ext}/{hash2}", false},
+		{"{prefix1}/{hash4}", false},
+		{"", true},
+		{"{week}", true},
+		{"{year", true},
+		{"year}", true},
+		{"../{year}", true},
+		{"/{year}", true},
+	}
+
+	for _, tt := range tests {
+		err := Validate(tt.tmpl)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%q) error = %v, wantErr %v", tt.tmpl, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+// Package sdnotify implements the systemd service notification protocol,
+// so that getblobz can run as a Type=notify service with a watchdog.
+//
+// Notifications are only sent on Linux when systemd has set NOTIFY_SOCKET;
+// everywhere else every call is a no-op.
+package sdnotify
+
+import "time"
+
+// Notification states understood by systemd.
+const (
+	Ready    = "READY=1"
+	Watchdog = "WATCHDOG=1"
+	Stopping = "STOPPING=1"
+)
+
+// RunWatchdog sends Watchdog notifications at half the interval systemd
+// expects until stop is closed. It returns at once when no watchdog is
+// configured.
+func RunWatchdog(stop <-chan struct{}, onError func(error)) {
+	interval := WatchdogInterval()
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := Notify(Watchdog); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
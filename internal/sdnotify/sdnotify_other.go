@@ -0,0 +1,16 @@
+//go:build !linux
+
+// Package sdnotify provides no-op notifications on systems without systemd.
+package sdnotify
+
+import "time"
+
+// Notify does nothing outside Linux.
+func Notify(state string) error {
+	return nil
+}
+
+// WatchdogInterval always returns 0 outside Linux.
+func WatchdogInterval() time.Duration {
+	return 0
+}
@@ -3,21 +3,46 @@ package storage
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"strings"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
 // DB wraps sql.DB with application-specific operations.
+//
+// Blob state, sync runs, and errors are recorded per container. A DB returned
+// by Open is unscoped: its queries cover every container, and writes use the
+// container recorded on each blob state. WithContainer returns a DB scoped to
+// one container.
 type DB struct {
-	db *sql.DB
+	db        *sql.DB
+	container string
 }
 
+// DefaultBusyTimeout is how long a connection waits for another connection's
+// lock to clear before failing with SQLITE_BUSY.
+const DefaultBusyTimeout = 5 * time.Second
+
+// ErrDatabaseNotFound is returned when opening an existing database at a path
+// that has none.
+var ErrDatabaseNotFound = errors.New("state database not found")
+
 // Open creates or opens an SQLite database at the specified path.
 // It initializes the schema if needed and configures performance settings.
 func Open(dbPath string) (*DB, error) {
-	db, err := sql.Open("sqlite3", dbPath)
+	return OpenWithBusyTimeout(dbPath, DefaultBusyTimeout)
+}
+
+// OpenWithBusyTimeout is like Open, but waits up to busyTimeout for locks
+// held by other connections, such as a concurrent sync, instead of failing.
+func OpenWithBusyTimeout(dbPath string, busyTimeout time.Duration) (*DB, error) {
+	db, err := sql.Open("sqlite3", DSN(dbPath, busyTimeout))
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -35,12 +60,105 @@ func Open(dbPath string) (*DB, error) {
 	return d, nil
 }
 
+// OpenExisting is like OpenWithBusyTimeout, but fails with an error wrapping
+// ErrDatabaseNotFound instead of creating a database that does not exist.
+func OpenExisting(dbPath string, busyTimeout time.Duration) (*DB, error) {
+	if err := checkExists(dbPath); err != nil {
+		return nil, err
+	}
+	return OpenWithBusyTimeout(dbPath, busyTimeout)
+}
+
+// OpenReadOnly opens an existing database for reading only. It neither
+// creates the file, changes its journal mode, nor migrates its schema, so
+// the schema must already be at the supported version.
+func OpenReadOnly(dbPath string, busyTimeout time.Duration) (*DB, error) {
+	if err := checkExists(dbPath); err != nil {
+		return nil, err
+	}
+
+	uri := dbPath
+	if !strings.HasPrefix(uri, "file:") {
+		uri = "file:" + uri
+	}
+	// DSN always adds a parameter, so mode can follow with "&".
+	db, err := sql.Open("sqlite3", DSN(uri, busyTimeout)+"&mode=ro")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	if err := db.Ping(); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	d := &DB{db: db}
+	version, err := d.schemaVersion()
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	if version < len(migrations) {
+		_ = db.Close()
+		return nil, fmt.Errorf("state database schema version %d is older than supported version %d; run sync to migrate it", version, len(migrations))
+	}
+	if version > len(migrations) {
+		_ = db.Close()
+		return nil, fmt.Errorf("state database schema version %d is newer than supported version %d", version, len(migrations))
+	}
+
+	return d, nil
+}
+
+// checkExists returns an error wrapping ErrDatabaseNotFound if there is no
+// file at dbPath. Any query parameters or "file:" prefix are ignored.
+func checkExists(dbPath string) error {
+	path := strings.TrimPrefix(dbPath, "file:")
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+	if _, err := os.Stat(path); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("%w: %s", ErrDatabaseNotFound, path)
+		}
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	return nil
+}
+
+// WithContainer returns a view of the database scoped to one container. It
+// shares the underlying connection, so only the DB returned by Open should be
+// closed.
+func (d *DB) WithContainer(containerName string) *DB {
+	return &DB{db: d.db, container: containerName}
+}
+
+// Container returns the container the DB is scoped to, or "" if unscoped.
+func (d *DB) Container() string {
+	return d.container
+}
+
+// containerFilter restricts a query to the scoped container; it takes the
+// container name twice and matches every container when it is empty.
+const containerFilter = `(? = '' OR container_name = ?)`
+
 // Close closes the database connection.
 func (d *DB) Close() error {
 	return d.db.Close()
 }
 
-// initialize creates the database schema and sets performance pragmas.
+// DSN returns the data source name for the database at dbPath. The busy
+// timeout is set in the DSN rather than by PRAGMA so that it applies to every
+// pooled connection, not just the first.
+func DSN(dbPath string, busyTimeout time.Duration) string {
+	sep := "?"
+	if strings.Contains(dbPath, "?") {
+		sep = "&"
+	}
+	return fmt.Sprintf("%s%s_busy_timeout=%d", dbPath, sep, busyTimeout.Milliseconds())
+}
+
+// initialize sets performance pragmas and migrates the schema to the latest version.
 func (d *DB) initialize() error {
 	pragmas := []string{
 		"PRAGMA journal_mode=WAL",
@@ -55,95 +173,14 @@ func (d *DB) initialize() error {
 		}
 	}
 
-	schema := `
-	CREATE TABLE IF NOT EXISTS sync_runs (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		started_at DATETIME NOT NULL,
-		completed_at DATETIME,
-		status TEXT NOT NULL,
-		total_files INTEGER DEFAULT 0,
-		downloaded_files INTEGER DEFAULT 0,
-		failed_files INTEGER DEFAULT 0,
-		total_bytes INTEGER DEFAULT 0,
-		error_message TEXT
-	);
-
-	CREATE TABLE IF NOT EXISTS blob_state (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		blob_name TEXT NOT NULL UNIQUE,
-		blob_path TEXT NOT NULL,
-		local_path TEXT NOT NULL,
-		size_bytes INTEGER NOT NULL,
-		content_md5 TEXT,
-		last_modified DATETIME NOT NULL,
-		etag TEXT NOT NULL,
-		first_seen_at DATETIME NOT NULL,
-		last_synced_at DATETIME,
-		sync_run_id INTEGER,
-		status TEXT NOT NULL,
-		error_message TEXT,
-		FOREIGN KEY (sync_run_id) REFERENCES sync_runs(id)
-	);
-
-	CREATE INDEX IF NOT EXISTS idx_blob_name ON blob_state(blob_name);
-	CREATE INDEX IF NOT EXISTS idx_status ON blob_state(status);
-	CREATE INDEX IF NOT EXISTS idx_last_synced ON blob_state(last_synced_at);
-	CREATE INDEX IF NOT EXISTS idx_etag_modified ON blob_state(etag, last_modified);
-
-	CREATE TABLE IF NOT EXISTS sync_checkpoint (
-		id INTEGER PRIMARY KEY CHECK (id = 1),
-		container_name TEXT NOT NULL,
-		last_check_time DATETIME NOT NULL,
-		last_continuation_token TEXT,
-		total_blobs_tracked INTEGER DEFAULT 0
-	);
-
-	CREATE TABLE IF NOT EXISTS performance_metrics (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		sync_run_id INTEGER NOT NULL,
-		timestamp DATETIME NOT NULL,
-		cpu_percent REAL,
-		memory_mb INTEGER,
-		network_mbps REAL,
-		disk_io_mbps REAL,
-		active_workers INTEGER,
-		download_rate_files_per_sec REAL,
-		download_rate_mbps REAL,
-		throttled BOOLEAN DEFAULT 0,
-		FOREIGN KEY (sync_run_id) REFERENCES sync_runs(id)
-	);
-
-	CREATE INDEX IF NOT EXISTS idx_perf_sync_run ON performance_metrics(sync_run_id);
-	CREATE INDEX IF NOT EXISTS idx_perf_timestamp ON performance_metrics(timestamp);
-
-	CREATE TABLE IF NOT EXISTS error_log (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		sync_run_id INTEGER,
-		timestamp DATETIME NOT NULL,
-		blob_name TEXT NOT NULL,
-		error_type TEXT NOT NULL,
-		error_message TEXT NOT NULL,
-		retry_count INTEGER DEFAULT 0,
-		resolved BOOLEAN DEFAULT 0,
-		FOREIGN KEY (sync_run_id) REFERENCES sync_runs(id)
-	);
-
-	CREATE INDEX IF NOT EXISTS idx_error_timestamp ON error_log(timestamp);
-	CREATE INDEX IF NOT EXISTS idx_error_resolved ON error_log(resolved);
-	`
-
-	if _, err := d.db.Exec(schema); err != nil {
-		return fmt.Errorf("failed to create schema: %w", err)
-	}
-
-	return nil
+	return d.migrate()
 }
 
 // CreateSyncRun creates a new sync run record and returns its ID.
 func (d *DB) CreateSyncRun() (int64, error) {
 	result, err := d.db.Exec(
-		"INSERT INTO sync_runs (started_at, status) VALUES (?, ?)",
-		time.Now(), SyncStatusRunning,
+		"INSERT INTO sync_runs (started_at, status, container_name) VALUES (?, ?, ?)",
+		time.Now(), SyncStatusRunning, d.container,
 	)
 	if err != nil {
 		return 0, fmt.Errorf("failed to create sync run: %w", err)
@@ -157,10 +194,12 @@ func (d *DB) UpdateSyncRun(run *SyncRun) error {
 	_, err := d.db.Exec(`
 		UPDATE sync_runs 
 		SET completed_at = ?, status = ?, total_files = ?, 
-		    downloaded_files = ?, failed_files = ?, total_bytes = ?, error_message = ?
+		    downloaded_files = ?, failed_files = ?, total_bytes = ?, error_message = ?,
+		    avg_mbps = ?, avg_files_per_sec = ?
 		WHERE id = ?`,
 		run.CompletedAt, run.Status, run.TotalFiles,
 		run.DownloadedFiles, run.FailedFiles, run.TotalBytes, run.ErrorMessage,
+		run.AvgMbps, run.AvgFilesPerSec,
 		run.ID,
 	)
 	return err
@@ -170,13 +209,15 @@ func (d *DB) UpdateSyncRun(run *SyncRun) error {
 func (d *DB) GetSyncRun(id int64) (*SyncRun, error) {
 	run := &SyncRun{}
 	err := d.db.QueryRow(`
-		SELECT id, started_at, completed_at, status, total_files, 
-		       downloaded_files, failed_files, total_bytes, error_message
+		SELECT id, container_name, started_at, completed_at, status, total_files, 
+		       downloaded_files, failed_files, total_bytes, error_message,
+		       avg_mbps, avg_files_per_sec
 		FROM sync_runs WHERE id = ?`, id,
 	).Scan(
-		&run.ID, &run.StartedAt, &run.CompletedAt, &run.Status,
+		&run.ID, &run.ContainerName, &run.StartedAt, &run.CompletedAt, &run.Status,
 		&run.TotalFiles, &run.DownloadedFiles, &run.FailedFiles,
 		&run.TotalBytes, &run.ErrorMessage,
+		&run.AvgMbps, &run.AvgFilesPerSec,
 	)
 	if err != nil {
 		return nil, err
@@ -184,14 +225,31 @@ func (d *DB) GetSyncRun(id int64) (*SyncRun, error) {
 	return run, nil
 }
 
-// UpsertBlobState inserts or updates a blob state record.
-func (d *DB) UpsertBlobState(blob *BlobState) error {
-	_, err := d.db.Exec(`
+// GetLatestSyncRun returns the most recent sync run with the given status,
+// or nil if there is none.
+func (d *DB) GetLatestSyncRun(status string) (*SyncRun, error) {
+	var id int64
+	err := d.db.QueryRow(
+		"SELECT id FROM sync_runs WHERE status = ? AND "+containerFilter+" ORDER BY id DESC LIMIT 1",
+		status, d.container, d.container,
+	).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return d.GetSyncRun(id)
+}
+
+// upsertBlobStateQuery inserts a blob state or updates the existing record.
+const upsertBlobStateQuery = `
 		INSERT INTO blob_state 
-		(blob_name, blob_path, local_path, size_bytes, content_md5, last_modified, 
-		 etag, first_seen_at, last_synced_at, sync_run_id, status, error_message)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-		ON CONFLICT(blob_name) DO UPDATE SET
+		(container_name, blob_name, blob_path, local_path, size_bytes, content_md5, last_modified, 
+		 etag, first_seen_at, last_synced_at, sync_run_id, status, error_message, content_type, linked_from,
+		 version_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(container_name, blob_name) DO UPDATE SET
 		blob_path = excluded.blob_path,
 		local_path = excluded.local_path,
 		size_bytes = excluded.size_bytes,
@@ -201,26 +259,77 @@ func (d *DB) UpsertBlobState(blob *BlobState) error {
 		last_synced_at = excluded.last_synced_at,
 		sync_run_id = excluded.sync_run_id,
 		status = excluded.status,
-		error_message = excluded.error_message`,
-		blob.BlobName, blob.BlobPath, blob.LocalPath, blob.SizeBytes, blob.ContentMD5,
-		blob.LastModified, blob.ETag, blob.FirstSeenAt, blob.LastSyncedAt,
-		blob.SyncRunID, blob.Status, blob.ErrorMessage,
-	)
+		error_message = excluded.error_message,
+		content_type = excluded.content_type,
+		linked_from = excluded.linked_from,
+		version_id = excluded.version_id`
+
+// UpsertBlobState inserts or updates a blob state record.
+func (d *DB) UpsertBlobState(blob *BlobState) error {
+	_, err := d.db.Exec(upsertBlobStateQuery, d.blobStateArgs(blob)...)
 	return err
 }
 
+// BatchUpsertBlobState inserts or updates blob state records in a single
+// transaction, which is much faster than individual upserts for large batches.
+// Either all records are written or none are.
+func (d *DB) BatchUpsertBlobState(blobs []*BlobState) error {
+	if len(blobs) == 0 {
+		return nil
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	stmt, err := tx.Prepare(upsertBlobStateQuery)
+	if err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("failed to prepare upsert: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	for _, blob := range blobs {
+		if _, err := stmt.Exec(d.blobStateArgs(blob)...); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("failed to upsert blob state %s: %w", blob.BlobName, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// blobStateArgs returns the upsertBlobStateQuery arguments for blob. The
+// blob's own container is used when set, otherwise the DB's scope.
+func (d *DB) blobStateArgs(blob *BlobState) []interface{} {
+	containerName := blob.ContainerName
+	if containerName == "" {
+		containerName = d.container
+	}
+	return []interface{}{
+		containerName, blob.BlobName, blob.BlobPath, blob.LocalPath, blob.SizeBytes, blob.ContentMD5,
+		blob.LastModified, blob.ETag, blob.FirstSeenAt, blob.LastSyncedAt,
+		blob.SyncRunID, blob.Status, blob.ErrorMessage, blob.ContentType, blob.LinkedFrom,
+		blob.VersionID,
+	}
+}
+
 // GetBlobState retrieves a blob state by blob name.
 func (d *DB) GetBlobState(blobName string) (*BlobState, error) {
 	blob := &BlobState{}
 	err := d.db.QueryRow(`
-		SELECT id, blob_name, blob_path, local_path, size_bytes, content_md5, 
-		       last_modified, etag, first_seen_at, last_synced_at, sync_run_id, 
-		       status, error_message
-		FROM blob_state WHERE blob_name = ?`, blobName,
+		SELECT `+blobStateColumns+`
+		FROM blob_state WHERE blob_name = ? AND `+containerFilter+`
+		ORDER BY id LIMIT 1`, blobName, d.container, d.container,
 	).Scan(
-		&blob.ID, &blob.BlobName, &blob.BlobPath, &blob.LocalPath, &blob.SizeBytes,
+		&blob.ID, &blob.ContainerName, &blob.BlobName, &blob.BlobPath, &blob.LocalPath, &blob.SizeBytes,
 		&blob.ContentMD5, &blob.LastModified, &blob.ETag, &blob.FirstSeenAt,
-		&blob.LastSyncedAt, &blob.SyncRunID, &blob.Status, &blob.ErrorMessage,
+		&blob.LastSyncedAt, &blob.SyncRunID, &blob.Status, &blob.ErrorMessage, &blob.ContentType,
+		&blob.LinkedFrom, &blob.VersionID,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -231,26 +340,244 @@ func (d *DB) GetBlobState(blobName string) (*BlobState, error) {
 	return blob, nil
 }
 
+// blobStateColumns is the column list scanned by scanBlobStates.
+const blobStateColumns = `id, container_name, blob_name, blob_path, local_path, size_bytes, content_md5, 
+		       last_modified, etag, first_seen_at, last_synced_at, sync_run_id, 
+		       status, error_message, content_type, linked_from, version_id`
+
 // GetPendingBlobs returns all blobs with pending status.
 func (d *DB) GetPendingBlobs() ([]*BlobState, error) {
 	rows, err := d.db.Query(`
-		SELECT id, blob_name, blob_path, local_path, size_bytes, content_md5, 
-		       last_modified, etag, first_seen_at, last_synced_at, sync_run_id, 
-		       status, error_message
-		FROM blob_state WHERE status = ?`, BlobStatusPending,
+		SELECT `+blobStateColumns+`
+		FROM blob_state WHERE status = ? AND `+containerFilter, BlobStatusPending, d.container, d.container,
 	)
 	if err != nil {
 		return nil, err
 	}
+	return scanBlobStates(rows)
+}
+
+// GetPendingBlobsPage returns up to limit pending blobs with an ID greater
+// than afterID, ordered by ID. Pass the last ID of the previous page to
+// continue; rows that stop being pending in the meantime are not revisited.
+func (d *DB) GetPendingBlobsPage(afterID int64, limit int) ([]*BlobState, error) {
+	rows, err := d.db.Query(`
+		SELECT `+blobStateColumns+`
+		FROM blob_state WHERE status = ? AND id > ? AND `+containerFilter+`
+		ORDER BY id LIMIT ?`, BlobStatusPending, afterID, d.container, d.container, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanBlobStates(rows)
+}
+
+// FindSyncedByMD5 returns the earliest blob with a good local copy, other
+// than blobName, whose Content-MD5 is md5, or nil if there is none.
+func (d *DB) FindSyncedByMD5(md5, blobName string) (*BlobState, error) {
+	rows, err := d.db.Query(`
+		SELECT `+blobStateColumns+`
+		FROM blob_state WHERE content_md5 = ? AND status IN (?, ?) AND blob_name != ? AND `+containerFilter+`
+		ORDER BY id LIMIT 1`,
+		md5, BlobStatusDownloaded, BlobStatusSkipped, blobName, d.container, d.container,
+	)
+	if err != nil {
+		return nil, err
+	}
+	blobs, err := scanBlobStates(rows)
+	if err != nil || len(blobs) == 0 {
+		return nil, err
+	}
+	return blobs[0], nil
+}
+
+// Orders in which GetPendingBlobsOrdered returns pending blobs.
+const (
+	DownloadOrderListed        = "listed"
+	DownloadOrderSmallestFirst = "smallest_first"
+	DownloadOrderLargestFirst  = "largest_first"
+	DownloadOrderRandom        = "random"
+)
+
+// randomOrderModulus is the prime the random order's multiplicative hash
+// works modulo. Any seed in [1, randomOrderModulus) permutes the IDs.
+const randomOrderModulus = math.MaxInt32
+
+// pendingSortKey returns the SQL expression GetPendingBlobsOrdered sorts by,
+// ahead of the ID, for order.
+func pendingSortKey(order string, seed int64) (string, error) {
+	switch order {
+	case "", DownloadOrderListed:
+		return "id", nil
+	case DownloadOrderSmallestFirst:
+		return "size_bytes", nil
+	case DownloadOrderLargestFirst:
+		return "-size_bytes", nil
+	case DownloadOrderRandom:
+		return fmt.Sprintf("(id * %d) %% %d", seed, randomOrderModulus), nil
+	}
+	return "", fmt.Errorf("unknown download order %q", order)
+}
+
+// pendingSortValue computes the sort key of pendingSortKey for blob in Go.
+func pendingSortValue(order string, seed int64, blob *BlobState) int64 {
+	switch order {
+	case DownloadOrderSmallestFirst:
+		return blob.SizeBytes
+	case DownloadOrderLargestFirst:
+		return -blob.SizeBytes
+	case DownloadOrderRandom:
+		return blob.ID * seed % randomOrderModulus
+	}
+	return blob.ID
+}
+
+// GetPendingBlobsOrdered returns up to limit pending blobs in order, which
+// is one of the DownloadOrder constants. Pass the last blob of the previous
+// page as after to continue, or nil to start; rows that stop being pending
+// in the meantime are not revisited. The random order is a permutation
+// fixed by seed, which must be in [1, math.MaxInt32).
+func (d *DB) GetPendingBlobsOrdered(order string, seed int64, after *BlobState, limit int) ([]*BlobState, error) {
+	key, err := pendingSortKey(order, seed)
+	if err != nil {
+		return nil, err
+	}
+
+	afterKey, afterID := int64(math.MinInt64), int64(0)
+	if after != nil {
+		afterKey, afterID = pendingSortValue(order, seed, after), after.ID
+	}
+
+	rows, err := d.db.Query(`
+		SELECT `+blobStateColumns+`
+		FROM blob_state WHERE status = ? AND `+containerFilter+`
+		AND (`+key+` > ? OR (`+key+` = ? AND id > ?))
+		ORDER BY `+key+`, id LIMIT ?`,
+		BlobStatusPending, d.container, d.container, afterKey, afterKey, afterID, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanBlobStates(rows)
+}
+
+// GetSyncedBlobsPage returns up to limit blobs with a good local copy and an
+// ID greater than afterID, ordered by ID. If syncRunID is not zero, only
+// blobs downloaded by that run are returned.
+func (d *DB) GetSyncedBlobsPage(syncRunID, afterID int64, limit int) ([]*BlobState, error) {
+	rows, err := d.db.Query(`
+		SELECT `+blobStateColumns+`
+		FROM blob_state WHERE status IN (?, ?) AND (? = 0 OR sync_run_id = ?) AND id > ? AND `+containerFilter+`
+		ORDER BY id LIMIT ?`,
+		BlobStatusDownloaded, BlobStatusSkipped, syncRunID, syncRunID, afterID, d.container, d.container, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanBlobStates(rows)
+}
+
+// GetBlobStatesPage returns up to limit blobs with an ID greater than
+// afterID, ordered by ID. If status is not empty, only blobs with that
+// status are returned.
+func (d *DB) GetBlobStatesPage(status string, afterID int64, limit int) ([]*BlobState, error) {
+	rows, err := d.db.Query(`
+		SELECT `+blobStateColumns+`
+		FROM blob_state WHERE (? = '' OR status = ?) AND id > ? AND `+containerFilter+`
+		ORDER BY id LIMIT ?`,
+		status, status, afterID, d.container, d.container, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanBlobStates(rows)
+}
+
+// CountBlobsByStatus returns the number of blobs with the given status.
+func (d *DB) CountBlobsByStatus(status string) (int64, error) {
+	var count int64
+	err := d.db.QueryRow(
+		"SELECT COUNT(*) FROM blob_state WHERE status = ? AND "+containerFilter,
+		status, d.container, d.container,
+	).Scan(&count)
+	return count, err
+}
+
+// GetBlobStatesByStatus returns all blobs with the given status.
+func (d *DB) GetBlobStatesByStatus(status string) ([]*BlobState, error) {
+	rows, err := d.db.Query(`
+		SELECT `+blobStateColumns+`
+		FROM blob_state WHERE status = ? AND `+containerFilter, status, d.container, d.container,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanBlobStates(rows)
+}
+
+// GetBlobStatesWithPrefix returns all blobs whose name starts with prefix.
+func (d *DB) GetBlobStatesWithPrefix(prefix string) ([]*BlobState, error) {
+	rows, err := d.db.Query(`
+		SELECT `+blobStateColumns+`
+		FROM blob_state WHERE substr(blob_name, 1, length(?)) = ? AND `+containerFilter,
+		prefix, prefix, d.container, d.container,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanBlobStates(rows)
+}
+
+// latestErrorTypeFilter restricts failed blobs to those whose most recent
+// error_log entry has the given error type. An empty type matches all.
+const latestErrorTypeFilter = `(? = '' OR blob_name IN (
+			SELECT blob_name FROM error_log
+			WHERE id IN (SELECT MAX(id) FROM error_log GROUP BY container_name, blob_name)
+			AND container_name = blob_state.container_name
+			AND error_type = ?))`
+
+// GetFailedBlobs returns all blobs with failed status. If errorType is not
+// empty, only blobs whose most recent error has that type are returned.
+func (d *DB) GetFailedBlobs(errorType string) ([]*BlobState, error) {
+	rows, err := d.db.Query(`
+		SELECT `+blobStateColumns+`
+		FROM blob_state WHERE status = ? AND `+containerFilter+` AND `+latestErrorTypeFilter,
+		BlobStatusFailed, d.container, d.container, errorType, errorType,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanBlobStates(rows)
+}
+
+// ResetFailedToPending marks failed blobs as pending so the next download
+// phase retries them, and returns the number of blobs reset. If errorType is
+// not empty, only blobs whose most recent error has that type are reset.
+func (d *DB) ResetFailedToPending(errorType string) (int64, error) {
+	result, err := d.db.Exec(`
+		UPDATE blob_state SET status = ?, error_message = NULL
+		WHERE status = ? AND `+containerFilter+` AND `+latestErrorTypeFilter,
+		BlobStatusPending, BlobStatusFailed, d.container, d.container, errorType, errorType,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// scanBlobStates reads blob states from rows selected with blobStateColumns
+// and closes rows.
+func scanBlobStates(rows *sql.Rows) ([]*BlobState, error) {
 	defer func() { _ = rows.Close() }()
 
 	var blobs []*BlobState
 	for rows.Next() {
 		blob := &BlobState{}
 		if err := rows.Scan(
-			&blob.ID, &blob.BlobName, &blob.BlobPath, &blob.LocalPath, &blob.SizeBytes,
+			&blob.ID, &blob.ContainerName, &blob.BlobName, &blob.BlobPath, &blob.LocalPath, &blob.SizeBytes,
 			&blob.ContentMD5, &blob.LastModified, &blob.ETag, &blob.FirstSeenAt,
-			&blob.LastSyncedAt, &blob.SyncRunID, &blob.Status, &blob.ErrorMessage,
+			&blob.LastSyncedAt, &blob.SyncRunID, &blob.Status, &blob.ErrorMessage, &blob.ContentType,
+			&blob.LinkedFrom, &blob.VersionID,
 		); err != nil {
 			return nil, err
 		}
@@ -260,16 +587,103 @@ func (d *DB) GetPendingBlobs() ([]*BlobState, error) {
 	return blobs, rows.Err()
 }
 
+// GetTrackedLocalPaths returns the set of local paths recorded for all blobs.
+func (d *DB) GetTrackedLocalPaths() (map[string]struct{}, error) {
+	rows, err := d.db.Query("SELECT local_path FROM blob_state WHERE "+containerFilter, d.container, d.container)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	paths := make(map[string]struct{})
+	for rows.Next() {
+		var localPath string
+		if err := rows.Scan(&localPath); err != nil {
+			return nil, err
+		}
+		paths[localPath] = struct{}{}
+	}
+
+	return paths, rows.Err()
+}
+
+// IsTrackedLocalPath reports whether any blob is recorded at localPath.
+func (d *DB) IsTrackedLocalPath(localPath string) (bool, error) {
+	var tracked bool
+	err := d.db.QueryRow(
+		"SELECT EXISTS(SELECT 1 FROM blob_state WHERE local_path = ? AND "+containerFilter+")",
+		localPath, d.container, d.container,
+	).Scan(&tracked)
+	return tracked, err
+}
+
+// DeleteBlobState removes a blob state record.
+func (d *DB) DeleteBlobState(blobName string) error {
+	_, err := d.db.Exec(
+		"DELETE FROM blob_state WHERE blob_name = ? AND "+containerFilter,
+		blobName, d.container, d.container,
+	)
+	return err
+}
+
+// UpsertUploadState inserts or updates an upload state record for the DB's
+// container.
+func (d *DB) UpsertUploadState(upload *UploadState) error {
+	_, err := d.db.Exec(`
+		INSERT INTO upload_state 
+		(container_name, blob_name, local_path, size_bytes, content_md5, uploaded_at, status, error_message)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(container_name, blob_name) DO UPDATE SET
+		local_path = excluded.local_path,
+		size_bytes = excluded.size_bytes,
+		content_md5 = excluded.content_md5,
+		uploaded_at = excluded.uploaded_at,
+		status = excluded.status,
+		error_message = excluded.error_message`,
+		d.container, upload.BlobName, upload.LocalPath, upload.SizeBytes, upload.ContentMD5,
+		upload.UploadedAt, upload.Status, upload.ErrorMessage,
+	)
+	return err
+}
+
 // RecordError logs an error to the error_log table.
 func (d *DB) RecordError(syncRunID *int64, blobName, errorType, errorMessage string, retryCount int) error {
 	_, err := d.db.Exec(`
-		INSERT INTO error_log (sync_run_id, timestamp, blob_name, error_type, error_message, retry_count)
-		VALUES (?, ?, ?, ?, ?, ?)`,
-		syncRunID, time.Now(), blobName, errorType, errorMessage, retryCount,
+		INSERT INTO error_log (sync_run_id, container_name, timestamp, blob_name, error_type, error_message, retry_count)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		syncRunID, d.container, time.Now(), blobName, errorType, errorMessage, retryCount,
 	)
 	return err
 }
 
+// GetRunErrors returns the most recent error recorded for each blob that
+// failed during a sync run, newest first, up to limit entries.
+func (d *DB) GetRunErrors(syncRunID int64, limit int) ([]*ErrorLog, error) {
+	rows, err := d.db.Query(`
+		SELECT id, sync_run_id, timestamp, blob_name, error_type, error_message, retry_count, resolved
+		FROM error_log
+		WHERE id IN (SELECT MAX(id) FROM error_log WHERE sync_run_id = ? GROUP BY blob_name)
+		ORDER BY id DESC
+		LIMIT ?`,
+		syncRunID, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var errs []*ErrorLog
+	for rows.Next() {
+		e := &ErrorLog{}
+		if err := rows.Scan(&e.ID, &e.SyncRunID, &e.Timestamp, &e.BlobName, &e.ErrorType,
+			&e.ErrorMessage, &e.RetryCount, &e.Resolved); err != nil {
+			return nil, err
+		}
+		errs = append(errs, e)
+	}
+	return errs, rows.Err()
+}
+
 // RecordMetric records a performance metric snapshot.
 func (d *DB) RecordMetric(metric *PerformanceMetric) error {
 	_, err := d.db.Exec(`
@@ -284,27 +698,47 @@ func (d *DB) RecordMetric(metric *PerformanceMetric) error {
 	return err
 }
 
-// UpdateCheckpoint updates or creates the sync checkpoint.
-func (d *DB) UpdateCheckpoint(containerName string, continuationToken *string) error {
+// UpdateCheckpoint updates or creates the sync checkpoint for a container.
+// checkTime is the time the recorded discovery started.
+func (d *DB) UpdateCheckpoint(containerName string, checkTime time.Time, continuationToken *string) error {
 	_, err := d.db.Exec(`
-		INSERT INTO sync_checkpoint (id, container_name, last_check_time, last_continuation_token)
-		VALUES (1, ?, ?, ?)
-		ON CONFLICT(id) DO UPDATE SET
-		container_name = excluded.container_name,
+		INSERT INTO sync_checkpoint (container_name, last_check_time, last_continuation_token)
+		VALUES (?, ?, ?)
+		ON CONFLICT(container_name) DO UPDATE SET
 		last_check_time = excluded.last_check_time,
 		last_continuation_token = excluded.last_continuation_token`,
-		containerName, time.Now(), continuationToken,
+		containerName, checkTime, continuationToken,
+	)
+	return err
+}
+
+// SaveDiscoveryProgress records how far a discovery of a container that
+// started at startedAt has listed under prefix. token is the marker of the
+// next page, or nil once the listing is complete. The time of the last
+// completed check is left alone; a container without one gets the zero time.
+func (d *DB) SaveDiscoveryProgress(containerName, prefix string, startedAt time.Time, token *string) error {
+	_, err := d.db.Exec(`
+		INSERT INTO sync_checkpoint (container_name, last_check_time, last_continuation_token, discovery_prefix, discovery_started_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(container_name) DO UPDATE SET
+		last_continuation_token = excluded.last_continuation_token,
+		discovery_prefix = excluded.discovery_prefix,
+		discovery_started_at = excluded.discovery_started_at`,
+		containerName, time.Time{}, token, prefix, startedAt,
 	)
 	return err
 }
 
-// GetCheckpoint retrieves the current sync checkpoint.
-func (d *DB) GetCheckpoint() (*SyncCheckpoint, error) {
+// GetCheckpoint retrieves the sync checkpoint for a container, or nil if
+// the container has none.
+func (d *DB) GetCheckpoint(containerName string) (*SyncCheckpoint, error) {
 	cp := &SyncCheckpoint{}
 	err := d.db.QueryRow(`
-		SELECT id, container_name, last_check_time, last_continuation_token, total_blobs_tracked
-		FROM sync_checkpoint WHERE id = 1`,
-	).Scan(&cp.ID, &cp.ContainerName, &cp.LastCheckTime, &cp.LastContinuationToken, &cp.TotalBlobsTracked)
+		SELECT container_name, last_check_time, last_continuation_token, total_blobs_tracked,
+		       discovery_prefix, discovery_started_at
+		FROM sync_checkpoint WHERE container_name = ?`, containerName,
+	).Scan(&cp.ContainerName, &cp.LastCheckTime, &cp.LastContinuationToken, &cp.TotalBlobsTracked,
+		&cp.DiscoveryPrefix, &cp.DiscoveryStartedAt)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
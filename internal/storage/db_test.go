@@ -0,0 +1,461 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func openTestDB(t *testing.T) *DB {
+	t.Helper()
+
+	db, err := Open(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func TestResetFailedToPending_ByErrorType(t *testing.T) {
+	db := openTestDB(t)
+
+	blobErrors := map[string][]string{
+		"network.txt":  {ErrorTypeNetwork},
+		"checksum.txt": {ErrorTypeChecksum},
+		// The most recent error decides the category.
+		"mixed.txt": {ErrorTypeChecksum, ErrorTypeNetwork},
+	}
+	for name, types := range blobErrors {
+		msg := "failed"
+		if err := db.UpsertBlobState(&BlobState{
+			BlobName:     name,
+			BlobPath:     name,
+			LocalPath:    "/data/" + name,
+			LastModified: time.Now(),
+			ETag:         "etag",
+			FirstSeenAt:  time.Now(),
+			Status:       BlobStatusFailed,
+			ErrorMessage: &msg,
+		}); err != nil {
+			t.Fatalf("failed to insert blob state: %v", err)
+		}
+		for _, errorType := range types {
+			if err := db.RecordError(nil, name, errorType, msg, 0); err != nil {
+				t.Fatalf("failed to record error: %v", err)
+			}
+		}
+	}
+
+	failed, err := db.GetFailedBlobs(ErrorTypeNetwork)
+	if err != nil {
+		t.Fatalf("GetFailedBlobs failed: %v", err)
+	}
+	if len(failed) != 2 {
+		t.Errorf("expected 2 failed network blobs, got %d", len(failed))
+	}
+
+	reset, err := db.ResetFailedToPending(ErrorTypeNetwork)
+	if err != nil {
+		t.Fatalf("ResetFailedToPending failed: %v", err)
+	}
+	if reset != 2 {
+		t.Errorf("expected 2 blobs reset, got %d", reset)
+	}
+
+	state, err := db.GetBlobState("checksum.txt")
+	if err != nil {
+		t.Fatalf("GetBlobState failed: %v", err)
+	}
+	if state.Status != BlobStatusFailed {
+		t.Errorf("checksum blob status = %q, want %q", state.Status, BlobStatusFailed)
+	}
+
+	reset, err = db.ResetFailedToPending("")
+	if err != nil {
+		t.Fatalf("ResetFailedToPending failed: %v", err)
+	}
+	if reset != 1 {
+		t.Errorf("expected remaining blob reset, got %d", reset)
+	}
+
+	pending, err := db.GetPendingBlobs()
+	if err != nil {
+		t.Fatalf("GetPendingBlobs failed: %v", err)
+	}
+	if len(pending) != 3 {
+		t.Errorf("expected 3 pending blobs, got %d", len(pending))
+	}
+	for _, blob := range pending {
+		if blob.ErrorMessage != nil {
+			t.Errorf("blob %s still has an error message", blob.BlobName)
+		}
+	}
+}
+
+func newTestBlobStates(prefix string, n int) []*BlobState {
+	blobs := make([]*BlobState, n)
+	for i := range blobs {
+		name := fmt.Sprintf("%s/blob-%05d.bin", prefix, i)
+		blobs[i] = &BlobState{
+			BlobName:     name,
+			BlobPath:     name,
+			LocalPath:    "/data/" + name,
+			SizeBytes:    int64(i),
+			LastModified: time.Now(),
+			ETag:         fmt.Sprintf("etag-%d", i),
+			FirstSeenAt:  time.Now(),
+			Status:       BlobStatusPending,
+		}
+	}
+	return blobs
+}
+
+func TestBatchUpsertBlobState(t *testing.T) {
+	db := openTestDB(t)
+	const n = 2000
+
+	single := newTestBlobStates("single", n)
+	start := time.Now()
+	for _, blob := range single {
+		if err := db.UpsertBlobState(blob); err != nil {
+			t.Fatalf("UpsertBlobState failed: %v", err)
+		}
+	}
+	singleDuration := time.Since(start)
+
+	batch := newTestBlobStates("batch", n)
+	start = time.Now()
+	for i := 0; i < n; i += 1000 {
+		if err := db.BatchUpsertBlobState(batch[i : i+1000]); err != nil {
+			t.Fatalf("BatchUpsertBlobState failed: %v", err)
+		}
+	}
+	batchDuration := time.Since(start)
+
+	t.Logf("upserted %d blob states: individually in %s, batched in %s", n, singleDuration, batchDuration)
+
+	states, err := db.GetBlobStatesWithPrefix("batch/")
+	if err != nil {
+		t.Fatalf("GetBlobStatesWithPrefix failed: %v", err)
+	}
+	if len(states) != n {
+		t.Errorf("expected %d batched blob states, got %d", n, len(states))
+	}
+
+	// Upserting again updates existing records rather than duplicating them.
+	batch[0].Status = BlobStatusDownloaded
+	if err := db.BatchUpsertBlobState(batch[:1]); err != nil {
+		t.Fatalf("BatchUpsertBlobState failed: %v", err)
+	}
+	state, err := db.GetBlobState(batch[0].BlobName)
+	if err != nil {
+		t.Fatalf("GetBlobState failed: %v", err)
+	}
+	if state.Status != BlobStatusDownloaded {
+		t.Errorf("status = %q, want %q", state.Status, BlobStatusDownloaded)
+	}
+}
+
+func TestGetPendingBlobsPage(t *testing.T) {
+	db := openTestDB(t)
+
+	if err := db.BatchUpsertBlobState(newTestBlobStates("page", 25)); err != nil {
+		t.Fatalf("BatchUpsertBlobState failed: %v", err)
+	}
+
+	seen := make(map[string]bool)
+	var afterID int64
+	pages := 0
+	for {
+		page, err := db.GetPendingBlobsPage(afterID, 10)
+		if err != nil {
+			t.Fatalf("GetPendingBlobsPage failed: %v", err)
+		}
+		if len(page) == 0 {
+			break
+		}
+		pages++
+		for _, blob := range page {
+			if seen[blob.BlobName] {
+				t.Errorf("blob %s returned twice", blob.BlobName)
+			}
+			seen[blob.BlobName] = true
+		}
+		afterID = page[len(page)-1].ID
+	}
+
+	if pages != 3 || len(seen) != 25 {
+		t.Errorf("got %d blobs in %d pages, want 25 in 3", len(seen), pages)
+	}
+}
+
+func TestGetPendingBlobsOrdered(t *testing.T) {
+	db := openTestDB(t)
+
+	sizes := []int64{5, 3, 9, 3, 0, 7, 1, 8, 2, 6}
+	blobs := newTestBlobStates("order", len(sizes))
+	for i, blob := range blobs {
+		blob.SizeBytes = sizes[i]
+	}
+	blobs[2].Status = BlobStatusDownloaded
+	if err := db.BatchUpsertBlobState(blobs); err != nil {
+		t.Fatalf("BatchUpsertBlobState failed: %v", err)
+	}
+
+	// Blob indexes in the expected order; blob 2 is not pending.
+	listed := []int{0, 1, 3, 4, 5, 6, 7, 8, 9}
+	smallest := []int{4, 6, 8, 1, 3, 0, 9, 5, 7}
+	largest := []int{7, 5, 9, 0, 1, 3, 8, 6, 4}
+
+	readAll := func(order string, seed int64) []int {
+		var got []int
+		var after *BlobState
+		for {
+			page, err := db.GetPendingBlobsOrdered(order, seed, after, 3)
+			if err != nil {
+				t.Fatalf("%s: GetPendingBlobsOrdered failed: %v", order, err)
+			}
+			if len(page) == 0 {
+				return got
+			}
+			for _, blob := range page {
+				var i int
+				if _, err := fmt.Sscanf(blob.BlobName, "order/blob-%05d.bin", &i); err != nil {
+					t.Fatalf("unexpected blob %s", blob.BlobName)
+				}
+				got = append(got, i)
+			}
+			after = page[len(page)-1]
+		}
+	}
+
+	tests := []struct {
+		order string
+		want  []int
+	}{
+		{DownloadOrderListed, listed},
+		{"", listed},
+		{DownloadOrderSmallestFirst, smallest},
+		{DownloadOrderLargestFirst, largest},
+	}
+	for _, tt := range tests {
+		if got := readAll(tt.order, 0); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("%q: order = %v, want %v", tt.order, got, tt.want)
+		}
+	}
+
+	const seed = 1000000007
+	random := readAll(DownloadOrderRandom, seed)
+	if again := readAll(DownloadOrderRandom, seed); fmt.Sprint(again) != fmt.Sprint(random) {
+		t.Errorf("random: order changed between reads with the same seed: %v, then %v", random, again)
+	}
+	if fmt.Sprint(random) == fmt.Sprint(listed) {
+		t.Errorf("random: order %v is the listed order", random)
+	}
+	seen := make(map[int]bool)
+	for _, i := range random {
+		seen[i] = true
+	}
+	if len(random) != len(listed) || len(seen) != len(listed) {
+		t.Errorf("random: got %v, want each of %v once", random, listed)
+	}
+
+	if _, err := db.GetPendingBlobsOrdered("alphabetical", 0, nil, 3); err == nil {
+		t.Error("expected an error for an unknown order")
+	}
+}
+
+func TestWithContainer_ScopesBlobState(t *testing.T) {
+	db := openTestDB(t)
+	logs := db.WithContainer("logs")
+	images := db.WithContainer("images")
+
+	for _, scoped := range []*DB{logs, images} {
+		if err := scoped.UpsertBlobState(&BlobState{
+			BlobName:     "shared.txt",
+			BlobPath:     "shared.txt",
+			LocalPath:    filepath.Join("/data", scoped.Container(), "shared.txt"),
+			LastModified: time.Now(),
+			FirstSeenAt:  time.Now(),
+			Status:       BlobStatusPending,
+		}); err != nil {
+			t.Fatalf("failed to upsert blob state for %s: %v", scoped.Container(), err)
+		}
+	}
+
+	for _, scoped := range []*DB{logs, images} {
+		state, err := scoped.GetBlobState("shared.txt")
+		if err != nil || state == nil {
+			t.Fatalf("failed to get blob state for %s: %v", scoped.Container(), err)
+		}
+		if state.ContainerName != scoped.Container() {
+			t.Errorf("got state for container %q, want %q", state.ContainerName, scoped.Container())
+		}
+
+		count, err := scoped.CountBlobsByStatus(BlobStatusPending)
+		if err != nil || count != 1 {
+			t.Errorf("%s: pending count = %d (err %v), want 1", scoped.Container(), count, err)
+		}
+	}
+
+	// The unscoped database sees every container.
+	count, err := db.CountBlobsByStatus(BlobStatusPending)
+	if err != nil || count != 2 {
+		t.Errorf("unscoped pending count = %d (err %v), want 2", count, err)
+	}
+
+	if err := logs.DeleteBlobState("shared.txt"); err != nil {
+		t.Fatalf("failed to delete blob state: %v", err)
+	}
+	if state, _ := images.GetBlobState("shared.txt"); state == nil {
+		t.Error("deleting a blob in one container removed it from another")
+	}
+
+	if err := logs.UpdateCheckpoint("logs", time.Now(), nil); err != nil {
+		t.Fatalf("failed to update checkpoint: %v", err)
+	}
+	if cp, err := db.GetCheckpoint("images"); err != nil || cp != nil {
+		t.Errorf("expected no checkpoint for images, got %v (err %v)", cp, err)
+	}
+}
+
+func TestWithContainer_ScopesUploadState(t *testing.T) {
+	db := openTestDB(t)
+
+	for _, container := range []string{"logs", "images", "logs"} {
+		if err := db.WithContainer(container).UpsertUploadState(&UploadState{
+			BlobName:  "shared.txt",
+			LocalPath: "/data/shared.txt",
+			SizeBytes: 6,
+			Status:    BlobStatusUploaded,
+		}); err != nil {
+			t.Fatalf("failed to upsert upload state for %s: %v", container, err)
+		}
+	}
+
+	rows, err := db.db.Query("SELECT container_name FROM upload_state ORDER BY container_name")
+	if err != nil {
+		t.Fatalf("failed to query upload state: %v", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var containers []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatalf("failed to scan upload state: %v", err)
+		}
+		containers = append(containers, name)
+	}
+	if got := strings.Join(containers, ","); got != "images,logs" {
+		t.Errorf("upload state containers = %s, want images,logs", got)
+	}
+}
+
+func TestOpen_BusyTimeoutWaitsForWriter(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "state.db")
+
+	writer, err := Open(dbPath)
+	if err != nil {
+		t.Fatalf("failed to open writer: %v", err)
+	}
+	defer func() { _ = writer.Close() }()
+
+	reader, err := OpenWithBusyTimeout(dbPath, 5*time.Second)
+	if err != nil {
+		t.Fatalf("failed to open reader: %v", err)
+	}
+	defer func() { _ = reader.Close() }()
+
+	// Hold the write lock briefly, as a sync flushing a batch would.
+	tx, err := writer.db.Begin()
+	if err != nil {
+		t.Fatalf("failed to begin transaction: %v", err)
+	}
+	if _, err := tx.Exec(`INSERT INTO blob_state (blob_name, blob_path, local_path, size_bytes, etag, last_modified, first_seen_at, status)
+		VALUES ('held.txt', 'held.txt', '/data/held.txt', 1, 'etag', ?, ?, 'pending')`, time.Now(), time.Now()); err != nil {
+		t.Fatalf("failed to insert row: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		// Writing needs the lock the transaction holds; without a busy
+		// timeout this fails immediately with "database is locked".
+		err := reader.UpsertBlobState(&BlobState{
+			BlobName:     "other.txt",
+			BlobPath:     "other.txt",
+			LocalPath:    "/data/other.txt",
+			LastModified: time.Now(),
+			FirstSeenAt:  time.Now(),
+			Status:       BlobStatusPending,
+		})
+		if err == nil {
+			_, err = reader.CountBlobsByStatus(BlobStatusPending)
+		}
+		done <- err
+	}()
+
+	time.Sleep(200 * time.Millisecond)
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("failed to commit transaction: %v", err)
+	}
+
+	if err := <-done; err != nil {
+		t.Fatalf("concurrent access failed despite busy timeout: %v", err)
+	}
+
+	count, err := reader.CountBlobsByStatus(BlobStatusPending)
+	if err != nil || count != 2 {
+		t.Errorf("pending count = %d (err %v), want 2", count, err)
+	}
+}
+
+func TestOpenReadOnly(t *testing.T) {
+	dir := t.TempDir()
+	missing := filepath.Join(dir, "typo.db")
+
+	if _, err := OpenReadOnly(missing, time.Second); !errors.Is(err, ErrDatabaseNotFound) {
+		t.Errorf("open missing database: err = %v, want ErrDatabaseNotFound", err)
+	}
+	if _, err := OpenExisting(missing, time.Second); !errors.Is(err, ErrDatabaseNotFound) {
+		t.Errorf("open missing database for writing: err = %v, want ErrDatabaseNotFound", err)
+	}
+	if _, err := os.Stat(missing); !os.IsNotExist(err) {
+		t.Errorf("expected no database to be created, stat err = %v", err)
+	}
+
+	dbPath := filepath.Join(dir, "state.db")
+	writer, err := Open(dbPath)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer func() { _ = writer.Close() }()
+	if err := writer.UpsertBlobState(&BlobState{
+		BlobName:     "a.txt",
+		BlobPath:     "a.txt",
+		LocalPath:    "/data/a.txt",
+		LastModified: time.Now(),
+		FirstSeenAt:  time.Now(),
+		Status:       BlobStatusPending,
+	}); err != nil {
+		t.Fatalf("failed to insert blob state: %v", err)
+	}
+
+	reader, err := OpenReadOnly(dbPath, time.Second)
+	if err != nil {
+		t.Fatalf("OpenReadOnly failed: %v", err)
+	}
+	defer func() { _ = reader.Close() }()
+
+	if count, err := reader.CountBlobsByStatus(BlobStatusPending); err != nil || count != 1 {
+		t.Errorf("pending count = %d (err %v), want 1", count, err)
+	}
+	if err := reader.DeleteBlobState("a.txt"); err == nil {
+		t.Error("expected a write through the read-only database to fail")
+	}
+}
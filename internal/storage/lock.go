@@ -0,0 +1,64 @@
+// Package storage provides the advisory lock that keeps concurrent syncs
+// away from the same state database.
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// ErrLocked is returned by AcquireLock when another process holds the lock.
+var ErrLocked = errors.New("another sync is already running against this state database")
+
+// Lock is an exclusive advisory lock on a state database, held through a
+// sidecar file next to it. The operating system releases it if the process
+// exits without calling Release.
+type Lock struct {
+	file *os.File
+}
+
+// LockPath returns the path of the lock file for the database at dbPath.
+func LockPath(dbPath string) string {
+	return dbPath + ".lock"
+}
+
+// AcquireLock takes the lock for the database at dbPath without waiting.
+// It returns an error wrapping ErrLocked if the lock is already held.
+func AcquireLock(dbPath string) (*Lock, error) {
+	path := LockPath(dbPath)
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+
+	if err := lockFile(file); err != nil {
+		_ = file.Close()
+		if errors.Is(err, ErrLocked) {
+			return nil, fmt.Errorf("%w (lock file %s)", ErrLocked, path)
+		}
+		return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+
+	// The PID is informational only; the lock itself is what matters.
+	if err := file.Truncate(0); err == nil {
+		_, _ = file.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	}
+
+	return &Lock{file: file}, nil
+}
+
+// Release unlocks and closes the lock file. The file itself is left in
+// place, since removing it could race with another process acquiring it.
+func (l *Lock) Release() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+	err := unlockFile(l.file)
+	if closeErr := l.file.Close(); err == nil {
+		err = closeErr
+	}
+	l.file = nil
+	return err
+}
@@ -0,0 +1,32 @@
+package storage
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestAcquireLock(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "state.db")
+
+	first, err := AcquireLock(dbPath)
+	if err != nil {
+		t.Fatalf("failed to acquire lock: %v", err)
+	}
+
+	if _, err := AcquireLock(dbPath); !errors.Is(err, ErrLocked) {
+		t.Fatalf("expected ErrLocked while the lock is held, got %v", err)
+	}
+
+	if err := first.Release(); err != nil {
+		t.Fatalf("failed to release lock: %v", err)
+	}
+
+	second, err := AcquireLock(dbPath)
+	if err != nil {
+		t.Fatalf("failed to acquire lock after release: %v", err)
+	}
+	if err := second.Release(); err != nil {
+		t.Fatalf("failed to release lock: %v", err)
+	}
+}
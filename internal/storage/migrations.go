@@ -0,0 +1,261 @@
+// Package storage provides schema migrations for the state database.
+package storage
+
+import "fmt"
+
+// migrations are the ordered schema migration steps. The schema version stored
+// in PRAGMA user_version is the number of steps applied. Steps must never be
+// edited once released; add a new step instead.
+var migrations = []string{
+	// 1: initial schema. Uses IF NOT EXISTS so databases created before
+	// versioning was introduced are adopted as version 1.
+	`
+	CREATE TABLE IF NOT EXISTS sync_runs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		started_at DATETIME NOT NULL,
+		completed_at DATETIME,
+		status TEXT NOT NULL,
+		total_files INTEGER DEFAULT 0,
+		downloaded_files INTEGER DEFAULT 0,
+		failed_files INTEGER DEFAULT 0,
+		total_bytes INTEGER DEFAULT 0,
+		error_message TEXT
+	);
+
+	CREATE TABLE IF NOT EXISTS blob_state (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		blob_name TEXT NOT NULL UNIQUE,
+		blob_path TEXT NOT NULL,
+		local_path TEXT NOT NULL,
+		size_bytes INTEGER NOT NULL,
+		content_md5 TEXT,
+		last_modified DATETIME NOT NULL,
+		etag TEXT NOT NULL,
+		first_seen_at DATETIME NOT NULL,
+		last_synced_at DATETIME,
+		sync_run_id INTEGER,
+		status TEXT NOT NULL,
+		error_message TEXT,
+		FOREIGN KEY (sync_run_id) REFERENCES sync_runs(id)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_blob_name ON blob_state(blob_name);
+	CREATE INDEX IF NOT EXISTS idx_status ON blob_state(status);
+	CREATE INDEX IF NOT EXISTS idx_last_synced ON blob_state(last_synced_at);
+	CREATE INDEX IF NOT EXISTS idx_etag_modified ON blob_state(etag, last_modified);
+
+	CREATE TABLE IF NOT EXISTS sync_checkpoint (
+		id INTEGER PRIMARY KEY CHECK (id = 1),
+		container_name TEXT NOT NULL,
+		last_check_time DATETIME NOT NULL,
+		last_continuation_token TEXT,
+		total_blobs_tracked INTEGER DEFAULT 0
+	);
+
+	CREATE TABLE IF NOT EXISTS performance_metrics (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		sync_run_id INTEGER NOT NULL,
+		timestamp DATETIME NOT NULL,
+		cpu_percent REAL,
+		memory_mb INTEGER,
+		network_mbps REAL,
+		disk_io_mbps REAL,
+		active_workers INTEGER,
+		download_rate_files_per_sec REAL,
+		download_rate_mbps REAL,
+		throttled BOOLEAN DEFAULT 0,
+		FOREIGN KEY (sync_run_id) REFERENCES sync_runs(id)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_perf_sync_run ON performance_metrics(sync_run_id);
+	CREATE INDEX IF NOT EXISTS idx_perf_timestamp ON performance_metrics(timestamp);
+
+	CREATE TABLE IF NOT EXISTS error_log (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		sync_run_id INTEGER,
+		timestamp DATETIME NOT NULL,
+		blob_name TEXT NOT NULL,
+		error_type TEXT NOT NULL,
+		error_message TEXT NOT NULL,
+		retry_count INTEGER DEFAULT 0,
+		resolved BOOLEAN DEFAULT 0,
+		FOREIGN KEY (sync_run_id) REFERENCES sync_runs(id)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_error_timestamp ON error_log(timestamp);
+	CREATE INDEX IF NOT EXISTS idx_error_resolved ON error_log(resolved);
+	`,
+
+	// 2: upload state for the push command.
+	`
+	CREATE TABLE IF NOT EXISTS upload_state (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		blob_name TEXT NOT NULL UNIQUE,
+		local_path TEXT NOT NULL,
+		size_bytes INTEGER NOT NULL,
+		content_md5 TEXT,
+		uploaded_at DATETIME,
+		status TEXT NOT NULL,
+		error_message TEXT
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_upload_status ON upload_state(status);
+	`,
+
+	// 3: per-container state, so several containers can share one database.
+	// Existing rows are assigned to the container of the old single checkpoint,
+	// except upload state, which that checkpoint says nothing about.
+	`
+	ALTER TABLE sync_runs ADD COLUMN container_name TEXT NOT NULL DEFAULT '';
+	UPDATE sync_runs SET container_name = COALESCE((SELECT container_name FROM sync_checkpoint WHERE id = 1), '');
+
+	ALTER TABLE error_log ADD COLUMN container_name TEXT NOT NULL DEFAULT '';
+	UPDATE error_log SET container_name = COALESCE((SELECT container_name FROM sync_checkpoint WHERE id = 1), '');
+
+	CREATE TABLE blob_state_v3 (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		container_name TEXT NOT NULL DEFAULT '',
+		blob_name TEXT NOT NULL,
+		blob_path TEXT NOT NULL,
+		local_path TEXT NOT NULL,
+		size_bytes INTEGER NOT NULL,
+		content_md5 TEXT,
+		last_modified DATETIME NOT NULL,
+		etag TEXT NOT NULL,
+		first_seen_at DATETIME NOT NULL,
+		last_synced_at DATETIME,
+		sync_run_id INTEGER,
+		status TEXT NOT NULL,
+		error_message TEXT,
+		UNIQUE (container_name, blob_name),
+		FOREIGN KEY (sync_run_id) REFERENCES sync_runs(id)
+	);
+
+	INSERT INTO blob_state_v3
+		(id, container_name, blob_name, blob_path, local_path, size_bytes, content_md5, last_modified,
+		 etag, first_seen_at, last_synced_at, sync_run_id, status, error_message)
+	SELECT id, COALESCE((SELECT container_name FROM sync_checkpoint WHERE id = 1), ''),
+		blob_name, blob_path, local_path, size_bytes, content_md5, last_modified,
+		etag, first_seen_at, last_synced_at, sync_run_id, status, error_message
+	FROM blob_state;
+
+	DROP TABLE blob_state;
+	ALTER TABLE blob_state_v3 RENAME TO blob_state;
+
+	CREATE INDEX idx_blob_name ON blob_state(blob_name);
+	CREATE INDEX idx_status ON blob_state(container_name, status);
+	CREATE INDEX idx_last_synced ON blob_state(last_synced_at);
+	CREATE INDEX idx_etag_modified ON blob_state(etag, last_modified);
+	CREATE INDEX idx_error_blob ON error_log(container_name, blob_name);
+
+	CREATE TABLE sync_checkpoint_v3 (
+		container_name TEXT PRIMARY KEY,
+		last_check_time DATETIME NOT NULL,
+		last_continuation_token TEXT,
+		total_blobs_tracked INTEGER DEFAULT 0
+	);
+
+	INSERT INTO sync_checkpoint_v3 (container_name, last_check_time, last_continuation_token, total_blobs_tracked)
+	SELECT container_name, last_check_time, last_continuation_token, total_blobs_tracked FROM sync_checkpoint;
+
+	DROP TABLE sync_checkpoint;
+	ALTER TABLE sync_checkpoint_v3 RENAME TO sync_checkpoint;
+
+	CREATE TABLE upload_state_v3 (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		container_name TEXT NOT NULL DEFAULT '',
+		blob_name TEXT NOT NULL,
+		local_path TEXT NOT NULL,
+		size_bytes INTEGER NOT NULL,
+		content_md5 TEXT,
+		uploaded_at DATETIME,
+		status TEXT NOT NULL,
+		error_message TEXT,
+		UNIQUE (container_name, blob_name)
+	);
+
+	INSERT INTO upload_state_v3
+		(id, blob_name, local_path, size_bytes, content_md5, uploaded_at, status, error_message)
+	SELECT id, blob_name, local_path, size_bytes, content_md5, uploaded_at, status, error_message
+	FROM upload_state;
+
+	DROP TABLE upload_state;
+	ALTER TABLE upload_state_v3 RENAME TO upload_state;
+
+	CREATE INDEX idx_upload_status ON upload_state(container_name, status);
+	`,
+
+	// 4: the blob's content type, as reported by the listing.
+	`
+	ALTER TABLE blob_state ADD COLUMN content_type TEXT NOT NULL DEFAULT '';
+	`,
+
+	// 5: the overall throughput of completed runs.
+	`
+	ALTER TABLE sync_runs ADD COLUMN avg_mbps REAL;
+	ALTER TABLE sync_runs ADD COLUMN avg_files_per_sec REAL;
+	`,
+
+	// 6: the local file a deduplicated blob is hardlinked to, and an index
+	// for finding downloaded blobs by MD5.
+	`
+	ALTER TABLE blob_state ADD COLUMN linked_from TEXT NOT NULL DEFAULT '';
+	CREATE INDEX idx_content_md5 ON blob_state(content_md5);
+	`,
+
+	// 7: the blob version a pinned sync downloads.
+	`
+	ALTER TABLE blob_state ADD COLUMN version_id TEXT NOT NULL DEFAULT '';
+	`,
+
+	// 8: the prefix and start time of an unfinished discovery, so it can
+	// resume from last_continuation_token.
+	`
+	ALTER TABLE sync_checkpoint ADD COLUMN discovery_prefix TEXT NOT NULL DEFAULT '';
+	ALTER TABLE sync_checkpoint ADD COLUMN discovery_started_at DATETIME;
+	`,
+}
+
+// schemaVersion returns the schema version recorded in the database.
+func (d *DB) schemaVersion() (int, error) {
+	var version int
+	if err := d.db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return version, nil
+}
+
+// migrate applies any pending migration steps, each in its own transaction.
+func (d *DB) migrate() error {
+	version, err := d.schemaVersion()
+	if err != nil {
+		return err
+	}
+
+	if version > len(migrations) {
+		return fmt.Errorf("state database schema version %d is newer than supported version %d", version, len(migrations))
+	}
+
+	for i := version; i < len(migrations); i++ {
+		tx, err := d.db.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to begin migration %d: %w", i+1, err)
+		}
+
+		if _, err := tx.Exec(migrations[i]); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("failed to apply migration %d: %w", i+1, err)
+		}
+
+		if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", i+1)); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("failed to record schema version %d: %w", i+1, err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration %d: %w", i+1, err)
+		}
+	}
+
+	return nil
+}
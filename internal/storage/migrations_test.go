@@ -0,0 +1,127 @@
+package storage
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// createLegacyDB creates a database with the schema used before versioning
+// was introduced: the initial tables only, with user_version left at 0.
+func createLegacyDB(t *testing.T, dbPath string) {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("failed to open legacy database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	if _, err := db.Exec(migrations[0]); err != nil {
+		t.Fatalf("failed to create legacy schema: %v", err)
+	}
+
+	_, err = db.Exec(`
+		INSERT INTO blob_state (blob_name, blob_path, local_path, size_bytes, last_modified, etag, first_seen_at, status)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		"dir/file.txt", "dir/file.txt", "/data/dir/file.txt", 42, time.Now(), "etag-1", time.Now(), BlobStatusDownloaded,
+	)
+	if err != nil {
+		t.Fatalf("failed to insert legacy row: %v", err)
+	}
+
+	_, err = db.Exec(`
+		INSERT INTO sync_checkpoint (id, container_name, last_check_time) VALUES (1, ?, ?)`,
+		"legacy-container", time.Now(),
+	)
+	if err != nil {
+		t.Fatalf("failed to insert legacy checkpoint: %v", err)
+	}
+}
+
+func TestOpen_MigratesLegacySchema(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "state.db")
+	createLegacyDB(t, dbPath)
+
+	db, err := Open(dbPath)
+	if err != nil {
+		t.Fatalf("failed to open legacy database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	version, err := db.schemaVersion()
+	if err != nil {
+		t.Fatalf("failed to read schema version: %v", err)
+	}
+	if version != len(migrations) {
+		t.Errorf("schema version = %d, want %d", version, len(migrations))
+	}
+
+	state, err := db.GetBlobState("dir/file.txt")
+	if err != nil {
+		t.Fatalf("failed to read migrated blob state: %v", err)
+	}
+	if state == nil || state.SizeBytes != 42 {
+		t.Errorf("existing blob state was not preserved: %+v", state)
+	}
+	if state != nil && state.ContainerName != "legacy-container" {
+		t.Errorf("existing blob state assigned to container %q, want the checkpoint's container", state.ContainerName)
+	}
+
+	cp, err := db.GetCheckpoint("legacy-container")
+	if err != nil || cp == nil {
+		t.Errorf("existing checkpoint was not preserved: %v (err %v)", cp, err)
+	}
+
+	if err := db.UpsertUploadState(&UploadState{
+		BlobName:  "dir/file.txt",
+		LocalPath: "/data/dir/file.txt",
+		SizeBytes: 42,
+		Status:    BlobStatusUploaded,
+	}); err != nil {
+		t.Errorf("upload state table was not created: %v", err)
+	}
+}
+
+func TestOpen_Reopen(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "state.db")
+
+	for i := 0; i < 2; i++ {
+		db, err := Open(dbPath)
+		if err != nil {
+			t.Fatalf("open %d failed: %v", i+1, err)
+		}
+		_ = db.Close()
+	}
+}
+
+func TestOpen_RejectsNewerSchema(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "state.db")
+
+	raw, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	if _, err := raw.Exec("PRAGMA user_version = 999"); err != nil {
+		t.Fatalf("failed to set user_version: %v", err)
+	}
+	_ = raw.Close()
+
+	_, err = Open(dbPath)
+	if err == nil || !strings.Contains(err.Error(), "newer than supported") {
+		t.Errorf("expected newer schema error, got %v", err)
+	}
+}
+
+func TestOpenReadOnly_RejectsOlderSchema(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "state.db")
+
+	createLegacyDB(t, dbPath)
+
+	_, err := OpenReadOnly(dbPath, time.Second)
+	if err == nil || !strings.Contains(err.Error(), "older than supported") {
+		t.Errorf("expected older schema error, got %v", err)
+	}
+}
@@ -8,6 +8,7 @@ import (
 // SyncRun represents a synchronisation run record.
 type SyncRun struct {
 	ID              int64
+	ContainerName   string
 	StartedAt       time.Time
 	CompletedAt     *time.Time
 	Status          string
@@ -16,32 +17,60 @@ type SyncRun struct {
 	FailedFiles     int64
 	TotalBytes      int64
 	ErrorMessage    *string
+	// AvgMbps and AvgFilesPerSec are the run's overall download throughput,
+	// set when it completes.
+	AvgMbps        *float64
+	AvgFilesPerSec *float64
 }
 
 // BlobState tracks the state of an individual blob.
 type BlobState struct {
+	ID            int64
+	ContainerName string
+	BlobName      string
+	BlobPath      string
+	LocalPath     string
+	SizeBytes     int64
+	ContentMD5    *string
+	LastModified  time.Time
+	ETag          string
+	FirstSeenAt   time.Time
+	LastSyncedAt  *time.Time
+	SyncRunID     *int64
+	Status        string
+	ErrorMessage  *string
+	// ContentType is the blob's Content-Type (empty if not set).
+	ContentType string
+	// LinkedFrom is the local file of an identical blob that LocalPath is
+	// hardlinked to instead of being downloaded (empty if downloaded).
+	LinkedFrom string
+	// VersionID is the blob version to download instead of the current one
+	// (empty for the current version).
+	VersionID string
+}
+
+// UploadState tracks the state of an individual local file pushed to Azure.
+type UploadState struct {
 	ID           int64
 	BlobName     string
-	BlobPath     string
 	LocalPath    string
 	SizeBytes    int64
 	ContentMD5   *string
-	LastModified time.Time
-	ETag         string
-	FirstSeenAt  time.Time
-	LastSyncedAt *time.Time
-	SyncRunID    *int64
+	UploadedAt   *time.Time
 	Status       string
 	ErrorMessage *string
 }
 
 // SyncCheckpoint stores the last known state for incremental syncing.
 type SyncCheckpoint struct {
-	ID                    int64
 	ContainerName         string
 	LastCheckTime         time.Time
 	LastContinuationToken *string
 	TotalBlobsTracked     int64
+	// DiscoveryPrefix and DiscoveryStartedAt describe the unfinished
+	// discovery LastContinuationToken belongs to.
+	DiscoveryPrefix    string
+	DiscoveryStartedAt *time.Time
 }
 
 // PerformanceMetric records system performance data during sync operations.
@@ -91,6 +120,12 @@ const (
 	BlobStatusFailed = "failed"
 	// BlobStatusSkipped indicates a skipped blob (already exists).
 	BlobStatusSkipped = "skipped"
+	// BlobStatusUploaded indicates a local file successfully pushed to Azure.
+	BlobStatusUploaded = "uploaded"
+	// BlobStatusDirectory indicates a directory-marker blob, created locally as a directory.
+	BlobStatusDirectory = "directory"
+	// BlobStatusArchived indicates a blob in the Archive tier that cannot be downloaded until rehydrated.
+	BlobStatusArchived = "archived"
 )
 
 const (
@@ -102,6 +137,10 @@ const (
 	ErrorTypeDisk = "disk"
 	// ErrorTypeAuth indicates an authentication error.
 	ErrorTypeAuth = "auth"
+	// ErrorTypeNotFound indicates the blob or container no longer exists.
+	ErrorTypeNotFound = "not_found"
+	// ErrorTypeThrottled indicates the storage account rejected the request due to load.
+	ErrorTypeThrottled = "throttled"
 	// ErrorTypeUnknown indicates an unclassified error.
 	ErrorTypeUnknown = "unknown"
 )
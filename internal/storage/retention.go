@@ -0,0 +1,142 @@
+// Package storage provides retention and compaction of the state database.
+package storage
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// PruneResult counts the rows removed by PruneSyncRuns.
+type PruneResult struct {
+	Runs    int64
+	Metrics int64
+	Errors  int64
+}
+
+// PruneSyncRuns deletes the history of old sync runs: the runs themselves,
+// their performance metrics and their error log entries. A run is kept if
+// it started at or after keepSince, or is among the newest keepLast runs of
+// its container; a zero keepSince or keepLast disables that rule. Running
+// runs and the newest run of each container are always kept.
+//
+// Blob states are never deleted. Those last synced by a removed run keep
+// their status and simply no longer refer to it.
+func (d *DB) PruneSyncRuns(keepSince time.Time, keepLast int) (PruneResult, error) {
+	var result PruneResult
+
+	rows, err := d.db.Query(
+		"SELECT id, container_name, started_at, status FROM sync_runs WHERE "+containerFilter+" ORDER BY id DESC",
+		d.container, d.container,
+	)
+	if err != nil {
+		return result, fmt.Errorf("failed to list sync runs: %w", err)
+	}
+
+	minKeep := keepLast
+	if minKeep < 1 {
+		minKeep = 1
+	}
+	seen := make(map[string]int)
+	var prune []int64
+	for rows.Next() {
+		var id int64
+		var container, status string
+		var startedAt time.Time
+		if err := rows.Scan(&id, &container, &startedAt, &status); err != nil {
+			_ = rows.Close()
+			return result, fmt.Errorf("failed to read sync run: %w", err)
+		}
+		seen[container]++
+		if status == SyncStatusRunning || seen[container] <= minKeep {
+			continue
+		}
+		if !keepSince.IsZero() && !startedAt.Before(keepSince) {
+			continue
+		}
+		prune = append(prune, id)
+	}
+	if err := rows.Close(); err != nil {
+		return result, fmt.Errorf("failed to list sync runs: %w", err)
+	}
+	if err := rows.Err(); err != nil {
+		return result, fmt.Errorf("failed to list sync runs: %w", err)
+	}
+	if len(prune) == 0 {
+		return result, nil
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return result, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	statements := []struct {
+		query string
+		count *int64
+	}{
+		{"DELETE FROM performance_metrics WHERE sync_run_id = ?", &result.Metrics},
+		{"DELETE FROM error_log WHERE sync_run_id = ?", &result.Errors},
+		{"UPDATE blob_state SET sync_run_id = NULL WHERE sync_run_id = ?", nil},
+		{"DELETE FROM sync_runs WHERE id = ?", &result.Runs},
+	}
+	for _, statement := range statements {
+		stmt, err := tx.Prepare(statement.query)
+		if err != nil {
+			_ = tx.Rollback()
+			return PruneResult{}, fmt.Errorf("failed to prepare prune: %w", err)
+		}
+		for _, id := range prune {
+			res, err := stmt.Exec(id)
+			if err != nil {
+				_ = stmt.Close()
+				_ = tx.Rollback()
+				return PruneResult{}, fmt.Errorf("failed to prune sync run %d: %w", id, err)
+			}
+			if statement.count != nil {
+				n, _ := res.RowsAffected()
+				*statement.count += n
+			}
+		}
+		_ = stmt.Close()
+	}
+
+	if err := tx.Commit(); err != nil {
+		return PruneResult{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return result, nil
+}
+
+// Vacuum rebuilds the database file to reclaim the space of deleted rows,
+// then checkpoints the write-ahead log into it and truncates the log.
+func (d *DB) Vacuum() error {
+	if _, err := d.db.Exec("VACUUM"); err != nil {
+		return fmt.Errorf("failed to vacuum database: %w", err)
+	}
+	// A busy result means a reader kept part of the log from being copied.
+	var busy, logFrames, checkpointed int
+	if err := d.db.QueryRow("PRAGMA wal_checkpoint(TRUNCATE)").Scan(&busy, &logFrames, &checkpointed); err != nil {
+		return fmt.Errorf("failed to checkpoint database: %w", err)
+	}
+	if busy != 0 {
+		return fmt.Errorf("failed to checkpoint database: log is in use by another connection")
+	}
+	return nil
+}
+
+// FileSize returns the combined size in bytes of the database file at path
+// and its write-ahead log.
+func FileSize(path string) (int64, error) {
+	var total int64
+	for _, p := range []string{path, path + "-wal"} {
+		info, err := os.Stat(p)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		total += info.Size()
+	}
+	return total, nil
+}
@@ -0,0 +1,144 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// seedRun creates a sync run in container that started age ago.
+func seedRun(t *testing.T, db *DB, container string, age time.Duration, status string) int64 {
+	t.Helper()
+	id, err := db.WithContainer(container).CreateSyncRun()
+	if err != nil {
+		t.Fatalf("CreateSyncRun failed: %v", err)
+	}
+	if _, err := db.db.Exec("UPDATE sync_runs SET started_at = ?, status = ? WHERE id = ?",
+		time.Now().Add(-age), status, id); err != nil {
+		t.Fatalf("failed to age sync run: %v", err)
+	}
+	return id
+}
+
+func countRows(t *testing.T, db *DB, query string, args ...interface{}) int64 {
+	t.Helper()
+	var n int64
+	if err := db.db.QueryRow(query, args...).Scan(&n); err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	return n
+}
+
+func TestPruneSyncRuns_KeepSince(t *testing.T) {
+	db := openTestDB(t)
+	day := 24 * time.Hour
+
+	oldest := seedRun(t, db, "a", 90*day, SyncStatusCompleted)
+	old := seedRun(t, db, "a", 60*day, SyncStatusFailed)
+	recent := seedRun(t, db, "a", day, SyncStatusCompleted)
+	stale := seedRun(t, db, "a", 45*day, SyncStatusRunning)
+	onlyB := seedRun(t, db, "b", 90*day, SyncStatusCompleted)
+
+	for _, id := range []int64{oldest, recent} {
+		id := id
+		if err := db.RecordMetric(&PerformanceMetric{SyncRunID: id, Timestamp: time.Now()}); err != nil {
+			t.Fatalf("RecordMetric failed: %v", err)
+		}
+		if err := db.WithContainer("a").RecordError(&id, "x.txt", ErrorTypeNetwork, "timeout", 0); err != nil {
+			t.Fatalf("RecordError failed: %v", err)
+		}
+	}
+	blob := newTestBlobStates("run", 1)[0]
+	blob.Status = BlobStatusDownloaded
+	blob.SyncRunID = &oldest
+	if err := db.WithContainer("a").UpsertBlobState(blob); err != nil {
+		t.Fatalf("UpsertBlobState failed: %v", err)
+	}
+
+	result, err := db.PruneSyncRuns(time.Now().Add(-30*day), 0)
+	if err != nil {
+		t.Fatalf("PruneSyncRuns failed: %v", err)
+	}
+	if result != (PruneResult{Runs: 2, Metrics: 1, Errors: 1}) {
+		t.Errorf("result = %+v, want 2 runs, 1 metric and 1 error", result)
+	}
+
+	for id, want := range map[int64]int64{oldest: 0, old: 0, recent: 1, stale: 1, onlyB: 1} {
+		if got := countRows(t, db, "SELECT COUNT(*) FROM sync_runs WHERE id = ?", id); got != want {
+			t.Errorf("run %d: rows = %d, want %d", id, got, want)
+		}
+	}
+	if got := countRows(t, db, "SELECT COUNT(*) FROM performance_metrics WHERE sync_run_id = ?", oldest); got != 0 {
+		t.Errorf("metrics of pruned run = %d, want 0", got)
+	}
+	if got := countRows(t, db, "SELECT COUNT(*) FROM error_log WHERE sync_run_id = ?", recent); got != 1 {
+		t.Errorf("errors of kept run = %d, want 1", got)
+	}
+
+	state, err := db.WithContainer("a").GetBlobState(blob.BlobName)
+	if err != nil || state == nil {
+		t.Fatalf("blob state missing after prune: %v", err)
+	}
+	if state.Status != BlobStatusDownloaded || state.SyncRunID != nil {
+		t.Errorf("blob state = %+v, want downloaded with no run", state)
+	}
+}
+
+func TestPruneSyncRuns_KeepLast(t *testing.T) {
+	db := openTestDB(t)
+
+	var ids []int64
+	for i := 0; i < 5; i++ {
+		ids = append(ids, seedRun(t, db, "a", time.Duration(5-i)*time.Hour, SyncStatusCompleted))
+	}
+
+	result, err := db.PruneSyncRuns(time.Time{}, 2)
+	if err != nil {
+		t.Fatalf("PruneSyncRuns failed: %v", err)
+	}
+	if result.Runs != 3 {
+		t.Errorf("pruned runs = %d, want 3", result.Runs)
+	}
+	if got := countRows(t, db, "SELECT COUNT(*) FROM sync_runs WHERE id IN (?, ?)", ids[3], ids[4]); got != 2 {
+		t.Errorf("newest runs kept = %d, want 2", got)
+	}
+}
+
+func TestVacuum_ReclaimsSpaceAndTruncatesLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.db")
+	db, err := Open(path)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	if err := db.BatchUpsertBlobState(newTestBlobStates("vacuum", 5000)); err != nil {
+		t.Fatalf("BatchUpsertBlobState failed: %v", err)
+	}
+	if _, err := db.db.Exec("DELETE FROM blob_state"); err != nil {
+		t.Fatalf("failed to delete blob states: %v", err)
+	}
+	if _, err := db.db.Exec("PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+		t.Fatalf("failed to checkpoint: %v", err)
+	}
+	before, err := FileSize(path)
+	if err != nil {
+		t.Fatalf("FileSize failed: %v", err)
+	}
+
+	if err := db.Vacuum(); err != nil {
+		t.Fatalf("Vacuum failed: %v", err)
+	}
+
+	after, err := FileSize(path)
+	if err != nil {
+		t.Fatalf("FileSize failed: %v", err)
+	}
+	if after >= before {
+		t.Errorf("size after vacuum = %d, want less than %d", after, before)
+	}
+	if info, err := os.Stat(path + "-wal"); err == nil && info.Size() != 0 {
+		t.Errorf("write-ahead log size = %d, want 0", info.Size())
+	}
+}
@@ -0,0 +1,181 @@
+// Package storage provides aggregate statistics over the state database.
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// RunCounts holds the number of sync runs in each status.
+type RunCounts struct {
+	Total     int64
+	Running   int64
+	Completed int64
+	Failed    int64
+}
+
+// BlobCounts holds the number of tracked blobs in each status.
+type BlobCounts struct {
+	Total      int64
+	Downloaded int64
+	Pending    int64
+	Failed     int64
+	Skipped    int64
+	Archived   int64
+}
+
+// AggregateStats summarizes the sync runs and blob state of the database, or
+// of one container if the DB is scoped.
+type AggregateStats struct {
+	Runs  RunCounts
+	Blobs BlobCounts
+	// DownloadedBytes is the total size of the downloaded blobs.
+	DownloadedBytes int64
+	// LatestCheckpoint is the most recently updated checkpoint, or nil if
+	// no discovery has finished.
+	LatestCheckpoint *SyncCheckpoint
+}
+
+// PerformanceSummary summarizes the performance metrics recorded during one
+// sync run.
+type PerformanceSummary struct {
+	SyncRunID          int64
+	Samples            int64
+	PeakFilesPerSec    float64
+	AvgFilesPerSec     float64
+	PeakMbps           float64
+	AvgMbps            float64
+	PeakMemoryMB       int64
+	ThrottledDuringRun bool
+	// Throttled and AsOf are taken from the run's latest sample.
+	Throttled bool
+	AsOf      time.Time
+}
+
+// GetAggregateStats returns run and blob counts by status, the bytes
+// downloaded and the latest checkpoint.
+func (d *DB) GetAggregateStats() (*AggregateStats, error) {
+	stats := &AggregateStats{}
+
+	runs := &stats.Runs
+	err := d.db.QueryRow(`
+		SELECT
+			COUNT(*),
+			COALESCE(SUM(CASE WHEN status = 'running' THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN status = 'completed' THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN status = 'failed' THEN 1 ELSE 0 END), 0)
+		FROM sync_runs WHERE `+containerFilter,
+		d.container, d.container,
+	).Scan(&runs.Total, &runs.Running, &runs.Completed, &runs.Failed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count sync runs: %w", err)
+	}
+
+	blobs := &stats.Blobs
+	err = d.db.QueryRow(`
+		SELECT
+			COUNT(*),
+			COALESCE(SUM(CASE WHEN status = 'downloaded' THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN status = 'pending' THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN status = 'failed' THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN status = 'skipped' THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN status = 'archived' THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN status = 'downloaded' THEN size_bytes ELSE 0 END), 0)
+		FROM blob_state WHERE `+containerFilter,
+		d.container, d.container,
+	).Scan(&blobs.Total, &blobs.Downloaded, &blobs.Pending, &blobs.Failed, &blobs.Skipped,
+		&blobs.Archived, &stats.DownloadedBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count blob state: %w", err)
+	}
+
+	cp := &SyncCheckpoint{}
+	err = d.db.QueryRow(`
+		SELECT container_name, last_check_time, last_continuation_token, total_blobs_tracked
+		FROM sync_checkpoint WHERE `+containerFilter+`
+		ORDER BY last_check_time DESC LIMIT 1`,
+		d.container, d.container,
+	).Scan(&cp.ContainerName, &cp.LastCheckTime, &cp.LastContinuationToken, &cp.TotalBlobsTracked)
+	switch {
+	case err == nil:
+		stats.LatestCheckpoint = cp
+	case err != sql.ErrNoRows:
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+
+	return stats, nil
+}
+
+// ContainerNames returns, in order, the containers that have blob state or
+// a checkpoint in the database.
+func (d *DB) ContainerNames() ([]string, error) {
+	rows, err := d.db.Query(`
+		SELECT container_name FROM blob_state
+		UNION
+		SELECT container_name FROM sync_checkpoint
+		ORDER BY 1`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to read container: %w", err)
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// GetLatestPerformanceSummary summarizes the metrics of the most recent run
+// that recorded any, or returns nil if none has.
+func (d *DB) GetLatestPerformanceSummary() (*PerformanceSummary, error) {
+	perf := &PerformanceSummary{}
+	err := d.db.QueryRow(`
+		SELECT sync_run_id, throttled, timestamp FROM performance_metrics ORDER BY timestamp DESC LIMIT 1
+	`).Scan(&perf.SyncRunID, &perf.Throttled, &perf.AsOf)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query performance metrics: %w", err)
+	}
+
+	err = d.db.QueryRow(`
+		SELECT
+			COUNT(*),
+			COALESCE(MAX(download_rate_files_per_sec), 0),
+			COALESCE(AVG(download_rate_files_per_sec), 0),
+			COALESCE(MAX(download_rate_mbps), 0),
+			COALESCE(AVG(download_rate_mbps), 0),
+			COALESCE(MAX(memory_mb), 0),
+			COALESCE(MAX(throttled), 0)
+		FROM performance_metrics WHERE sync_run_id = ?
+	`, perf.SyncRunID).Scan(
+		&perf.Samples, &perf.PeakFilesPerSec, &perf.AvgFilesPerSec,
+		&perf.PeakMbps, &perf.AvgMbps, &perf.PeakMemoryMB, &perf.ThrottledDuringRun,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize performance metrics: %w", err)
+	}
+	return perf, nil
+}
+
+// GetRecentFailures returns up to limit failed blobs, most recently
+// attempted first.
+func (d *DB) GetRecentFailures(limit int) ([]*BlobState, error) {
+	rows, err := d.db.Query(`
+		SELECT `+blobStateColumns+`
+		FROM blob_state WHERE status = ? AND `+containerFilter+`
+		ORDER BY last_synced_at DESC LIMIT ?`,
+		BlobStatusFailed, d.container, d.container, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanBlobStates(rows)
+}
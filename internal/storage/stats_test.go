@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetAggregateStats(t *testing.T) {
+	db := openTestDB(t)
+	hour := time.Hour
+
+	seedRun(t, db, "a", 3*hour, SyncStatusCompleted)
+	seedRun(t, db, "a", 2*hour, SyncStatusFailed)
+	seedRun(t, db, "a", hour, SyncStatusRunning)
+	seedRun(t, db, "b", hour, SyncStatusCompleted)
+
+	// Sizes are 0..4; blob 3 fails and blob 4 stays pending.
+	blobs := newTestBlobStates("stats", 5)
+	for i, blob := range blobs[:3] {
+		blob.SizeBytes = int64(100 * (i + 1))
+		blob.Status = BlobStatusDownloaded
+	}
+	blobs[3].Status = BlobStatusFailed
+	if err := db.WithContainer("a").BatchUpsertBlobState(blobs); err != nil {
+		t.Fatalf("BatchUpsertBlobState failed: %v", err)
+	}
+	other := newTestBlobStates("other", 1)[0]
+	other.SizeBytes = 1000
+	other.Status = BlobStatusDownloaded
+	if err := db.WithContainer("b").UpsertBlobState(other); err != nil {
+		t.Fatalf("UpsertBlobState failed: %v", err)
+	}
+
+	checkA := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	checkB := checkA.Add(hour)
+	if err := db.UpdateCheckpoint("a", checkA, nil); err != nil {
+		t.Fatalf("UpdateCheckpoint failed: %v", err)
+	}
+	if err := db.UpdateCheckpoint("b", checkB, nil); err != nil {
+		t.Fatalf("UpdateCheckpoint failed: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		db         *DB
+		runs       RunCounts
+		blobs      BlobCounts
+		bytes      int64
+		checkpoint string
+	}{
+		{
+			name:       "all containers",
+			db:         db,
+			runs:       RunCounts{Total: 4, Running: 1, Completed: 2, Failed: 1},
+			blobs:      BlobCounts{Total: 6, Downloaded: 4, Pending: 1, Failed: 1},
+			bytes:      1600,
+			checkpoint: "b",
+		},
+		{
+			name:       "one container",
+			db:         db.WithContainer("a"),
+			runs:       RunCounts{Total: 3, Running: 1, Completed: 1, Failed: 1},
+			blobs:      BlobCounts{Total: 5, Downloaded: 3, Pending: 1, Failed: 1},
+			bytes:      600,
+			checkpoint: "a",
+		},
+		{
+			name: "empty container",
+			db:   db.WithContainer("c"),
+		},
+	}
+
+	for _, tt := range tests {
+		stats, err := tt.db.GetAggregateStats()
+		if err != nil {
+			t.Fatalf("%s: GetAggregateStats failed: %v", tt.name, err)
+		}
+		if stats.Runs != tt.runs {
+			t.Errorf("%s: runs = %+v, want %+v", tt.name, stats.Runs, tt.runs)
+		}
+		if stats.Blobs != tt.blobs {
+			t.Errorf("%s: blobs = %+v, want %+v", tt.name, stats.Blobs, tt.blobs)
+		}
+		if stats.DownloadedBytes != tt.bytes {
+			t.Errorf("%s: downloaded bytes = %d, want %d", tt.name, stats.DownloadedBytes, tt.bytes)
+		}
+		switch cp := stats.LatestCheckpoint; {
+		case tt.checkpoint == "" && cp != nil:
+			t.Errorf("%s: checkpoint = %q, want none", tt.name, cp.ContainerName)
+		case tt.checkpoint != "" && (cp == nil || cp.ContainerName != tt.checkpoint):
+			t.Errorf("%s: checkpoint = %+v, want container %q", tt.name, cp, tt.checkpoint)
+		}
+	}
+
+	names, err := db.ContainerNames()
+	if err != nil {
+		t.Fatalf("ContainerNames failed: %v", err)
+	}
+	if len(names) != 2 || names[0] != "a" || names[1] != "b" {
+		t.Errorf("containers = %v, want [a b]", names)
+	}
+}
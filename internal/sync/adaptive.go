@@ -0,0 +1,116 @@
+// Package sync provides throughput-driven scaling of the download worker pool.
+package sync
+
+import "time"
+
+const (
+	// adaptiveBaselineWorkers is the number of workers an adaptive run starts with.
+	adaptiveBaselineWorkers = 4
+	// adaptiveTolerance is the relative throughput change treated as noise.
+	adaptiveTolerance = 0.05
+)
+
+// workerScaler adjusts the number of active workers by hill climbing on
+// throughput: it keeps moving in one direction while throughput improves,
+// turns around when it drops, and halves the pool when the service
+// throttles requests. It is not safe for concurrent use.
+type workerScaler struct {
+	max     int
+	current int
+	// direction is +1 while adding workers and -1 while removing them.
+	direction int
+	// lastRate is the throughput measured at the current size, or 0 when
+	// the current size has not been measured yet.
+	lastRate float64
+}
+
+// newWorkerScaler returns a scaler bounded by [1, max], starting at the
+// baseline.
+func newWorkerScaler(max int) *workerScaler {
+	return &workerScaler{
+		max:       max,
+		current:   min(max, adaptiveBaselineWorkers),
+		direction: 1,
+	}
+}
+
+// next takes the throughput measured over the last interval, in Mbps, and
+// whether any request was throttled during it, and returns the number of
+// workers to use next with the reason for any change.
+func (w *workerScaler) next(mbps float64, throttled bool) (int, string) {
+	if throttled {
+		w.lastRate = 0
+		w.direction = 1
+		if w.current == 1 {
+			return w.current, ""
+		}
+		w.current = max(1, w.current/2)
+		return w.current, "throttled"
+	}
+
+	// An idle interval, such as one spent waiting for discovery, says
+	// nothing about the pool size.
+	if mbps <= 0 {
+		return w.current, ""
+	}
+
+	reason := ""
+	switch {
+	case w.lastRate == 0:
+		reason = "probing"
+	case mbps >= w.lastRate*(1+adaptiveTolerance):
+		reason = "throughput improved"
+	case mbps <= w.lastRate*(1-adaptiveTolerance):
+		w.direction = -w.direction
+		reason = "throughput dropped"
+	default:
+		w.lastRate = mbps
+		return w.current, ""
+	}
+	w.lastRate = mbps
+
+	size := min(w.max, max(1, w.current+w.direction))
+	if size == w.current {
+		// At a bound: hold until throughput drops and turns the search.
+		return w.current, ""
+	}
+	w.current = size
+	return w.current, reason
+}
+
+// scaleWorkers feeds a throughput sample to the scaler and applies its
+// decision. Requests throttled since the previous sample count as
+// throttling.
+func (s *Syncer) scaleWorkers(mbps float64) {
+	throttled := s.throttledRequests.Swap(0) > 0
+	prev := int(s.workerLimit.Load())
+	size, reason := s.scaler.next(mbps, throttled)
+	if size == prev {
+		return
+	}
+
+	s.workerLimit.Store(int32(size))
+	s.logger.Infow("Adaptive workers scaled",
+		"from", prev,
+		"to", size,
+		"reason", reason,
+		"mbps", mbps,
+	)
+}
+
+// waitForWorkerLimit blocks workers beyond the adaptive worker limit until
+// the pool grows to include them. It returns false if the sync is cancelled
+// while waiting.
+func (s *Syncer) waitForWorkerLimit(workerID int) bool {
+	if s.scaler == nil {
+		return true
+	}
+	for workerID >= int(s.workerLimit.Load()) {
+		select {
+		case <-s.ctx.Done():
+			return false
+		case <-time.After(throttlePollInterval):
+		}
+	}
+	return true
+}
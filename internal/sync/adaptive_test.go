@@ -0,0 +1,74 @@
+package sync
+
+import "testing"
+
+func TestWorkerScaler(t *testing.T) {
+	type sample struct {
+		mbps      float64
+		throttled bool
+		want      int
+		changed   bool
+	}
+
+	tests := []struct {
+		name    string
+		max     int
+		start   int
+		samples []sample
+	}{
+		{
+			name:  "climbs while throughput improves then turns back",
+			max:   8,
+			start: 4,
+			samples: []sample{
+				{mbps: 100, want: 5, changed: true},
+				{mbps: 120, want: 6, changed: true},
+				{mbps: 130, want: 7, changed: true},
+				{mbps: 131, want: 7},
+				{mbps: 110, want: 6, changed: true},
+				{mbps: 0, want: 6},
+			},
+		},
+		{
+			name:  "halves when throttled and probes again",
+			max:   16,
+			start: 4,
+			samples: []sample{
+				{mbps: 100, want: 5, changed: true},
+				{mbps: 200, want: 6, changed: true},
+				{mbps: 200, throttled: true, want: 3, changed: true},
+				{mbps: 50, want: 4, changed: true},
+			},
+		},
+		{
+			name:  "stays within bounds",
+			max:   2,
+			start: 2,
+			samples: []sample{
+				{mbps: 100, want: 2},
+				{mbps: 50, want: 1, changed: true},
+				{mbps: 20, want: 2, changed: true},
+				{mbps: 40, want: 2},
+				{mbps: 40, throttled: true, want: 1, changed: true},
+				{mbps: 40, throttled: true, want: 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		scaler := newWorkerScaler(tt.max)
+		if scaler.current != tt.start {
+			t.Errorf("%s: baseline = %d, want %d", tt.name, scaler.current, tt.start)
+			continue
+		}
+		for i, s := range tt.samples {
+			got, reason := scaler.next(s.mbps, s.throttled)
+			if got != s.want {
+				t.Errorf("%s: sample %d: workers = %d, want %d", tt.name, i, got, s.want)
+			}
+			if (reason != "") != s.changed {
+				t.Errorf("%s: sample %d: reason = %q, want change %v", tt.name, i, reason, s.changed)
+			}
+		}
+	}
+}
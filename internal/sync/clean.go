@@ -0,0 +1,81 @@
+// Package sync provides detection of orphaned files in the output directory.
+package sync
+
+import (
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/haepapa/getblobz/internal/storage"
+)
+
+// Orphan is a local file under the output path that no blob state tracks.
+type Orphan struct {
+	// Path is the file path.
+	Path string
+	// Size is the file size in bytes.
+	Size int64
+	// Temp reports whether the file is a leftover partial download.
+	Temp bool
+}
+
+// StateFiles returns the state database at dbPath together with the journal
+// and lock files kept beside it.
+func StateFiles(dbPath string) []string {
+	return []string{dbPath, dbPath + "-wal", dbPath + "-shm", dbPath + "-journal", storage.LockPath(dbPath)}
+}
+
+// FindOrphans walks outputPath and returns the regular files that are not in
+// tracked, including stale .tmp partial downloads. Paths in protected, such
+// as the state database and its journal files, are never reported.
+func FindOrphans(outputPath string, tracked map[string]struct{}, protected []string) ([]Orphan, error) {
+	known := make(map[string]struct{}, len(tracked)+len(protected))
+	for p := range tracked {
+		known[absPath(p)] = struct{}{}
+	}
+
+	keep := make(map[string]struct{}, len(protected))
+	for _, p := range protected {
+		keep[absPath(p)] = struct{}{}
+	}
+
+	var orphans []Orphan
+	err := filepath.WalkDir(outputPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		abs := absPath(path)
+		if _, ok := keep[abs]; ok {
+			return nil
+		}
+
+		temp := strings.HasSuffix(path, ".tmp")
+		if _, ok := known[abs]; ok && !temp {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		orphans = append(orphans, Orphan{Path: path, Size: info.Size(), Temp: temp})
+		return nil
+	})
+
+	return orphans, err
+}
+
+// absPath returns the cleaned absolute form of p, or p cleaned if it cannot
+// be made absolute.
+func absPath(p string) string {
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return filepath.Clean(p)
+	}
+	return abs
+}
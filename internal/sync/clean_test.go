@@ -0,0 +1,63 @@
+package sync
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestFindOrphans(t *testing.T) {
+	dir := t.TempDir()
+
+	files := []string{
+		"tracked.txt",
+		"sub/tracked.csv",
+		"sub/untracked.csv",
+		"sub/partial.bin.tmp",
+		"state.db",
+		"state.db-wal",
+	}
+	for _, name := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("mkdir failed: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+
+	tracked := map[string]struct{}{
+		filepath.Join(dir, "tracked.txt"):     {},
+		filepath.Join(dir, "sub/tracked.csv"): {},
+	}
+	dbPath := filepath.Join(dir, "state.db")
+	protected := []string{dbPath, dbPath + "-wal", dbPath + "-shm"}
+
+	orphans, err := FindOrphans(dir, tracked, protected)
+	if err != nil {
+		t.Fatalf("FindOrphans failed: %v", err)
+	}
+
+	var got []string
+	for _, o := range orphans {
+		rel, _ := filepath.Rel(dir, o.Path)
+		got = append(got, filepath.ToSlash(rel))
+		if o.Temp != (filepath.Ext(o.Path) == ".tmp") {
+			t.Errorf("%s: Temp = %v", rel, o.Temp)
+		}
+	}
+	sort.Strings(got)
+
+	want := []string{"sub/partial.bin.tmp", "sub/untracked.csv"}
+	if len(got) != len(want) {
+		t.Fatalf("orphans = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("orphans = %v, want %v", got, want)
+			break
+		}
+	}
+}
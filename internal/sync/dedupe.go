@@ -0,0 +1,61 @@
+// Package sync provides deduplication of identical blobs through hardlinks.
+package sync
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+
+	"github.com/haepapa/getblobz/internal/storage"
+)
+
+// linkDuplicate hardlinks blob's local path to the file of an already
+// synced blob with the same Content-MD5 and size, and records that file
+// in blob.LinkedFrom. It returns false, leaving the blob to be downloaded,
+// when there is no such file or it cannot be linked, for example because it
+// is on another filesystem.
+func (s *Syncer) linkDuplicate(workerID int, blob *storage.BlobState) bool {
+	if blob.ContentMD5 == nil || *blob.ContentMD5 == "" {
+		return false
+	}
+
+	original, err := s.db.FindSyncedByMD5(*blob.ContentMD5, blob.BlobName)
+	if err != nil {
+		s.logger.Warnw("Failed to look up duplicate blob", "worker", workerID, "blob", blob.BlobName, "error", err)
+		return false
+	}
+	if original == nil || original.SizeBytes != blob.SizeBytes || original.LocalPath == blob.LocalPath {
+		return false
+	}
+	info, err := os.Stat(original.LocalPath)
+	if err != nil || !info.Mode().IsRegular() || info.Size() != blob.SizeBytes {
+		return false
+	}
+
+	// Link next to the destination and rename over it, so an existing file
+	// is replaced atomically. A linked file makes any partial download moot.
+	tmpPath := blob.LocalPath + tempSuffix
+	if err := os.Remove(tmpPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return false
+	}
+	if err := os.Link(original.LocalPath, tmpPath); err != nil {
+		s.logger.Debugw("Failed to link duplicate blob; downloading it instead",
+			"worker", workerID,
+			"blob", blob.BlobName,
+			"error", err,
+		)
+		return false
+	}
+	if err := os.Rename(tmpPath, blob.LocalPath); err != nil {
+		_ = os.Remove(tmpPath)
+		return false
+	}
+
+	blob.LinkedFrom = original.LocalPath
+	s.logger.Infow("Linked duplicate blob",
+		"worker", workerID,
+		"blob", blob.BlobName,
+		"linked_from", original.LocalPath,
+	)
+	return true
+}
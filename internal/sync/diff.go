@@ -0,0 +1,125 @@
+// Package sync provides comparison of a container with its local copy.
+package sync
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/haepapa/getblobz/internal/storage"
+)
+
+// Reasons reported for a DiffEntry.
+const (
+	DiffReasonNew            = "not synced"
+	DiffReasonMissingLocally = "missing locally"
+	DiffReasonETagChanged    = "etag changed"
+	DiffReasonSizeChanged    = "size changed"
+	DiffReasonLocalSize      = "local size differs"
+	DiffReasonDeleted        = "deleted remotely"
+)
+
+// DiffEntry is one blob that differs between a container and its local copy.
+type DiffEntry struct {
+	Blob       string `json:"blob"`
+	LocalPath  string `json:"local_path,omitempty"`
+	RemoteSize int64  `json:"remote_size"`
+	LocalSize  int64  `json:"local_size"`
+	Reason     string `json:"reason"`
+}
+
+// Diff lists how a container differs from its local copy.
+type Diff struct {
+	// RemoteOnly holds blobs without a good local copy, which a sync
+	// would download.
+	RemoteOnly []DiffEntry `json:"remote_only"`
+	// LocalOnly holds synced blobs that no longer exist in the container,
+	// which mirroring would delete.
+	LocalOnly []DiffEntry `json:"local_only"`
+	// Changed holds synced blobs whose ETag or size no longer matches.
+	Changed []DiffEntry `json:"changed"`
+}
+
+// ComputeDiff lists the blobs under prefix in container and compares them
+// with their state in db, which must be scoped to container, and with the
+// local files it records. Nothing is written.
+func ComputeDiff(ctx context.Context, client BlobClient, db *storage.DB, container, prefix string, pageSize int32) (*Diff, error) {
+	diff := &Diff{RemoteOnly: []DiffEntry{}, LocalOnly: []DiffEntry{}, Changed: []DiffEntry{}}
+	seen := make(map[string]struct{})
+
+	var marker *string
+	for {
+		blobs, next, err := client.ListBlobs(ctx, container, prefix, marker, pageSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list blobs: %w", err)
+		}
+
+		for _, blob := range blobs {
+			seen[blob.Name] = struct{}{}
+			if isDirectoryMarker(blob) {
+				continue
+			}
+
+			state, err := db.GetBlobState(blob.Name)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get state of %s: %w", blob.Name, err)
+			}
+			if state == nil || !isSynced(state.Status) {
+				entry := DiffEntry{Blob: blob.Name, RemoteSize: blob.Size, Reason: DiffReasonNew}
+				if state != nil {
+					entry.LocalPath = state.LocalPath
+				}
+				diff.RemoteOnly = append(diff.RemoteOnly, entry)
+				continue
+			}
+
+			entry := DiffEntry{Blob: blob.Name, LocalPath: state.LocalPath, RemoteSize: blob.Size}
+			info, err := os.Stat(state.LocalPath)
+			if err != nil || !info.Mode().IsRegular() {
+				entry.Reason = DiffReasonMissingLocally
+				diff.RemoteOnly = append(diff.RemoteOnly, entry)
+				continue
+			}
+			entry.LocalSize = info.Size()
+
+			switch {
+			case !sameETag(state.ETag, blob.ETag):
+				entry.Reason = DiffReasonETagChanged
+			case state.SizeBytes != blob.Size:
+				entry.Reason = DiffReasonSizeChanged
+			case info.Size() != blob.Size:
+				entry.Reason = DiffReasonLocalSize
+			default:
+				continue
+			}
+			diff.Changed = append(diff.Changed, entry)
+		}
+
+		if next == nil {
+			break
+		}
+		marker = next
+	}
+
+	tracked, err := db.GetBlobStatesWithPrefix(prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tracked blobs: %w", err)
+	}
+	for _, state := range tracked {
+		if _, ok := seen[state.BlobName]; ok || !isSynced(state.Status) {
+			continue
+		}
+		info, err := os.Stat(state.LocalPath)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		diff.LocalOnly = append(diff.LocalOnly, DiffEntry{
+			Blob:      state.BlobName,
+			LocalPath: state.LocalPath,
+			LocalSize: info.Size(),
+			Reason:    DiffReasonDeleted,
+		})
+	}
+
+	return diff, nil
+}
@@ -0,0 +1,71 @@
+package sync
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestComputeDiff(t *testing.T) {
+	client := newStubClient(map[string][]byte{
+		"same.txt":    []byte("same"),
+		"changed.txt": []byte("old content"),
+		"missing.txt": []byte("missing"),
+		"removed.txt": []byte("removed"),
+	})
+	s, db := newTestSyncer(t, client)
+	if err := s.Start(); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	client.blobs["new.txt"] = []byte("new")
+	client.blobs["changed.txt"] = []byte("new content")
+	delete(client.blobs, "removed.txt")
+	if err := os.Remove(filepath.Join(s.cfg.Sync.OutputPath, "missing.txt")); err != nil {
+		t.Fatalf("failed to remove local file: %v", err)
+	}
+
+	scoped := db.WithContainer(s.cfg.Sync.Container)
+	diff, err := ComputeDiff(context.Background(), client, scoped, s.cfg.Sync.Container, "", 2)
+	if err != nil {
+		t.Fatalf("ComputeDiff failed: %v", err)
+	}
+
+	reasons := func(entries []DiffEntry) map[string]string {
+		got := make(map[string]string)
+		for _, e := range entries {
+			got[e.Blob] = e.Reason
+		}
+		return got
+	}
+	tests := []struct {
+		name    string
+		entries []DiffEntry
+		want    map[string]string
+	}{
+		{"remote only", diff.RemoteOnly, map[string]string{"new.txt": DiffReasonNew, "missing.txt": DiffReasonMissingLocally}},
+		{"local only", diff.LocalOnly, map[string]string{"removed.txt": DiffReasonDeleted}},
+		{"changed", diff.Changed, map[string]string{"changed.txt": DiffReasonETagChanged}},
+	}
+	for _, tt := range tests {
+		got := reasons(tt.entries)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for blob, reason := range tt.want {
+			if got[blob] != reason {
+				t.Errorf("%s: %s reason = %q, want %q", tt.name, blob, got[blob], reason)
+			}
+		}
+	}
+
+	// The diff is read-only.
+	if state, err := scoped.GetBlobState("new.txt"); err != nil || state != nil {
+		t.Errorf("state of new.txt = %v, %v, want none", state, err)
+	}
+	if _, err := os.Stat(filepath.Join(s.cfg.Sync.OutputPath, "removed.txt")); err != nil {
+		t.Errorf("removed.txt local copy: %v", err)
+	}
+}
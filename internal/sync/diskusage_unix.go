@@ -0,0 +1,17 @@
+//go:build !windows
+
+// Package sync provides the Unix filesystem usage check.
+package sync
+
+import "syscall"
+
+// diskUsagePercent reports the usage percent of the filesystem holding path.
+// Space reserved for root is counted as used, matching df.
+func diskUsagePercent(path string) (int, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+	bsize := uint64(stat.Bsize)
+	return usagePercent(uint64(stat.Blocks)*bsize, uint64(stat.Bavail)*bsize)
+}
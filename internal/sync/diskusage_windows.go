@@ -0,0 +1,21 @@
+//go:build windows
+
+// Package sync provides the Windows filesystem usage check.
+package sync
+
+import "golang.org/x/sys/windows"
+
+// diskUsagePercent reports the usage percent of the volume holding path.
+// Free space is measured as available to the current user, honouring quotas.
+func diskUsagePercent(path string) (int, error) {
+	dir, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+
+	var freeToCaller, total, totalFree uint64
+	if err := windows.GetDiskFreeSpaceEx(dir, &freeToCaller, &total, &totalFree); err != nil {
+		return 0, err
+	}
+	return usagePercent(total, freeToCaller)
+}
@@ -0,0 +1,57 @@
+// Package sync provides the post-download and post-run hooks.
+package sync
+
+import (
+	"strconv"
+
+	"github.com/haepapa/getblobz/internal/hook"
+	"github.com/haepapa/getblobz/internal/storage"
+)
+
+// runPostDownloadHook runs the post-download hook for a downloaded blob. The
+// returned error is only non-nil when hook failures are configured to fail
+// the blob.
+func (s *Syncer) runPostDownloadHook(workerID int, blob *storage.BlobState) error {
+	if s.postDownload == nil {
+		return nil
+	}
+
+	return s.runHook(s.postDownload, map[string]string{
+		"path":      blob.LocalPath,
+		"blob":      blob.BlobName,
+		"container": s.cfg.Sync.Container,
+	}, "worker", workerID, "blob", blob.BlobName)
+}
+
+// runPostRunHook runs the post-run hook for a completed run. The returned
+// error is only non-nil when hook failures are configured to fail the run.
+func (s *Syncer) runPostRunHook(run *storage.SyncRun) error {
+	if s.postRun == nil {
+		return nil
+	}
+
+	return s.runHook(s.postRun, map[string]string{
+		"run_id":     strconv.FormatInt(run.ID, 10),
+		"status":     run.Status,
+		"container":  s.cfg.Sync.Container,
+		"downloaded": strconv.FormatInt(run.DownloadedFiles, 10),
+		"failed":     strconv.FormatInt(run.FailedFiles, 10),
+	}, "run_id", run.ID)
+}
+
+// runHook runs cmd, logging its output along with fields.
+func (s *Syncer) runHook(cmd *hook.Command, vars map[string]string, fields ...interface{}) error {
+	result, err := cmd.Run(s.ctx, s.cfg.Hooks.Timeout, vars)
+	if result.Stdout != "" || result.Stderr != "" {
+		s.logger.Infow("Hook output", append(fields, "stdout", result.Stdout, "stderr", result.Stderr)...)
+	}
+	if err == nil {
+		return nil
+	}
+
+	s.logger.Warnw("Hook failed", append(fields, "error", err)...)
+	if s.cfg.Hooks.FailOnError {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+// Package sync provides the download manifest written when a run completes.
+package sync
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// manifestEntry is one line of the download manifest.
+type manifestEntry struct {
+	Blob         string    `json:"blob"`
+	LocalPath    string    `json:"local_path"`
+	Size         int64     `json:"size"`
+	ContentMD5   string    `json:"content_md5,omitempty"`
+	ContentType  string    `json:"content_type,omitempty"`
+	LastModified time.Time `json:"last_modified"`
+}
+
+// writeManifest writes the files downloaded by the current run, or every
+// tracked file with manifest_all, to the manifest path as newline-delimited
+// JSON. The manifest is replaced atomically, so readers never see a partial
+// file.
+func (s *Syncer) writeManifest() (err error) {
+	path := s.cfg.Sync.ManifestPath
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create manifest directory: %w", err)
+		}
+	}
+
+	tmpPath := path + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("failed to create manifest: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = file.Close()
+			_ = os.Remove(tmpPath)
+		}
+	}()
+
+	runID := s.runID
+	if s.cfg.Sync.ManifestAll {
+		runID = 0
+	}
+
+	w := bufio.NewWriter(file)
+	enc := json.NewEncoder(w)
+	var count int64
+	var afterID int64
+	for {
+		page, err := s.db.GetSyncedBlobsPage(runID, afterID, pendingPageSize)
+		if err != nil {
+			return fmt.Errorf("failed to read downloaded blobs: %w", err)
+		}
+		if len(page) == 0 {
+			break
+		}
+
+		for _, blob := range page {
+			entry := manifestEntry{
+				Blob:         blob.BlobName,
+				LocalPath:    blob.LocalPath,
+				Size:         blob.SizeBytes,
+				ContentType:  blob.ContentType,
+				LastModified: blob.LastModified,
+			}
+			if blob.ContentMD5 != nil {
+				entry.ContentMD5 = *blob.ContentMD5
+			}
+			if err := enc.Encode(entry); err != nil {
+				return fmt.Errorf("failed to write manifest: %w", err)
+			}
+			count++
+		}
+		afterID = page[len(page)-1].ID
+	}
+
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace manifest: %w", err)
+	}
+
+	s.logger.Infow("Manifest written", "path", path, "files", count, "all", s.cfg.Sync.ManifestAll)
+	return nil
+}
@@ -0,0 +1,94 @@
+// Package sync provides a memory governor that back-pressures download workers.
+package sync
+
+import (
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// memoryHighWaterPercent is the share of the memory limit at which workers
+// stop starting new downloads.
+const memoryHighWaterPercent = 90
+
+// cgroupMemoryLimitFiles are the cgroup v2 and v1 memory limit locations.
+var cgroupMemoryLimitFiles = []string{
+	"/sys/fs/cgroup/memory.max",
+	"/sys/fs/cgroup/memory/memory.limit_in_bytes",
+}
+
+// memoryLimitBytes returns the memory limit to enforce. An explicit
+// MaxMemoryMB wins; 0 auto-detects a cgroup limit. It returns 0 when no
+// limit applies.
+func memoryLimitBytes(maxMemoryMB int) int64 {
+	if maxMemoryMB > 0 {
+		return int64(maxMemoryMB) * 1024 * 1024
+	}
+
+	for _, path := range cgroupMemoryLimitFiles {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		value := strings.TrimSpace(string(data))
+		if value == "max" {
+			return 0
+		}
+		limit, err := strconv.ParseInt(value, 10, 64)
+		// cgroup v1 reports a huge sentinel value when unlimited.
+		if err != nil || limit <= 0 || limit >= 1<<62 {
+			return 0
+		}
+		return limit
+	}
+
+	return 0
+}
+
+// currentMemoryBytes approximates the process's resident memory as the
+// memory the Go runtime holds from the OS.
+func currentMemoryBytes() int64 {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	return int64(mem.Sys - mem.HeapReleased)
+}
+
+// waitForMemory blocks a worker from starting a new download while memory
+// usage is above the high-water mark. Downloads already in flight continue,
+// and a worker is always allowed through when none are active. It returns
+// false if the sync is cancelled while waiting.
+func (s *Syncer) waitForMemory() bool {
+	if s.memoryLimit <= 0 {
+		return true
+	}
+
+	highWater := s.memoryLimit * memoryHighWaterPercent / 100
+	for s.activeWorkers.Load() > 0 {
+		usage := currentMemoryBytes()
+		if usage < highWater {
+			break
+		}
+
+		if !s.memoryPressure.Swap(true) {
+			s.logger.Warnw("Memory governor engaged; pausing new downloads",
+				"usage_mb", usage/(1024*1024),
+				"limit_mb", s.memoryLimit/(1024*1024),
+			)
+			debug.FreeOSMemory()
+		}
+
+		select {
+		case <-s.ctx.Done():
+			return false
+		case <-time.After(throttlePollInterval):
+		}
+	}
+
+	if s.memoryPressure.Swap(false) {
+		s.logger.Infow("Memory governor released; resuming new downloads")
+	}
+	return true
+}
@@ -0,0 +1,140 @@
+// Package sync provides periodic performance metric collection during sync runs.
+package sync
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/haepapa/getblobz/internal/storage"
+)
+
+// cpuSample holds cumulative CPU times read from /proc/stat.
+type cpuSample struct {
+	idle  uint64
+	total uint64
+}
+
+// readCPUSample reads the aggregate CPU times from /proc/stat.
+func readCPUSample() (cpuSample, error) {
+	file, err := os.Open("/proc/stat")
+	if err != nil {
+		return cpuSample{}, err
+	}
+	defer func() { _ = file.Close() }()
+
+	scanner := bufio.NewScanner(file)
+	if !scanner.Scan() {
+		return cpuSample{}, fmt.Errorf("empty /proc/stat")
+	}
+
+	fields := strings.Fields(scanner.Text())
+	if len(fields) < 5 || fields[0] != "cpu" {
+		return cpuSample{}, fmt.Errorf("unexpected /proc/stat format")
+	}
+
+	var sample cpuSample
+	for i, field := range fields[1:] {
+		v, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return cpuSample{}, fmt.Errorf("unexpected /proc/stat value %q", field)
+		}
+		sample.total += v
+		// idle and iowait are the 4th and 5th values.
+		if i == 3 || i == 4 {
+			sample.idle += v
+		}
+	}
+
+	return sample, nil
+}
+
+// cpuPercent returns the system CPU utilisation between two samples.
+func cpuPercent(prev, cur cpuSample) (float64, bool) {
+	if cur.total <= prev.total {
+		return 0, false
+	}
+	total := cur.total - prev.total
+	idle := cur.idle - prev.idle
+	return float64(total-idle) / float64(total) * 100, true
+}
+
+// collectMetrics periodically records worker activity, download rates, and
+// resource usage for the current run.
+func (s *Syncer) collectMetrics(ctx context.Context) {
+	defer s.monitors.Done()
+
+	ticker := time.NewTicker(s.cfg.Performance.MetricsInterval)
+	defer ticker.Stop()
+
+	lastTime := time.Now()
+	lastFiles := s.downloadedFiles.Load()
+	lastBytes := s.downloadedBytes.Load()
+	lastCPU, cpuErr := readCPUSample()
+
+	for {
+		var now time.Time
+		select {
+		case <-ctx.Done():
+			return
+		case now = <-ticker.C:
+		}
+
+		files := s.downloadedFiles.Load()
+		bytes := s.downloadedBytes.Load()
+		elapsed := now.Sub(lastTime).Seconds()
+
+		filesPerSec := float64(files-lastFiles) / elapsed
+		mbps := float64(bytes-lastBytes) * 8 / 1e6 / elapsed
+		active := int(s.activeWorkers.Load())
+
+		var mem runtime.MemStats
+		runtime.ReadMemStats(&mem)
+		memoryMB := int64(mem.Sys / (1024 * 1024))
+
+		metric := &storage.PerformanceMetric{
+			SyncRunID:               s.runID,
+			Timestamp:               now,
+			MemoryMB:                &memoryMB,
+			NetworkMbps:             &mbps,
+			ActiveWorkers:           &active,
+			DownloadRateFilesPerSec: &filesPerSec,
+			DownloadRateMbps:        &mbps,
+			// Memory back-pressure is reported as throttling too.
+			Throttled: s.throttled.Load() || s.memoryPressure.Load(),
+		}
+
+		if cpuErr == nil {
+			if cur, err := readCPUSample(); err == nil {
+				if pct, ok := cpuPercent(lastCPU, cur); ok {
+					metric.CPUPercent = &pct
+				}
+				lastCPU = cur
+			}
+		}
+
+		if err := s.db.RecordMetric(metric); err != nil {
+			s.logger.Warnw("Failed to record performance metric", "error", err)
+		}
+
+		s.logger.Debugw("Performance metrics",
+			"active_workers", active,
+			"files_per_sec", filesPerSec,
+			"mbps", mbps,
+			"memory_mb", memoryMB,
+			"throttled", s.throttled.Load(),
+			"memory_governor", s.memoryPressure.Load(),
+		)
+
+		if s.scaler != nil {
+			s.scaleWorkers(mbps)
+		}
+
+		lastTime, lastFiles, lastBytes = now, files, bytes
+	}
+}
@@ -0,0 +1,134 @@
+// Package sync provides the interactive progress display for downloads.
+package sync
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// progressInterval is how often the progress display is redrawn.
+const progressInterval = 500 * time.Millisecond
+
+// progressWindow is the span of recent samples the transfer rate and ETA
+// are computed over, so they follow changes in throughput.
+const progressWindow = 10 * time.Second
+
+// progressBarWidth is the number of cells in the progress bar.
+const progressBarWidth = 24
+
+// progressSample is a reading of the cumulative run counters.
+type progressSample struct {
+	at    time.Time
+	files int64
+	bytes int64
+}
+
+// progressRate computes a rolling transfer rate from cumulative samples.
+type progressRate struct {
+	samples []progressSample
+}
+
+// add records a sample and drops samples older than progressWindow, keeping
+// the newest of them as the base of the window.
+func (r *progressRate) add(sample progressSample) {
+	r.samples = append(r.samples, sample)
+	cutoff := sample.at.Add(-progressWindow)
+	drop := 0
+	for drop+1 < len(r.samples) && !r.samples[drop+1].at.After(cutoff) {
+		drop++
+	}
+	r.samples = r.samples[drop:]
+}
+
+// rates returns files and bytes per second across the window.
+func (r *progressRate) rates() (filesPerSec, bytesPerSec float64) {
+	if len(r.samples) < 2 {
+		return 0, 0
+	}
+	first, last := r.samples[0], r.samples[len(r.samples)-1]
+	elapsed := last.at.Sub(first.at).Seconds()
+	if elapsed <= 0 {
+		return 0, 0
+	}
+	return float64(last.files-first.files) / elapsed, float64(last.bytes-first.bytes) / elapsed
+}
+
+// SetProgressOutput enables a progress display written to w while blobs are
+// downloading. It is meant for terminals; the display redraws a single line.
+func (s *Syncer) SetProgressOutput(w io.Writer) {
+	s.progress = w
+}
+
+// reportProgress redraws the progress display from the run counters until
+// ctx is cancelled, then leaves the final state on its own line.
+func (s *Syncer) reportProgress(ctx context.Context) {
+	defer s.monitors.Done()
+
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+
+	var rate progressRate
+	draw := func(now time.Time) {
+		done := s.downloadedFiles.Load() + s.failedFiles.Load()
+		bytes := s.downloadedBytes.Load()
+		rate.add(progressSample{at: now, files: done, bytes: bytes})
+		filesPerSec, bytesPerSec := rate.rates()
+
+		// The cursor is returned to the start of the line, so a log line
+		// written between redraws overwrites the display instead of being
+		// appended to it.
+		line := formatProgress(done, s.totalFiles.Load(), bytes, filesPerSec, bytesPerSec)
+		_, _ = fmt.Fprintf(s.progress, "\r\x1b[K%s\r", line)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			draw(time.Now())
+			_, _ = fmt.Fprintln(s.progress)
+			return
+		case now := <-ticker.C:
+			draw(now)
+		}
+	}
+}
+
+// formatProgress renders one line of the progress display.
+func formatProgress(done, total, bytes int64, filesPerSec, bytesPerSec float64) string {
+	if total < done {
+		total = done
+	}
+
+	filled := 0
+	if total > 0 {
+		filled = int(done * progressBarWidth / total)
+	}
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", progressBarWidth-filled)
+
+	eta := "--"
+	if remaining := total - done; remaining == 0 && total > 0 {
+		eta = "0s"
+	} else if filesPerSec > 0 {
+		eta = time.Duration(float64(remaining) / filesPerSec * float64(time.Second)).Round(time.Second).String()
+	}
+
+	return fmt.Sprintf("[%s] %d/%d files  %s  %s/s  ETA %s",
+		bar, done, total, formatBytes(bytes), formatBytes(int64(bytesPerSec)), eta)
+}
+
+// formatBytes renders a byte count with a binary unit suffix.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
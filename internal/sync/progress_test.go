@@ -0,0 +1,61 @@
+package sync
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatProgress(t *testing.T) {
+	tests := []struct {
+		name                     string
+		done, total, bytes       int64
+		filesPerSec, bytesPerSec float64
+		want                     []string
+	}{
+		{
+			name: "starting",
+			want: []string{"[                        ]", "0/0 files", "0 B", "ETA --"},
+		},
+		{
+			name: "halfway",
+			done: 50, total: 100, bytes: 3 * 1024 * 1024,
+			filesPerSec: 10, bytesPerSec: 512 * 1024,
+			want: []string{"[============            ]", "50/100 files", "3.0 MiB", "512.0 KiB/s", "ETA 5s"},
+		},
+		{
+			name: "stalled",
+			done: 10, total: 20,
+			want: []string{"10/20 files", "ETA --"},
+		},
+		{
+			name: "finished",
+			done: 20, total: 20, bytes: 2048,
+			want: []string{"[========================]", "20/20 files", "2.0 KiB", "ETA 0s"},
+		},
+	}
+
+	for _, tt := range tests {
+		got := formatProgress(tt.done, tt.total, tt.bytes, tt.filesPerSec, tt.bytesPerSec)
+		for _, want := range tt.want {
+			if !strings.Contains(got, want) {
+				t.Errorf("%s: formatProgress() = %q, want it to contain %q", tt.name, got, want)
+			}
+		}
+	}
+}
+
+func TestProgressRate(t *testing.T) {
+	var rate progressRate
+	start := time.Now()
+
+	// A slow start falls out of the window once throughput picks up.
+	rate.add(progressSample{at: start, files: 0, bytes: 0})
+	rate.add(progressSample{at: start.Add(10 * time.Second), files: 1, bytes: 100})
+	rate.add(progressSample{at: start.Add(20 * time.Second), files: 21, bytes: 10100})
+
+	filesPerSec, bytesPerSec := rate.rates()
+	if filesPerSec != 2 || bytesPerSec != 1000 {
+		t.Errorf("rates() = %v files/s, %v bytes/s, want 2 and 1000", filesPerSec, bytesPerSec)
+	}
+}
@@ -0,0 +1,351 @@
+// Package sync provides the push workflow for uploading local files to Azure.
+package sync
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/haepapa/getblobz/internal/azure"
+	"github.com/haepapa/getblobz/internal/config"
+	"github.com/haepapa/getblobz/internal/storage"
+	"github.com/haepapa/getblobz/pkg/logger"
+)
+
+// Pusher manages uploading a local directory to a container.
+// It reads from the sync output path and writes blobs under the sync prefix,
+// mirroring the layout that Syncer downloads.
+type Pusher struct {
+	cfg    *config.Config
+	client *azure.Client
+	db     *storage.DB
+	logger *logger.Logger
+
+	uploaded atomic.Int64
+	skipped  atomic.Int64
+	failed   atomic.Int64
+
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// uploadTask describes a local file queued for upload.
+type uploadTask struct {
+	localPath string
+	blobName  string
+	size      int64
+	modTime   time.Time
+}
+
+// NewPusher creates a new Pusher instance.
+func NewPusher(cfg *config.Config, client *azure.Client, db *storage.DB, log *logger.Logger) *Pusher {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Pusher{
+		cfg:    cfg,
+		client: client,
+		db:     db.WithContainer(cfg.Sync.Container),
+		logger: log,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Start walks the source directory and uploads new or changed files using a worker pool.
+func (p *Pusher) Start() error {
+	p.logger.Infow("Push started",
+		"container", p.cfg.Sync.Container,
+		"source_path", p.cfg.Sync.OutputPath,
+		"prefix", p.cfg.Sync.Prefix,
+		"workers", p.cfg.Sync.Workers,
+	)
+
+	owned, err := p.ownedFiles()
+	if err != nil {
+		return err
+	}
+
+	queue := make(chan *uploadTask, p.cfg.Sync.Workers*2)
+	for i := 0; i < p.cfg.Sync.Workers; i++ {
+		p.wg.Add(1)
+		go p.worker(i, queue)
+	}
+
+	walkErr := filepath.WalkDir(p.cfg.Sync.OutputPath, func(localPath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p.cfg.Sync.TempDir != "" && absPath(localPath) == absPath(p.cfg.Sync.TempDir) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if _, ok := owned[absPath(localPath)]; ok {
+			return nil
+		}
+
+		rel, err := filepath.Rel(p.cfg.Sync.OutputPath, localPath)
+		if err != nil {
+			return err
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		task := &uploadTask{
+			localPath: localPath,
+			blobName:  path.Join(p.cfg.Sync.Prefix, filepath.ToSlash(rel)),
+			size:      info.Size(),
+			modTime:   info.ModTime(),
+		}
+
+		select {
+		case queue <- task:
+			return nil
+		case <-p.ctx.Done():
+			return p.ctx.Err()
+		}
+	})
+	close(queue)
+	p.wg.Wait()
+
+	p.logger.Infow("Push completed",
+		"uploaded", p.uploaded.Load(),
+		"skipped", p.skipped.Load(),
+		"failed", p.failed.Load(),
+	)
+
+	if walkErr != nil {
+		return fmt.Errorf("failed to walk source path: %w", walkErr)
+	}
+	if n := p.failed.Load(); n > 0 {
+		return fmt.Errorf("%d files failed to upload", n)
+	}
+
+	return nil
+}
+
+// Stop gracefully stops the push process.
+func (p *Pusher) Stop() {
+	p.logger.Info("Stopping push...")
+	p.cancel()
+	p.wg.Wait()
+}
+
+// worker is a goroutine that uploads files from the queue.
+func (p *Pusher) worker(id int, queue <-chan *uploadTask) {
+	defer p.wg.Done()
+
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case task, ok := <-queue:
+			if !ok {
+				return
+			}
+			p.processFile(id, task)
+		}
+	}
+}
+
+// processFile uploads a single file with retry logic, skipping it when it is
+// the unchanged local copy of a downloaded blob or the remote blob already
+// has the same MD5.
+func (p *Pusher) processFile(workerID int, task *uploadTask) {
+	state := &storage.UploadState{
+		BlobName:  task.blobName,
+		LocalPath: task.localPath,
+		SizeBytes: task.size,
+	}
+
+	localMD5, err := fileMD5(task.localPath)
+	if err != nil {
+		p.recordFailure(workerID, state, err)
+		return
+	}
+	md5Str := base64.StdEncoding.EncodeToString(localMD5)
+	state.ContentMD5 = &md5Str
+
+	if !p.cfg.Sync.ForceResync {
+		skip := p.downloadedUnchanged(task, localMD5)
+		if !skip {
+			remote, err := p.client.GetBlobProperties(p.ctx, p.cfg.Sync.Container, task.blobName)
+			if err != nil && !azure.IsNotFoundError(err) {
+				p.recordFailure(workerID, state, err)
+				return
+			}
+			skip = remote != nil && bytes.Equal(remote.ContentMD5, localMD5)
+		}
+		if skip {
+			state.Status = storage.BlobStatusSkipped
+			if err := p.db.UpsertUploadState(state); err != nil {
+				p.logger.Warnw("Failed to update upload state", "blob", task.blobName, "error", err)
+			}
+			p.skipped.Add(1)
+			return
+		}
+	}
+
+	var lastErr error
+	retry := p.cfg.Retry
+	for attempt := 0; attempt < retry.MaxAttempts; attempt++ {
+		if attempt > 0 {
+			delay := retryDelay(attempt, retry, lastErr)
+			p.logger.Infow("Retrying file upload",
+				"worker", workerID,
+				"blob", task.blobName,
+				"attempt", attempt+1,
+				"delay", delay,
+			)
+			if !sleepContext(p.ctx, delay) {
+				return
+			}
+		}
+
+		lastErr = p.uploadFile(task, localMD5)
+		if lastErr == nil {
+			now := time.Now()
+			state.Status = storage.BlobStatusUploaded
+			state.UploadedAt = &now
+			if err := p.db.UpsertUploadState(state); err != nil {
+				p.logger.Warnw("Failed to update upload state", "blob", task.blobName, "error", err)
+			}
+			p.uploaded.Add(1)
+
+			p.logger.Infow("Uploaded file",
+				"worker", workerID,
+				"blob", task.blobName,
+				"size", task.size,
+			)
+			return
+		}
+
+		if !isRetryable(lastErr) {
+			break
+		}
+	}
+
+	p.recordFailure(workerID, state, lastErr)
+}
+
+// downloadedUnchanged reports whether the file of task is the unchanged local
+// copy of a blob that sync downloaded. Large blobs usually have no Content-MD5
+// to compare, and uploading such a file again would only give the blob a new
+// ETag, which the next sync would download again.
+func (p *Pusher) downloadedUnchanged(task *uploadTask, localMD5 []byte) bool {
+	blob, err := p.db.GetBlobState(task.blobName)
+	if err != nil {
+		p.logger.Warnw("Failed to get blob state", "blob", task.blobName, "error", err)
+		return false
+	}
+	if blob == nil || (blob.Status != storage.BlobStatusDownloaded && blob.Status != storage.BlobStatusSkipped) {
+		return false
+	}
+	if blob.SizeBytes != task.size || absPath(blob.LocalPath) != absPath(task.localPath) {
+		return false
+	}
+	if blob.ContentMD5 != nil && *blob.ContentMD5 != "" {
+		want, err := decodeMD5(*blob.ContentMD5)
+		return err == nil && bytes.Equal(want, localMD5)
+	}
+	// Without a checksum, a file not modified since it was downloaded is
+	// taken to be unchanged.
+	return blob.LastSyncedAt != nil && !task.modTime.After(*blob.LastSyncedAt)
+}
+
+// uploadFile uploads a single local file whose MD5 has already been computed.
+func (p *Pusher) uploadFile(task *uploadTask, contentMD5 []byte) error {
+	file, err := os.Open(task.localPath)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	return p.client.UploadBlob(p.ctx, p.cfg.Sync.Container, task.blobName, file, task.size, contentMD5)
+}
+
+// recordFailure marks an upload as failed in the state database.
+func (p *Pusher) recordFailure(workerID int, state *storage.UploadState, err error) {
+	p.failed.Add(1)
+
+	state.Status = storage.BlobStatusFailed
+	errMsg := err.Error()
+	state.ErrorMessage = &errMsg
+
+	if dbErr := p.db.UpsertUploadState(state); dbErr != nil {
+		p.logger.Warnw("Failed to update failed upload state", "blob", state.BlobName, "error", dbErr)
+	}
+
+	p.logger.Errorw("Failed to upload file",
+		"worker", workerID,
+		"blob", state.BlobName,
+		"error", err,
+	)
+}
+
+// ownedFiles returns the absolute paths of files getblobz writes for itself,
+// which push never uploads: the state database with its journal and lock
+// files, the manifest, the log files, and partial downloads of tracked
+// blobs. Files in sync.temp_dir are skipped separately.
+func (p *Pusher) ownedFiles() (map[string]struct{}, error) {
+	owned := make(map[string]struct{})
+	for _, path := range StateFiles(p.cfg.State.Database) {
+		owned[absPath(path)] = struct{}{}
+	}
+	for _, path := range []string{p.cfg.Sync.ManifestPath, p.cfg.Logging.File, p.cfg.Logging.AuditFile} {
+		if path != "" {
+			owned[absPath(path)] = struct{}{}
+		}
+	}
+
+	tracked, err := p.db.WithContainer("").GetTrackedLocalPaths()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tracked paths: %w", err)
+	}
+	known := make(map[string]struct{}, len(tracked))
+	for path := range tracked {
+		known[absPath(path)] = struct{}{}
+	}
+	for path := range known {
+		// A blob whose own name ends in .tmp is a finished download.
+		if _, ok := known[path+tempSuffix]; !ok {
+			owned[path+tempSuffix] = struct{}{}
+		}
+	}
+
+	return owned, nil
+}
+
+// fileMD5 computes the MD5 digest of a local file.
+func fileMD5(localPath string) ([]byte, error) {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	hasher := md5.New()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return nil, fmt.Errorf("failed to hash file: %w", err)
+	}
+
+	return hasher.Sum(nil), nil
+}
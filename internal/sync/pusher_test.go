@@ -0,0 +1,151 @@
+package sync
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/haepapa/getblobz/internal/config"
+	"github.com/haepapa/getblobz/internal/storage"
+	"github.com/haepapa/getblobz/pkg/logger"
+)
+
+func TestPusher_OwnedFiles(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "state.db")
+
+	db, err := storage.Open(dbPath)
+	if err != nil {
+		t.Fatalf("failed to open state database: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	for _, name := range []string{"report.csv", "notes.tmp", "notes.tmp.tmp"} {
+		if err := db.UpsertBlobState(&storage.BlobState{
+			BlobName:     name,
+			BlobPath:     name,
+			LocalPath:    filepath.Join(dir, name),
+			LastModified: time.Now(),
+			ETag:         "etag",
+			FirstSeenAt:  time.Now(),
+			Status:       storage.BlobStatusDownloaded,
+		}); err != nil {
+			t.Fatalf("failed to insert blob state: %v", err)
+		}
+	}
+
+	cfg := config.Default()
+	cfg.Sync.Container = "test-container"
+	cfg.Sync.OutputPath = dir
+	cfg.State.Database = dbPath
+	cfg.Sync.ManifestPath = filepath.Join(dir, "manifest.json")
+	cfg.Logging.AuditFile = filepath.Join(dir, "audit.log")
+
+	p := &Pusher{cfg: cfg, db: db.WithContainer(cfg.Sync.Container)}
+	owned, err := p.ownedFiles()
+	if err != nil {
+		t.Fatalf("ownedFiles failed: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		owned bool
+	}{
+		{"state.db", true},
+		{"state.db-wal", true},
+		{filepath.Base(storage.LockPath(dbPath)), true},
+		{"manifest.json", true},
+		{"audit.log", true},
+		{"report.csv.tmp", true},
+		{"report.csv", false},
+		{"scratch.tmp", false},
+		// Tracked blobs are uploaded even when their names end in .tmp.
+		{"notes.tmp", false},
+		{"notes.tmp.tmp", false},
+	}
+	for _, tt := range tests {
+		_, ok := owned[filepath.Join(dir, tt.name)]
+		if ok != tt.owned {
+			t.Errorf("%s: owned = %v, want %v", tt.name, ok, tt.owned)
+		}
+	}
+}
+
+func TestPusher_DownloadedUnchanged(t *testing.T) {
+	dir := t.TempDir()
+	db, err := storage.Open(filepath.Join(dir, "state.db"))
+	if err != nil {
+		t.Fatalf("failed to open state database: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	scoped := db.WithContainer("test-container")
+
+	content := []byte("downloaded content")
+	sum := md5.Sum(content)
+	localMD5 := sum[:]
+	md5Str := base64.StdEncoding.EncodeToString(localMD5)
+	otherMD5 := base64.StdEncoding.EncodeToString(make([]byte, md5.Size))
+	synced := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	rows := []struct {
+		name   string
+		md5    *string
+		status string
+	}{
+		{"checksum.bin", &md5Str, storage.BlobStatusDownloaded},
+		{"edited.bin", &otherMD5, storage.BlobStatusDownloaded},
+		{"large.bin", nil, storage.BlobStatusDownloaded},
+		{"pending.bin", &md5Str, storage.BlobStatusPending},
+	}
+	for _, row := range rows {
+		if err := scoped.UpsertBlobState(&storage.BlobState{
+			BlobName:     row.name,
+			BlobPath:     row.name,
+			LocalPath:    filepath.Join(dir, row.name),
+			SizeBytes:    int64(len(content)),
+			ContentMD5:   row.md5,
+			LastModified: synced.Add(-time.Hour),
+			ETag:         "etag",
+			FirstSeenAt:  synced,
+			LastSyncedAt: &synced,
+			Status:       row.status,
+		}); err != nil {
+			t.Fatalf("failed to insert blob state: %v", err)
+		}
+	}
+
+	log, err := logger.New(logger.Config{Level: "error", Format: "json"})
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+	p := &Pusher{cfg: config.Default(), db: scoped, logger: log}
+
+	tests := []struct {
+		name    string
+		blob    string
+		size    int64
+		modTime time.Time
+		want    bool
+	}{
+		{"recorded checksum matches", "checksum.bin", int64(len(content)), synced.Add(time.Hour), true},
+		{"edited since download", "edited.bin", int64(len(content)), synced, false},
+		{"no checksum, untouched since download", "large.bin", int64(len(content)), synced.Add(-time.Hour), true},
+		{"no checksum, modified after download", "large.bin", int64(len(content)), synced.Add(time.Hour), false},
+		{"size changed", "large.bin", 1, synced.Add(-time.Hour), false},
+		{"not downloaded yet", "pending.bin", int64(len(content)), synced, false},
+		{"not a downloaded blob", "local-only.bin", int64(len(content)), synced, false},
+	}
+	for _, tt := range tests {
+		task := &uploadTask{
+			localPath: filepath.Join(dir, tt.blob),
+			blobName:  tt.blob,
+			size:      tt.size,
+			modTime:   tt.modTime,
+		}
+		if got := p.downloadedUnchanged(task, localMD5); got != tt.want {
+			t.Errorf("%s: downloadedUnchanged = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+// Package sync provides bandwidth limiting shared across download workers.
+package sync
+
+import (
+	"context"
+	"io"
+
+	"golang.org/x/time/rate"
+)
+
+// maxLimiterBurst caps the number of bytes a single write may consume at once.
+const maxLimiterBurst = 1024 * 1024
+
+// newBandwidthLimiter creates a token-bucket limiter for bytesPerSec.
+// It returns nil when bytesPerSec is zero, meaning unlimited.
+func newBandwidthLimiter(bytesPerSec int64) *rate.Limiter {
+	if bytesPerSec <= 0 {
+		return nil
+	}
+
+	burst := int(min(bytesPerSec, maxLimiterBurst))
+	return rate.NewLimiter(rate.Limit(bytesPerSec), burst)
+}
+
+// rateLimitedWriter throttles writes using a limiter shared by all workers,
+// so the aggregate rate stays under the limit regardless of worker count.
+type rateLimitedWriter struct {
+	ctx     context.Context
+	w       io.Writer
+	limiter *rate.Limiter
+}
+
+// Write waits for enough tokens before writing each burst-sized piece of p.
+func (w *rateLimitedWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := min(len(p), w.limiter.Burst())
+		if err := w.limiter.WaitN(w.ctx, n); err != nil {
+			return written, err
+		}
+
+		m, err := w.w.Write(p[:n])
+		written += m
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
+
+// throttle wraps w with the syncer's bandwidth limiter, if one is configured.
+// Waiting for the limiter stops when ctx is cancelled.
+func (s *Syncer) throttle(ctx context.Context, w io.Writer) io.Writer {
+	if s.limiter == nil {
+		return w
+	}
+	return &rateLimitedWriter{ctx: ctx, w: w, limiter: s.limiter}
+}
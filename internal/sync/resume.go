@@ -0,0 +1,47 @@
+// Package sync provides resumption of interrupted discovery.
+package sync
+
+import "time"
+
+// resumable reports whether discovery may continue from where an
+// interrupted one stopped. Pruning needs every blob name listed, a pinned
+// version holds back part of each page, a dry run records nothing to resume
+// from, and a forced resync must see blobs the interrupted run kept.
+func (s *Syncer) resumable() bool {
+	return !s.cfg.Sync.Mirror && !s.cfg.Sync.MirrorDryRun && s.cfg.Sync.Version == "" &&
+		!s.cfg.Sync.DryRun && !s.cfg.Sync.ForceResync
+}
+
+// resumeDiscovery returns the marker an interrupted discovery of the
+// container stopped at and the time that discovery started. It returns a
+// nil marker when discovery should list from the start.
+func (s *Syncer) resumeDiscovery() (*string, time.Time) {
+	if !s.resumable() {
+		return nil, time.Time{}
+	}
+
+	cp, err := s.db.GetCheckpoint(s.cfg.Sync.Container)
+	if err != nil {
+		s.logger.Warnw("Failed to get checkpoint; listing from the start", "error", err)
+		return nil, time.Time{}
+	}
+	// A marker only makes sense for the prefix it was listed under.
+	if cp == nil || cp.LastContinuationToken == nil || cp.DiscoveryStartedAt == nil ||
+		cp.DiscoveryPrefix != s.cfg.Sync.Prefix {
+		return nil, time.Time{}
+	}
+
+	return cp.LastContinuationToken, *cp.DiscoveryStartedAt
+}
+
+// saveDiscoveryProgress records that every blob before token has been
+// listed and recorded, so an interrupted discovery can continue from there.
+// A nil token marks the listing complete.
+func (s *Syncer) saveDiscoveryProgress(token *string) {
+	if !s.resumable() {
+		return
+	}
+	if err := s.db.SaveDiscoveryProgress(s.cfg.Sync.Container, s.cfg.Sync.Prefix, s.checkTime, token); err != nil {
+		s.logger.Warnw("Failed to save discovery progress", "error", err)
+	}
+}
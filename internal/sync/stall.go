@@ -0,0 +1,106 @@
+// Package sync provides the stall timeout applied to each blob download.
+package sync
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// errDownloadStalled is the cause of a download cancelled because no data
+// arrived for the configured blob timeout.
+var errDownloadStalled = errors.New("download stalled")
+
+// stallWatch cancels a download once no data has arrived for its timeout.
+// Measuring progress rather than total time lets large blobs take as long
+// as they need while a stalled transfer is still abandoned.
+type stallWatch struct {
+	timeout time.Duration
+	// last is the time of the most recent progress, in Unix nanoseconds.
+	last atomic.Int64
+}
+
+// watchStall returns a child of parent that is cancelled with
+// errDownloadStalled when no progress is reported for timeout. A zero
+// timeout disables the check. The stop function must be called once the
+// download finishes.
+func watchStall(parent context.Context, timeout time.Duration) (context.Context, *stallWatch, context.CancelFunc) {
+	if timeout <= 0 {
+		ctx, cancel := context.WithCancel(parent)
+		return ctx, nil, cancel
+	}
+
+	ctx, cancel := context.WithCancelCause(parent)
+	w := &stallWatch{timeout: timeout}
+	w.progress()
+
+	// Checking several times per timeout keeps the overshoot small.
+	ticker := time.NewTicker(timeout / 4)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if time.Since(time.Unix(0, w.last.Load())) >= timeout {
+					cancel(errDownloadStalled)
+					return
+				}
+			}
+		}
+	}()
+
+	return ctx, w, func() { cancel(context.Canceled) }
+}
+
+// progress records that data arrived.
+func (w *stallWatch) progress() {
+	if w != nil {
+		w.last.Store(time.Now().UnixNano())
+	}
+}
+
+// writer reports progress whenever data is written to dst.
+func (w *stallWatch) writer(dst io.Writer) io.Writer {
+	if w == nil {
+		return dst
+	}
+	return &stallWriter{w: dst, watch: w}
+}
+
+// writerAt reports progress whenever data is written to dst.
+func (w *stallWatch) writerAt(dst io.WriterAt) io.WriterAt {
+	if w == nil {
+		return dst
+	}
+	return &stallWriterAt{w: dst, watch: w}
+}
+
+type stallWriter struct {
+	w     io.Writer
+	watch *stallWatch
+}
+
+func (sw *stallWriter) Write(p []byte) (int, error) {
+	n, err := sw.w.Write(p)
+	if n > 0 {
+		sw.watch.progress()
+	}
+	return n, err
+}
+
+type stallWriterAt struct {
+	w     io.WriterAt
+	watch *stallWatch
+}
+
+func (sw *stallWriterAt) WriteAt(p []byte, off int64) (int, error) {
+	n, err := sw.w.WriteAt(p, off)
+	if n > 0 {
+		sw.watch.progress()
+	}
+	return n, err
+}
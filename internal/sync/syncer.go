@@ -3,34 +3,150 @@ package sync
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/haepapa/getblobz/internal/audit"
 	"github.com/haepapa/getblobz/internal/azure"
+	"github.com/haepapa/getblobz/internal/blobfilter"
 	"github.com/haepapa/getblobz/internal/config"
+	"github.com/haepapa/getblobz/internal/hook"
+	"github.com/haepapa/getblobz/internal/notify"
 	"github.com/haepapa/getblobz/internal/organizer"
 	"github.com/haepapa/getblobz/internal/storage"
+	"github.com/haepapa/getblobz/internal/telemetry"
 	"github.com/haepapa/getblobz/pkg/logger"
+	"golang.org/x/time/rate"
 )
 
+// ErrInterrupted is returned when a run is stopped before it completes.
+// The run is recorded as interrupted and can be continued with Resume.
+var ErrInterrupted = errors.New("sync interrupted")
+
+// pendingPageSize is the number of pending blobs read from the database at a time.
+const pendingPageSize = 500
+
+// discoveryFlushSize is the number of discovered blob states written per transaction.
+const discoveryFlushSize = 1000
+
+// checkpointSkew is subtracted from the checkpoint time during incremental
+// discovery to allow for clock differences between this host and Azure.
+const checkpointSkew = 5 * time.Minute
+
+// rehydrateTier is the access tier archived blobs are moved to when rehydrating.
+const rehydrateTier = "Hot"
+
+// notifyErrorCount is the number of blob errors included in a run notification.
+const notifyErrorCount = 10
+
+// BlobClient is the subset of Azure operations the syncer depends on.
+// It is satisfied by *azure.Client and allows stubbing in tests.
+type BlobClient interface {
+	ContainerExists(ctx context.Context, containerName string) (bool, error)
+	ListBlobs(ctx context.Context, containerName, prefix string, marker *string, maxResults int32) ([]*azure.BlobInfo, *string, error)
+	GetBlobProperties(ctx context.Context, containerName, blobName string) (*azure.BlobInfo, error)
+	ListBlobsWithOptions(ctx context.Context, containerName, prefix string, marker *string, maxResults int32, opts azure.ListOptions) ([]*azure.BlobInfo, *string, error)
+	DownloadBlobRange(ctx context.Context, containerName, blobName string, offset int64, etag string, writer io.Writer) error
+	DownloadBlobVersion(ctx context.Context, containerName, blobName, versionID string, writer io.Writer) error
+	DownloadBlobChunked(ctx context.Context, containerName, blobName, etag string, file io.WriterAt, size, chunkSize int64, concurrency int) error
+	DownloadBlobDecompressed(ctx context.Context, containerName, blobName string, writer, raw io.Writer) error
+	RehydrateBlob(ctx context.Context, containerName, blobName, targetTier string) error
+}
+
 // Syncer manages the blob synchronisation process.
 type Syncer struct {
 	cfg       *config.Config
-	client    *azure.Client
+	client    BlobClient
 	db        *storage.DB
 	logger    *logger.Logger
 	organizer *organizer.Organizer
+	filter    *blobfilter.Filter
+	limiter   *rate.Limiter
+	// memoryLimit is the memory governor limit in bytes (0 = disabled).
+	memoryLimit int64
+	// progress receives the progress display; nil disables it.
+	progress io.Writer
+	// webhook receives run summaries; nil when no webhook is configured.
+	webhook *notify.Webhook
+	// metrics records sync activity; nil when metrics are not exposed.
+	metrics *telemetry.Metrics
+	// audit records downloaded and deleted files; nil when disabled.
+	audit *audit.Log
+	// postDownload and postRun are the configured hooks, or nil.
+	postDownload *hook.Command
+	postRun      *hook.Command
 
 	runID   int64
 	workers int
-	wg      sync.WaitGroup
-	ctx     context.Context
-	cancel  context.CancelFunc
+	// checkTime is when the current run's discovery started; it becomes the
+	// checkpoint time once the run completes.
+	checkTime time.Time
+	// seen records blob names listed during discovery for mirror pruning.
+	seen map[string]struct{}
+	// claimed maps each local path, relative to the output path, to the blob
+	// listed first for it. It is only kept when unsafe names are rewritten.
+	claimed map[string]string
+	// heldVersions are the versions of the last blob of a discovery page,
+	// kept back until the next page completes them; see listBlobs.
+	heldVersions []*azure.BlobInfo
+	// queuedFiles and queuedBytes count the blobs the last discovery marked
+	// for download.
+	queuedFiles int64
+	queuedBytes int64
+	// tempCleaned is set once stale partial downloads have been cleaned up.
+	tempCleaned bool
+	// discovered feeds pending blobs to the workers while discovery is still
+	// listing; nil when discovery runs on its own.
+	discovered chan<- *storage.BlobState
+
+	throttled atomic.Bool
+	// scaler sizes the worker pool when adaptive workers are enabled, and
+	// workerLimit is the number of workers currently allowed to take blobs.
+	// throttledRequests counts throttled requests since the last sample.
+	scaler            *workerScaler
+	workerLimit       atomic.Int32
+	throttledRequests atomic.Int64
+	// memoryPressure is set while the memory governor is holding back workers.
+	memoryPressure atomic.Bool
+
+	// diskUsage measures filesystem usage; replaced in tests. Readings are
+	// cached for diskUsageCacheTTL since every download attempt checks them.
+	diskUsage   func(path string) (int, error)
+	diskMu      sync.Mutex
+	diskChecked time.Time
+	diskPercent int
+	diskErr     error
+
+	// Per-run counters updated by workers.
+	totalFiles      atomic.Int64
+	downloadedFiles atomic.Int64
+	failedFiles     atomic.Int64
+	downloadedBytes atomic.Int64
+	activeWorkers   atomic.Int32
+
+	wg       sync.WaitGroup
+	monitors sync.WaitGroup
+	// parent is the context every run derives its own context from.
+	parent context.Context
+	// ctx is the current run's context; ctxMu guards replacing it and
+	// cancel, which Stop may call from another goroutine.
+	ctxMu  sync.Mutex
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
 // New creates a new Syncer instance.
-func New(cfg *config.Config, client *azure.Client, db *storage.DB, log *logger.Logger) *Syncer {
+func New(cfg *config.Config, client BlobClient, db *storage.DB, log *logger.Logger) *Syncer {
 	ctx, cancel := context.WithCancel(context.Background())
 	org := organizer.New(&cfg.Sync.FolderOrganization, cfg.Sync.OutputPath)
 
@@ -38,56 +154,406 @@ func New(cfg *config.Config, client *azure.Client, db *storage.DB, log *logger.L
 		log.Warnw("Failed to load organizer state", "error", err)
 	}
 
+	// Validate has already checked the patterns.
+	filter, _ := blobfilter.New(cfg.Sync.Include, cfg.Sync.Exclude)
+
+	memoryLimit := memoryLimitBytes(cfg.Performance.MaxMemoryMB)
+	if memoryLimit > 0 {
+		log.Infow("Memory governor enabled", "limit_mb", memoryLimit/(1024*1024))
+	}
+
+	var webhook *notify.Webhook
+	if cfg.Notifications.WebhookURL != "" {
+		webhook = notify.NewWebhook(cfg.Notifications.WebhookURL)
+	}
+
+	// Validate has already checked the hook templates.
+	var postDownload, postRun *hook.Command
+	if cfg.Hooks.PostDownload != "" {
+		postDownload, _ = hook.Parse(cfg.Hooks.PostDownload, hook.PostDownloadTokens)
+	}
+	if cfg.Hooks.PostRun != "" {
+		postRun, _ = hook.Parse(cfg.Hooks.PostRun, hook.PostRunTokens)
+	}
+
 	return &Syncer{
-		cfg:       cfg,
-		client:    client,
-		db:        db,
-		logger:    log,
-		organizer: org,
-		workers:   cfg.Sync.Workers,
-		ctx:       ctx,
-		cancel:    cancel,
+		cfg:          cfg,
+		client:       client,
+		db:           db.WithContainer(cfg.Sync.Container),
+		logger:       log,
+		organizer:    org,
+		filter:       filter,
+		limiter:      newBandwidthLimiter(cfg.Performance.BandwidthBytes),
+		memoryLimit:  memoryLimit,
+		webhook:      webhook,
+		postDownload: postDownload,
+		postRun:      postRun,
+		workers:      cfg.Sync.Workers,
+		diskUsage:    diskUsagePercent,
+		parent:       context.Background(),
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+}
+
+// SetContext makes every later run a child of ctx, so that cancelling ctx
+// interrupts the run in progress and any run started afterwards.
+func (s *Syncer) SetContext(ctx context.Context) {
+	s.parent = ctx
+}
+
+// newRunContext gives the next run a fresh context, so a syncer stopped
+// during one watch run can still start the next.
+func (s *Syncer) newRunContext() {
+	s.ctxMu.Lock()
+	defer s.ctxMu.Unlock()
+	s.cancel()
+	s.ctx, s.cancel = context.WithCancel(s.parent)
+}
+
+// SetMetrics records the syncer's activity in m.
+func (s *Syncer) SetMetrics(m *telemetry.Metrics) {
+	s.metrics = m
+}
+
+// SetAuditLog records every downloaded and deleted file in l.
+func (s *Syncer) SetAuditLog(l *audit.Log) {
+	s.audit = l
+}
+
+// recordAudit appends an audit entry for blob, logging any failure.
+func (s *Syncer) recordAudit(action string, blob *storage.BlobState) {
+	entry := audit.Entry{
+		Action:    action,
+		RunID:     s.runID,
+		Container: s.cfg.Sync.Container,
+		Blob:      blob.BlobName,
+		LocalPath: blob.LocalPath,
+		Size:      blob.SizeBytes,
+		ETag:      blob.ETag,
+	}
+	if blob.ContentMD5 != nil {
+		entry.ContentMD5 = *blob.ContentMD5
+	}
+	if err := s.audit.Record(entry); err != nil {
+		s.logger.Warnw("Failed to write audit entry", "blob", blob.BlobName, "error", err)
 	}
 }
 
 // Start begins the synchronisation process.
 // It orchestrates discovery, download, and completion phases.
 func (s *Syncer) Start() error {
-	var err error
-	s.runID, err = s.db.CreateSyncRun()
+	s.newRunContext()
+
+	if err := s.checkContainer(); err != nil {
+		return err
+	}
+
+	if s.cfg.Sync.DryRun {
+		return s.dryRun()
+	}
+
+	stopMonitors, err := s.startRun("Sync started")
+	if err != nil {
+		return err
+	}
+	defer stopMonitors()
+
+	if err := s.runDiscovery(); err != nil {
+		return err
+	}
+
+	if s.cfg.Sync.Mirror || s.cfg.Sync.MirrorDryRun {
+		if err := s.prune(); err != nil {
+			return s.abortRun("prune", err)
+		}
+	}
+
+	// The state database stays authoritative: blobs that did not fit in the
+	// queue during discovery, and blobs left pending by earlier runs, are
+	// still pending there and are downloaded now. A stopped sync must not
+	// start a second worker pool while Stop is waiting for the first.
+	if s.ctx.Err() == nil {
+		if err := s.download(); err != nil {
+			return s.abortRun("download", err)
+		}
+	}
+
+	return s.finishRun()
+}
+
+// runDiscovery runs the discovery phase, ending the run if it fails.
+//
+// Downloads start as soon as discovery records the first pending blobs,
+// rather than after the whole container has been listed. Any other download
+// order needs the whole listing, so it waits for the download phase.
+func (s *Syncer) runDiscovery() error {
+	if !s.streamsDiscovery() {
+		if err := s.discovery(); err != nil {
+			return s.abortRun("discovery", err)
+		}
+		return nil
+	}
+
+	queue, stopWorkers, err := s.startWorkers(discoveryFlushSize)
+	if err != nil {
+		return s.abortRun("download", err)
+	}
+	s.discovered = queue
+	err = s.discovery()
+	s.discovered = nil
+	stopWorkers()
+	if err != nil {
+		return s.abortRun("discovery", err)
+	}
+	return nil
+}
+
+// streamsDiscovery reports whether blobs are downloaded while discovery is
+// still listing, which only the listed download order allows.
+func (s *Syncer) streamsDiscovery() bool {
+	order := s.cfg.Sync.DownloadOrder
+	return order == "" || order == storage.DownloadOrderListed
+}
+
+// Resume reopens the most recent interrupted run and continues downloading
+// its pending blobs. If the run was interrupted while listing, the listing
+// continues from where it stopped; otherwise the container is not listed
+// again. If there is no interrupted run, or in dry-run mode, it starts a
+// normal sync instead.
+func (s *Syncer) Resume() error {
+	if s.cfg.Sync.DryRun {
+		return s.Start()
+	}
+
+	run, err := s.db.GetLatestSyncRun(storage.SyncStatusInterrupted)
 	if err != nil {
-		return fmt.Errorf("failed to create sync run: %w", err)
+		return fmt.Errorf("failed to find interrupted sync run: %w", err)
+	}
+	if run == nil {
+		s.logger.Info("No interrupted sync run to resume; starting a new sync")
+		return s.Start()
+	}
+
+	s.newRunContext()
+	if err := s.checkContainer(); err != nil {
+		return err
+	}
+
+	run.Status = storage.SyncStatusRunning
+	run.CompletedAt = nil
+	if err := s.db.UpdateSyncRun(run); err != nil {
+		return fmt.Errorf("failed to reopen sync run: %w", err)
 	}
 
-	s.logger.Infow("Sync started",
+	s.runID = run.ID
+	s.logger.Infow("Sync resumed",
 		"container", s.cfg.Sync.Container,
 		"output_path", s.cfg.Sync.OutputPath,
 		"workers", s.workers,
 		"run_id", s.runID,
 	)
 
+	// Blobs processed before the interruption keep counting towards the run;
+	// download adds the blobs that are still pending.
+	s.resetCounters()
+	s.totalFiles.Store(run.DownloadedFiles + run.FailedFiles)
+	s.downloadedFiles.Store(run.DownloadedFiles)
+	s.failedFiles.Store(run.FailedFiles)
+	s.downloadedBytes.Store(run.TotalBytes)
+
+	stopMonitors := s.startMonitors()
+	defer stopMonitors()
+
+	if marker, _ := s.resumeDiscovery(); marker != nil {
+		if err := s.runDiscovery(); err != nil {
+			return err
+		}
+	}
+
+	if s.ctx.Err() == nil {
+		if err := s.download(); err != nil {
+			return s.abortRun("download", err)
+		}
+	}
+
+	return s.finishRun()
+}
+
+// Retry resets failed blobs to pending and runs only the download phase,
+// without listing the container. If errorType is not empty, only blobs whose
+// most recent error has that type are retried.
+func (s *Syncer) Retry(errorType string) error {
+	if s.cfg.Sync.DryRun {
+		return fmt.Errorf("retry does not support dry-run mode")
+	}
+
+	s.newRunContext()
+	if err := s.checkContainer(); err != nil {
+		return err
+	}
+
+	reset, err := s.db.ResetFailedToPending(errorType)
+	if err != nil {
+		return fmt.Errorf("failed to reset failed blobs: %w", err)
+	}
+	s.logger.Infow("Reset failed blobs to pending", "count", reset, "error_type", errorType)
+
+	stopMonitors, err := s.startRun("Retry started")
+	if err != nil {
+		return err
+	}
+	defer stopMonitors()
+
+	if err := s.download(); err != nil {
+		return s.abortRun("download", err)
+	}
+
+	return s.finishRun()
+}
+
+// dryRun runs discovery, and pruning if enabled, without recording a sync
+// run, writing blob state, or touching local files, then reports what a real
+// sync would download.
+func (s *Syncer) dryRun() error {
+	s.logger.Infow("Dry run started",
+		"container", s.cfg.Sync.Container,
+		"output_path", s.cfg.Sync.OutputPath,
+	)
+
+	s.resetCounters()
 	if err := s.discovery(); err != nil {
-		s.markRunFailed(err)
 		return fmt.Errorf("discovery failed: %w", err)
 	}
 
-	if err := s.download(); err != nil {
-		s.markRunFailed(err)
-		return fmt.Errorf("download failed: %w", err)
+	if s.cfg.Sync.Mirror || s.cfg.Sync.MirrorDryRun {
+		if err := s.prune(); err != nil {
+			return fmt.Errorf("prune failed: %w", err)
+		}
+	}
+
+	s.logger.Infow("Dry run completed",
+		"would_download", s.queuedFiles,
+		"would_download_bytes", s.queuedBytes,
+	)
+	return nil
+}
+
+// finishRun completes the current run, or marks it interrupted if the sync
+// was stopped before all pending blobs were processed.
+func (s *Syncer) finishRun() error {
+	if s.ctx.Err() != nil {
+		s.markRunInterrupted()
+		return ErrInterrupted
 	}
 
 	if err := s.complete(); err != nil {
-		s.markRunFailed(err)
-		return fmt.Errorf("completion failed: %w", err)
+		return s.abortRun("completion", err)
+	}
+
+	return nil
+}
+
+// abortRun records the outcome of a run that stopped in phase with err. A
+// run stopped by Stop is marked interrupted and ErrInterrupted is returned.
+func (s *Syncer) abortRun(phase string, err error) error {
+	if s.ctx.Err() != nil {
+		s.markRunInterrupted()
+		return ErrInterrupted
+	}
+
+	s.markRunFailed(err)
+	return fmt.Errorf("%s failed: %w", phase, err)
+}
+
+// startRun records a new sync run, resets the per-run counters, and starts
+// the background monitors. The returned function stops the monitors.
+func (s *Syncer) startRun(message string) (func(), error) {
+	var err error
+	s.runID, err = s.db.CreateSyncRun()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sync run: %w", err)
+	}
+
+	s.logger.Infow(message,
+		"container", s.cfg.Sync.Container,
+		"output_path", s.cfg.Sync.OutputPath,
+		"workers", s.workers,
+		"run_id", s.runID,
+	)
+
+	if err := s.cleanStaleTempFiles(); err != nil {
+		s.logger.Warnw("Failed to clean up stale partial downloads", "error", err)
+	}
+
+	s.resetCounters()
+	return s.startMonitors(), nil
+}
+
+// startMonitors launches the background monitors for the current run and
+// returns a function that stops them and waits for them to exit.
+func (s *Syncer) startMonitors() func() {
+	ctx, cancel := context.WithCancel(s.ctx)
+
+	if s.cfg.Performance.AutoThrottle {
+		s.monitors.Add(1)
+		go s.monitorLoad(ctx)
+	}
+
+	if s.cfg.Performance.MetricsInterval > 0 {
+		s.monitors.Add(1)
+		go s.collectMetrics(ctx)
+	}
+
+	if s.progress != nil {
+		s.monitors.Add(1)
+		go s.reportProgress(ctx)
+	}
+
+	return func() {
+		cancel()
+		s.monitors.Wait()
+	}
+}
+
+// resetCounters clears the per-run download counters and discovery state.
+func (s *Syncer) resetCounters() {
+	s.checkTime = time.Time{}
+	s.totalFiles.Store(0)
+	s.downloadedFiles.Store(0)
+	s.failedFiles.Store(0)
+	s.downloadedBytes.Store(0)
+	s.activeWorkers.Store(0)
+	s.memoryPressure.Store(false)
+
+	s.scaler = nil
+	s.workerLimit.Store(int32(s.workers))
+	s.throttledRequests.Store(0)
+	if s.cfg.Performance.AdaptiveWorkers {
+		s.scaler = newWorkerScaler(s.workers)
+		s.workerLimit.Store(int32(s.scaler.current))
 	}
+}
 
+// checkContainer verifies the container is reachable before a sync run is created.
+func (s *Syncer) checkContainer() error {
+	exists, err := s.client.ContainerExists(s.ctx, s.cfg.Sync.Container)
+	if err != nil {
+		return fmt.Errorf("container '%s' not found or inaccessible with the provided credentials: %w", s.cfg.Sync.Container, err)
+	}
+	if !exists {
+		return fmt.Errorf("container '%s' not found or inaccessible with the provided credentials", s.cfg.Sync.Container)
+	}
 	return nil
 }
 
 // Stop gracefully stops the synchronisation process.
 func (s *Syncer) Stop() {
 	s.logger.Info("Stopping sync...")
-	s.cancel()
+	s.ctxMu.Lock()
+	cancel := s.cancel
+	s.ctxMu.Unlock()
+	cancel()
 	s.wg.Wait()
 }
 
@@ -99,73 +565,232 @@ func (s *Syncer) discovery() error {
 	var totalNew int64
 	var totalChanged int64
 	var totalSkipped int64
+	var totalArchived int64
+
+	var totalUnchanged int64
+	var totalMissing int64
+	var totalRejected int64
+	var totalDirectories int64
+	var totalFiltered int64
+	var totalOutsideWindow int64
+	var totalAdopted int64
 
-	var continuationToken *string
 	batchSize := int32(s.cfg.Sync.BatchSize)
 
+	// Blob states are written in batches; see flushBlobStates.
+	pending := make([]*storage.BlobState, 0, discoveryFlushSize)
+
+	started := time.Now()
+	s.checkTime = started
+	// Validate has already checked the bounds; relative ages count back from now.
+	modifiedAfter, _ := config.ParseTimeBound(s.cfg.Sync.ModifiedAfter, s.checkTime)
+	modifiedBefore, _ := config.ParseTimeBound(s.cfg.Sync.ModifiedBefore, s.checkTime)
+
+	// Blobs listed before an interruption are already recorded, so the
+	// listing continues after them. The run then only covers changes since
+	// the interrupted discovery started.
+	continuationToken, resumedAt := s.resumeDiscovery()
+	resuming := continuationToken != nil
+	// recorded holds while every blob listed so far has been recorded;
+	// progress is saved only until one is not, so a resumed listing
+	// never skips it.
+	recorded := true
+	if resuming {
+		s.checkTime = resumedAt
+		s.logger.Infow("Resuming interrupted discovery", "started_at", resumedAt)
+	}
+	since := s.incrementalSince()
+	if !since.IsZero() {
+		s.logger.Infow("Incremental discovery", "since", since)
+	}
+
+	s.queuedFiles = 0
+	s.queuedBytes = 0
+
+	s.heldVersions = nil
+	s.claimed = nil
+	if s.cfg.Sync.UnsafeNames == "rewrite" {
+		s.claimed = make(map[string]string)
+	}
+	s.seen = nil
+	if s.cfg.Sync.Mirror || s.cfg.Sync.MirrorDryRun {
+		s.seen = make(map[string]struct{})
+	}
+
 	for {
-		blobs, token, err := s.client.ListBlobs(
-			s.ctx,
-			s.cfg.Sync.Container,
-			s.cfg.Sync.Prefix,
-			batchSize,
-		)
+		blobs, token, err := s.listBlobs(continuationToken, batchSize)
+		if err != nil && resuming && s.ctx.Err() == nil && !isRetryable(err) {
+			// The stored token is stale: start the listing over.
+			s.logger.Warnw("Failed to resume discovery; listing from the start", "error", err)
+			resuming = false
+			continuationToken = nil
+			s.checkTime = started
+			continue
+		}
 		if err != nil {
+			s.flushDiscovered(pending)
 			return fmt.Errorf("failed to list blobs: %w", err)
 		}
+		resuming = false
 
 		for _, blob := range blobs {
+			// A page can hold thousands of blobs, each needing database
+			// work, so a stopped sync must not wait for the page to finish.
+			if err := s.ctx.Err(); err != nil {
+				s.flushDiscovered(pending)
+				return err
+			}
+
 			totalFound++
+			if s.seen != nil {
+				s.seen[blob.Name] = struct{}{}
+			}
+
+			// Filtered blobs still count as seen, so mirroring never deletes
+			// local copies of blobs the filters now exclude.
+			if !s.filter.Match(blob.Name) {
+				totalFiltered++
+				continue
+			}
+
+			lastModified := blob.LastModified
+
+			if (!modifiedAfter.IsZero() && lastModified.Before(modifiedAfter)) ||
+				(!modifiedBefore.IsZero() && !lastModified.Before(modifiedBefore)) {
+				totalOutsideWindow++
+				continue
+			}
+
+			if s.cfg.Sync.UnsafeNames != "rewrite" && organizer.EscapesRoot(blob.Path) {
+				totalRejected++
+				pending = append(pending, s.rejectUnsafeBlob(blob, lastModified, "blob name escapes the output directory"))
+				continue
+			}
+
+			// A rewritten name can coincide with another blob's, and two
+			// blobs downloading to one path would corrupt each other.
+			// Listing order is stable, so the same blob keeps the path on
+			// every run.
+			if s.claimed != nil {
+				relPath := organizer.LocalRelPath(blob.Path)
+				if owner, ok := s.claimed[relPath]; ok {
+					totalRejected++
+					pending = append(pending, s.rejectUnsafeBlob(blob, lastModified,
+						fmt.Sprintf("local path collides with blob %s", owner)))
+					continue
+				}
+				s.claimed[relPath] = blob.Name
+			}
+
+			if isDirectoryMarker(blob) {
+				totalDirectories++
+				pending = append(pending, s.createDirectory(blob, lastModified))
+				continue
+			}
 
 			existing, err := s.db.GetBlobState(blob.Name)
 			if err != nil {
 				s.logger.Warnw("Failed to get blob state", "blob", blob.Name, "error", err)
+				recorded = false
+				continue
+			}
+
+			// A synced blob whose local copy was deleted or truncated is
+			// downloaded again, whatever the blob itself looks like.
+			synced := existing != nil && isSynced(existing.Status)
+			if synced && !s.localCopyIntact(existing) {
+				synced = false
+				totalMissing++
+			}
+
+			// Only a pinned version is downloaded by ID; listings of
+			// versioned containers report the current version's ID too.
+			versionID := ""
+			if s.cfg.Sync.Version != "" {
+				versionID = blob.VersionID
+			}
+
+			// A synced blob last modified before the previous successful run cannot
+			// have changed, so it is not re-evaluated or rewritten, unless a
+			// different version of it is wanted now.
+			if synced && !since.IsZero() && lastModified.Before(since) && existing.VersionID == versionID {
+				totalUnchanged++
 				continue
 			}
 
+			localPath := s.organizer.GetTargetPath(blob.Name, blob.Path, lastModified)
 			status := storage.BlobStatusPending
 			isNew := existing == nil
 
-			if !isNew {
-				if !s.cfg.Sync.ForceResync {
-					if existing.ETag == blob.ETag && existing.LastModified.Format("2006-01-02T15:04:05Z") == blob.LastModified {
-						if s.cfg.Sync.SkipExisting {
-							status = storage.BlobStatusSkipped
-							totalSkipped++
-						} else {
-							totalChanged++
-						}
-					} else {
-						totalChanged++
-					}
-				}
-			} else {
+			if !s.cfg.Sync.ForceResync && s.keepLocalFile(existing, blob, synced, localPath) {
+				status = storage.BlobStatusSkipped
+				totalSkipped++
+			} else if isNew {
 				totalNew++
+			} else if !s.cfg.Sync.ForceResync {
+				totalChanged++
+			}
+
+			// A partial download of an earlier version cannot be resumed.
+			if existing != nil && !sameETag(existing.ETag, blob.ETag) {
+				s.discardPartial(existing)
+			}
+
+			if blob.AccessTier == azure.AccessTierArchive {
+				status = storage.BlobStatusArchived
+				totalArchived++
+				s.handleArchivedBlob(blob)
 			}
 
-			lastModified, _ := time.Parse("2006-01-02T15:04:05Z", blob.LastModified)
-			localPath := s.organizer.GetTargetPath(blob.Name, blob.Path)
 			blobState := &storage.BlobState{
 				BlobName:     blob.Name,
 				BlobPath:     blob.Path,
 				LocalPath:    localPath,
 				SizeBytes:    blob.Size,
-				ETag:         blob.ETag,
+				ETag:         azure.NormalizeETag(blob.ETag),
 				LastModified: lastModified,
 				FirstSeenAt:  time.Now(),
 				Status:       status,
+				ContentType:  blob.ContentType,
+				VersionID:    versionID,
 			}
 
 			if len(blob.ContentMD5) > 0 {
-				md5Str := fmt.Sprintf("%x", blob.ContentMD5)
+				md5Str := base64.StdEncoding.EncodeToString(blob.ContentMD5)
 				blobState.ContentMD5 = &md5Str
 			}
+			if status == storage.BlobStatusSkipped && existing != nil {
+				blobState.LinkedFrom = existing.LinkedFrom
+			}
 
-			if err := s.db.UpsertBlobState(blobState); err != nil {
-				s.logger.Warnw("Failed to upsert blob state", "blob", blob.Name, "error", err)
+			// Files left by another tool are taken over rather than downloaded.
+			if status == storage.BlobStatusPending && s.cfg.Sync.Adopt && !synced && !s.cfg.Sync.ForceResync && s.adoptLocalFile(blobState) {
+				totalAdopted++
+			}
+
+			if blobState.Status == storage.BlobStatusPending {
+				s.queuedFiles++
+				s.queuedBytes += blob.Size
+				if s.cfg.Sync.DryRun {
+					s.logger.Infow("Would download blob", "blob", blob.Name, "path", localPath, "size", blob.Size)
+				}
+			}
+
+			pending = append(pending, blobState)
+			if len(pending) >= discoveryFlushSize {
+				recorded = s.flushDiscovered(pending) && recorded
+				pending = pending[:0]
 			}
 		}
 
+		// Flushing every page lets downloads start without waiting for a
+		// full batch when pages are small.
+		recorded = s.flushDiscovered(pending) && recorded
+		pending = pending[:0]
+		if recorded {
+			s.saveDiscoveryProgress(token)
+		}
+
 		continuationToken = token
 		if continuationToken == nil {
 			break
@@ -175,50 +800,388 @@ func (s *Syncer) discovery() error {
 	}
 
 	s.logger.Infow("Discovery completed",
+		"duration", time.Since(started).String(),
 		"total", totalFound,
 		"new", totalNew,
 		"changed", totalChanged,
 		"skipped", totalSkipped,
+		"archived", totalArchived,
+		"unchanged", totalUnchanged,
+		"missing_locally", totalMissing,
+		"rejected", totalRejected,
+		"directories", totalDirectories,
+		"filtered", totalFiltered,
+		"outside_window", totalOutsideWindow,
+		"adopted", totalAdopted,
+		"queued", s.queuedFiles,
+		"queued_bytes", s.queuedBytes,
 	)
+	s.metrics.BlobsSkipped(s.cfg.Sync.Container, totalSkipped)
+
+	return nil
+}
+
+// keepLocalFile reports whether the overwrite policy leaves the file at
+// localPath as it is rather than downloading blob over it. existing is the
+// blob's recorded state, if any, and synced whether that state is intact.
+func (s *Syncer) keepLocalFile(existing *storage.BlobState, blob *azure.BlobInfo, synced bool, localPath string) bool {
+	switch s.cfg.Sync.Overwrite() {
+	case "skip":
+		return synced && sameETag(existing.ETag, blob.ETag) && sameModTime(existing.LastModified, blob.LastModified)
+	case "newer":
+		info, err := os.Stat(localPath)
+		return err == nil && info.Mode().IsRegular() && !info.ModTime().Before(blob.LastModified)
+	case "never":
+		info, err := os.Stat(localPath)
+		return err == nil && info.Mode().IsRegular()
+	default:
+		return false
+	}
+}
+
+// adoptLocalFile marks blob as downloaded if a file matching it is already
+// at its local path, and reports whether it did.
+func (s *Syncer) adoptLocalFile(blob *storage.BlobState) bool {
+	problem, err := VerifyLocalFile(blob, s.cfg.Sync.VerifyChecksums)
+	if err != nil || problem != "" {
+		if problem != "" && problem != VerifyMissing {
+			s.logger.Debugw("Local file does not match blob; downloading it", "blob", blob.BlobName, "problem", problem)
+		}
+		return false
+	}
+
+	if s.cfg.Sync.DryRun {
+		s.logger.Infow("Would adopt local file", "blob", blob.BlobName, "path", blob.LocalPath)
+	}
+	now := time.Now()
+	blob.Status = storage.BlobStatusDownloaded
+	blob.LastSyncedAt = &now
+	if s.runID != 0 {
+		blob.SyncRunID = &s.runID
+	}
+	return true
+}
+
+// isDirectoryMarker reports whether a blob only marks a directory: a name
+// ending in "/", or an empty blob flagged as a folder by ADLS Gen2 or
+// tools that emulate it.
+func isDirectoryMarker(blob *azure.BlobInfo) bool {
+	if strings.HasSuffix(blob.Name, "/") {
+		return true
+	}
+	if blob.Size != 0 {
+		return false
+	}
+	for key, value := range blob.Metadata {
+		if strings.EqualFold(key, "hdi_isfolder") && strings.EqualFold(value, "true") {
+			return true
+		}
+	}
+	return false
+}
+
+// createDirectory creates the local directory for a directory-marker blob
+// and returns its state. With folder organization enabled the blob layout is
+// not mirrored, so the marker is only recorded.
+func (s *Syncer) createDirectory(blob *azure.BlobInfo, lastModified time.Time) *storage.BlobState {
+	state := &storage.BlobState{
+		BlobName:     blob.Name,
+		BlobPath:     blob.Path,
+		LocalPath:    filepath.Join(s.cfg.Sync.OutputPath, organizer.LocalRelPath(blob.Path)),
+		ETag:         azure.NormalizeETag(blob.ETag),
+		LastModified: lastModified,
+		FirstSeenAt:  time.Now(),
+		Status:       storage.BlobStatusDirectory,
+	}
+
+	if s.cfg.Sync.FolderOrganization.Enabled || s.cfg.Sync.DryRun {
+		return state
+	}
+
+	if err := mkdirAll(state.LocalPath, s.cfg.Sync.DirPerm); err != nil {
+		s.logger.Warnw("Failed to create directory for marker blob", "blob", blob.Name, "path", state.LocalPath, "error", err)
+		errMsg := err.Error()
+		state.Status = storage.BlobStatusFailed
+		state.ErrorMessage = &errMsg
+		s.failedFiles.Add(1)
+		s.metrics.BlobFailed(s.cfg.Sync.Container)
+	}
+	return state
+}
+
+// rejectUnsafeBlob returns a failed state for a blob whose name would place
+// it outside the output directory, or whose rewritten name collides with
+// another blob's, so that it is never downloaded. errMsg says why.
+func (s *Syncer) rejectUnsafeBlob(blob *azure.BlobInfo, lastModified time.Time, errMsg string) *storage.BlobState {
+	if s.cfg.Sync.UnsafeNames == "rewrite" {
+		s.logger.Warnw("Rejecting blob with unsafe name", "blob", blob.Name, "reason", errMsg)
+	} else {
+		s.logger.Warnw("Rejecting blob whose name escapes the output directory",
+			"blob", blob.Name,
+			"hint", "set sync.unsafe_names to rewrite to download it inside the output directory",
+		)
+	}
+	s.failedFiles.Add(1)
+	s.metrics.BlobFailed(s.cfg.Sync.Container)
+
+	return &storage.BlobState{
+		BlobName:     blob.Name,
+		BlobPath:     blob.Path,
+		LocalPath:    s.organizer.GetTargetPath(blob.Name, blob.Path, lastModified),
+		SizeBytes:    blob.Size,
+		ETag:         azure.NormalizeETag(blob.ETag),
+		LastModified: lastModified,
+		FirstSeenAt:  time.Now(),
+		Status:       storage.BlobStatusFailed,
+		ErrorMessage: &errMsg,
+	}
+}
+
+// localCopyIntact reports whether the local file for a synced blob still
+// exists with the expected size. Decompressed downloads differ in size from
+// the blob, so only their presence is checked.
+func (s *Syncer) localCopyIntact(blob *storage.BlobState) bool {
+	info, err := os.Stat(blob.LocalPath)
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+	return s.cfg.Sync.DecompressOnDownload || info.Size() == blob.SizeBytes
+}
+
+// flushDiscovered records discovered blob states and then hands the pending
+// ones to the workers, if any are running. Blobs are queued only after their
+// state is written, so a worker's update can never be overwritten by the
+// discovery record. It reports whether the states were written.
+func (s *Syncer) flushDiscovered(blobs []*storage.BlobState) bool {
+	if len(blobs) == 0 {
+		return true
+	}
+	if !s.flushBlobStates(blobs) {
+		return false
+	}
+	if s.discovered == nil {
+		return true
+	}
+
+	for _, blob := range blobs {
+		if blob.Status != storage.BlobStatusPending {
+			continue
+		}
+		// Discovery never waits for the workers: a blob that does not fit in
+		// the queue stays pending and is picked up by download.
+		select {
+		case s.discovered <- blob:
+			s.totalFiles.Add(1)
+		default:
+			return true
+		}
+	}
+	return true
+}
+
+// flushBlobStates writes discovered blob states in a single transaction and
+// reports whether they were written. Nothing is written in dry-run mode.
+func (s *Syncer) flushBlobStates(blobs []*storage.BlobState) bool {
+	if s.cfg.Sync.DryRun {
+		return false
+	}
+	if err := s.db.BatchUpsertBlobState(blobs); err != nil {
+		s.logger.Warnw("Failed to upsert blob states", "count", len(blobs), "error", err)
+		return false
+	}
+	return true
+}
+
+// incrementalSince returns the time before which synced blobs can be assumed
+// unchanged, based on the last successful run for this container. It returns
+// the zero time when a full discovery is needed.
+func (s *Syncer) incrementalSince() time.Time {
+	// A pinned version is chosen by time, not by what changed since the
+	// last run.
+	if s.cfg.Sync.ForceResync || s.cfg.Sync.Version != "" {
+		return time.Time{}
+	}
+
+	cp, err := s.db.GetCheckpoint(s.cfg.Sync.Container)
+	if err != nil {
+		s.logger.Warnw("Failed to get checkpoint; running full discovery", "error", err)
+		return time.Time{}
+	}
+	// A checkpoint recording only discovery progress has no completed run.
+	if cp == nil || cp.LastCheckTime.IsZero() {
+		return time.Time{}
+	}
+
+	return cp.LastCheckTime.Add(-checkpointSkew)
+}
+
+// isSynced reports whether a blob status means its local copy is known to be
+// good. Archived, failed, and pending blobs (e.g. queued by verify --repair)
+// still need downloading.
+func isSynced(status string) bool {
+	return status == storage.BlobStatusDownloaded || status == storage.BlobStatusSkipped
+}
+
+// sameModTime reports whether two last-modified times are the same instant.
+// Azure reports them to the second, so any finer precision is ignored, and
+// the time zone they are expressed in does not matter.
+func sameModTime(a, b time.Time) bool {
+	return a.Truncate(time.Second).Equal(b.Truncate(time.Second))
+}
+
+// sameETag reports whether two ETags match, ignoring surrounding quotes.
+// States recorded by earlier versions may hold either form.
+func sameETag(a, b string) bool {
+	return azure.NormalizeETag(a) == azure.NormalizeETag(b)
+}
+
+// prune removes local files and state for blobs under the active prefix that
+// were not seen during discovery. In dry-run mode it only logs them.
+func (s *Syncer) prune() error {
+	tracked, err := s.db.GetBlobStatesWithPrefix(s.cfg.Sync.Prefix)
+	if err != nil {
+		return fmt.Errorf("failed to get tracked blobs: %w", err)
+	}
+
+	dryRun := !s.cfg.Sync.Mirror || s.cfg.Sync.MirrorDryRun || s.cfg.Sync.DryRun
+
+	var removed int64
+	for _, blob := range tracked {
+		if _, ok := s.seen[blob.BlobName]; ok {
+			continue
+		}
+
+		if dryRun {
+			s.logger.Infow("Would delete local file for removed blob", "blob", blob.BlobName, "path", blob.LocalPath)
+			removed++
+			continue
+		}
+
+		if err := os.Remove(blob.LocalPath); err != nil && !os.IsNotExist(err) {
+			s.logger.Warnw("Failed to delete local file", "blob", blob.BlobName, "path", blob.LocalPath, "error", err)
+			continue
+		}
 
-	if err := s.db.UpdateCheckpoint(s.cfg.Sync.Container, continuationToken); err != nil {
-		s.logger.Warnw("Failed to update checkpoint", "error", err)
+		if err := s.db.DeleteBlobState(blob.BlobName); err != nil {
+			s.logger.Warnw("Failed to delete blob state", "blob", blob.BlobName, "error", err)
+			continue
+		}
+
+		s.logger.Infow("Deleted local file for removed blob", "blob", blob.BlobName, "path", blob.LocalPath)
+		s.recordAudit(audit.ActionDeleted, blob)
+		removed++
 	}
 
+	s.logger.Infow("Prune completed", "removed", removed, "dry_run", dryRun)
 	return nil
 }
 
+// handleArchivedBlob reports an archive-tier blob and, if configured,
+// starts rehydrating it so that a later run can download it.
+func (s *Syncer) handleArchivedBlob(blob *azure.BlobInfo) {
+	if !s.cfg.Sync.Rehydrate {
+		s.logger.Debugw("Skipping archived blob", "blob", blob.Name)
+		return
+	}
+
+	if s.cfg.Sync.DryRun {
+		s.logger.Infow("Would request rehydration for archived blob", "blob", blob.Name, "target_tier", rehydrateTier)
+		return
+	}
+
+	if blob.ArchiveStatus != "" {
+		s.logger.Debugw("Archived blob is already rehydrating", "blob", blob.Name, "archive_status", blob.ArchiveStatus)
+		return
+	}
+
+	if err := s.client.RehydrateBlob(s.ctx, s.cfg.Sync.Container, blob.Name, rehydrateTier); err != nil {
+		s.logger.Warnw("Failed to rehydrate archived blob", "blob", blob.Name, "error", err)
+		return
+	}
+
+	s.logger.Infow("Rehydration requested for archived blob", "blob", blob.Name, "target_tier", rehydrateTier)
+}
+
 // download processes pending blobs using a worker pool.
 func (s *Syncer) download() error {
 	s.logger.Info("Starting download phase")
 
-	pending, err := s.db.GetPendingBlobs()
+	count, err := s.db.CountBlobsByStatus(storage.BlobStatusPending)
 	if err != nil {
-		return fmt.Errorf("failed to get pending blobs: %w", err)
+		return fmt.Errorf("failed to count pending blobs: %w", err)
 	}
 
-	if len(pending) == 0 {
+	if count == 0 {
 		s.logger.Info("No blobs to download")
 		return nil
 	}
 
-	s.logger.Infow("Downloading blobs", "count", len(pending))
+	s.logger.Infow("Downloading blobs", "count", count)
+
+	blobQueue, stopWorkers, err := s.startWorkers(s.workers * 2)
+	if err != nil {
+		return err
+	}
+
+	err = s.enqueuePending(blobQueue)
+	stopWorkers()
+
+	if err != nil {
+		return err
+	}
+
+	s.logger.Info("Download phase completed")
+	return nil
+}
 
-	blobQueue := make(chan *storage.BlobState, len(pending))
-	for _, blob := range pending {
-		blobQueue <- blob
+// startWorkers starts the worker pool on a queue with room for size blobs.
+// The returned function closes the queue and waits for the workers to exit.
+func (s *Syncer) startWorkers(size int) (chan<- *storage.BlobState, func(), error) {
+	// The disk usage check measures the output path itself, so it must exist.
+	if err := mkdirAll(s.cfg.Sync.OutputPath, s.cfg.Sync.DirPerm); err != nil {
+		return nil, nil, fmt.Errorf("failed to create output path: %w", err)
 	}
-	close(blobQueue)
 
+	queue := make(chan *storage.BlobState, size)
 	for i := 0; i < s.workers; i++ {
 		s.wg.Add(1)
-		go s.worker(i, blobQueue)
+		go s.worker(i, queue)
 	}
 
-	s.wg.Wait()
-	s.logger.Info("Download phase completed")
+	return queue, func() {
+		close(queue)
+		s.wg.Wait()
+	}, nil
+}
 
-	return nil
+// enqueuePending feeds pending blobs to the workers a page at a time, in the
+// configured download order, so memory use stays bounded however large the
+// backlog is. It stops early when the sync is stopped.
+func (s *Syncer) enqueuePending(queue chan<- *storage.BlobState) error {
+	order := s.cfg.Sync.DownloadOrder
+	seed := rand.Int63n(math.MaxInt32-1) + 1
+
+	var after *storage.BlobState
+	for {
+		page, err := s.db.GetPendingBlobsOrdered(order, seed, after, pendingPageSize)
+		if err != nil {
+			return fmt.Errorf("failed to get pending blobs: %w", err)
+		}
+		if len(page) == 0 {
+			return nil
+		}
+
+		for _, blob := range page {
+			select {
+			case queue <- blob:
+				s.totalFiles.Add(1)
+			case <-s.ctx.Done():
+				return nil
+			}
+		}
+
+		after = page[len(page)-1]
+	}
 }
 
 // complete finalizes the sync run and logs statistics.
@@ -230,20 +1193,44 @@ func (s *Syncer) complete() error {
 		return fmt.Errorf("failed to get sync run: %w", err)
 	}
 
+	if s.cfg.Sync.ManifestPath != "" {
+		if err := s.writeManifest(); err != nil {
+			return err
+		}
+	}
+
 	now := time.Now()
 	run.CompletedAt = &now
 	run.Status = storage.SyncStatusCompleted
+	s.applyCounters(run)
+	mbps, filesPerSec := throughput(run.TotalBytes, run.DownloadedFiles, now.Sub(run.StartedAt))
+	run.AvgMbps = &mbps
+	run.AvgFilesPerSec = &filesPerSec
 
 	if err := s.db.UpdateSyncRun(run); err != nil {
 		return fmt.Errorf("failed to update sync run: %w", err)
 	}
 
+	// A failing hook that fails the run is reported once the run is marked failed.
+	if err := s.runPostRunHook(run); err != nil {
+		return fmt.Errorf("post-run hook failed: %w", err)
+	}
+	s.notifyRunFinished(run)
+
+	if !s.checkTime.IsZero() {
+		if err := s.db.UpdateCheckpoint(s.cfg.Sync.Container, s.checkTime, nil); err != nil {
+			s.logger.Warnw("Failed to update checkpoint", "error", err)
+		}
+	}
+
 	duration := run.CompletedAt.Sub(run.StartedAt)
 	s.logger.Infow("Sync completed",
 		"duration", duration.String(),
 		"downloaded", run.DownloadedFiles,
 		"failed", run.FailedFiles,
 		"total_bytes", run.TotalBytes,
+		"avg_mbps", mbps,
+		"avg_files_per_sec", filesPerSec,
 	)
 
 	if s.cfg.Sync.FolderOrganization.Enabled {
@@ -258,6 +1245,51 @@ func (s *Syncer) complete() error {
 	return nil
 }
 
+// throughput returns the average rate, in megabits and files per second, of
+// downloading bytes in files over d. Rates are zero for an instant run.
+func throughput(bytes, files int64, d time.Duration) (mbps, filesPerSec float64) {
+	seconds := d.Seconds()
+	if seconds <= 0 {
+		return 0, 0
+	}
+	return float64(bytes) * 8 / 1e6 / seconds, float64(files) / seconds
+}
+
+// applyCounters copies the per-run download counters onto run.
+func (s *Syncer) applyCounters(run *storage.SyncRun) {
+	run.TotalFiles = s.totalFiles.Load()
+	run.DownloadedFiles = s.downloadedFiles.Load()
+	run.FailedFiles = s.failedFiles.Load()
+	run.TotalBytes = s.downloadedBytes.Load()
+}
+
+// markRunInterrupted marks the sync run as interrupted so that it can be resumed.
+func (s *Syncer) markRunInterrupted() {
+	run, err := s.db.GetSyncRun(s.runID)
+	if err != nil {
+		s.logger.Errorw("Failed to get sync run for interruption marking", "error", err)
+		return
+	}
+
+	now := time.Now()
+	run.CompletedAt = &now
+	run.Status = storage.SyncStatusInterrupted
+	s.applyCounters(run)
+
+	if err := s.db.UpdateSyncRun(run); err != nil {
+		s.logger.Errorw("Failed to update interrupted sync run", "error", err)
+		return
+	}
+	s.notifyRunFinished(run)
+
+	s.logger.Infow("Sync interrupted",
+		"run_id", s.runID,
+		"downloaded", run.DownloadedFiles,
+		"failed", run.FailedFiles,
+		"total_bytes", run.TotalBytes,
+	)
+}
+
 // markRunFailed marks the sync run as failed with an error message.
 func (s *Syncer) markRunFailed(err error) {
 	run, dbErr := s.db.GetSyncRun(s.runID)
@@ -269,10 +1301,65 @@ func (s *Syncer) markRunFailed(err error) {
 	now := time.Now()
 	run.CompletedAt = &now
 	run.Status = storage.SyncStatusFailed
+	s.applyCounters(run)
 	errMsg := err.Error()
 	run.ErrorMessage = &errMsg
 
 	if updateErr := s.db.UpdateSyncRun(run); updateErr != nil {
 		s.logger.Errorw("Failed to update failed sync run", "error", updateErr)
 	}
+	s.notifyRunFinished(run)
+}
+
+// notifyRunFinished records a finished run in the metrics and posts a
+// summary of it to the configured webhook. Delivery failures are logged and
+// never affect the run.
+func (s *Syncer) notifyRunFinished(run *storage.SyncRun) {
+	if run.CompletedAt != nil {
+		s.metrics.RunFinished(s.cfg.Sync.Container, run.Status, run.CompletedAt.Sub(run.StartedAt))
+	}
+
+	if s.webhook == nil {
+		return
+	}
+
+	failed := run.Status == storage.SyncStatusFailed || run.FailedFiles > 0
+	if s.cfg.Notifications.WebhookOn == "failure" && !failed {
+		return
+	}
+
+	summary := &notify.RunSummary{
+		RunID:           run.ID,
+		Container:       s.cfg.Sync.Container,
+		Status:          run.Status,
+		StartedAt:       run.StartedAt,
+		TotalFiles:      run.TotalFiles,
+		DownloadedFiles: run.DownloadedFiles,
+		FailedFiles:     run.FailedFiles,
+		TotalBytes:      run.TotalBytes,
+	}
+	if run.CompletedAt != nil {
+		summary.CompletedAt = *run.CompletedAt
+		summary.DurationSeconds = run.CompletedAt.Sub(run.StartedAt).Seconds()
+	}
+	if run.ErrorMessage != nil {
+		summary.Error = *run.ErrorMessage
+	}
+
+	errs, err := s.db.GetRunErrors(run.ID, notifyErrorCount)
+	if err != nil {
+		s.logger.Warnw("Failed to get run errors for notification", "error", err)
+	}
+	for _, e := range errs {
+		summary.TopErrors = append(summary.TopErrors, notify.BlobError{
+			Blob:    e.BlobName,
+			Type:    e.ErrorType,
+			Message: e.ErrorMessage,
+		})
+	}
+
+	// An interrupted run is reported after the sync context is cancelled.
+	if err := s.webhook.Send(context.Background(), summary); err != nil {
+		s.logger.Warnw("Failed to send run notification", "error", err)
+	}
 }
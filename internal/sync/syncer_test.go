@@ -0,0 +1,2139 @@
+package sync
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/haepapa/getblobz/internal/audit"
+	"github.com/haepapa/getblobz/internal/azure"
+	"github.com/haepapa/getblobz/internal/config"
+	"github.com/haepapa/getblobz/internal/hook"
+	"github.com/haepapa/getblobz/internal/notify"
+	"github.com/haepapa/getblobz/internal/storage"
+	"github.com/haepapa/getblobz/pkg/logger"
+)
+
+// stubClient is an in-memory BlobClient used to exercise the syncer.
+type stubClient struct {
+	containerExists bool
+	containerErr    error
+	blobs           map[string][]byte
+	// blocking lists blobs whose download waits until the context is cancelled.
+	blocking map[string]bool
+	// started receives the name of each blocking blob when its download begins.
+	started chan string
+	// metadata holds optional blob metadata, keyed by blob name.
+	metadata map[string]map[string]string
+	// lastModified overrides the default last-modified time, keyed by blob name.
+	lastModified map[string]string
+	// contentTypes holds optional blob content types, keyed by blob name.
+	contentTypes map[string]string
+	// stalls counts, per blob, the downloads that send half the content and
+	// then stop sending data until the context is cancelled.
+	stallMu sync.Mutex
+	stalls  map[string]int
+}
+
+// stall reports whether this download of blobName should stall.
+func (c *stubClient) stall(blobName string) bool {
+	c.stallMu.Lock()
+	defer c.stallMu.Unlock()
+	if c.stalls[blobName] == 0 {
+		return false
+	}
+	c.stalls[blobName]--
+	return true
+}
+
+func newStubClient(blobs map[string][]byte) *stubClient {
+	return &stubClient{containerExists: true, blobs: blobs}
+}
+
+func (c *stubClient) info(name string) *azure.BlobInfo {
+	content := c.blobs[name]
+	sum := md5.Sum(content)
+	lastModified := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	if t, ok := c.lastModified[name]; ok {
+		lastModified, _ = time.Parse(time.RFC3339, t)
+	}
+	return &azure.BlobInfo{
+		Name:         name,
+		Path:         name,
+		Size:         int64(len(content)),
+		ETag:         fmt.Sprintf("etag-%x", sum[:4]),
+		LastModified: lastModified,
+		ContentMD5:   sum[:],
+		Metadata:     c.metadata[name],
+		ContentType:  c.contentTypes[name],
+	}
+}
+
+func (c *stubClient) ContainerExists(ctx context.Context, containerName string) (bool, error) {
+	return c.containerExists, c.containerErr
+}
+
+func (c *stubClient) ListBlobs(ctx context.Context, containerName, prefix string, marker *string, maxResults int32) ([]*azure.BlobInfo, *string, error) {
+	var names []string
+	for name := range c.blobs {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	// The marker is the name of the first blob on the next page.
+	start := 0
+	if marker != nil {
+		start = sort.SearchStrings(names, *marker)
+	}
+	end := len(names)
+	if maxResults > 0 && start+int(maxResults) < end {
+		end = start + int(maxResults)
+	}
+
+	var blobs []*azure.BlobInfo
+	for _, name := range names[start:end] {
+		blobs = append(blobs, c.info(name))
+	}
+
+	var next *string
+	if end < len(names) {
+		next = &names[end]
+	}
+	return blobs, next, nil
+}
+
+func (c *stubClient) ListBlobsWithOptions(ctx context.Context, containerName, prefix string, marker *string, maxResults int32, opts azure.ListOptions) ([]*azure.BlobInfo, *string, error) {
+	return c.ListBlobs(ctx, containerName, prefix, marker, maxResults)
+}
+
+func (c *stubClient) GetBlobProperties(ctx context.Context, containerName, blobName string) (*azure.BlobInfo, error) {
+	if _, ok := c.blobs[blobName]; !ok {
+		return nil, fmt.Errorf("blob %s not found", blobName)
+	}
+	return c.info(blobName), nil
+}
+
+func (c *stubClient) DownloadBlobRange(ctx context.Context, containerName, blobName string, offset int64, etag string, writer io.Writer) error {
+	if c.blocking[blobName] {
+		content := c.blobs[blobName]
+		if _, err := writer.Write(content[:len(content)/2]); err != nil {
+			return err
+		}
+		c.started <- blobName
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	if c.stall(blobName) {
+		content := c.blobs[blobName][offset:]
+		if _, err := writer.Write(content[:len(content)/2]); err != nil {
+			return err
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	_, err := writer.Write(c.blobs[blobName][offset:])
+	return err
+}
+
+func (c *stubClient) DownloadBlobVersion(ctx context.Context, containerName, blobName, versionID string, writer io.Writer) error {
+	return fmt.Errorf("blob %s has no version %s", blobName, versionID)
+}
+
+func (c *stubClient) DownloadBlobChunked(ctx context.Context, containerName, blobName, etag string, file io.WriterAt, size, chunkSize int64, concurrency int) error {
+	_, err := file.WriteAt(c.blobs[blobName], 0)
+	return err
+}
+
+func (c *stubClient) DownloadBlobDecompressed(ctx context.Context, containerName, blobName string, writer, raw io.Writer) error {
+	if raw != nil {
+		writer = io.MultiWriter(writer, raw)
+	}
+	_, err := writer.Write(c.blobs[blobName])
+	return err
+}
+
+func (c *stubClient) RehydrateBlob(ctx context.Context, containerName, blobName, targetTier string) error {
+	return nil
+}
+
+// newTestSyncer creates a Syncer backed by client, a temporary state database,
+// and a temporary output directory.
+func newTestSyncer(t *testing.T, client BlobClient) (*Syncer, *storage.DB) {
+	t.Helper()
+
+	dir := t.TempDir()
+	cfg := config.Default()
+	cfg.Sync.Container = "test-container"
+	cfg.Sync.OutputPath = filepath.Join(dir, "data")
+	cfg.Sync.Workers = 2
+	cfg.Retry.MaxAttempts = 1
+
+	db, err := storage.Open(filepath.Join(dir, "state.db"))
+	if err != nil {
+		t.Fatalf("failed to open state database: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	log, err := logger.New(logger.Config{Level: "error", Format: "json"})
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+
+	return New(cfg, client, db, log), db
+}
+
+func TestSyncer_Start_ContainerMissing(t *testing.T) {
+	client := newStubClient(nil)
+	client.containerExists = false
+
+	s, db := newTestSyncer(t, client)
+
+	err := s.Start()
+	if err == nil {
+		t.Fatal("expected error for missing container")
+	}
+	if !strings.Contains(err.Error(), "container 'test-container' not found or inaccessible") {
+		t.Errorf("unexpected error message: %v", err)
+	}
+
+	// No sync run should have been recorded, so the next ID is the first.
+	id, err := db.CreateSyncRun()
+	if err != nil {
+		t.Fatalf("failed to create sync run: %v", err)
+	}
+	if id != 1 {
+		t.Errorf("expected no sync run before pre-flight failure, next id = %d", id)
+	}
+}
+
+func TestSyncer_Start_RecordsRunCounters(t *testing.T) {
+	blobs := make(map[string][]byte)
+	var totalBytes int64
+	for i := 0; i < 5; i++ {
+		content := []byte(strings.Repeat(fmt.Sprintf("blob-%d;", i), i+1))
+		blobs[fmt.Sprintf("dir/file-%d.txt", i)] = content
+		totalBytes += int64(len(content))
+	}
+
+	s, db := newTestSyncer(t, newStubClient(blobs))
+
+	if err := s.Start(); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	run, err := db.GetSyncRun(s.runID)
+	if err != nil {
+		t.Fatalf("failed to get sync run: %v", err)
+	}
+
+	if run.Status != storage.SyncStatusCompleted {
+		t.Errorf("status = %q, want %q", run.Status, storage.SyncStatusCompleted)
+	}
+	if run.TotalFiles != 5 {
+		t.Errorf("total files = %d, want 5", run.TotalFiles)
+	}
+	if run.DownloadedFiles != 5 {
+		t.Errorf("downloaded files = %d, want 5", run.DownloadedFiles)
+	}
+	if run.FailedFiles != 0 {
+		t.Errorf("failed files = %d, want 0", run.FailedFiles)
+	}
+	if run.TotalBytes != totalBytes {
+		t.Errorf("total bytes = %d, want %d", run.TotalBytes, totalBytes)
+	}
+	if run.AvgMbps == nil || run.AvgFilesPerSec == nil {
+		t.Errorf("throughput not recorded: avg_mbps = %v, avg_files_per_sec = %v", run.AvgMbps, run.AvgFilesPerSec)
+	}
+}
+
+func TestThroughput(t *testing.T) {
+	tests := []struct {
+		name            string
+		bytes, files    int64
+		duration        time.Duration
+		wantMbps        float64
+		wantFilesPerSec float64
+	}{
+		{"ten megabytes in ten seconds", 10_000_000, 50, 10 * time.Second, 8, 5},
+		{"sub-second run", 1_000_000, 1, 500 * time.Millisecond, 16, 2},
+		{"nothing downloaded", 0, 0, time.Minute, 0, 0},
+		{"zero duration", 1_000_000, 1, 0, 0, 0},
+	}
+
+	for _, tt := range tests {
+		mbps, filesPerSec := throughput(tt.bytes, tt.files, tt.duration)
+		if mbps != tt.wantMbps || filesPerSec != tt.wantFilesPerSec {
+			t.Errorf("%s: throughput() = %v Mbps, %v files/sec, want %v, %v",
+				tt.name, mbps, filesPerSec, tt.wantMbps, tt.wantFilesPerSec)
+		}
+	}
+}
+
+func TestSyncer_Start_PaginatesDiscovery(t *testing.T) {
+	blobs := make(map[string][]byte)
+	for i := 0; i < 7; i++ {
+		blobs[fmt.Sprintf("file-%d.txt", i)] = []byte(fmt.Sprintf("content %d", i))
+	}
+
+	s, db := newTestSyncer(t, newStubClient(blobs))
+	s.cfg.Sync.BatchSize = 3
+
+	if err := s.Start(); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	run, err := db.GetSyncRun(s.runID)
+	if err != nil {
+		t.Fatalf("failed to get sync run: %v", err)
+	}
+	if run.DownloadedFiles != 7 {
+		t.Errorf("downloaded files = %d, want 7", run.DownloadedFiles)
+	}
+}
+
+// cancellingClient cancels the sync as soon as the first listing page is
+// returned.
+type cancellingClient struct {
+	*stubClient
+	cancel    context.CancelFunc
+	listCalls int
+}
+
+func (c *cancellingClient) ListBlobs(ctx context.Context, containerName, prefix string, marker *string, maxResults int32) ([]*azure.BlobInfo, *string, error) {
+	c.listCalls++
+	blobs, next, err := c.stubClient.ListBlobs(ctx, containerName, prefix, marker, maxResults)
+	c.cancel()
+	return blobs, next, err
+}
+
+func TestSyncer_Start_CancelledDuringDiscovery(t *testing.T) {
+	blobs := make(map[string][]byte)
+	for i := 0; i < 100; i++ {
+		blobs[fmt.Sprintf("file-%03d.txt", i)] = []byte(fmt.Sprintf("content %d", i))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	client := &cancellingClient{stubClient: newStubClient(blobs), cancel: cancel}
+	s, db := newTestSyncer(t, client)
+	s.cfg.Sync.BatchSize = 50
+	s.SetContext(ctx)
+
+	if err := s.Start(); !errors.Is(err, ErrInterrupted) {
+		t.Fatalf("expected ErrInterrupted, got %v", err)
+	}
+
+	if client.listCalls != 1 {
+		t.Errorf("listing pages = %d, want 1", client.listCalls)
+	}
+	if state, err := db.GetBlobState("file-049.txt"); err != nil || state != nil {
+		t.Errorf("last blob of the cancelled page was recorded: %v, %v", state, err)
+	}
+
+	run, err := db.GetSyncRun(s.runID)
+	if err != nil {
+		t.Fatalf("failed to get sync run: %v", err)
+	}
+	if run.Status != storage.SyncStatusInterrupted {
+		t.Errorf("status = %q, want %q", run.Status, storage.SyncStatusInterrupted)
+	}
+}
+
+func TestSyncer_Start_InterruptedAndResumed(t *testing.T) {
+	blobs := map[string][]byte{
+		"a.txt":    []byte("first"),
+		"slow.bin": []byte("slow content"),
+	}
+	client := newStubClient(blobs)
+	client.blocking = map[string]bool{"slow.bin": true}
+	client.started = make(chan string, 1)
+
+	s, db := newTestSyncer(t, client)
+
+	done := make(chan error, 1)
+	go func() { done <- s.Start() }()
+
+	<-client.started
+	s.Stop()
+
+	if err := <-done; !errors.Is(err, ErrInterrupted) {
+		t.Fatalf("expected ErrInterrupted, got %v", err)
+	}
+
+	run, err := db.GetSyncRun(s.runID)
+	if err != nil {
+		t.Fatalf("failed to get sync run: %v", err)
+	}
+	if run.Status != storage.SyncStatusInterrupted {
+		t.Errorf("status = %q, want %q", run.Status, storage.SyncStatusInterrupted)
+	}
+
+	state, err := db.GetBlobState("slow.bin")
+	if err != nil {
+		t.Fatalf("failed to get blob state: %v", err)
+	}
+	if state.Status != storage.BlobStatusPending {
+		t.Errorf("interrupted blob status = %q, want %q", state.Status, storage.BlobStatusPending)
+	}
+	if _, err := os.Stat(state.LocalPath + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("expected partial temp file to be removed, stat error = %v", err)
+	}
+
+	// Resume with a fresh syncer on the same state and let the slow blob finish.
+	client.blocking = nil
+	resumed := New(s.cfg, client, db, s.logger)
+	if err := resumed.Resume(); err != nil {
+		t.Fatalf("resume failed: %v", err)
+	}
+
+	if resumed.runID != s.runID {
+		t.Errorf("resumed run id = %d, want %d", resumed.runID, s.runID)
+	}
+
+	run, err = db.GetSyncRun(s.runID)
+	if err != nil {
+		t.Fatalf("failed to get sync run: %v", err)
+	}
+	if run.Status != storage.SyncStatusCompleted {
+		t.Errorf("status after resume = %q, want %q", run.Status, storage.SyncStatusCompleted)
+	}
+	if run.DownloadedFiles != 2 || run.TotalFiles != 2 {
+		t.Errorf("run counters after resume = %d/%d downloaded/total, want 2/2", run.DownloadedFiles, run.TotalFiles)
+	}
+}
+
+func TestSyncer_Start_RetriesStalledDownload(t *testing.T) {
+	content := []byte(strings.Repeat("stalled blob content;", 50))
+	client := newStubClient(map[string][]byte{"slow.bin": content})
+	client.stalls = map[string]int{"slow.bin": 1}
+
+	s, db := newTestSyncer(t, client)
+	s.cfg.Sync.BlobTimeout = 100 * time.Millisecond
+	s.cfg.Retry.MaxAttempts = 2
+	s.cfg.Retry.BaseDelay = 10 * time.Millisecond
+
+	done := make(chan error, 1)
+	go func() { done <- s.Start() }()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("sync failed: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		s.Stop()
+		t.Fatal("stalled download was never abandoned")
+	}
+
+	state, err := db.GetBlobState("slow.bin")
+	if err != nil {
+		t.Fatalf("failed to get blob state: %v", err)
+	}
+	if state.Status != storage.BlobStatusDownloaded {
+		t.Fatalf("status = %q, want %q", state.Status, storage.BlobStatusDownloaded)
+	}
+	got, err := os.ReadFile(state.LocalPath)
+	if err != nil {
+		t.Fatalf("failed to read downloaded file: %v", err)
+	}
+	if string(got) != string(content) {
+		t.Errorf("downloaded content = %q, want %q", got, content)
+	}
+
+	errs, err := db.GetRunErrors(s.runID, 10)
+	if err != nil {
+		t.Fatalf("failed to get run errors: %v", err)
+	}
+	if len(errs) != 1 || errs[0].ErrorType != storage.ErrorTypeNetwork || !strings.Contains(errs[0].ErrorMessage, "stalled") {
+		t.Errorf("expected one network error for the stall, got %+v", errs)
+	}
+}
+
+func TestSyncer_Start_CleansStaleTempFiles(t *testing.T) {
+	blobs := map[string][]byte{
+		"done.txt":    []byte("finished content"),
+		"partial.txt": []byte("content interrupted by a crash"),
+	}
+	s, db := newTestSyncer(t, newStubClient(blobs))
+	if err := s.Start(); err != nil {
+		t.Fatalf("first sync failed: %v", err)
+	}
+	out := s.cfg.Sync.OutputPath
+
+	// A crash after done.txt was downloaded again left its partial file.
+	stale := filepath.Join(out, "done.txt.tmp")
+	if err := os.WriteFile(stale, []byte("fin"), 0644); err != nil {
+		t.Fatalf("failed to write stale temp file: %v", err)
+	}
+	// A crash mid-download of partial.txt left it pending with a partial file.
+	state, err := db.GetBlobState("partial.txt")
+	if err != nil {
+		t.Fatalf("failed to get blob state: %v", err)
+	}
+	state.Status = storage.BlobStatusPending
+	if err := db.UpsertBlobState(state); err != nil {
+		t.Fatalf("failed to update blob state: %v", err)
+	}
+	if err := os.Remove(state.LocalPath); err != nil {
+		t.Fatalf("failed to remove downloaded file: %v", err)
+	}
+	if err := os.WriteFile(state.LocalPath+".tmp", blobs["partial.txt"][:7], 0644); err != nil {
+		t.Fatalf("failed to write partial file: %v", err)
+	}
+	// Files that getblobz did not create are left alone.
+	unrelated := filepath.Join(out, "notes.tmp")
+	if err := os.WriteFile(unrelated, []byte("mine"), 0644); err != nil {
+		t.Fatalf("failed to write unrelated file: %v", err)
+	}
+
+	s = New(s.cfg, s.client, db, s.logger)
+	if err := s.Start(); err != nil {
+		t.Fatalf("second sync failed: %v", err)
+	}
+
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("expected stale temp file to be removed, stat error = %v", err)
+	}
+	if _, err := os.Stat(unrelated); err != nil {
+		t.Errorf("expected unrelated .tmp file to be kept: %v", err)
+	}
+	got, err := os.ReadFile(state.LocalPath)
+	if err != nil {
+		t.Fatalf("failed to read resumed file: %v", err)
+	}
+	if string(got) != string(blobs["partial.txt"]) {
+		t.Errorf("resumed content = %q, want %q", got, blobs["partial.txt"])
+	}
+
+	// The cleanup scans the tracked blobs once per syncer, not on every
+	// run of a watch loop.
+	if err := os.WriteFile(stale, []byte("fin"), 0644); err != nil {
+		t.Fatalf("failed to write stale temp file: %v", err)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatalf("third sync failed: %v", err)
+	}
+	if _, err := os.Stat(stale); err != nil {
+		t.Errorf("expected the cleanup not to run again, stat error = %v", err)
+	}
+}
+
+func TestSyncer_Start_QuotedETagIsUnchanged(t *testing.T) {
+	tests := []struct {
+		name   string
+		stored func(etag string) string
+		listed func(etag string) string
+	}{
+		{"quoted in state", quoteETag, unchangedETag},
+		{"quoted in listing", unchangedETag, quoteETag},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newStubClient(map[string][]byte{"a.txt": []byte("content")})
+			// A last-modified time after the checkpoint keeps the blob from
+			// being skipped before its ETag is compared.
+			client.lastModified = map[string]string{"a.txt": "2099-01-01T00:00:00Z"}
+
+			s, db := newTestSyncer(t, client)
+			if err := s.Start(); err != nil {
+				t.Fatalf("first sync failed: %v", err)
+			}
+
+			state, err := db.GetBlobState("a.txt")
+			if err != nil {
+				t.Fatalf("failed to get blob state: %v", err)
+			}
+			etag := state.ETag
+			state.ETag = tt.stored(etag)
+			if err := db.UpsertBlobState(state); err != nil {
+				t.Fatalf("failed to update blob state: %v", err)
+			}
+
+			listed := &etagClient{stubClient: client, etag: tt.listed(etag)}
+			s = New(s.cfg, listed, db, s.logger)
+			if err := s.Start(); err != nil {
+				t.Fatalf("second sync failed: %v", err)
+			}
+
+			run, err := db.GetSyncRun(s.runID)
+			if err != nil {
+				t.Fatalf("failed to get sync run: %v", err)
+			}
+			if run.DownloadedFiles != 0 {
+				t.Errorf("%s: downloaded %d files, want 0", tt.name, run.DownloadedFiles)
+			}
+			state, err = db.GetBlobState("a.txt")
+			if err != nil {
+				t.Fatalf("failed to get blob state: %v", err)
+			}
+			if state.Status != storage.BlobStatusSkipped || state.ETag != etag {
+				t.Errorf("%s: state = %q with ETag %q, want %q with ETag %q", tt.name, state.Status, state.ETag, storage.BlobStatusSkipped, etag)
+			}
+		})
+	}
+}
+
+func quoteETag(etag string) string     { return `"` + etag + `"` }
+func unchangedETag(etag string) string { return etag }
+
+// throttlingClient rejects the first download of each blob with 503 Server
+// Busy and a Retry-After of retryAfterMS milliseconds.
+type throttlingClient struct {
+	*stubClient
+	retryAfterMS string
+	mu           sync.Mutex
+	throttled    map[string]time.Time
+	retried      map[string]time.Time
+}
+
+func (c *throttlingClient) DownloadBlobRange(ctx context.Context, containerName, blobName string, offset int64, etag string, writer io.Writer) error {
+	c.mu.Lock()
+	if _, ok := c.throttled[blobName]; !ok {
+		c.throttled[blobName] = time.Now()
+		c.mu.Unlock()
+		return &azcore.ResponseError{
+			StatusCode: http.StatusServiceUnavailable,
+			ErrorCode:  "ServerBusy",
+			RawResponse: &http.Response{
+				StatusCode: http.StatusServiceUnavailable,
+				Header:     http.Header{"X-Ms-Retry-After-Ms": {c.retryAfterMS}},
+			},
+		}
+	}
+	c.retried[blobName] = time.Now()
+	c.mu.Unlock()
+	return c.stubClient.DownloadBlobRange(ctx, containerName, blobName, offset, etag, writer)
+}
+
+func TestSyncer_Start_ThrottledDownloadWaitsForRetryAfter(t *testing.T) {
+	client := &throttlingClient{
+		stubClient:   newStubClient(map[string][]byte{"busy.txt": []byte("content")}),
+		retryAfterMS: "300",
+		throttled:    make(map[string]time.Time),
+		retried:      make(map[string]time.Time),
+	}
+	s, db := newTestSyncer(t, client)
+	s.cfg.Retry.MaxAttempts = 2
+	s.cfg.Retry.BaseDelay = time.Millisecond
+	s.cfg.Retry.MaxDelay = time.Millisecond
+
+	if err := s.Start(); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	state, err := db.GetBlobState("busy.txt")
+	if err != nil || state == nil {
+		t.Fatalf("failed to get blob state: %v", err)
+	}
+	if state.Status != storage.BlobStatusDownloaded {
+		t.Errorf("status = %q, want %q", state.Status, storage.BlobStatusDownloaded)
+	}
+	if waited := client.retried["busy.txt"].Sub(client.throttled["busy.txt"]); waited < 300*time.Millisecond {
+		t.Errorf("retried after %v, want at least the 300ms Retry-After", waited)
+	}
+}
+
+// orderClient records the order blobs are downloaded in.
+type orderClient struct {
+	*stubClient
+	mu         sync.Mutex
+	downloaded []string
+}
+
+func (c *orderClient) DownloadBlobRange(ctx context.Context, containerName, blobName string, offset int64, etag string, writer io.Writer) error {
+	c.mu.Lock()
+	c.downloaded = append(c.downloaded, blobName)
+	c.mu.Unlock()
+	return c.stubClient.DownloadBlobRange(ctx, containerName, blobName, offset, etag, writer)
+}
+
+func TestSyncer_Start_DownloadOrder(t *testing.T) {
+	blobs := map[string][]byte{
+		"a.txt": []byte("medium"),
+		"b.txt": []byte("the largest blob"),
+		"c.txt": []byte("s"),
+	}
+
+	tests := []struct {
+		order string
+		want  string
+	}{
+		{storage.DownloadOrderSmallestFirst, "[c.txt a.txt b.txt]"},
+		{storage.DownloadOrderLargestFirst, "[b.txt a.txt c.txt]"},
+	}
+
+	for _, tt := range tests {
+		client := &orderClient{stubClient: newStubClient(blobs)}
+		s, _ := newTestSyncer(t, client)
+		s.cfg.Sync.DownloadOrder = tt.order
+		s.workers = 1
+
+		if err := s.Start(); err != nil {
+			t.Fatalf("%s: sync failed: %v", tt.order, err)
+		}
+		if got := fmt.Sprint(client.downloaded); got != tt.want {
+			t.Errorf("%s: downloaded %s, want %s", tt.order, got, tt.want)
+		}
+	}
+}
+
+func TestSyncer_Start_DedupeLinksIdenticalBlobs(t *testing.T) {
+	client := &orderClient{stubClient: newStubClient(map[string][]byte{
+		"a.txt":      []byte("same content"),
+		"copy/b.txt": []byte("same content"),
+		"c.txt":      []byte("other content"),
+	})}
+	s, db := newTestSyncer(t, client)
+	s.cfg.Sync.Dedupe = true
+	s.workers = 1
+
+	if err := s.Start(); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	if got := fmt.Sprint(client.downloaded); got != "[a.txt c.txt]" {
+		t.Errorf("downloaded %s, want [a.txt c.txt]", got)
+	}
+
+	original, err := db.GetBlobState("a.txt")
+	if err != nil || original == nil {
+		t.Fatalf("failed to get blob state: %v", err)
+	}
+	linked, err := db.GetBlobState("copy/b.txt")
+	if err != nil || linked == nil {
+		t.Fatalf("failed to get blob state: %v", err)
+	}
+	if linked.Status != storage.BlobStatusDownloaded || linked.LinkedFrom != original.LocalPath {
+		t.Errorf("linked blob status = %q, linked from %q; want %q from %q",
+			linked.Status, linked.LinkedFrom, storage.BlobStatusDownloaded, original.LocalPath)
+	}
+
+	originalInfo, err := os.Stat(original.LocalPath)
+	if err != nil {
+		t.Fatalf("original file missing: %v", err)
+	}
+	linkedInfo, err := os.Stat(linked.LocalPath)
+	if err != nil {
+		t.Fatalf("linked file missing: %v", err)
+	}
+	if !os.SameFile(originalInfo, linkedInfo) {
+		t.Error("expected the duplicate to be a hardlink to the original file")
+	}
+}
+
+// etagMatchClient serves blobs whose current ETag is etag, failing
+// conditional downloads for any other ETag as the service does.
+type etagMatchClient struct {
+	*stubClient
+	etag string
+}
+
+func (c *etagMatchClient) DownloadBlobRange(ctx context.Context, containerName, blobName string, offset int64, etag string, writer io.Writer) error {
+	if etag != "" && etag != c.etag {
+		return &azcore.ResponseError{StatusCode: http.StatusPreconditionFailed, ErrorCode: "ConditionNotMet"}
+	}
+	return c.stubClient.DownloadBlobRange(ctx, containerName, blobName, offset, etag, writer)
+}
+
+func TestSyncer_Start_PartialDownloads(t *testing.T) {
+	tests := []struct {
+		name        string
+		partial     string
+		currentETag string
+		verify      bool
+		wantStatus  string
+		wantContent string
+	}{
+		{
+			name:        "blob overwritten since listing discards the partial",
+			partial:     "old ",
+			currentETag: "etag-new",
+			wantStatus:  storage.BlobStatusFailed,
+		},
+		{
+			name:        "resumes a partial of the listed blob",
+			partial:     "new ",
+			wantStatus:  storage.BlobStatusDownloaded,
+			wantContent: "new content",
+		},
+		{
+			name:        "unverifiable full-size partial is downloaded again",
+			partial:     "old content",
+			wantStatus:  storage.BlobStatusDownloaded,
+			wantContent: "new content",
+		},
+		{
+			name:       "verified full-size partial with a bad checksum is rejected",
+			partial:    "old content",
+			verify:     true,
+			wantStatus: storage.BlobStatusFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		stub := newStubClient(map[string][]byte{"a.txt": []byte("new content")})
+		client := &etagMatchClient{stubClient: stub, etag: tt.currentETag}
+		if client.etag == "" {
+			client.etag = stub.info("a.txt").ETag
+		}
+		s, db := newTestSyncer(t, client)
+		s.cfg.Sync.VerifyChecksums = tt.verify
+
+		localPath := filepath.Join(s.cfg.Sync.OutputPath, "a.txt")
+		if err := os.MkdirAll(s.cfg.Sync.OutputPath, 0755); err != nil {
+			t.Fatalf("failed to create output path: %v", err)
+		}
+		if err := os.WriteFile(localPath+".tmp", []byte(tt.partial), 0644); err != nil {
+			t.Fatalf("failed to write partial file: %v", err)
+		}
+
+		if err := s.Start(); err != nil {
+			t.Fatalf("%s: sync failed: %v", tt.name, err)
+		}
+
+		state, err := db.GetBlobState("a.txt")
+		if err != nil || state == nil {
+			t.Fatalf("%s: failed to get blob state: %v", tt.name, err)
+		}
+		if state.Status != tt.wantStatus {
+			t.Errorf("%s: status = %q, want %q", tt.name, state.Status, tt.wantStatus)
+		}
+		if tt.wantStatus == storage.BlobStatusFailed {
+			if _, err := os.Stat(localPath + ".tmp"); !os.IsNotExist(err) {
+				t.Errorf("%s: expected the partial file to be discarded, stat err = %v", tt.name, err)
+			}
+			continue
+		}
+		data, err := os.ReadFile(localPath)
+		if err != nil || string(data) != tt.wantContent {
+			t.Errorf("%s: content = %q (err %v), want %q", tt.name, data, err, tt.wantContent)
+		}
+	}
+}
+
+// markerClient records the marker of the first listing call and rejects
+// markers that do not name a blob, as the service does with stale tokens.
+// Listing from failMarker fails once.
+type markerClient struct {
+	*stubClient
+	firstMarker *string
+	listed      bool
+	failMarker  string
+}
+
+func (c *markerClient) ListBlobs(ctx context.Context, containerName, prefix string, marker *string, maxResults int32) ([]*azure.BlobInfo, *string, error) {
+	if !c.listed {
+		c.listed = true
+		c.firstMarker = marker
+	}
+	if marker != nil && *marker == c.failMarker {
+		c.failMarker = ""
+		return nil, nil, fmt.Errorf("listing failed")
+	}
+	if marker != nil {
+		if _, ok := c.blobs[*marker]; !ok {
+			return nil, nil, fmt.Errorf("invalid marker %q", *marker)
+		}
+	}
+	return c.stubClient.ListBlobs(ctx, containerName, prefix, marker, maxResults)
+}
+
+func TestSyncer_Start_ResumesDiscovery(t *testing.T) {
+	interruptedAt := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		token      string
+		prefix     string
+		downloaded []string
+		checkTime  time.Time
+	}{
+		{
+			name:       "resumes from the saved marker",
+			token:      "c.txt",
+			downloaded: []string{"c.txt", "d.txt"},
+			checkTime:  interruptedAt,
+		},
+		{
+			name:       "stale marker lists from the start",
+			token:      "gone.txt",
+			downloaded: []string{"a.txt", "b.txt", "c.txt", "d.txt"},
+		},
+		{
+			name:       "marker for another prefix is ignored",
+			token:      "c.txt",
+			prefix:     "logs/",
+			downloaded: []string{"a.txt", "b.txt", "c.txt", "d.txt"},
+		},
+	}
+
+	for _, tt := range tests {
+		client := &markerClient{stubClient: newStubClient(map[string][]byte{
+			"a.txt": []byte("alpha"),
+			"b.txt": []byte("bravo"),
+			"c.txt": []byte("charlie"),
+			"d.txt": []byte("delta"),
+		})}
+		s, db := newTestSyncer(t, client)
+		s.cfg.Sync.BatchSize = 2
+
+		token := tt.token
+		if err := db.SaveDiscoveryProgress("test-container", tt.prefix, interruptedAt, &token); err != nil {
+			t.Fatalf("%s: failed to save discovery progress: %v", tt.name, err)
+		}
+
+		if err := s.Start(); err != nil {
+			t.Fatalf("%s: sync failed: %v", tt.name, err)
+		}
+
+		wantMarker := ""
+		if tt.prefix == "" {
+			wantMarker = tt.token
+		}
+		if got := client.firstMarker; (got == nil) != (wantMarker == "") || (got != nil && *got != wantMarker) {
+			t.Errorf("%s: first listing marker = %v, want %q", tt.name, got, wantMarker)
+		}
+
+		var downloaded []string
+		for _, name := range []string{"a.txt", "b.txt", "c.txt", "d.txt"} {
+			state, err := db.GetBlobState(name)
+			if err != nil {
+				t.Fatalf("%s: failed to get blob state: %v", tt.name, err)
+			}
+			if state != nil && state.Status == storage.BlobStatusDownloaded {
+				downloaded = append(downloaded, name)
+			}
+		}
+		if fmt.Sprint(downloaded) != fmt.Sprint(tt.downloaded) {
+			t.Errorf("%s: downloaded %v, want %v", tt.name, downloaded, tt.downloaded)
+		}
+
+		cp, err := db.GetCheckpoint("test-container")
+		if err != nil || cp == nil {
+			t.Fatalf("%s: failed to get checkpoint: %v", tt.name, err)
+		}
+		if cp.LastContinuationToken != nil {
+			t.Errorf("%s: continuation token = %q after a completed run, want none", tt.name, *cp.LastContinuationToken)
+		}
+		// A resumed run only covers changes since the interrupted discovery started.
+		if !tt.checkTime.IsZero() && !cp.LastCheckTime.Equal(tt.checkTime) {
+			t.Errorf("%s: check time = %v, want %v", tt.name, cp.LastCheckTime, tt.checkTime)
+		}
+		if tt.checkTime.IsZero() && !cp.LastCheckTime.After(interruptedAt) {
+			t.Errorf("%s: check time = %v, want the time of this run", tt.name, cp.LastCheckTime)
+		}
+	}
+}
+
+// stallListClient blocks listing from stallMarker until the sync is
+// stopped, signalling on started once it does.
+type stallListClient struct {
+	*markerClient
+	stallMarker string
+	started     chan struct{}
+}
+
+func (c *stallListClient) ListBlobs(ctx context.Context, containerName, prefix string, marker *string, maxResults int32) ([]*azure.BlobInfo, *string, error) {
+	if marker != nil && *marker == c.stallMarker {
+		c.started <- struct{}{}
+		<-ctx.Done()
+		return nil, nil, ctx.Err()
+	}
+	return c.markerClient.ListBlobs(ctx, containerName, prefix, marker, maxResults)
+}
+
+func TestSyncer_Resume_ContinuesInterruptedDiscovery(t *testing.T) {
+	names := []string{"a.txt", "b.txt", "c.txt", "d.txt"}
+	client := &stallListClient{
+		markerClient: &markerClient{stubClient: newStubClient(map[string][]byte{
+			"a.txt": []byte("alpha"),
+			"b.txt": []byte("bravo"),
+			"c.txt": []byte("charlie"),
+			"d.txt": []byte("delta"),
+		})},
+		stallMarker: "c.txt",
+		started:     make(chan struct{}, 1),
+	}
+	s, db := newTestSyncer(t, client)
+	s.cfg.Sync.BatchSize = 2
+
+	done := make(chan error, 1)
+	go func() { done <- s.Start() }()
+
+	<-client.started
+	s.Stop()
+	if err := <-done; !errors.Is(err, ErrInterrupted) {
+		t.Fatalf("expected ErrInterrupted, got %v", err)
+	}
+
+	// Resume with a fresh syncer; the listing continues after the first page.
+	client.stallMarker = ""
+	client.listed = false
+	resumed := New(s.cfg, client, db, s.logger)
+	if err := resumed.Resume(); err != nil {
+		t.Fatalf("resume failed: %v", err)
+	}
+
+	if resumed.runID != s.runID {
+		t.Errorf("resumed run id = %d, want %d", resumed.runID, s.runID)
+	}
+	if got := client.firstMarker; got == nil || *got != "c.txt" {
+		t.Errorf("first listing marker after resume = %v, want %q", got, "c.txt")
+	}
+	for _, name := range names {
+		state, err := db.GetBlobState(name)
+		if err != nil {
+			t.Fatalf("failed to get blob state for %s: %v", name, err)
+		}
+		if state == nil || state.Status != storage.BlobStatusDownloaded {
+			t.Errorf("%s: expected the blob to be downloaded after resume, got %+v", name, state)
+		}
+	}
+
+	cp, err := db.GetCheckpoint("test-container")
+	if err != nil || cp == nil {
+		t.Fatalf("failed to get checkpoint: %v", err)
+	}
+	if cp.LastContinuationToken != nil {
+		t.Errorf("continuation token = %q after the resumed run, want none", *cp.LastContinuationToken)
+	}
+}
+
+func TestSyncer_Start_SavesDiscoveryProgress(t *testing.T) {
+	client := &markerClient{
+		stubClient: newStubClient(map[string][]byte{
+			"a.txt": []byte("alpha"),
+			"b.txt": []byte("bravo"),
+			"c.txt": []byte("charlie"),
+		}),
+		failMarker: "c.txt",
+	}
+	s, db := newTestSyncer(t, client)
+	s.cfg.Sync.BatchSize = 2
+
+	if err := s.Start(); err == nil {
+		t.Fatal("expected the sync to fail when listing fails")
+	}
+
+	cp, err := db.GetCheckpoint("test-container")
+	if err != nil || cp == nil {
+		t.Fatalf("expected discovery progress to be saved, got %+v (err %v)", cp, err)
+	}
+	if cp.LastContinuationToken == nil || *cp.LastContinuationToken != "c.txt" {
+		t.Errorf("continuation token = %v, want c.txt", cp.LastContinuationToken)
+	}
+	if !cp.LastCheckTime.IsZero() {
+		t.Errorf("check time = %v, want none before a run completes", cp.LastCheckTime)
+	}
+
+	client.listed = false
+	if err := s.Start(); err != nil {
+		t.Fatalf("resumed sync failed: %v", err)
+	}
+	if client.firstMarker == nil || *client.firstMarker != "c.txt" {
+		t.Errorf("resumed listing marker = %v, want c.txt", client.firstMarker)
+	}
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		state, err := db.GetBlobState(name)
+		if err != nil || state == nil || state.Status != storage.BlobStatusDownloaded {
+			t.Errorf("%s: state = %+v (err %v), want downloaded", name, state, err)
+		}
+	}
+}
+
+// blobVersion is one version of a blob served by versionClient.
+type blobVersion struct {
+	name      string
+	versionID string
+	snapshot  string
+	content   string
+}
+
+// versionClient lists and downloads blob versions. Versions are listed in
+// the order given, which must group them by blob name.
+type versionClient struct {
+	*stubClient
+	versions []blobVersion
+}
+
+func (c *versionClient) ListBlobsWithOptions(ctx context.Context, containerName, prefix string, marker *string, maxResults int32, opts azure.ListOptions) ([]*azure.BlobInfo, *string, error) {
+	if !opts.Versions {
+		return nil, nil, fmt.Errorf("versions not requested")
+	}
+
+	// The marker is the index of the first version on the next page.
+	start := 0
+	if marker != nil {
+		start, _ = strconv.Atoi(*marker)
+	}
+	end := min(len(c.versions), start+int(maxResults))
+
+	var blobs []*azure.BlobInfo
+	for _, v := range c.versions[start:end] {
+		versionTime, _ := time.Parse(time.RFC3339Nano, v.versionID)
+		sum := md5.Sum([]byte(v.content))
+		blobs = append(blobs, &azure.BlobInfo{
+			Name:         v.name,
+			Path:         v.name,
+			Size:         int64(len(v.content)),
+			ETag:         fmt.Sprintf("etag-%x", sum[:4]),
+			LastModified: versionTime,
+			ContentMD5:   sum[:],
+			VersionID:    v.versionID,
+			Snapshot:     v.snapshot,
+		})
+	}
+
+	var next *string
+	if end < len(c.versions) {
+		token := strconv.Itoa(end)
+		next = &token
+	}
+	return blobs, next, nil
+}
+
+func (c *versionClient) DownloadBlobVersion(ctx context.Context, containerName, blobName, versionID string, writer io.Writer) error {
+	for _, v := range c.versions {
+		if v.name == blobName && v.versionID == versionID && v.snapshot == "" {
+			_, err := io.WriteString(writer, v.content)
+			return err
+		}
+	}
+	return fmt.Errorf("blob %s has no version %s", blobName, versionID)
+}
+
+func TestSyncer_Start_PinnedVersion(t *testing.T) {
+	client := &versionClient{
+		stubClient: newStubClient(nil),
+		versions: []blobVersion{
+			{name: "a.txt", versionID: "2024-01-01T00:00:00.0000000Z", content: "a old"},
+			{name: "a.txt", versionID: "2024-03-01T00:00:00.0000000Z", content: "a new"},
+			{name: "b.txt", versionID: "2024-01-01T00:00:00.0000000Z", content: "b first"},
+			{name: "b.txt", versionID: "2024-01-15T00:00:00.0000000Z", content: "b second"},
+			{name: "b.txt", versionID: "2024-01-20T00:00:00.0000000Z", snapshot: "2024-01-20T00:00:00.0000000Z", content: "b snapshot"},
+			{name: "c.txt", versionID: "2024-03-01T00:00:00.0000000Z", content: "c"},
+		},
+	}
+	s, db := newTestSyncer(t, client)
+	s.cfg.Sync.Version = "2024-02-01T00:00:00Z"
+	s.cfg.Sync.VersionTime = time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	// Pages of two split the versions of a blob across pages.
+	s.cfg.Sync.BatchSize = 2
+
+	if err := s.Start(); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	want := map[string]struct{ versionID, content string }{
+		"a.txt": {"2024-01-01T00:00:00.0000000Z", "a old"},
+		"b.txt": {"2024-01-15T00:00:00.0000000Z", "b second"},
+	}
+	for name, w := range want {
+		state, err := db.GetBlobState(name)
+		if err != nil || state == nil {
+			t.Fatalf("failed to get blob state for %s: %v", name, err)
+		}
+		if state.VersionID != w.versionID {
+			t.Errorf("%s: version = %q, want %q", name, state.VersionID, w.versionID)
+		}
+		data, err := os.ReadFile(state.LocalPath)
+		if err != nil {
+			t.Fatalf("%s: failed to read file: %v", name, err)
+		}
+		if string(data) != w.content {
+			t.Errorf("%s: content = %q, want %q", name, data, w.content)
+		}
+	}
+
+	if state, err := db.GetBlobState("c.txt"); err != nil || state != nil {
+		t.Errorf("c.txt did not exist at the pinned time but has state %+v (err %v)", state, err)
+	}
+}
+
+func TestSyncer_Start_PinningVersionAfterSync(t *testing.T) {
+	client := &versionClient{
+		stubClient: newStubClient(map[string][]byte{"a.txt": []byte("a new")}),
+		versions: []blobVersion{
+			{name: "a.txt", versionID: "2024-01-01T00:00:00.0000000Z", content: "a old"},
+			{name: "a.txt", versionID: "2024-03-01T00:00:00.0000000Z", content: "a new"},
+		},
+	}
+	s, db := newTestSyncer(t, client)
+
+	steps := []struct {
+		version string
+		want    string
+	}{
+		{"", "a new"},
+		{"2024-02-01T00:00:00Z", "a old"},
+		{"", "a new"},
+	}
+	for _, step := range steps {
+		s.cfg.Sync.Version = step.version
+		s.cfg.Sync.VersionTime, _ = time.Parse(time.RFC3339, step.version)
+
+		if err := s.Start(); err != nil {
+			t.Fatalf("sync with version %q failed: %v", step.version, err)
+		}
+
+		state, err := db.GetBlobState("a.txt")
+		if err != nil || state == nil {
+			t.Fatalf("failed to get blob state: %v", err)
+		}
+		data, err := os.ReadFile(state.LocalPath)
+		if err != nil {
+			t.Fatalf("failed to read file: %v", err)
+		}
+		if string(data) != step.want {
+			t.Errorf("with version %q: content = %q, want %q", step.version, data, step.want)
+		}
+	}
+}
+
+// etagClient lists blobs with a fixed ETag.
+type etagClient struct {
+	*stubClient
+	etag string
+}
+
+func (c *etagClient) ListBlobs(ctx context.Context, containerName, prefix string, marker *string, maxResults int32) ([]*azure.BlobInfo, *string, error) {
+	blobs, next, err := c.stubClient.ListBlobs(ctx, containerName, prefix, marker, maxResults)
+	for _, blob := range blobs {
+		blob.ETag = c.etag
+	}
+	return blobs, next, err
+}
+
+func TestSameModTime(t *testing.T) {
+	base := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	auckland := time.FixedZone("NZDT", 13*60*60)
+
+	tests := []struct {
+		name string
+		a, b time.Time
+		want bool
+	}{
+		{"identical", base, base, true},
+		{"other time zone", base, base.In(auckland), true},
+		{"sub-second precision", base, base.Add(500 * time.Millisecond), true},
+		{"parsed from RFC 1123", base, mustParse(t, time.RFC1123, "Tue, 02 Jan 2024 03:04:05 GMT"), true},
+		{"parsed with offset", base, mustParse(t, time.RFC3339Nano, "2024-01-02T16:04:05.25+13:00"), true},
+		{"different second", base, base.Add(time.Second), false},
+	}
+
+	for _, tt := range tests {
+		if got := sameModTime(tt.a, tt.b); got != tt.want {
+			t.Errorf("%s: sameModTime(%v, %v) = %v, want %v", tt.name, tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func mustParse(t *testing.T, layout, value string) time.Time {
+	t.Helper()
+	parsed, err := time.Parse(layout, value)
+	if err != nil {
+		t.Fatalf("failed to parse %q: %v", value, err)
+	}
+	return parsed
+}
+
+func TestSyncer_Start_StoredModTimeInOtherZoneIsUnchanged(t *testing.T) {
+	client := newStubClient(map[string][]byte{"a.txt": []byte("content")})
+	// A last-modified time after the checkpoint keeps the blob from being
+	// skipped before its last-modified time is compared.
+	client.lastModified = map[string]string{"a.txt": "2099-01-01T00:00:00Z"}
+
+	s, db := newTestSyncer(t, client)
+	if err := s.Start(); err != nil {
+		t.Fatalf("first sync failed: %v", err)
+	}
+
+	// Earlier versions could record the same instant in another form.
+	state, err := db.GetBlobState("a.txt")
+	if err != nil {
+		t.Fatalf("failed to get blob state: %v", err)
+	}
+	state.LastModified = state.LastModified.In(time.FixedZone("NZDT", 13*60*60)).Add(250 * time.Millisecond)
+	if err := db.UpsertBlobState(state); err != nil {
+		t.Fatalf("failed to update blob state: %v", err)
+	}
+
+	s = New(s.cfg, client, db, s.logger)
+	if err := s.Start(); err != nil {
+		t.Fatalf("second sync failed: %v", err)
+	}
+
+	run, err := db.GetSyncRun(s.runID)
+	if err != nil {
+		t.Fatalf("failed to get sync run: %v", err)
+	}
+	if run.DownloadedFiles != 0 {
+		t.Errorf("downloaded %d files, want 0", run.DownloadedFiles)
+	}
+}
+
+func TestSyncer_Start_OverwriteNewerKeepsNewerLocalFiles(t *testing.T) {
+	blobs := map[string][]byte{
+		"newer.txt":  []byte("remote content"),
+		"older.txt":  []byte("remote content"),
+		"absent.txt": []byte("remote content"),
+	}
+	s, db := newTestSyncer(t, newStubClient(blobs))
+	s.cfg.Sync.OverwritePolicy = "newer"
+
+	// The stub reports every blob as last modified on 2024-01-02.
+	seed := map[string]time.Time{
+		"newer.txt": time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		"older.txt": time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	for name, modTime := range seed {
+		path := filepath.Join(s.cfg.Sync.OutputPath, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("local edit"), 0644); err != nil {
+			t.Fatalf("failed to seed %s: %v", name, err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatalf("failed to set mtime of %s: %v", name, err)
+		}
+	}
+
+	if err := s.Start(); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		status  string
+		content string
+	}{
+		{"newer.txt", storage.BlobStatusSkipped, "local edit"},
+		{"older.txt", storage.BlobStatusDownloaded, "remote content"},
+		{"absent.txt", storage.BlobStatusDownloaded, "remote content"},
+	}
+	for _, tt := range tests {
+		state, err := db.GetBlobState(tt.name)
+		if err != nil {
+			t.Fatalf("failed to get blob state for %s: %v", tt.name, err)
+		}
+		if state.Status != tt.status {
+			t.Errorf("%s: status = %q, want %q", tt.name, state.Status, tt.status)
+		}
+		got, err := os.ReadFile(state.LocalPath)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", tt.name, err)
+		}
+		if string(got) != tt.content {
+			t.Errorf("%s: content = %q, want %q", tt.name, got, tt.content)
+		}
+	}
+}
+
+func TestSyncer_Start_AdoptsMatchingLocalFiles(t *testing.T) {
+	blobs := map[string][]byte{
+		"dir/match.txt": []byte("already here"),
+		"stale.txt":     []byte("remote content"),
+		"absent.txt":    []byte("not on disk yet"),
+	}
+	s, db := newTestSyncer(t, newStubClient(blobs))
+	s.cfg.Sync.Adopt = true
+
+	// Files left by another tool: one matches its blob, and one has the
+	// right size but different content.
+	seed := map[string][]byte{
+		"dir/match.txt": blobs["dir/match.txt"],
+		"stale.txt":     []byte("local content!"),
+	}
+	for name, content := range seed {
+		path := filepath.Join(s.cfg.Sync.OutputPath, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			t.Fatalf("failed to seed %s: %v", name, err)
+		}
+	}
+
+	if err := s.Start(); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	run, err := db.GetSyncRun(s.runID)
+	if err != nil {
+		t.Fatalf("failed to get sync run: %v", err)
+	}
+	if run.DownloadedFiles != 2 {
+		t.Errorf("downloaded files = %d, want 2", run.DownloadedFiles)
+	}
+
+	for name, content := range blobs {
+		state, err := db.GetBlobState(name)
+		if err != nil {
+			t.Fatalf("failed to get blob state for %s: %v", name, err)
+		}
+		if state.Status != storage.BlobStatusDownloaded {
+			t.Errorf("%s: status = %q, want %q", name, state.Status, storage.BlobStatusDownloaded)
+		}
+		got, err := os.ReadFile(state.LocalPath)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", name, err)
+		}
+		if string(got) != string(content) {
+			t.Errorf("%s: content = %q, want %q", name, got, content)
+		}
+	}
+
+	state, err := db.GetBlobState("dir/match.txt")
+	if err != nil {
+		t.Fatalf("failed to get blob state: %v", err)
+	}
+	if state.SyncRunID == nil || *state.SyncRunID != s.runID || state.LastSyncedAt == nil {
+		t.Errorf("adopted blob not recorded against the run: %+v", state)
+	}
+}
+
+func TestSyncer_Start_FileAndDirModes(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("permission bits are not supported on Windows")
+	}
+
+	s, db := newTestSyncer(t, newStubClient(map[string][]byte{"a/b/file.txt": []byte("content")}))
+	// Group-writable modes would be reduced by a typical 022 umask.
+	s.cfg.Sync.FilePerm = 0660
+	s.cfg.Sync.DirPerm = 0770
+
+	if err := s.Start(); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	state, err := db.GetBlobState("a/b/file.txt")
+	if err != nil {
+		t.Fatalf("failed to get blob state: %v", err)
+	}
+	checks := []struct {
+		path string
+		want os.FileMode
+	}{
+		{state.LocalPath, 0660},
+		{filepath.Join(s.cfg.Sync.OutputPath, "a", "b"), 0770},
+		{filepath.Join(s.cfg.Sync.OutputPath, "a"), 0770},
+		{s.cfg.Sync.OutputPath, 0770},
+	}
+	for _, check := range checks {
+		info, err := os.Stat(check.path)
+		if err != nil {
+			t.Fatalf("failed to stat %s: %v", check.path, err)
+		}
+		if got := info.Mode().Perm(); got != check.want {
+			t.Errorf("%s: mode = %o, want %o", check.path, got, check.want)
+		}
+	}
+}
+
+func TestSyncer_Start_WritesAuditLog(t *testing.T) {
+	client := newStubClient(map[string][]byte{
+		"a.txt":     []byte("alpha"),
+		"dir/b.txt": []byte("bravo"),
+	})
+	s, _ := newTestSyncer(t, client)
+	s.cfg.Sync.Mirror = true
+
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	auditLog, err := audit.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open audit log: %v", err)
+	}
+	s.SetAuditLog(auditLog)
+
+	if err := s.Start(); err != nil {
+		t.Fatalf("first sync failed: %v", err)
+	}
+	delete(client.blobs, "a.txt")
+	if err := s.Start(); err != nil {
+		t.Fatalf("second sync failed: %v", err)
+	}
+	if err := auditLog.Close(); err != nil {
+		t.Fatalf("failed to close audit log: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read audit log: %v", err)
+	}
+	got := make(map[string]audit.Entry)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	for _, line := range lines {
+		var entry audit.Entry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("failed to parse audit line %q: %v", line, err)
+		}
+		got[entry.Action+" "+entry.Blob] = entry
+	}
+	if len(lines) != 3 {
+		t.Errorf("audit entries = %d, want 3: %s", len(lines), data)
+	}
+
+	for _, key := range []string{"downloaded a.txt", "downloaded dir/b.txt", "deleted a.txt"} {
+		entry, ok := got[key]
+		if !ok {
+			t.Errorf("missing audit entry %q: %s", key, data)
+			continue
+		}
+		if entry.Size != 5 || entry.ContentMD5 == "" || entry.LocalPath == "" || entry.RunID == 0 {
+			t.Errorf("%s: incomplete audit entry %+v", key, entry)
+		}
+	}
+}
+
+func TestSyncer_Start_RecordsContentType(t *testing.T) {
+	client := newStubClient(map[string][]byte{
+		"report.csv": []byte("a,b\n1,2\n"),
+		"plain.bin":  []byte("raw"),
+	})
+	client.contentTypes = map[string]string{"report.csv": "text/csv"}
+	s, db := newTestSyncer(t, client)
+	s.cfg.Sync.ManifestPath = filepath.Join(t.TempDir(), "manifest.jsonl")
+
+	if err := s.Start(); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	for name, want := range map[string]string{"report.csv": "text/csv", "plain.bin": ""} {
+		state, err := db.GetBlobState(name)
+		if err != nil {
+			t.Fatalf("failed to get blob state for %s: %v", name, err)
+		}
+		if state.ContentType != want {
+			t.Errorf("%s: content type = %q, want %q", name, state.ContentType, want)
+		}
+	}
+
+	manifest, err := os.ReadFile(s.cfg.Sync.ManifestPath)
+	if err != nil {
+		t.Fatalf("failed to read manifest: %v", err)
+	}
+	if !strings.Contains(string(manifest), `"content_type":"text/csv"`) {
+		t.Errorf("manifest does not include the content type: %s", manifest)
+	}
+}
+
+func TestSyncer_Start_AfterStop(t *testing.T) {
+	s, db := newTestSyncer(t, newStubClient(map[string][]byte{"a.txt": []byte("content")}))
+
+	// A syncer stopped during one watch run must still run the next.
+	s.Stop()
+	if err := s.Start(); err != nil {
+		t.Fatalf("sync after stop failed: %v", err)
+	}
+
+	state, err := db.GetBlobState("a.txt")
+	if err != nil {
+		t.Fatalf("failed to get blob state: %v", err)
+	}
+	if state.Status != storage.BlobStatusDownloaded {
+		t.Errorf("status = %q, want %q", state.Status, storage.BlobStatusDownloaded)
+	}
+}
+
+func TestSyncer_SetContext_CancelsRuns(t *testing.T) {
+	s, _ := newTestSyncer(t, newStubClient(map[string][]byte{"a.txt": []byte("content")}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.SetContext(ctx)
+	cancel()
+
+	if err := s.Start(); !errors.Is(err, ErrInterrupted) {
+		t.Fatalf("expected ErrInterrupted after the parent context was cancelled, got %v", err)
+	}
+}
+
+func TestSyncer_Start_IncrementalSkipsSyncedBlobs(t *testing.T) {
+	client := newStubClient(map[string][]byte{
+		"a.txt": []byte("alpha"),
+		"b.txt": []byte("bravo"),
+	})
+	s, db := newTestSyncer(t, client)
+
+	if err := s.Start(); err != nil {
+		t.Fatalf("first sync failed: %v", err)
+	}
+
+	cp, err := db.GetCheckpoint("test-container")
+	if err != nil || cp == nil {
+		t.Fatalf("expected checkpoint after successful run, got %v (err %v)", cp, err)
+	}
+
+	// A blob added since the last run is untracked, so it is evaluated even
+	// though its last-modified time predates the checkpoint.
+	client.blobs["c.txt"] = []byte("charlie")
+
+	if err := s.Start(); err != nil {
+		t.Fatalf("second sync failed: %v", err)
+	}
+
+	// Without incremental discovery, unchanged blobs would be rewritten as skipped.
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		state, err := db.GetBlobState(name)
+		if err != nil {
+			t.Fatalf("failed to get blob state: %v", err)
+		}
+		if state == nil || state.Status != storage.BlobStatusDownloaded {
+			t.Errorf("%s: expected downloaded state, got %+v", name, state)
+		}
+	}
+}
+
+func TestSyncer_Start_RedownloadsMissingLocalFiles(t *testing.T) {
+	client := newStubClient(map[string][]byte{
+		"a.txt": []byte("alpha"),
+		"b.txt": []byte("bravo"),
+		"c.txt": []byte("charlie"),
+	})
+	s, db := newTestSyncer(t, client)
+
+	if err := s.Start(); err != nil {
+		t.Fatalf("first sync failed: %v", err)
+	}
+
+	deleted := filepath.Join(s.cfg.Sync.OutputPath, "a.txt")
+	truncated := filepath.Join(s.cfg.Sync.OutputPath, "b.txt")
+	if err := os.Remove(deleted); err != nil {
+		t.Fatalf("failed to delete local file: %v", err)
+	}
+	if err := os.WriteFile(truncated, []byte("br"), 0644); err != nil {
+		t.Fatalf("failed to truncate local file: %v", err)
+	}
+
+	if err := s.Start(); err != nil {
+		t.Fatalf("second sync failed: %v", err)
+	}
+
+	for path, want := range map[string]string{deleted: "alpha", truncated: "bravo"} {
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("expected %s to be downloaded again: %v", path, err)
+		}
+		if string(got) != want {
+			t.Errorf("%s = %q, want %q", path, got, want)
+		}
+	}
+
+	run, err := db.GetSyncRun(s.runID)
+	if err != nil {
+		t.Fatalf("failed to get sync run: %v", err)
+	}
+	if run.DownloadedFiles != 2 {
+		t.Errorf("second run downloaded %d files, want 2", run.DownloadedFiles)
+	}
+}
+
+func TestSyncer_Start_PreservesTimestamps(t *testing.T) {
+	client := newStubClient(map[string][]byte{"a.txt": []byte("alpha")})
+	s, _ := newTestSyncer(t, client)
+
+	if err := s.Start(); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	info, err := os.Stat(filepath.Join(s.cfg.Sync.OutputPath, "a.txt"))
+	if err != nil {
+		t.Fatalf("failed to stat downloaded file: %v", err)
+	}
+
+	want := client.info("a.txt").LastModified
+	if diff := info.ModTime().Sub(want); diff < -time.Second || diff > time.Second {
+		t.Errorf("file mtime = %s, want %s", info.ModTime(), want)
+	}
+}
+
+func TestSyncer_Start_TempDir(t *testing.T) {
+	client := newStubClient(map[string][]byte{"nested/a.txt": []byte("alpha")})
+	s, _ := newTestSyncer(t, client)
+	s.cfg.Sync.TempDir = filepath.Join(t.TempDir(), "partial")
+
+	// A crash left a partial file of a blob that is no longer pending.
+	if err := os.MkdirAll(s.cfg.Sync.TempDir, 0755); err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(s.cfg.Sync.TempDir, "0123abcd.tmp"), []byte("stale"), 0644); err != nil {
+		t.Fatalf("failed to write stale temp file: %v", err)
+	}
+
+	if err := s.Start(); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(s.cfg.Sync.OutputPath, "nested", "a.txt"))
+	if err != nil || string(got) != "alpha" {
+		t.Errorf("downloaded file = %q (err %v), want %q", got, err, "alpha")
+	}
+
+	entries, err := os.ReadDir(s.cfg.Sync.TempDir)
+	if err != nil {
+		t.Fatalf("failed to read temp dir: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected temp dir to be empty, found %d entries", len(entries))
+	}
+}
+
+func TestSyncer_Start_UnsafeBlobNames(t *testing.T) {
+	// The first two names rewrite to escape.txt; only the first listed may
+	// claim it.
+	unsafe := []string{"../../escape.txt", `..\escape.txt`, "/abs/escape.txt"}
+
+	for _, mode := range []string{"reject", "rewrite"} {
+		blobs := map[string][]byte{"ok.txt": []byte("fine")}
+		for _, name := range unsafe {
+			blobs[name] = []byte("payload from " + name)
+		}
+
+		s, db := newTestSyncer(t, newStubClient(blobs))
+		s.cfg.Sync.UnsafeNames = mode
+		root := filepath.Dir(s.cfg.Sync.OutputPath)
+
+		if err := s.Start(); err != nil {
+			t.Fatalf("%s: sync failed: %v", mode, err)
+		}
+
+		// Nothing may be written beside the output directory.
+		entries, err := os.ReadDir(root)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", root, err)
+		}
+		for _, entry := range entries {
+			if name := entry.Name(); name != "data" && !strings.HasPrefix(name, "state.db") {
+				t.Errorf("%s: unexpected file %s outside the output directory", mode, name)
+			}
+		}
+
+		for i, name := range unsafe {
+			state, err := db.GetBlobState(name)
+			if err != nil || state == nil {
+				t.Fatalf("%s: missing state for %s (err %v)", mode, name, err)
+			}
+			want := storage.BlobStatusFailed
+			if mode == "rewrite" && i != 1 {
+				want = storage.BlobStatusDownloaded
+			}
+			if state.Status != want {
+				t.Errorf("%s: %s status = %s, want %s", mode, name, state.Status, want)
+			}
+		}
+
+		data, err := os.ReadFile(filepath.Join(s.cfg.Sync.OutputPath, "escape.txt"))
+		if mode == "rewrite" && (err != nil || string(data) != "payload from "+unsafe[0]) {
+			t.Errorf("rewrite: escape.txt = %q (err %v), want the content of %s", data, err, unsafe[0])
+		}
+		if mode == "reject" && !os.IsNotExist(err) {
+			t.Errorf("reject: expected no escape.txt to be written, stat err = %v", err)
+		}
+	}
+}
+
+func TestSyncer_Start_DirectoryMarkers(t *testing.T) {
+	client := newStubClient(map[string][]byte{
+		"folder/":           nil,
+		"adls":              nil,
+		"folder/empty.txt":  {},
+		"folder/report.txt": []byte("report"),
+	})
+	client.metadata = map[string]map[string]string{"adls": {"hdi_isfolder": "true"}}
+	s, db := newTestSyncer(t, client)
+
+	if err := s.Start(); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	for _, name := range []string{"folder", "adls"} {
+		info, err := os.Stat(filepath.Join(s.cfg.Sync.OutputPath, name))
+		if err != nil || !info.IsDir() {
+			t.Errorf("expected %s to be created as a directory (err %v)", name, err)
+		}
+	}
+
+	info, err := os.Stat(filepath.Join(s.cfg.Sync.OutputPath, "folder", "empty.txt"))
+	if err != nil || !info.Mode().IsRegular() || info.Size() != 0 {
+		t.Errorf("expected empty.txt to be downloaded as an empty file (err %v)", err)
+	}
+
+	want := map[string]string{
+		"folder/":           storage.BlobStatusDirectory,
+		"adls":              storage.BlobStatusDirectory,
+		"folder/empty.txt":  storage.BlobStatusDownloaded,
+		"folder/report.txt": storage.BlobStatusDownloaded,
+	}
+	for name, status := range want {
+		state, err := db.GetBlobState(name)
+		if err != nil || state == nil {
+			t.Fatalf("missing state for %s (err %v)", name, err)
+		}
+		if state.Status != status {
+			t.Errorf("%s status = %s, want %s", name, state.Status, status)
+		}
+	}
+}
+
+func TestSyncer_Start_MeasuresOutputPathDiskUsage(t *testing.T) {
+	client := newStubClient(map[string][]byte{
+		"a.txt": []byte("alpha"),
+		"b.txt": []byte("bravo"),
+		"c.txt": []byte("charlie"),
+	})
+	s, _ := newTestSyncer(t, client)
+
+	var mu sync.Mutex
+	var measured []string
+	s.diskUsage = func(path string) (int, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		measured = append(measured, path)
+		return 10, nil
+	}
+
+	if err := s.Start(); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	if len(measured) != 1 {
+		t.Errorf("disk usage measured %d times, want 1 cached reading", len(measured))
+	}
+	for _, path := range measured {
+		if path != s.cfg.Sync.OutputPath {
+			t.Errorf("measured %s, want the output path %s", path, s.cfg.Sync.OutputPath)
+		}
+	}
+}
+
+func TestSyncer_Start_ModifiedWindow(t *testing.T) {
+	client := newStubClient(map[string][]byte{
+		"january.txt": []byte("jan"),
+		"april.txt":   []byte("apr"),
+		"july.txt":    []byte("jul"),
+	})
+	client.lastModified = map[string]string{
+		"january.txt": "2024-01-15T00:00:00Z",
+		"april.txt":   "2024-04-15T00:00:00Z",
+		"july.txt":    "2024-07-15T00:00:00Z",
+	}
+	s, db := newTestSyncer(t, client)
+	s.cfg.Sync.ModifiedAfter = "2024-03-01T00:00:00Z"
+	s.cfg.Sync.ModifiedBefore = "2024-06-01"
+
+	if err := s.Start(); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	for name, wantTracked := range map[string]bool{"january.txt": false, "april.txt": true, "july.txt": false} {
+		state, err := db.GetBlobState(name)
+		if err != nil {
+			t.Fatalf("failed to get blob state: %v", err)
+		}
+		if (state != nil) != wantTracked {
+			t.Errorf("%s: tracked = %v, want %v", name, state != nil, wantTracked)
+		}
+
+		_, err = os.Stat(filepath.Join(s.cfg.Sync.OutputPath, name))
+		if downloaded := err == nil; downloaded != wantTracked {
+			t.Errorf("%s: downloaded = %v, want %v", name, downloaded, wantTracked)
+		}
+	}
+}
+
+func TestSyncer_Start_MoreBlobsThanQueue(t *testing.T) {
+	blobs := make(map[string][]byte)
+	for i := 0; i < 25; i++ {
+		blobs[fmt.Sprintf("queue/file-%02d.txt", i)] = []byte(fmt.Sprintf("payload %d", i))
+	}
+
+	s, db := newTestSyncer(t, newStubClient(blobs))
+	if len(blobs) <= s.workers*2 {
+		t.Fatalf("test needs more blobs than the queue buffer of %d", s.workers*2)
+	}
+
+	if err := s.Start(); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	run, err := db.GetSyncRun(s.runID)
+	if err != nil {
+		t.Fatalf("failed to get sync run: %v", err)
+	}
+	if run.DownloadedFiles != 25 || run.TotalFiles != 25 {
+		t.Errorf("run counters = %d/%d downloaded/total, want 25/25", run.DownloadedFiles, run.TotalFiles)
+	}
+
+	pending, err := db.CountBlobsByStatus(storage.BlobStatusPending)
+	if err != nil {
+		t.Fatalf("failed to count pending blobs: %v", err)
+	}
+	if pending != 0 {
+		t.Errorf("expected no pending blobs, got %d", pending)
+	}
+}
+
+// pipelineClient refuses to list past the first page until a blob has been
+// downloaded, so a sync only completes if downloads overlap discovery.
+type pipelineClient struct {
+	*stubClient
+	once       sync.Once
+	downloaded chan struct{}
+}
+
+func (c *pipelineClient) ListBlobs(ctx context.Context, containerName, prefix string, marker *string, maxResults int32) ([]*azure.BlobInfo, *string, error) {
+	if marker != nil {
+		select {
+		case <-c.downloaded:
+		case <-time.After(5 * time.Second):
+			return nil, nil, errors.New("next page listed before any download started")
+		}
+	}
+	return c.stubClient.ListBlobs(ctx, containerName, prefix, marker, maxResults)
+}
+
+func (c *pipelineClient) DownloadBlobRange(ctx context.Context, containerName, blobName string, offset int64, etag string, writer io.Writer) error {
+	err := c.stubClient.DownloadBlobRange(ctx, containerName, blobName, offset, etag, writer)
+	c.once.Do(func() { close(c.downloaded) })
+	return err
+}
+
+func TestSyncer_Start_DownloadsDuringDiscovery(t *testing.T) {
+	blobs := make(map[string][]byte)
+	for i := 0; i < 6; i++ {
+		blobs[fmt.Sprintf("page/file-%d.txt", i)] = []byte(fmt.Sprintf("content %d", i))
+	}
+	client := &pipelineClient{stubClient: newStubClient(blobs), downloaded: make(chan struct{})}
+
+	s, db := newTestSyncer(t, client)
+	s.cfg.Sync.BatchSize = 2
+
+	if err := s.Start(); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	run, err := db.GetSyncRun(s.runID)
+	if err != nil {
+		t.Fatalf("failed to get sync run: %v", err)
+	}
+	if run.DownloadedFiles != 6 || run.TotalFiles != 6 {
+		t.Errorf("run counters = %d/%d downloaded/total, want 6/6", run.DownloadedFiles, run.TotalFiles)
+	}
+}
+
+func TestSyncer_Start_DryRun(t *testing.T) {
+	client := newStubClient(map[string][]byte{
+		"folder/":        nil,
+		"folder/a.txt":   []byte("alpha"),
+		"folder/b.txt":   []byte("bravo!"),
+		"../outside.txt": []byte("unsafe"),
+	})
+	s, db := newTestSyncer(t, client)
+	s.cfg.Sync.DryRun = true
+
+	if err := s.Start(); err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+
+	if s.queuedFiles != 2 || s.queuedBytes != 11 {
+		t.Errorf("queued %d files / %d bytes, want 2 / 11", s.queuedFiles, s.queuedBytes)
+	}
+
+	if _, err := os.Stat(s.cfg.Sync.OutputPath); !os.IsNotExist(err) {
+		t.Errorf("expected dry run not to create the output path (err %v)", err)
+	}
+
+	for _, name := range []string{"folder/", "folder/a.txt", "../outside.txt"} {
+		if state, err := db.GetBlobState(name); err != nil || state != nil {
+			t.Errorf("expected no state for %s after a dry run, got %+v (err %v)", name, state, err)
+		}
+	}
+
+	// No sync run or checkpoint should have been recorded.
+	if cp, err := db.GetCheckpoint("test-container"); err != nil || cp != nil {
+		t.Errorf("expected no checkpoint after a dry run, got %+v (err %v)", cp, err)
+	}
+	id, err := db.CreateSyncRun()
+	if err != nil {
+		t.Fatalf("failed to create sync run: %v", err)
+	}
+	if id != 1 {
+		t.Errorf("expected no sync run after a dry run, next id = %d", id)
+	}
+}
+
+func TestSyncer_Start_PostsWebhook(t *testing.T) {
+	payloads := make(chan notify.RunSummary, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var summary notify.RunSummary
+		if err := json.NewDecoder(r.Body).Decode(&summary); err != nil {
+			t.Errorf("failed to decode webhook payload: %v", err)
+		}
+		payloads <- summary
+	}))
+	defer server.Close()
+
+	client := newStubClient(map[string][]byte{"a.txt": []byte("alpha"), "b.txt": []byte("bravo")})
+	s, _ := newTestSyncer(t, client)
+	s.cfg.Notifications.WebhookURL = server.URL
+	s.webhook = notify.NewWebhook(server.URL)
+
+	if err := s.Start(); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	select {
+	case summary := <-payloads:
+		if summary.RunID != s.runID || summary.Status != storage.SyncStatusCompleted {
+			t.Errorf("unexpected run in payload: %+v", summary)
+		}
+		if summary.Container != "test-container" || summary.DownloadedFiles != 2 || summary.TotalBytes != 10 {
+			t.Errorf("unexpected counts in payload: %+v", summary)
+		}
+	default:
+		t.Fatal("expected a webhook to be posted when the run completed")
+	}
+
+	// Only failures are reported with webhook_on: failure.
+	s.cfg.Notifications.WebhookOn = "failure"
+	if err := s.Start(); err != nil {
+		t.Fatalf("second sync failed: %v", err)
+	}
+	select {
+	case summary := <-payloads:
+		t.Errorf("unexpected webhook for a successful run: %+v", summary)
+	default:
+	}
+}
+
+func TestSyncer_Start_PostDownloadHook(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook script uses sh")
+	}
+
+	dir := t.TempDir()
+	record := filepath.Join(dir, "hook.log")
+	script := filepath.Join(dir, "hook.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\necho \"$1\" >> \""+record+"\"\n"), 0755); err != nil {
+		t.Fatalf("failed to write hook script: %v", err)
+	}
+
+	client := newStubClient(map[string][]byte{"data/report.csv": []byte("a,b")})
+	s, db := newTestSyncer(t, client)
+	s.cfg.Hooks.PostDownload = script + " {path}"
+	s.postDownload, _ = hook.Parse(s.cfg.Hooks.PostDownload, hook.PostDownloadTokens)
+
+	if err := s.Start(); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	got, err := os.ReadFile(record)
+	if err != nil {
+		t.Fatalf("hook did not run: %v", err)
+	}
+	want := filepath.Join(s.cfg.Sync.OutputPath, "data", "report.csv")
+	if strings.TrimSpace(string(got)) != want {
+		t.Errorf("hook received %q, want %q", strings.TrimSpace(string(got)), want)
+	}
+
+	// With fail_on_error, a failing hook fails the blob.
+	s.cfg.Sync.ForceResync = true
+	s.cfg.Hooks.FailOnError = true
+	s.postDownload, _ = hook.Parse("false", hook.PostDownloadTokens)
+	if err := s.Start(); err != nil {
+		t.Fatalf("second sync failed: %v", err)
+	}
+	state, err := db.GetBlobState("data/report.csv")
+	if err != nil || state == nil {
+		t.Fatalf("missing blob state (err %v)", err)
+	}
+	if state.Status != storage.BlobStatusFailed {
+		t.Errorf("status = %s, want %s after a failing hook", state.Status, storage.BlobStatusFailed)
+	}
+}
+
+// readManifest returns the manifest entries keyed by blob name.
+func readManifest(t *testing.T, path string) map[string]manifestEntry {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read manifest: %v", err)
+	}
+
+	entries := make(map[string]manifestEntry)
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry manifestEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("invalid manifest line %q: %v", line, err)
+		}
+		entries[entry.Blob] = entry
+	}
+	return entries
+}
+
+func TestSyncer_Start_WritesManifest(t *testing.T) {
+	blobs := map[string][]byte{"a.txt": []byte("alpha"), "dir/b.txt": []byte("bravo!")}
+	client := newStubClient(blobs)
+	s, _ := newTestSyncer(t, client)
+	s.cfg.Sync.ManifestPath = filepath.Join(t.TempDir(), "manifest.ndjson")
+
+	if err := s.Start(); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	entries := readManifest(t, s.cfg.Sync.ManifestPath)
+	if len(entries) != len(blobs) {
+		t.Fatalf("manifest has %d entries, want %d", len(entries), len(blobs))
+	}
+	for name, content := range blobs {
+		entry, ok := entries[name]
+		if !ok {
+			t.Errorf("manifest is missing %s", name)
+			continue
+		}
+		if entry.Size != int64(len(content)) || entry.ContentMD5 == "" || entry.LastModified.IsZero() {
+			t.Errorf("unexpected manifest entry for %s: %+v", name, entry)
+		}
+		got, err := os.ReadFile(entry.LocalPath)
+		if err != nil || string(got) != string(content) {
+			t.Errorf("manifest path for %s does not hold the blob (err %v)", name, err)
+		}
+	}
+
+	// The next run only downloads the new blob; manifest_all lists both runs.
+	client.blobs["c.txt"] = []byte("charlie")
+	if err := s.Start(); err != nil {
+		t.Fatalf("second sync failed: %v", err)
+	}
+	if entries := readManifest(t, s.cfg.Sync.ManifestPath); len(entries) != 1 || entries["c.txt"].Blob == "" {
+		t.Errorf("expected only c.txt in the second run's manifest, got %v", entries)
+	}
+
+	s.cfg.Sync.ManifestAll = true
+	if err := s.Start(); err != nil {
+		t.Fatalf("third sync failed: %v", err)
+	}
+	if entries := readManifest(t, s.cfg.Sync.ManifestPath); len(entries) != 3 {
+		t.Errorf("expected all 3 tracked files with manifest_all, got %d", len(entries))
+	}
+}
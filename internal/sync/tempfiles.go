@@ -0,0 +1,156 @@
+// Package sync provides cleanup of partial downloads left by earlier runs.
+package sync
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/haepapa/getblobz/internal/storage"
+)
+
+// tempSuffix marks a file that is still being downloaded.
+const tempSuffix = ".tmp"
+
+// cleanedTempDirs records the temp_dir paths already cleaned by this
+// process. Every container's syncer shares one temp_dir, so it is cleaned
+// only once.
+var cleanedTempDirs sync.Map
+
+// cleanStaleTempFiles removes partial downloads left behind when an earlier
+// process crashed or was killed. Partial files of blobs that are still
+// pending are kept, since their next download resumes from them. Later runs
+// of the same syncer only leave partials behind on failure, which the next
+// attempt resumes or discards, so the cleanup runs once per syncer.
+//
+// Next to the destination files only "<path>.tmp" for a tracked "<path>" of
+// this container is considered, so unrelated files in the output path are
+// never touched. A separate sync.temp_dir holds nothing else, so every stale
+// file there goes.
+func (s *Syncer) cleanStaleTempFiles() error {
+	if s.tempCleaned {
+		return nil
+	}
+	s.tempCleaned = true
+
+	removed, err := s.cleanStalePartials()
+	if err != nil {
+		return err
+	}
+	if s.cfg.Sync.TempDir != "" {
+		if _, done := cleanedTempDirs.LoadOrStore(absPath(s.cfg.Sync.TempDir), struct{}{}); !done {
+			n, err := s.cleanTempDir()
+			removed += n
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if removed > 0 {
+		s.logger.Infow("Removed stale partial downloads", "count", removed)
+	}
+	return nil
+}
+
+// cleanStalePartials removes stale "<path>.tmp" files next to the tracked
+// blobs of this container, checking each blob's partial in turn rather than
+// walking the output path.
+func (s *Syncer) cleanStalePartials() (int, error) {
+	var removed int
+	var afterID int64
+	for {
+		page, err := s.db.GetBlobStatesPage("", afterID, pendingPageSize)
+		if err != nil {
+			return removed, fmt.Errorf("failed to get blob states: %w", err)
+		}
+		for _, blob := range page {
+			path := blob.LocalPath + tempSuffix
+			if blob.Status == storage.BlobStatusPending && s.tempPath(blob) == path {
+				continue
+			}
+			info, err := os.Lstat(path)
+			if err != nil || !info.Mode().IsRegular() {
+				continue
+			}
+			// A blob whose own name ends in .tmp is a finished download.
+			tracked, err := s.db.WithContainer("").IsTrackedLocalPath(path)
+			if err != nil {
+				return removed, fmt.Errorf("failed to look up %s: %w", path, err)
+			}
+			if tracked {
+				continue
+			}
+			if err := os.Remove(path); err != nil {
+				s.logger.Warnw("Failed to remove stale partial download", "path", path, "error", err)
+				continue
+			}
+			removed++
+		}
+		if len(page) < pendingPageSize {
+			return removed, nil
+		}
+		afterID = page[len(page)-1].ID
+	}
+}
+
+// cleanTempDir removes the files in sync.temp_dir that are not the partial
+// download of a pending blob of any container. Only the files found there
+// are held in memory while the pending blobs are paged through.
+func (s *Syncer) cleanTempDir() (int, error) {
+	entries, err := os.ReadDir(s.cfg.Sync.TempDir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to scan %s for partial downloads: %w", s.cfg.Sync.TempDir, err)
+	}
+
+	stale := make(map[string]struct{})
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && strings.HasSuffix(entry.Name(), tempSuffix) {
+			stale[filepath.Join(s.cfg.Sync.TempDir, entry.Name())] = struct{}{}
+		}
+	}
+
+	all := s.db.WithContainer("")
+	var afterID int64
+	for len(stale) > 0 {
+		page, err := all.GetPendingBlobsPage(afterID, pendingPageSize)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get pending blobs: %w", err)
+		}
+		for _, blob := range page {
+			delete(stale, s.tempPath(blob))
+		}
+		if len(page) < pendingPageSize {
+			break
+		}
+		afterID = page[len(page)-1].ID
+	}
+
+	var removed int
+	for path := range stale {
+		if err := os.Remove(path); err != nil {
+			s.logger.Warnw("Failed to remove stale partial download", "path", path, "error", err)
+			continue
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// discardPartial removes the partial download of blob, which cannot be
+// resumed once the blob has changed.
+func (s *Syncer) discardPartial(blob *storage.BlobState) {
+	if s.cfg.Sync.DryRun {
+		return
+	}
+	if err := os.Remove(s.tempPath(blob)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		s.logger.Warnw("Failed to remove partial download", "blob", blob.BlobName, "error", err)
+	}
+}
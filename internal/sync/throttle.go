@@ -0,0 +1,110 @@
+// Package sync provides load-based automatic throttling of download workers.
+package sync
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/haepapa/getblobz/internal/storage"
+)
+
+const (
+	// loadSampleInterval is how often system load is sampled when auto-throttle is enabled.
+	loadSampleInterval = 5 * time.Second
+	// throttlePollInterval is how often paused workers check whether throttling has lifted.
+	throttlePollInterval = 1 * time.Second
+	// throttledWorkers is the number of workers that keep running while throttled.
+	throttledWorkers = 1
+)
+
+// readLoadAverage returns the 1-minute load average from /proc/loadavg.
+func readLoadAverage() (float64, error) {
+	data, err := os.ReadFile("/proc/loadavg")
+	if err != nil {
+		return 0, err
+	}
+
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("unexpected /proc/loadavg format")
+	}
+
+	return strconv.ParseFloat(fields[0], 64)
+}
+
+// monitorLoad samples the system load and throttles workers while the load,
+// normalized by CPU count, exceeds the configured threshold.
+func (s *Syncer) monitorLoad(ctx context.Context) {
+	defer s.monitors.Done()
+	defer s.setThrottled(false, 0)
+
+	ticker := time.NewTicker(loadSampleInterval)
+	defer ticker.Stop()
+
+	for {
+		load, err := readLoadAverage()
+		if err != nil {
+			s.logger.Warnw("Auto-throttle disabled: unable to read system load", "error", err)
+			return
+		}
+
+		normalized := load / float64(runtime.NumCPU())
+		s.setThrottled(normalized > s.cfg.Performance.ThrottleThreshold, normalized)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// setThrottled updates the throttle state, logging and recording a metric on change.
+func (s *Syncer) setThrottled(throttled bool, load float64) {
+	if s.throttled.Swap(throttled) == throttled {
+		return
+	}
+
+	active := s.workers
+	if throttled {
+		active = min(s.workers, throttledWorkers)
+		s.logger.Warnw("System load above threshold; throttling workers",
+			"load", load,
+			"threshold", s.cfg.Performance.ThrottleThreshold,
+			"active_workers", active,
+		)
+	} else {
+		s.logger.Infow("Throttling lifted; resuming all workers",
+			"load", load,
+			"active_workers", active,
+		)
+	}
+
+	metric := &storage.PerformanceMetric{
+		SyncRunID:     s.runID,
+		Timestamp:     time.Now(),
+		ActiveWorkers: &active,
+		Throttled:     throttled,
+	}
+	if err := s.db.RecordMetric(metric); err != nil {
+		s.logger.Warnw("Failed to record throttle metric", "error", err)
+	}
+}
+
+// waitWhileThrottled blocks workers beyond the throttled allowance until
+// throttling lifts. It returns false if the sync is cancelled while waiting.
+func (s *Syncer) waitWhileThrottled(workerID int) bool {
+	for workerID >= throttledWorkers && s.throttled.Load() {
+		select {
+		case <-s.ctx.Done():
+			return false
+		case <-time.After(throttlePollInterval):
+		}
+	}
+	return true
+}
@@ -0,0 +1,58 @@
+// Package sync provides verification of downloaded files against recorded state.
+package sync
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/haepapa/getblobz/internal/storage"
+)
+
+// Problems reported by VerifyLocalFile.
+const (
+	// VerifyMissing indicates the local file does not exist.
+	VerifyMissing = "missing"
+	// VerifySizeMismatch indicates the local file size differs from the recorded size.
+	VerifySizeMismatch = "size mismatch"
+	// VerifyChecksumMismatch indicates the local file MD5 differs from the recorded MD5.
+	VerifyChecksumMismatch = "checksum mismatch"
+)
+
+// VerifyLocalFile checks that a downloaded blob's local file exists and has
+// the recorded size and, when checksums is set, the recorded MD5. It returns
+// an empty problem when the file matches. Blobs without a recorded MD5 are
+// only checked for size.
+func VerifyLocalFile(blob *storage.BlobState, checksums bool) (string, error) {
+	info, err := os.Stat(blob.LocalPath)
+	if os.IsNotExist(err) {
+		return VerifyMissing, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	if info.Size() != blob.SizeBytes {
+		return VerifySizeMismatch, nil
+	}
+
+	if !checksums || blob.ContentMD5 == nil {
+		return "", nil
+	}
+
+	expected, err := decodeMD5(*blob.ContentMD5)
+	if err != nil {
+		return "", fmt.Errorf("invalid recorded MD5 %q: %w", *blob.ContentMD5, err)
+	}
+
+	actual, err := fileMD5(blob.LocalPath)
+	if err != nil {
+		return "", err
+	}
+
+	if !bytes.Equal(actual, expected) {
+		return VerifyChecksumMismatch, nil
+	}
+
+	return "", nil
+}
@@ -0,0 +1,49 @@
+package sync
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/haepapa/getblobz/internal/storage"
+)
+
+func TestVerifyLocalFile(t *testing.T) {
+	dir := t.TempDir()
+	content := []byte("verified content")
+	sum := md5.Sum(content)
+	goodMD5 := base64.StdEncoding.EncodeToString(sum[:])
+	badSum := md5.Sum([]byte("other content"))
+	badMD5 := base64.StdEncoding.EncodeToString(badSum[:])
+
+	localPath := filepath.Join(dir, "file.txt")
+	if err := os.WriteFile(localPath, content, 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		blob      *storage.BlobState
+		checksums bool
+		want      string
+	}{
+		{"match", &storage.BlobState{LocalPath: localPath, SizeBytes: int64(len(content)), ContentMD5: &goodMD5}, true, ""},
+		{"missing", &storage.BlobState{LocalPath: filepath.Join(dir, "gone.txt"), SizeBytes: 1}, false, VerifyMissing},
+		{"size", &storage.BlobState{LocalPath: localPath, SizeBytes: 1}, false, VerifySizeMismatch},
+		{"checksum", &storage.BlobState{LocalPath: localPath, SizeBytes: int64(len(content)), ContentMD5: &badMD5}, true, VerifyChecksumMismatch},
+		{"checksum skipped", &storage.BlobState{LocalPath: localPath, SizeBytes: int64(len(content)), ContentMD5: &badMD5}, false, ""},
+	}
+
+	for _, tt := range tests {
+		got, err := VerifyLocalFile(tt.blob, tt.checksums)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: problem = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
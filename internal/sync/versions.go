@@ -0,0 +1,70 @@
+// Package sync provides selection of pinned blob versions during discovery.
+package sync
+
+import (
+	"time"
+
+	"github.com/haepapa/getblobz/internal/azure"
+)
+
+// listBlobs lists one discovery page. With a pinned version, every version
+// is listed and each blob is replaced by the version selected for it. A
+// blob's versions are listed together but may continue on the next page, so
+// the last blob of a page is held back until its versions are complete.
+func (s *Syncer) listBlobs(marker *string, size int32) ([]*azure.BlobInfo, *string, error) {
+	if s.cfg.Sync.Version == "" {
+		return s.client.ListBlobs(s.ctx, s.cfg.Sync.Container, s.cfg.Sync.Prefix, marker, size)
+	}
+
+	blobs, next, err := s.client.ListBlobsWithOptions(s.ctx, s.cfg.Sync.Container, s.cfg.Sync.Prefix,
+		marker, size, azure.ListOptions{Versions: true})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	blobs = append(s.heldVersions, blobs...)
+	s.heldVersions = nil
+	if next != nil && len(blobs) > 0 {
+		last := blobs[len(blobs)-1].Name
+		cut := len(blobs)
+		for cut > 0 && blobs[cut-1].Name == last {
+			cut--
+		}
+		s.heldVersions = append(s.heldVersions, blobs[cut:]...)
+		blobs = blobs[:cut]
+	}
+
+	return selectVersions(blobs, s.cfg.Sync.VersionTime), next, nil
+}
+
+// selectVersions keeps, for each blob in blobs, the newest version whose ID
+// is not after asOf. blobs must list the versions of each blob together.
+// Blobs without such a version did not exist at asOf and are left out, as
+// are snapshots.
+func selectVersions(blobs []*azure.BlobInfo, asOf time.Time) []*azure.BlobInfo {
+	var selected []*azure.BlobInfo
+	var best *azure.BlobInfo
+	var bestTime time.Time
+
+	for _, blob := range blobs {
+		if best != nil && blob.Name != best.Name {
+			selected = append(selected, best)
+			best = nil
+		}
+		if blob.Snapshot != "" {
+			continue
+		}
+		versionTime, err := time.Parse(time.RFC3339Nano, blob.VersionID)
+		if err != nil || versionTime.After(asOf) {
+			continue
+		}
+		if best == nil || versionTime.After(bestTime) {
+			best, bestTime = blob, versionTime
+		}
+	}
+	if best != nil {
+		selected = append(selected, best)
+	}
+
+	return selected
+}
@@ -2,28 +2,80 @@
 package sync
 
 import (
+	"bytes"
+	"context"
 	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"hash"
 	"io"
+	"math/rand"
+	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/haepapa/getblobz/internal/audit"
+	"github.com/haepapa/getblobz/internal/azure"
+	"github.com/haepapa/getblobz/internal/config"
+	"github.com/haepapa/getblobz/internal/organizer"
 	"github.com/haepapa/getblobz/internal/storage"
 )
 
-const (
-	maxRetries = 3
-	baseDelay  = 1 * time.Second
-)
+// throttledBackoffFactor scales the retry backoff after the storage account
+// reports that it is busy.
+const throttledBackoffFactor = 4
+
+// backoffDelay returns the wait before retry attempt (1-based) using
+// exponential backoff with jitter, so that workers retrying against a
+// throttled account do not all retry at the same moment. The delay is
+// between half and all of base * 2^(attempt-1), capped at maxDelay.
+func backoffDelay(attempt int, base, maxDelay time.Duration) time.Duration {
+	delay := base
+	for i := 1; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// sleepContext waits for d or until ctx is cancelled, and reports whether
+// the full duration elapsed.
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
 
 // worker is a goroutine that processes blobs from the queue.
 func (s *Syncer) worker(id int, queue <-chan *storage.BlobState) {
 	defer s.wg.Done()
 
 	for {
+		if !s.waitWhileThrottled(id) || !s.waitForWorkerLimit(id) || !s.waitForMemory() {
+			return
+		}
+
 		select {
 		case <-s.ctx.Done():
 			return
@@ -31,51 +83,75 @@ func (s *Syncer) worker(id int, queue <-chan *storage.BlobState) {
 			if !ok {
 				return
 			}
+			s.activeWorkers.Add(1)
+			s.metrics.WorkerBusy(s.cfg.Sync.Container, 1)
 			s.processBlob(id, blob)
+			s.metrics.WorkerBusy(s.cfg.Sync.Container, -1)
+			s.activeWorkers.Add(-1)
 		}
 	}
 }
 
-// fsUsagePercent calculates filesystem usage percent for the directory containing the target path.
-func fsUsagePercent(dir string) (int, error) {
-	var stat syscall.Statfs_t
-	if err := syscall.Statfs(dir, &stat); err != nil {
-		return 0, err
-	}
-	// Use Bavail for non-root available blocks.
-	total := float64(stat.Blocks) * float64(stat.Bsize)
-	avail := float64(stat.Bavail) * float64(stat.Bsize)
-	if total <= 0 {
+// usagePercent converts filesystem totals into a usage percentage in 0-100.
+func usagePercent(total, avail uint64) (int, error) {
+	if total == 0 {
 		return 0, fmt.Errorf("invalid filesystem size")
 	}
-	usedPercent := int(((total - avail) / total) * 100.0)
-	if usedPercent < 0 {
-		usedPercent = 0
+	if avail > total {
+		avail = total
 	}
-	if usedPercent > 100 {
-		usedPercent = 100
+	return int(float64(total-avail) / float64(total) * 100.0), nil
+}
+
+// diskUsageCacheTTL is how long a disk usage reading is reused.
+const diskUsageCacheTTL = 5 * time.Second
+
+// outputDiskUsage returns the usage percent of the filesystem holding the
+// output path, measuring it at most once per diskUsageCacheTTL.
+func (s *Syncer) outputDiskUsage() (int, error) {
+	s.diskMu.Lock()
+	defer s.diskMu.Unlock()
+
+	if s.diskChecked.IsZero() || time.Since(s.diskChecked) >= diskUsageCacheTTL {
+		s.diskPercent, s.diskErr = s.diskUsage(s.cfg.Sync.OutputPath)
+		s.diskChecked = time.Now()
 	}
-	return usedPercent, nil
+	return s.diskPercent, s.diskErr
+}
+
+// DiskUsagePercent reports the filesystem usage percent for dir, as compared
+// against the disk warn and stop thresholds during a sync.
+func DiskUsagePercent(dir string) (int, error) {
+	return diskUsagePercent(dir)
 }
 
 // processBlob downloads and saves a single blob with retry logic.
 func (s *Syncer) processBlob(workerID int, blob *storage.BlobState) {
 	var lastErr error
 
-	for attempt := 0; attempt < maxRetries; attempt++ {
+	// The properties of the current blob say nothing about an older version.
+	if s.cfg.Sync.VerifyChecksums && blob.ContentMD5 == nil && blob.VersionID == "" {
+		s.fillContentMD5(workerID, blob)
+	}
+
+	retry := s.cfg.Retry
+	for attempt := 0; attempt < retry.MaxAttempts; attempt++ {
 		if attempt > 0 {
-			delay := baseDelay * time.Duration(1<<uint(attempt-1))
+			delay := retryDelay(attempt, retry, lastErr)
 			s.logger.Infow("Retrying blob download",
 				"worker", workerID,
 				"blob", blob.BlobName,
 				"attempt", attempt+1,
 				"delay", delay,
 			)
-			time.Sleep(delay)
+			if !sleepContext(s.ctx, delay) {
+				// Stopped during backoff: leave the blob pending for a resumed run.
+				return
+			}
 		}
 
 		// Check disk usage before attempting download
-		usage, duErr := fsUsagePercent(filepath.Dir(s.cfg.Sync.OutputPath))
+		usage, duErr := s.outputDiskUsage()
 		if duErr == nil {
 			if usage >= s.cfg.Sync.DiskStopPercent {
 				s.logger.Errorw("Filesystem usage exceeded stop threshold; stopping downloads",
@@ -96,7 +172,21 @@ func (s *Syncer) processBlob(workerID int, blob *storage.BlobState) {
 		}
 
 		err := s.downloadBlob(workerID, blob)
+		if err != nil && s.ctx.Err() != nil {
+			// Stopped mid-download: leave the blob pending so a resumed run retries it.
+			s.logger.Debugw("Download interrupted", "worker", workerID, "blob", blob.BlobName)
+			return
+		}
 		if err == nil {
+			// A hook failure is not retried: the file itself downloaded fine.
+			if hookErr := s.runPostDownloadHook(workerID, blob); hookErr != nil {
+				lastErr = fmt.Errorf("post-download hook failed: %w", hookErr)
+				if err := s.db.RecordError(&s.runID, blob.BlobName, storage.ErrorTypeUnknown, lastErr.Error(), attempt); err != nil {
+					s.logger.Warnw("Failed to record error", "error", err)
+				}
+				break
+			}
+
 			blob.Status = storage.BlobStatusDownloaded
 			now := time.Now()
 			blob.LastSyncedAt = &now
@@ -110,6 +200,16 @@ func (s *Syncer) processBlob(workerID int, blob *storage.BlobState) {
 				)
 			}
 
+			// A linked duplicate transferred nothing.
+			transferred := blob.SizeBytes
+			if blob.LinkedFrom != "" {
+				transferred = 0
+			}
+			s.downloadedFiles.Add(1)
+			s.downloadedBytes.Add(transferred)
+			s.metrics.BlobDownloaded(s.cfg.Sync.Container, transferred)
+			s.recordAudit(audit.ActionDownloaded, blob)
+
 			s.logger.Infow("Downloaded blob",
 				"worker", workerID,
 				"blob", blob.BlobName,
@@ -120,6 +220,9 @@ func (s *Syncer) processBlob(workerID int, blob *storage.BlobState) {
 
 		lastErr = err
 		errorType := classifyError(err)
+		if errorType == storage.ErrorTypeThrottled {
+			s.throttledRequests.Add(1)
+		}
 		if err := s.db.RecordError(&s.runID, blob.BlobName, errorType, err.Error(), attempt); err != nil {
 			s.logger.Warnw("Failed to record error", "error", err)
 		}
@@ -129,6 +232,9 @@ func (s *Syncer) processBlob(workerID int, blob *storage.BlobState) {
 		}
 	}
 
+	s.failedFiles.Add(1)
+	s.metrics.BlobFailed(s.cfg.Sync.Container)
+
 	blob.Status = storage.BlobStatusFailed
 	errMsg := lastErr.Error()
 	blob.ErrorMessage = &errMsg
@@ -148,99 +254,565 @@ func (s *Syncer) processBlob(workerID int, blob *storage.BlobState) {
 	)
 }
 
+// fillContentMD5 fetches the blob's Content-MD5 from its properties when the
+// listing did not include one. Blobs uploaded in blocks often have no MD5 at
+// all, in which case verification is skipped with a warning.
+func (s *Syncer) fillContentMD5(workerID int, blob *storage.BlobState) {
+	props, err := s.client.GetBlobProperties(s.ctx, s.cfg.Sync.Container, blob.BlobName)
+	if err != nil {
+		s.logger.Warnw("Failed to get blob properties; checksum verification skipped",
+			"worker", workerID,
+			"blob", blob.BlobName,
+			"error", err,
+		)
+		return
+	}
+
+	if len(props.ContentMD5) == 0 {
+		s.logger.Warnw("Blob has no server-side MD5; checksum verification skipped",
+			"worker", workerID,
+			"blob", blob.BlobName,
+		)
+		return
+	}
+
+	md5Str := base64.StdEncoding.EncodeToString(props.ContentMD5)
+	blob.ContentMD5 = &md5Str
+}
+
 // downloadBlob performs the actual blob download.
+// Encoded blobs are decompressed when configured, large blobs are fetched with
+// parallel ranged reads, and smaller blobs use a single stream that can resume
+// from a partial .tmp file.
 func (s *Syncer) downloadBlob(workerID int, blob *storage.BlobState) error {
+	if !organizer.Within(s.cfg.Sync.OutputPath, blob.LocalPath) {
+		return fmt.Errorf("refusing to write %s outside the output path", blob.LocalPath)
+	}
+
 	dir := filepath.Dir(blob.LocalPath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
+	if err := mkdirAll(dir, s.cfg.Sync.DirPerm); err != nil {
 		return fmt.Errorf("failed to create directory: %w", err)
 	}
 
-	tmpPath := blob.LocalPath + ".tmp"
-	file, err := os.Create(tmpPath)
+	// A linked file shares its timestamps with the file it is linked to, so
+	// it returns before they are set.
+	blob.LinkedFrom = ""
+	if s.cfg.Sync.Dedupe && s.linkDuplicate(workerID, blob) {
+		return nil
+	}
+
+	tmpPath := s.tempPath(blob)
+	if err := mkdirAll(filepath.Dir(tmpPath), s.cfg.Sync.DirPerm); err != nil {
+		return fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	file, err := os.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE, s.cfg.Sync.FilePerm)
 	if err != nil {
 		return fmt.Errorf("failed to create temp file: %w", err)
 	}
 	defer func() { _ = file.Close() }()
+	// The mode passed to OpenFile is reduced by the umask.
+	if err := file.Chmod(s.cfg.Sync.FilePerm); err != nil {
+		return fmt.Errorf("failed to set temp file mode: %w", err)
+	}
+
+	ctx, stall, stop := watchStall(s.ctx, s.cfg.Sync.BlobTimeout)
+	defer stop()
+
+	switch {
+	case blob.VersionID != "":
+		err = s.downloadVersion(ctx, stall, blob, file)
+	case s.cfg.Sync.DecompressOnDownload:
+		err = s.downloadDecompressed(ctx, stall, blob, file)
+	case s.useChunkedDownload(blob):
+		err = s.downloadChunked(ctx, stall, workerID, blob, file)
+	default:
+		err = s.downloadStream(ctx, stall, workerID, blob, file)
+	}
+	if err != nil {
+		if s.ctx.Err() != nil {
+			// Stopped mid-download: discard the partial file rather than leave it behind.
+			_ = file.Close()
+			_ = os.Remove(tmpPath)
+		} else if errors.Is(context.Cause(ctx), errDownloadStalled) {
+			// Any partial file is kept so the retry can resume from it.
+			return fmt.Errorf("%w: no data received for %s", errDownloadStalled, s.cfg.Sync.BlobTimeout)
+		}
+		return err
+	}
+
+	_ = file.Close()
+
+	if err := moveFile(tmpPath, blob.LocalPath); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to rename temp file: %w", err)
+	}
+
+	if s.cfg.Sync.PreserveTimestamps && !blob.LastModified.IsZero() {
+		if err := os.Chtimes(blob.LocalPath, time.Now(), blob.LastModified); err != nil {
+			s.logger.Warnw("Failed to set file modification time",
+				"worker", workerID,
+				"blob", blob.BlobName,
+				"error", err,
+			)
+		}
+	}
+
+	return nil
+}
+
+// tempPath returns where a blob is written while it downloads. By default
+// this is next to the destination so the final rename stays on one
+// filesystem. With sync.temp_dir set, the name is derived from the container
+// and blob name so that an interrupted download still resumes from its
+// partial file, and containers sharing temp_dir never share one.
+func (s *Syncer) tempPath(blob *storage.BlobState) string {
+	if s.cfg.Sync.TempDir == "" {
+		return blob.LocalPath + ".tmp"
+	}
+	// States read back from the database carry their container; states
+	// built by discovery belong to the container being synced.
+	container := blob.ContainerName
+	if container == "" {
+		container = s.cfg.Sync.Container
+	}
+	// Container names cannot contain "/", so the key is unambiguous.
+	sum := sha256.Sum256([]byte(container + "/" + blob.BlobName))
+	return filepath.Join(s.cfg.Sync.TempDir, hex.EncodeToString(sum[:16])+".tmp")
+}
+
+// rename is os.Rename, replaceable in tests.
+var rename = os.Rename
+
+// moveFile moves src to dst. When they are on different filesystems the
+// rename fails with EXDEV, and the file is copied and the source removed.
+func moveFile(src, dst string) error {
+	err := rename(src, dst)
+	if err == nil || !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+
+	if err := copyFile(src, dst); err != nil {
+		_ = os.Remove(dst)
+		return err
+	}
+	return os.Remove(src)
+}
+
+// copyFile copies the contents of src into a new file at dst with the same
+// permission mode.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer func() { _ = in.Close() }()
+
+	info, err := in.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat file: %w", err)
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	if err := out.Chmod(info.Mode().Perm()); err != nil {
+		_ = out.Close()
+		return fmt.Errorf("failed to set file mode: %w", err)
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return fmt.Errorf("failed to copy file: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("failed to close file: %w", err)
+	}
+	return nil
+}
+
+// mkdirAll creates dir and any missing parents with mode. Unlike
+// os.MkdirAll the mode is not reduced by the umask; directories that already
+// exist keep their own.
+func mkdirAll(dir string, mode os.FileMode) error {
+	var missing []string
+	for d := filepath.Clean(dir); ; d = filepath.Dir(d) {
+		if _, err := os.Stat(d); err == nil {
+			break
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+		missing = append(missing, d)
+		if filepath.Dir(d) == d {
+			break
+		}
+	}
+
+	if err := os.MkdirAll(dir, mode); err != nil {
+		return err
+	}
+	for _, d := range missing {
+		if err := os.Chmod(d, mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// useChunkedDownload reports whether a blob is large enough to use parallel chunked downloads.
+// Chunking is disabled under a bandwidth limit since it cannot increase throughput.
+func (s *Syncer) useChunkedDownload(blob *storage.BlobState) bool {
+	if s.limiter != nil {
+		return false
+	}
+	threshold := int64(s.cfg.Performance.ChunkThresholdMB) * 1024 * 1024
+	return threshold > 0 && blob.SizeBytes > threshold
+}
+
+// downloadStream downloads a blob as a single stream into file.
+// A partial file left by an earlier attempt is kept and the download resumes
+// from its current size using a ranged request.
+func (s *Syncer) downloadStream(ctx context.Context, stall *stallWatch, workerID int, blob *storage.BlobState, file *os.File) error {
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat temp file: %w", err)
+	}
 
 	var writer io.Writer = file
-	var hash io.Writer
+	var hasher hash.Hash
+
+	if s.cfg.Sync.VerifyChecksums && blob.ContentMD5 != nil {
+		hasher = md5.New()
+		writer = io.MultiWriter(file, hasher)
+	}
+	writer = s.throttle(ctx, stall.writer(writer))
+
+	// A complete partial file is only trusted when its checksum is verified.
+	offset := info.Size()
+	if offset > blob.SizeBytes || (offset == blob.SizeBytes && hasher == nil) {
+		if err := file.Truncate(0); err != nil {
+			return fmt.Errorf("failed to truncate temp file: %w", err)
+		}
+		offset = 0
+	}
+
+	// Hash the bytes already on disk; this also positions the file at offset.
+	if hasher != nil {
+		if _, err := io.CopyN(hasher, file, offset); err != nil {
+			return fmt.Errorf("failed to read partial temp file: %w", err)
+		}
+	} else if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek temp file: %w", err)
+	}
+
+	if offset > 0 {
+		s.logger.Infow("Resuming partial download",
+			"worker", workerID,
+			"blob", blob.BlobName,
+			"offset", offset,
+			"size", blob.SizeBytes,
+		)
+	}
+
+	if offset < blob.SizeBytes || blob.SizeBytes == 0 {
+		// The ETag condition keeps a blob overwritten since discovery from
+		// being appended to the partial file of the old one.
+		err = s.client.DownloadBlobRange(ctx, s.cfg.Sync.Container, blob.BlobName, offset, blob.ETag, writer)
+		if azure.IsConditionNotMetError(err) {
+			_ = file.Close()
+			_ = os.Remove(file.Name())
+			return fmt.Errorf("blob changed since it was listed: %w", err)
+		}
+		if err != nil {
+			return fmt.Errorf("download failed: %w", err)
+		}
+	}
+
+	if hasher != nil {
+		if err := verifyChecksum(hasher, *blob.ContentMD5); err != nil {
+			_ = file.Close()
+			_ = os.Remove(file.Name())
+			return err
+		}
+	}
+
+	return nil
+}
+
+// downloadChunked downloads a blob into file using concurrent ranged reads.
+// Chunks may complete out of order, so a partial file cannot be resumed and
+// is discarded on failure.
+func (s *Syncer) downloadChunked(ctx context.Context, stall *stallWatch, workerID int, blob *storage.BlobState, file *os.File) error {
+	if err := file.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate temp file: %w", err)
+	}
+
+	s.logger.Debugw("Using chunked download",
+		"worker", workerID,
+		"blob", blob.BlobName,
+		"size", blob.SizeBytes,
+	)
+
+	chunkSize := int64(s.cfg.Performance.ChunkSizeMB) * 1024 * 1024
+	err := s.client.DownloadBlobChunked(ctx, s.cfg.Sync.Container, blob.BlobName, blob.ETag, stall.writerAt(file),
+		blob.SizeBytes, chunkSize, s.cfg.Performance.ChunkConcurrency)
+	if err != nil {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+		if azure.IsConditionNotMetError(err) {
+			return fmt.Errorf("blob changed since it was listed: %w", err)
+		}
+		return fmt.Errorf("download failed: %w", err)
+	}
 
 	if s.cfg.Sync.VerifyChecksums && blob.ContentMD5 != nil {
 		hasher := md5.New()
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("failed to seek temp file: %w", err)
+		}
+		if _, err := io.Copy(hasher, file); err != nil {
+			return fmt.Errorf("failed to read temp file: %w", err)
+		}
+		if err := verifyChecksum(hasher, *blob.ContentMD5); err != nil {
+			_ = file.Close()
+			_ = os.Remove(file.Name())
+			return err
+		}
+	}
+
+	return nil
+}
+
+// downloadDecompressed downloads a blob, decompressing encoded content.
+// The decompressed size differs from the stored size, so a partial file
+// cannot be resumed; the checksum is verified against the stored bytes.
+func (s *Syncer) downloadDecompressed(ctx context.Context, stall *stallWatch, blob *storage.BlobState, file *os.File) error {
+	if err := file.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate temp file: %w", err)
+	}
+
+	var hasher hash.Hash
+	var raw io.Writer
+	if s.cfg.Sync.VerifyChecksums && blob.ContentMD5 != nil {
+		hasher = md5.New()
+		raw = hasher
+	}
+
+	err := s.client.DownloadBlobDecompressed(ctx, s.cfg.Sync.Container, blob.BlobName, s.throttle(ctx, stall.writer(file)), raw)
+	if err != nil {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+		return fmt.Errorf("download failed: %w", err)
+	}
+
+	if hasher != nil {
+		if err := verifyChecksum(hasher, *blob.ContentMD5); err != nil {
+			_ = file.Close()
+			_ = os.Remove(file.Name())
+			return err
+		}
+	}
+
+	return nil
+}
+
+// downloadVersion downloads the version of a blob pinned at discovery into
+// file. Version downloads always start over rather than resuming.
+func (s *Syncer) downloadVersion(ctx context.Context, stall *stallWatch, blob *storage.BlobState, file *os.File) error {
+	if err := file.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate temp file: %w", err)
+	}
+
+	var writer io.Writer = file
+	var hasher hash.Hash
+	if s.cfg.Sync.VerifyChecksums && blob.ContentMD5 != nil {
+		hasher = md5.New()
 		writer = io.MultiWriter(file, hasher)
-		hash = hasher
 	}
 
-	err = s.client.DownloadBlob(s.ctx, s.cfg.Sync.Container, blob.BlobName, writer)
+	err := s.client.DownloadBlobVersion(ctx, s.cfg.Sync.Container, blob.BlobName, blob.VersionID, s.throttle(ctx, stall.writer(writer)))
 	if err != nil {
-		_ = os.Remove(tmpPath)
 		return fmt.Errorf("download failed: %w", err)
 	}
 
-	if s.cfg.Sync.VerifyChecksums && blob.ContentMD5 != nil && hash != nil {
-		computed := hex.EncodeToString(hash.(interface{ Sum([]byte) []byte }).Sum(nil))
-		if computed != *blob.ContentMD5 {
-			_ = os.Remove(tmpPath)
-			return fmt.Errorf("checksum mismatch: expected %s, got %s", *blob.ContentMD5, computed)
+	if hasher != nil {
+		if err := verifyChecksum(hasher, *blob.ContentMD5); err != nil {
+			_ = file.Close()
+			_ = os.Remove(file.Name())
+			return err
 		}
 	}
 
-	_ = file.Close()
+	return nil
+}
 
-	if err := os.Rename(tmpPath, blob.LocalPath); err != nil {
-		_ = os.Remove(tmpPath)
-		return fmt.Errorf("failed to rename temp file: %w", err)
+// verifyChecksum compares the hasher's digest with the expected MD5.
+// Azure reports Content-MD5 as base64; older state databases stored hex.
+func verifyChecksum(hasher hash.Hash, expected string) error {
+	want, err := decodeMD5(expected)
+	if err != nil {
+		return fmt.Errorf("invalid expected checksum %q: %w", expected, err)
 	}
 
+	computed := hasher.Sum(nil)
+	if !bytes.Equal(computed, want) {
+		return fmt.Errorf("checksum mismatch: expected %s, got %s",
+			base64.StdEncoding.EncodeToString(want), base64.StdEncoding.EncodeToString(computed))
+	}
 	return nil
 }
 
+// decodeMD5 decodes a stored MD5 digest into its raw 16 bytes.
+func decodeMD5(s string) ([]byte, error) {
+	if len(s) == hex.EncodedLen(md5.Size) {
+		return hex.DecodeString(s)
+	}
+
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) != md5.Size {
+		return nil, fmt.Errorf("expected %d bytes, got %d", md5.Size, len(b))
+	}
+	return b, nil
+}
+
+// Keywords used to classify errors that did not come from the storage service.
+var (
+	checksumErrorKeywords = []string{"checksum", "md5"}
+	networkErrorKeywords  = []string{
+		"network", "timeout", "connection", "deadline exceeded",
+		"no such host", "broken pipe", "unexpected eof", "tls handshake",
+	}
+	diskErrorKeywords = []string{"disk", "no space", "permission denied", "read-only file system"}
+	authErrorKeywords = []string{"auth", "unauthorized", "forbidden"}
+)
+
 // classifyError categorizes errors for logging and reporting.
+// Errors returned by the storage service are classified by status code;
+// keyword matching is only used for local and transport errors.
 func classifyError(err error) string {
 	if err == nil {
 		return storage.ErrorTypeUnknown
 	}
 
-	errStr := err.Error()
-	if contains(errStr, "checksum") || contains(errStr, "md5") {
-		return storage.ErrorTypeChecksum
-	}
-	if contains(errStr, "network") || contains(errStr, "timeout") || contains(errStr, "connection") {
+	// A stalled download is retried like any other dropped connection.
+	if errors.Is(err, errDownloadStalled) {
 		return storage.ErrorTypeNetwork
 	}
-	if contains(errStr, "disk") || contains(errStr, "space") || contains(errStr, "permission") {
-		return storage.ErrorTypeDisk
+
+	var respErr *azcore.ResponseError
+	if errors.As(err, &respErr) {
+		return classifyResponseError(respErr)
 	}
-	if contains(errStr, "auth") || contains(errStr, "unauthorized") {
+
+	errStr := strings.ToLower(err.Error())
+	switch {
+	case containsAny(errStr, checksumErrorKeywords):
+		return storage.ErrorTypeChecksum
+	case containsAny(errStr, networkErrorKeywords):
+		return storage.ErrorTypeNetwork
+	case containsAny(errStr, diskErrorKeywords):
+		return storage.ErrorTypeDisk
+	case containsAny(errStr, authErrorKeywords):
 		return storage.ErrorTypeAuth
 	}
 
 	return storage.ErrorTypeUnknown
 }
 
+// classifyResponseError categorizes an error response from the storage service.
+func classifyResponseError(respErr *azcore.ResponseError) string {
+	switch {
+	case respErr.StatusCode == http.StatusUnauthorized,
+		respErr.StatusCode == http.StatusForbidden,
+		azure.IsAuthError(respErr):
+		return storage.ErrorTypeAuth
+	case respErr.StatusCode == http.StatusNotFound:
+		return storage.ErrorTypeNotFound
+	case respErr.StatusCode == http.StatusTooManyRequests,
+		respErr.StatusCode == http.StatusServiceUnavailable,
+		respErr.ErrorCode == string(bloberror.ServerBusy):
+		return storage.ErrorTypeThrottled
+	case respErr.StatusCode >= http.StatusInternalServerError:
+		return storage.ErrorTypeNetwork
+	}
+	return storage.ErrorTypeUnknown
+}
+
 // isRetryable determines if an error should trigger a retry.
 func isRetryable(err error) bool {
 	if err == nil {
 		return false
 	}
 
-	errType := classifyError(err)
-	return errType == storage.ErrorTypeNetwork || errType == storage.ErrorTypeChecksum
+	switch classifyError(err) {
+	case storage.ErrorTypeNetwork, storage.ErrorTypeChecksum, storage.ErrorTypeThrottled:
+		return true
+	}
+	return false
+}
+
+// retryDelay returns the wait before retry attempt (1-based) after lastErr.
+// Throttled requests back off for longer to give the account time to recover,
+// and never retry sooner than the service asked in its Retry-After header.
+func retryDelay(attempt int, retry config.RetryConfig, lastErr error) time.Duration {
+	if classifyError(lastErr) != storage.ErrorTypeThrottled {
+		return backoffDelay(attempt, retry.BaseDelay, retry.MaxDelay)
+	}
+	delay := backoffDelay(attempt, retry.BaseDelay*throttledBackoffFactor, retry.MaxDelay*throttledBackoffFactor)
+	if after := retryAfter(lastErr); after > delay {
+		delay = after
+	}
+	return delay
 }
 
-// contains checks if a string contains a substring (case-insensitive).
-func contains(s, substr string) bool {
-	return len(s) >= len(substr) && (s == substr || len(s) > len(substr) &&
-		(s[:len(substr)] == substr || s[len(s)-len(substr):] == substr ||
-			indexOf(s, substr) >= 0))
+// maxRetryAfter bounds the wait requested by a Retry-After header, so that a
+// malformed or hostile value cannot stall a worker indefinitely.
+const maxRetryAfter = 10 * time.Minute
+
+// retryAfter returns how long the storage service asked the client to wait
+// before retrying err, or 0 if it did not say. The millisecond headers sent
+// by Azure Storage take precedence over the standard Retry-After header,
+// which holds either seconds or an HTTP date.
+func retryAfter(err error) time.Duration {
+	var respErr *azcore.ResponseError
+	if !errors.As(err, &respErr) || respErr.RawResponse == nil {
+		return 0
+	}
+	header := respErr.RawResponse.Header
+
+	var after time.Duration
+	for _, name := range []string{"x-ms-retry-after-ms", "retry-after-ms"} {
+		if ms, err := strconv.ParseInt(header.Get(name), 10, 64); err == nil && ms > 0 {
+			after = time.Duration(ms) * time.Millisecond
+			break
+		}
+	}
+	if after == 0 {
+		value := header.Get("Retry-After")
+		if seconds, err := strconv.ParseInt(value, 10, 64); err == nil && seconds > 0 {
+			after = time.Duration(seconds) * time.Second
+		} else if at, err := http.ParseTime(value); err == nil {
+			after = time.Until(at)
+		}
+	}
+
+	if after < 0 {
+		return 0
+	}
+	if after > maxRetryAfter {
+		return maxRetryAfter
+	}
+	return after
 }
 
-// indexOf returns the index of substr in s, or -1 if not found.
-func indexOf(s, substr string) int {
-	for i := 0; i <= len(s)-len(substr); i++ {
-		if s[i:i+len(substr)] == substr {
-			return i
+// containsAny reports whether s contains any of the keywords.
+func containsAny(s string, keywords []string) bool {
+	for _, keyword := range keywords {
+		if strings.Contains(s, keyword) {
+			return true
 		}
 	}
-	return -1
+	return false
 }
@@ -0,0 +1,237 @@
+package sync
+
+import (
+	"context"
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/haepapa/getblobz/internal/config"
+	"github.com/haepapa/getblobz/internal/storage"
+)
+
+func TestVerifyChecksum(t *testing.T) {
+	content := []byte("hello world")
+
+	tests := []struct {
+		name     string
+		expected string
+		wantErr  bool
+	}{
+		{"azure base64", "XrY7u+Ae7tCTyyK7j1rNww==", false},
+		{"legacy hex", "5eb63bbbe01eeed093cb22bb8f5acdc3", false},
+		{"mismatch", "1B2M2Y8AsgTpgAmY7PhCfg==", true},
+		{"invalid", "not-a-checksum", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hasher := md5.New()
+			_, _ = hasher.Write(content)
+
+			err := verifyChecksum(hasher, tt.expected)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("verifyChecksum() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBackoffDelay(t *testing.T) {
+	base, maxDelay := time.Second, 5*time.Second
+	for attempt := 1; attempt <= 6; attempt++ {
+		full := base * time.Duration(1<<uint(attempt-1))
+		if full > maxDelay {
+			full = maxDelay
+		}
+		for i := 0; i < 50; i++ {
+			delay := backoffDelay(attempt, base, maxDelay)
+			if delay < full/2 || delay > full {
+				t.Fatalf("attempt %d: delay %s outside [%s, %s]", attempt, delay, full/2, full)
+			}
+		}
+	}
+}
+
+func TestSleepContext_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	start := time.Now()
+	if sleepContext(ctx, time.Minute) {
+		t.Error("expected sleep to be interrupted")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("sleep took %s after cancellation", elapsed)
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"nil", nil, storage.ErrorTypeUnknown},
+		{"checksum mismatch", errors.New("checksum mismatch: expected abc, got def"), storage.ErrorTypeChecksum},
+		{"connection reset", errors.New("read tcp 10.0.0.5:51234->20.60.1.1:443: read: Connection reset by peer"), storage.ErrorTypeNetwork},
+		{"upper-case timeout", errors.New("TIMEOUT waiting for response"), storage.ErrorTypeNetwork},
+		{"client timeout", errors.New("context deadline exceeded (Client.Timeout exceeded while awaiting headers)"), storage.ErrorTypeNetwork},
+		{"dns failure", errors.New("dial tcp: lookup acct.blob.core.windows.net: no such host"), storage.ErrorTypeNetwork},
+		{"truncated body", fmt.Errorf("failed to write file: %w", errors.New("unexpected EOF")), storage.ErrorTypeNetwork},
+		{"disk full", errors.New("failed to write file: write /data/a.bin.tmp: no space left on device"), storage.ErrorTypeDisk},
+		{"permission denied", errors.New("failed to create file: open /data/a.bin.tmp: permission denied"), storage.ErrorTypeDisk},
+		{"sdk auth failure", &azcore.ResponseError{StatusCode: http.StatusForbidden, ErrorCode: "AuthenticationFailed"}, storage.ErrorTypeAuth},
+		{"wrapped sdk permission mismatch", fmt.Errorf("failed to download blob: %w",
+			&azcore.ResponseError{StatusCode: http.StatusForbidden, ErrorCode: "AuthorizationPermissionMismatch"}), storage.ErrorTypeAuth},
+		{"sdk forbidden without code", &azcore.ResponseError{StatusCode: http.StatusForbidden}, storage.ErrorTypeAuth},
+		{"sdk blob not found", &azcore.ResponseError{StatusCode: http.StatusNotFound, ErrorCode: "BlobNotFound"}, storage.ErrorTypeNotFound},
+		{"sdk too many requests", &azcore.ResponseError{StatusCode: http.StatusTooManyRequests}, storage.ErrorTypeThrottled},
+		{"sdk server busy", &azcore.ResponseError{StatusCode: http.StatusServiceUnavailable, ErrorCode: "ServerBusy"}, storage.ErrorTypeThrottled},
+		{"sdk internal error", &azcore.ResponseError{StatusCode: http.StatusInternalServerError, ErrorCode: "InternalError"}, storage.ErrorTypeNetwork},
+		{"sdk lease conflict", &azcore.ResponseError{StatusCode: http.StatusConflict, ErrorCode: "LeaseIdMissing"}, storage.ErrorTypeUnknown},
+		{"unauthorized text", errors.New("401 Unauthorized"), storage.ErrorTypeAuth},
+		{"unrecognised", errors.New("something odd happened"), storage.ErrorTypeUnknown},
+	}
+
+	for _, tt := range tests {
+		if got := classifyError(tt.err); got != tt.want {
+			t.Errorf("%s: classifyError() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	throttled := func(header http.Header) error {
+		return fmt.Errorf("failed to download blob: %w", &azcore.ResponseError{
+			StatusCode:  http.StatusServiceUnavailable,
+			RawResponse: &http.Response{StatusCode: http.StatusServiceUnavailable, Header: header},
+		})
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want time.Duration
+	}{
+		{"seconds", throttled(http.Header{"Retry-After": {"7"}}), 7 * time.Second},
+		{"milliseconds", throttled(http.Header{"X-Ms-Retry-After-Ms": {"1500"}, "Retry-After": {"2"}}), 1500 * time.Millisecond},
+		{"capped", throttled(http.Header{"Retry-After": {"86400"}}), maxRetryAfter},
+		{"date in the past", throttled(http.Header{"Retry-After": {"Tue, 02 Jan 2024 03:04:05 GMT"}}), 0},
+		{"malformed", throttled(http.Header{"Retry-After": {"soon"}}), 0},
+		{"no header", throttled(http.Header{}), 0},
+		{"not a response error", errors.New("connection reset by peer"), 0},
+	}
+
+	for _, tt := range tests {
+		if got := retryAfter(tt.err); got != tt.want {
+			t.Errorf("%s: retryAfter() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	future := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	if got := retryAfter(throttled(http.Header{"Retry-After": {future}})); got < 59*time.Minute && got != maxRetryAfter {
+		t.Errorf("date in the future: retryAfter() = %v, want about an hour capped to %v", got, maxRetryAfter)
+	}
+}
+
+func TestRetryDelay_HonoursRetryAfter(t *testing.T) {
+	retry := config.RetryConfig{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 2 * time.Millisecond}
+	err := &azcore.ResponseError{
+		StatusCode:  http.StatusTooManyRequests,
+		RawResponse: &http.Response{Header: http.Header{"Retry-After": {"3"}}},
+	}
+	if got := retryDelay(1, retry, err); got != 3*time.Second {
+		t.Errorf("retryDelay() = %v, want the 3s Retry-After", got)
+	}
+
+	// A server error is not throttling, so its Retry-After is not needed.
+	err.StatusCode = http.StatusInternalServerError
+	if got := retryDelay(1, retry, err); got > retry.MaxDelay {
+		t.Errorf("retryDelay() = %v for a server error, want at most %v", got, retry.MaxDelay)
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"throttled", &azcore.ResponseError{StatusCode: http.StatusServiceUnavailable}, true},
+		{"server error", &azcore.ResponseError{StatusCode: http.StatusBadGateway}, true},
+		{"not found", &azcore.ResponseError{StatusCode: http.StatusNotFound}, false},
+		{"auth", &azcore.ResponseError{StatusCode: http.StatusForbidden}, false},
+		{"connection reset", errors.New("connection reset by peer"), true},
+		{"disk full", errors.New("no space left on device"), false},
+	}
+
+	for _, tt := range tests {
+		if got := isRetryable(tt.err); got != tt.want {
+			t.Errorf("%s: isRetryable() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMoveFile_CrossDevice(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "blob.tmp")
+	dst := filepath.Join(dir, "blob.bin")
+	if err := os.WriteFile(src, []byte("payload"), 0644); err != nil {
+		t.Fatalf("failed to write source: %v", err)
+	}
+
+	rename = func(oldpath, newpath string) error {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: syscall.EXDEV}
+	}
+	t.Cleanup(func() { rename = os.Rename })
+
+	if err := moveFile(src, dst); err != nil {
+		t.Fatalf("moveFile failed: %v", err)
+	}
+
+	got, err := os.ReadFile(dst)
+	if err != nil || string(got) != "payload" {
+		t.Errorf("destination = %q (err %v), want %q", got, err, "payload")
+	}
+	if _, err := os.Stat(src); !os.IsNotExist(err) {
+		t.Errorf("expected source to be removed, stat err = %v", err)
+	}
+}
+
+func TestDiskUsagePercent(t *testing.T) {
+	usage, err := diskUsagePercent(t.TempDir())
+	if err != nil {
+		t.Fatalf("diskUsagePercent failed: %v", err)
+	}
+	if usage < 0 || usage > 100 {
+		t.Errorf("usage = %d, want a value between 0 and 100", usage)
+	}
+
+	if _, err := diskUsagePercent(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("expected an error for a missing directory")
+	}
+}
+
+func TestTempPath_ScopedByContainer(t *testing.T) {
+	s, _ := newTestSyncer(t, newStubClient(nil))
+	s.cfg.Sync.TempDir = t.TempDir()
+
+	discovered := s.tempPath(&storage.BlobState{BlobName: "a.txt"})
+	stored := s.tempPath(&storage.BlobState{ContainerName: "test-container", BlobName: "a.txt"})
+	other := s.tempPath(&storage.BlobState{ContainerName: "other", BlobName: "a.txt"})
+
+	if discovered != stored {
+		t.Errorf("temp path of a discovered blob = %s, want %s as stored", discovered, stored)
+	}
+	if other == stored {
+		t.Errorf("blobs with the same name in two containers share temp path %s", other)
+	}
+}
@@ -0,0 +1,95 @@
+// Package telemetry provides liveness and readiness endpoints for probes.
+package telemetry
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Health tracks the outcome of sync runs to answer readiness probes. Its
+// methods are safe to call on a nil *Health, which records nothing and is
+// never ready.
+type Health struct {
+	// staleAfter is how long after the last successful run the process
+	// stops being ready.
+	staleAfter time.Duration
+	// now returns the current time; replaced in tests.
+	now func() time.Time
+
+	mu          sync.Mutex
+	lastSuccess time.Time
+	lastErr     error
+}
+
+// NewHealth creates a Health that reports ready once a run succeeds, until
+// a run fails or staleAfter passes without another success.
+func NewHealth(staleAfter time.Duration) *Health {
+	return &Health{staleAfter: staleAfter, now: time.Now}
+}
+
+// RunSucceeded records a successful run.
+func (h *Health) RunSucceeded() {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lastSuccess = h.now()
+	h.lastErr = nil
+}
+
+// RunFailed records a failed run, which stays the reported state until the
+// next run succeeds.
+func (h *Health) RunFailed(err error) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lastErr = err
+}
+
+// Ready returns nil if the most recent run succeeded within the staleness
+// window, and otherwise the reason the process is not ready.
+func (h *Health) Ready() error {
+	if h == nil {
+		return fmt.Errorf("no run has succeeded yet")
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	switch {
+	case h.lastErr != nil:
+		return fmt.Errorf("last run failed: %w", h.lastErr)
+	case h.lastSuccess.IsZero():
+		return fmt.Errorf("no run has succeeded yet")
+	case h.now().Sub(h.lastSuccess) > h.staleAfter:
+		return fmt.Errorf("no run has succeeded since %s", h.lastSuccess.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// Handler returns an HTTP handler serving /healthz, which reports the
+// process is alive, and /readyz, which returns 503 while it is not ready.
+func (h *Health) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if err := h.Ready(); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = fmt.Fprintln(w, "ok")
+	})
+	return mux
+}
+
+// ServeHealth starts serving h's endpoints on addr, such as ":8080", in the
+// background. It fails if the address cannot be bound.
+func ServeHealth(addr string, h *Health) (*Server, error) {
+	return serve(addr, h.Handler())
+}
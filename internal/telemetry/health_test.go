@@ -0,0 +1,107 @@
+package telemetry
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHealth_Endpoints(t *testing.T) {
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		record    func(h *Health, now *time.Time)
+		wantReady int
+	}{
+		{
+			name:      "no run yet",
+			record:    func(h *Health, now *time.Time) {},
+			wantReady: http.StatusServiceUnavailable,
+		},
+		{
+			name: "recent success",
+			record: func(h *Health, now *time.Time) {
+				h.RunSucceeded()
+				*now = now.Add(10 * time.Minute)
+			},
+			wantReady: http.StatusOK,
+		},
+		{
+			name: "stale success",
+			record: func(h *Health, now *time.Time) {
+				h.RunSucceeded()
+				*now = now.Add(16 * time.Minute)
+			},
+			wantReady: http.StatusServiceUnavailable,
+		},
+		{
+			name: "last run failed",
+			record: func(h *Health, now *time.Time) {
+				h.RunSucceeded()
+				h.RunFailed(errors.New("container not found"))
+			},
+			wantReady: http.StatusServiceUnavailable,
+		},
+		{
+			name: "recovered after failure",
+			record: func(h *Health, now *time.Time) {
+				h.RunFailed(errors.New("container not found"))
+				h.RunSucceeded()
+			},
+			wantReady: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := start
+			h := NewHealth(15 * time.Minute)
+			h.now = func() time.Time { return now }
+
+			srv, err := ServeHealth("127.0.0.1:0", h)
+			if err != nil {
+				t.Fatalf("ServeHealth() error = %v", err)
+			}
+			defer func() { _ = srv.Shutdown() }()
+
+			tt.record(h, &now)
+
+			if got := getStatus(t, "http://"+srv.Addr()+"/healthz"); got != http.StatusOK {
+				t.Errorf("%s: /healthz status = %d, want %d", tt.name, got, http.StatusOK)
+			}
+			if got := getStatus(t, "http://"+srv.Addr()+"/readyz"); got != tt.wantReady {
+				t.Errorf("%s: /readyz status = %d, want %d", tt.name, got, tt.wantReady)
+			}
+		})
+	}
+}
+
+func TestHealth_NilIsNoop(t *testing.T) {
+	var h *Health
+	h.RunSucceeded()
+	h.RunFailed(errors.New("failed"))
+	if err := h.Ready(); err == nil {
+		t.Error("expected a nil Health not to be ready")
+	}
+
+	rec := httptest.NewRecorder()
+	h.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("/readyz on a nil Health = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func getStatus(t *testing.T, url string) int {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s error = %v", url, err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode
+}
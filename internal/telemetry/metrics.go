@@ -0,0 +1,170 @@
+// Package telemetry exposes sync activity as Prometheus metrics.
+//
+// All methods are safe to call on a nil *Metrics, which records nothing, so
+// callers do not need to check whether metrics are enabled.
+package telemetry
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// shutdownTimeout bounds how long Shutdown waits for in-flight requests.
+const shutdownTimeout = 5 * time.Second
+
+// Metrics holds the Prometheus collectors for sync activity. Every metric
+// is labelled with the container it describes.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	blobsDownloaded *prometheus.CounterVec
+	blobsFailed     *prometheus.CounterVec
+	blobsSkipped    *prometheus.CounterVec
+	bytesDownloaded *prometheus.CounterVec
+	syncRuns        *prometheus.CounterVec
+	activeWorkers   *prometheus.GaugeVec
+	lastRunDuration *prometheus.GaugeVec
+	lastRunTime     *prometheus.GaugeVec
+}
+
+// New creates a Metrics with its own registry, which also carries the
+// standard Go runtime and process collectors.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		blobsDownloaded: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "getblobz_blobs_downloaded_total",
+			Help: "Blobs downloaded successfully.",
+		}, []string{"container"}),
+		blobsFailed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "getblobz_blobs_failed_total",
+			Help: "Blobs that failed to download after all retries.",
+		}, []string{"container"}),
+		blobsSkipped: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "getblobz_blobs_skipped_total",
+			Help: "Blobs skipped during discovery because the local copy is up to date.",
+		}, []string{"container"}),
+		bytesDownloaded: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "getblobz_bytes_downloaded_total",
+			Help: "Bytes of blob data downloaded.",
+		}, []string{"container"}),
+		syncRuns: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "getblobz_sync_runs_total",
+			Help: "Sync runs finished, by final status.",
+		}, []string{"container", "status"}),
+		activeWorkers: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "getblobz_active_workers",
+			Help: "Workers currently downloading a blob.",
+		}, []string{"container"}),
+		lastRunDuration: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "getblobz_last_run_duration_seconds",
+			Help: "Duration of the most recently finished sync run.",
+		}, []string{"container"}),
+		lastRunTime: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "getblobz_last_run_timestamp_seconds",
+			Help: "Unix time the most recent sync run finished.",
+		}, []string{"container"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.blobsDownloaded, m.blobsFailed, m.blobsSkipped, m.bytesDownloaded,
+		m.syncRuns, m.activeWorkers, m.lastRunDuration, m.lastRunTime,
+	)
+	return m
+}
+
+// BlobDownloaded records a successful download of size bytes.
+func (m *Metrics) BlobDownloaded(container string, size int64) {
+	if m == nil {
+		return
+	}
+	m.blobsDownloaded.WithLabelValues(container).Inc()
+	m.bytesDownloaded.WithLabelValues(container).Add(float64(size))
+}
+
+// BlobFailed records a blob that failed to download.
+func (m *Metrics) BlobFailed(container string) {
+	if m == nil {
+		return
+	}
+	m.blobsFailed.WithLabelValues(container).Inc()
+}
+
+// BlobsSkipped records blobs skipped during discovery.
+func (m *Metrics) BlobsSkipped(container string, count int64) {
+	if m == nil {
+		return
+	}
+	m.blobsSkipped.WithLabelValues(container).Add(float64(count))
+}
+
+// WorkerBusy adjusts the active worker gauge by delta.
+func (m *Metrics) WorkerBusy(container string, delta int) {
+	if m == nil {
+		return
+	}
+	m.activeWorkers.WithLabelValues(container).Add(float64(delta))
+}
+
+// RunFinished records a finished sync run.
+func (m *Metrics) RunFinished(container, status string, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	m.syncRuns.WithLabelValues(container, status).Inc()
+	m.lastRunDuration.WithLabelValues(container).Set(duration.Seconds())
+	m.lastRunTime.WithLabelValues(container).SetToCurrentTime()
+}
+
+// Handler returns an HTTP handler serving the metrics.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Server is an HTTP server running in the background.
+type Server struct {
+	srv  *http.Server
+	addr string
+}
+
+// Serve starts serving m on /metrics at addr, such as ":9090", in the
+// background. It fails if the address cannot be bound.
+func Serve(addr string, m *Metrics) (*Server, error) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m.Handler())
+	return serve(addr, mux)
+}
+
+// serve starts serving handler on addr in the background.
+func serve(addr string, handler http.Handler) (*Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	srv := &http.Server{Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+
+	// Serve only returns once Shutdown is called or the listener fails.
+	go func() { _ = srv.Serve(listener) }()
+	return &Server{srv: srv, addr: listener.Addr().String()}, nil
+}
+
+// Addr returns the address the server is listening on.
+func (s *Server) Addr() string {
+	return s.addr
+}
+
+// Shutdown stops the server, waiting briefly for in-flight requests.
+func (s *Server) Shutdown() error {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return s.srv.Shutdown(ctx)
+}
@@ -0,0 +1,73 @@
+package telemetry
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServe_ExposesMetrics(t *testing.T) {
+	m := New()
+	m.BlobDownloaded("logs", 1024)
+	m.BlobDownloaded("logs", 1024)
+	m.BlobFailed("logs")
+	m.BlobsSkipped("logs", 3)
+	m.WorkerBusy("logs", 1)
+	m.RunFinished("logs", "completed", 90*time.Second)
+
+	srv, err := Serve("127.0.0.1:0", m)
+	if err != nil {
+		t.Fatalf("Serve() error = %v", err)
+	}
+	defer func() { _ = srv.Shutdown() }()
+
+	resp, err := http.Get("http://" + srv.Addr() + "/metrics")
+	if err != nil {
+		t.Fatalf("GET /metrics error = %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read response: %v", err)
+	}
+
+	want := []string{
+		`getblobz_blobs_downloaded_total{container="logs"} 2`,
+		`getblobz_bytes_downloaded_total{container="logs"} 2048`,
+		`getblobz_blobs_failed_total{container="logs"} 1`,
+		`getblobz_blobs_skipped_total{container="logs"} 3`,
+		`getblobz_active_workers{container="logs"} 1`,
+		`getblobz_sync_runs_total{container="logs",status="completed"} 1`,
+		`getblobz_last_run_duration_seconds{container="logs"} 90`,
+		`go_goroutines`,
+	}
+	for _, line := range want {
+		if !strings.Contains(string(body), line) {
+			t.Errorf("metrics output missing %q", line)
+		}
+	}
+}
+
+func TestServer_Shutdown(t *testing.T) {
+	srv, err := Serve("127.0.0.1:0", New())
+	if err != nil {
+		t.Fatalf("Serve() error = %v", err)
+	}
+	if err := srv.Shutdown(); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if _, err := http.Get("http://" + srv.Addr() + "/metrics"); err == nil {
+		t.Errorf("expected scrape after Shutdown to fail")
+	}
+}
+
+func TestMetrics_NilIsNoop(t *testing.T) {
+	var m *Metrics
+	m.BlobDownloaded("logs", 1)
+	m.BlobFailed("logs")
+	m.BlobsSkipped("logs", 1)
+	m.WorkerBusy("logs", 1)
+	m.RunFinished("logs", "completed", time.Second)
+}
@@ -3,15 +3,20 @@
 package logger
 
 import (
+	"errors"
+	"io"
 	"os"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
 )
 
 // Logger wraps zap.SugaredLogger for structured logging.
 type Logger struct {
 	*zap.SugaredLogger
+	// file is the rotating log file, if logging to one.
+	file io.Closer
 }
 
 // Config contains logger configuration options.
@@ -20,6 +25,22 @@ type Config struct {
 	Level string
 	// Format specifies the output format (text, json).
 	Format string
+	// Color controls coloured levels in text output written to Output:
+	// "auto" (or empty) colours them only on a terminal when NO_COLOR is
+	// not set, "always" and "never" force it on or off.
+	Color string
+	// Output is where logs are written (nil = standard output).
+	Output io.Writer
+	// File is a path logs are also written to, rotated by size (empty = Output only).
+	File string
+	// FileOnly stops logs going to Output when File is set.
+	FileOnly bool
+	// MaxSizeMB is the size in megabytes at which File is rotated (0 = 100).
+	MaxSizeMB int
+	// MaxBackups is the number of rotated files to keep (0 = all).
+	MaxBackups int
+	// MaxAgeDays is the number of days to keep rotated files (0 = no limit).
+	MaxAgeDays int
 }
 
 // New creates a new Logger instance with the given configuration.
@@ -30,29 +51,79 @@ func New(cfg Config) (*Logger, error) {
 		level = zapcore.InfoLevel
 	}
 
+	output := cfg.Output
+	if output == nil {
+		output = os.Stdout
+	}
+
+	var cores []zapcore.Core
+	if cfg.File == "" || !cfg.FileOnly {
+		cores = append(cores, zapcore.NewCore(newEncoder(cfg.Format, useColor(cfg.Color, output)), zapcore.AddSync(output), level))
+	}
+
+	var file *lumberjack.Logger
+	if cfg.File != "" {
+		file = &lumberjack.Logger{
+			Filename:   cfg.File,
+			MaxSize:    cfg.MaxSizeMB,
+			MaxBackups: cfg.MaxBackups,
+			MaxAge:     cfg.MaxAgeDays,
+		}
+		cores = append(cores, zapcore.NewCore(newEncoder(cfg.Format, false), zapcore.AddSync(file), level))
+	}
+
+	core := redactCore{zapcore.NewTee(cores...)}
+
+	zapLogger := zap.New(core, zap.AddCaller(), zap.AddStacktrace(zapcore.ErrorLevel))
+	l := &Logger{SugaredLogger: zapLogger.Sugar()}
+	if file != nil {
+		l.file = file
+	}
+	return l, nil
+}
+
+// useColor reports whether text written to output should be coloured under
+// the given color setting.
+func useColor(color string, output io.Writer) bool {
+	switch color {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	// See https://no-color.org: any non-empty value disables colour.
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	f, ok := output.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// newEncoder returns the encoder for format. Text output has coloured levels
+// when color is set.
+func newEncoder(format string, color bool) zapcore.Encoder {
 	encoderConfig := zap.NewProductionEncoderConfig()
 	encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
 	encoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
 
-	var encoder zapcore.Encoder
-	if cfg.Format == "json" {
-		encoder = zapcore.NewJSONEncoder(encoderConfig)
-	} else {
+	if format == "json" {
+		return zapcore.NewJSONEncoder(encoderConfig)
+	}
+	if color {
 		encoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
-		encoder = zapcore.NewConsoleEncoder(encoderConfig)
 	}
-
-	core := zapcore.NewCore(
-		encoder,
-		zapcore.AddSync(os.Stdout),
-		level,
-	)
-
-	zapLogger := zap.New(core, zap.AddCaller(), zap.AddStacktrace(zapcore.ErrorLevel))
-	return &Logger{zapLogger.Sugar()}, nil
+	return zapcore.NewConsoleEncoder(encoderConfig)
 }
 
-// Close flushes any buffered log entries.
+// Close flushes any buffered log entries and closes the log file, if any.
 func (l *Logger) Close() error {
-	return l.Sync()
+	err := l.Sync()
+	if l.file != nil {
+		err = errors.Join(err, l.file.Close())
+	}
+	return err
 }
@@ -0,0 +1,117 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNew_Output(t *testing.T) {
+	var buf bytes.Buffer
+	log, err := New(Config{Level: "warn", Format: "json", Output: &buf})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	log.Infow("Below the level", "blob", "a.txt")
+	log.Warnw("Failed to get blob state", "blob", "b.txt")
+	if err := log.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("logged %d lines, want 1: %q", len(lines), buf.String())
+	}
+	var entry map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatalf("failed to parse log line %q: %v", lines[0], err)
+	}
+	if entry["level"] != "WARN" || entry["msg"] != "Failed to get blob state" || entry["blob"] != "b.txt" {
+		t.Errorf("unexpected log entry: %v", entry)
+	}
+}
+
+func TestNew_Color(t *testing.T) {
+	tests := []struct {
+		name    string
+		color   string
+		noColor string
+		want    bool
+	}{
+		{"never", "never", "", false},
+		{"auto without a terminal", "auto", "", false},
+		{"auto with NO_COLOR", "", "1", false},
+		{"always", "always", "", true},
+		{"always overrides NO_COLOR", "always", "1", true},
+	}
+
+	for _, tt := range tests {
+		t.Setenv("NO_COLOR", tt.noColor)
+
+		var buf bytes.Buffer
+		log, err := New(Config{Level: "info", Format: "text", Color: tt.color, Output: &buf})
+		if err != nil {
+			t.Fatalf("%s: New() error = %v", tt.name, err)
+		}
+		log.Warnw("Disk usage high", "percent", 91)
+		if err := log.Close(); err != nil {
+			t.Fatalf("%s: Close() error = %v", tt.name, err)
+		}
+
+		if got := strings.Contains(buf.String(), "\x1b["); got != tt.want {
+			t.Errorf("%s: ANSI codes in output = %v, want %v: %q", tt.name, got, tt.want, buf.String())
+		}
+		if !strings.Contains(buf.String(), "WARN") {
+			t.Errorf("%s: level missing from output: %q", tt.name, buf.String())
+		}
+	}
+}
+
+func TestNew_FileRotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "getblobz.log")
+
+	log, err := New(Config{
+		Level:      "info",
+		Format:     "json",
+		File:       path,
+		FileOnly:   true,
+		MaxSizeMB:  1,
+		MaxBackups: 2,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	// Well over 1 MB of entries, so the file is rotated at least once.
+	padding := strings.Repeat("x", 200)
+	for i := 0; i < 8000; i++ {
+		log.Infow("Downloaded blob", "n", i, "padding", padding)
+	}
+	if err := log.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to read log directory: %v", err)
+	}
+	if len(entries) < 2 {
+		t.Fatalf("log files = %d, want the current file and at least one backup", len(entries))
+	}
+	if len(entries) > 3 {
+		t.Errorf("log files = %d, want at most the current file and 2 backups", len(entries))
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("failed to stat current log file: %v", err)
+	}
+	if info.Size() == 0 || info.Size() > 1024*1024 {
+		t.Errorf("current log file size = %d, want between 1 byte and 1 MB", info.Size())
+	}
+}
@@ -0,0 +1,82 @@
+// Package logger provides masking of secret values in log fields.
+package logger
+
+import (
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// redacted replaces the value of a field whose key names a secret.
+const redacted = "***"
+
+// secretKeyParts are the key fragments that mark a field as secret. Keys are
+// compared in lower case with underscores and dashes removed.
+var secretKeyParts = []string{
+	"secret",
+	"password",
+	"accountkey",
+	"connectionstring",
+	"sastoken",
+	"credential",
+}
+
+// isSecretKey reports whether a field keyed key holds a secret.
+func isSecretKey(key string) bool {
+	key = strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(key))
+	if key == "sas" || key == "sig" {
+		return true
+	}
+	for _, part := range secretKeyParts {
+		if strings.Contains(key, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactCore masks secret fields before they reach the wrapped core, so a
+// credential passed to the logger by mistake is never written out.
+type redactCore struct {
+	zapcore.Core
+}
+
+// With masks secret fields added to the logger's context.
+func (c redactCore) With(fields []zapcore.Field) zapcore.Core {
+	return redactCore{c.Core.With(redactFields(fields))}
+}
+
+// Check adds this core, rather than the wrapped one, to the checked entry so
+// that Write is routed through it.
+func (c redactCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+// Write masks secret fields of the entry.
+func (c redactCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(entry, redactFields(fields))
+}
+
+// redactFields returns fields with the values of secret fields masked. The
+// caller's slice is left untouched.
+func redactFields(fields []zapcore.Field) []zapcore.Field {
+	var out []zapcore.Field
+	for i, field := range fields {
+		if !isSecretKey(field.Key) {
+			continue
+		}
+		if out == nil {
+			out = make([]zapcore.Field, len(fields))
+			copy(out, fields)
+		}
+		out[i] = zap.String(field.Key, redacted)
+	}
+	if out == nil {
+		return fields
+	}
+	return out
+}
@@ -0,0 +1,70 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestIsSecretKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"account_key", true},
+		{"AccountKey", true},
+		{"client_secret", true},
+		{"connection-string", true},
+		{"proxy_password", true},
+		{"sas", true},
+		{"sas_token", true},
+		{"blob", false},
+		{"path", false},
+		{"error", false},
+		{"container", false},
+	}
+
+	for _, tt := range tests {
+		if got := isSecretKey(tt.key); got != tt.want {
+			t.Errorf("isSecretKey(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestRedactCore_SecretsNeverLogged(t *testing.T) {
+	var buf bytes.Buffer
+	core := zapcore.NewCore(
+		zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()),
+		zapcore.AddSync(&buf),
+		zapcore.DebugLevel,
+	)
+	log := zap.New(redactCore{core}).Sugar()
+
+	secrets := []string{"key-value-1", "secret-value-2", "AccountKey=conn-value-3", "sig=sas-value-4"}
+	log.With("client_secret", secrets[1]).Infow("Connecting",
+		"account_key", secrets[0],
+		"connection_string", secrets[2],
+		"sas_token", secrets[3],
+		"container", "backups",
+	)
+	log.Debugw("Retrying", "AccountKey", []byte(secrets[0]))
+	if err := log.Sync(); err != nil {
+		t.Fatalf("failed to flush logger: %v", err)
+	}
+
+	out := buf.String()
+	for _, secret := range secrets {
+		if strings.Contains(out, secret) {
+			t.Errorf("log output contains secret %q: %s", secret, out)
+		}
+	}
+	if !strings.Contains(out, `"account_key":"***"`) {
+		t.Errorf("expected masked account key in log output: %s", out)
+	}
+	if !strings.Contains(out, `"container":"backups"`) {
+		t.Errorf("expected non-secret field in log output: %s", out)
+	}
+}
@@ -11,9 +11,8 @@ import (
 	"testing"
 	"time"
 
-	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
-	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
 	"github.com/haepapa/getblobz/internal/azure"
+	"github.com/haepapa/getblobz/internal/config"
 )
 
 // getAzuriteConnString returns the Azurite connection string, defaulting to local emulator.
@@ -27,23 +26,12 @@ func getAzuriteConnString() string {
 func TestAzureClient_ListAndDownload_WithAzurite(t *testing.T) {
 	ctx := context.Background()
 
-	// Use shared key credential with HTTP endpoint
-	accountName := "devstoreaccount1"
-	accountKey := "Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw=="
-	serviceURL := "http://127.0.0.1:10000/devstoreaccount1"
-
-	cred, err := azblob.NewSharedKeyCredential(accountName, accountKey)
-	if err != nil {
-		t.Fatalf("failed to create credential: %v", err)
-	}
-
-	// Create client with InsecureAllowCredentialWithHTTP enabled for Azurite
-	clientOpts := &azblob.ClientOptions{
-		ClientOptions: azcore.ClientOptions{
-			InsecureAllowCredentialWithHTTP: true,
-		},
-	}
-	sdkClient, err := azblob.NewClientWithSharedKeyCredential(serviceURL, cred, clientOpts)
+	// Point the shared key path at the Azurite endpoint
+	sdkClient, err := azure.CreateClient(&config.AzureConfig{
+		AccountName:  "devstoreaccount1",
+		AccountKey:   "Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw==",
+		BlobEndpoint: "http://127.0.0.1:10000/devstoreaccount1",
+	})
 	if err != nil {
 		t.Fatalf("failed to create azblob client: %v", err)
 	}
@@ -71,7 +59,7 @@ func TestAzureClient_ListAndDownload_WithAzurite(t *testing.T) {
 	}
 
 	// List via wrapper
-	blobs, _, err := c.ListBlobs(ctx, containerName, "", 100)
+	blobs, _, err := c.ListBlobs(ctx, containerName, "", nil, 100)
 	if err != nil {
 		t.Fatalf("ListBlobs error: %v", err)
 	}